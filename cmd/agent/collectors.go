@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/maynagashev/go-metrics/internal/agent/collector"
+)
+
+// buildRegistry формирует набор коллекторов агента с учетом настроек
+// и списков включения/отключения коллекторов.
+func buildRegistry(flags Flags) (*collector.Registry, error) {
+	for _, f := range []collector.Filter{flags.Devices, flags.Mounts} {
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	registry, err := collector.NewRegistry(
+		collector.NewRuntime(),
+		collector.NewSystem(),
+		collector.NewLoad(),
+		collector.NewSwap(),
+		collector.NewFilesystem(flags.Mounts),
+		collector.NewDiskIO(flags.Devices),
+		collector.NewNet(flags.Devices),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Коллектор процессов добавляется, только если заданы отслеживаемые процессы
+	if len(flags.Processes) > 0 {
+		targets := make([]collector.ProcessTarget, 0, len(flags.Processes))
+		for _, spec := range flags.Processes {
+			target, err := collector.ParseProcessTarget(spec)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, target)
+		}
+		if err = registry.Register(collector.NewProcess(targets)); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry.Filter(flags.Collectors.Enabled, flags.Collectors.Disabled)
+}
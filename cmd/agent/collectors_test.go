@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildRegistry(t *testing.T) {
+	t.Run("default collectors", func(t *testing.T) {
+		registry, err := buildRegistry(Flags{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"runtime", "system", "load", "swap", "filesystem", "diskio", "net"}, registry.Names())
+	})
+
+	t.Run("process collector enabled by targets", func(t *testing.T) {
+		flags := Flags{Processes: []string{"name:nginx", "cmdline:java"}}
+		flags.Collectors.Enabled = []string{"runtime", "system", "process"}
+		flags.Collectors.Disabled = []string{"system"}
+		registry, err := buildRegistry(flags)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"runtime", "process"}, registry.Names())
+	})
+
+	t.Run("invalid process target", func(t *testing.T) {
+		_, err := buildRegistry(Flags{Processes: []string{"cmdline:("}})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid filter pattern", func(t *testing.T) {
+		flags := Flags{}
+		flags.Mounts.Exclude = []string{"[invalid"}
+		_, err := buildRegistry(flags)
+		require.Error(t, err)
+	})
+
+	t.Run("unknown collector", func(t *testing.T) {
+		flags := Flags{}
+		flags.Collectors.Enabled = []string{"process"}
+		_, err := buildRegistry(flags)
+		require.Error(t, err)
+	})
+}
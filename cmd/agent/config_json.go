@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
+
+	"github.com/maynagashev/go-metrics/internal/agent"
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
 )
 
 // ErrConfigFileNotSpecified возвращается, когда путь к файлу конфигурации не указан.
@@ -13,13 +17,42 @@ var ErrConfigFileNotSpecified = errors.New("config file path not specified")
 
 // JSONConfig представляет структуру конфигурационного файла агента в формате JSON.
 type JSONConfig struct {
-	Address        string `json:"address"`         // Адрес и порт сервера
+	Address string `json:"address"` // Адрес и порт сервера
+	// Адреса нескольких серверов, используются вместо address
+	Addresses []string `json:"addresses"`
+	// Режим отправки на несколько серверов: "failover" или "fanout"
+	EndpointMode string `json:"endpoint_mode"`
+	// Протокол отправки метрик: "http" или "grpc"
+	Transport string `json:"transport"`
+	// Интервал проверки доступности приоритетных серверов в виде строки (например, "10s")
+	ProbeInterval  string `json:"probe_interval"`
 	ReportInterval string `json:"report_interval"` // Интервал отправки метрик в виде строки (например, "1s")
 	PollInterval   string `json:"poll_interval"`   // Интервал сбора метрик в виде строки (например, "1s")
 	CryptoKey      string `json:"crypto_key"`      // Путь к файлу с публичным ключом для шифрования
 	RateLimit      int    `json:"rate_limit"`      // Максимальное количество одновременно исходящих запросов
 	EnablePprof    bool   `json:"enable_pprof"`    // Включить профилирование через pprof
 	PprofPort      string `json:"pprof_port"`      // Порт для pprof сервера
+	SpoolDir       string `json:"spool_dir"`       // Директория дисковой очереди метрик
+	SpoolMaxSize   int64  `json:"spool_max_size"`  // Максимальный размер дисковой очереди в байтах
+	// Политика повторной отправки: количество попыток, задержки в виде строки (например, "1s") и разброс
+	RetryMaxAttempts int     `json:"retry_max_attempts"`
+	RetryBaseDelay   string  `json:"retry_base_delay"`
+	RetryMaxDelay    string  `json:"retry_max_delay"`
+	RetryJitter      float64 `json:"retry_jitter"`
+	// Автоматический выключатель: количество ошибок подряд и время до пробного запроса
+	BreakerThreshold   int    `json:"breaker_threshold"`
+	BreakerOpenTimeout string `json:"breaker_open_timeout"`
+	// Включенные коллекторы метрик, пустой список включает все
+	Collectors []string `json:"collectors"`
+	// Отключенные коллекторы метрик
+	DisabledCollectors []string `json:"disabled_collectors"`
+	// Отслеживаемые процессы в формате "pidfile:/путь", "name:имя" или "cmdline:регулярное выражение"
+	Processes []string `json:"processes"`
+	// Фильтры дисковых устройств, сетевых интерфейсов и точек монтирования
+	DevicesInclude []string `json:"devices_include"`
+	DevicesExclude []string `json:"devices_exclude"`
+	MountsInclude  []string `json:"mounts_include"`
+	MountsExclude  []string `json:"mounts_exclude"`
 }
 
 // LoadJSONConfig загружает конфигурацию из JSON-файла.
@@ -57,6 +90,28 @@ func ApplyJSONConfig(flags *Flags, jsonConfig *JSONConfig) error {
 	if flags.Server.Addr == defaultAgentServerAddr && jsonConfig.Address != "" {
 		flags.Server.Addr = jsonConfig.Address
 	}
+	if flags.Server.Addr == defaultAgentServerAddr && len(jsonConfig.Addresses) > 0 {
+		flags.Server.Addr = strings.Join(jsonConfig.Addresses, ",")
+	}
+
+	// Режим отправки на несколько серверов
+	if flags.EndpointMode == string(agent.ModeFailover) && jsonConfig.EndpointMode != "" {
+		flags.EndpointMode = jsonConfig.EndpointMode
+	}
+
+	// Протокол отправки метрик
+	if flags.Transport == string(agent.TransportHTTP) && jsonConfig.Transport != "" {
+		flags.Transport = jsonConfig.Transport
+	}
+
+	// Интервал проверки доступности серверов
+	if flags.ProbeInterval == agent.DefaultProbeInterval && jsonConfig.ProbeInterval != "" {
+		duration, err := time.ParseDuration(jsonConfig.ProbeInterval)
+		if err != nil {
+			return fmt.Errorf("invalid probe_interval in config: %w", err)
+		}
+		flags.ProbeInterval = duration
+	}
 
 	// Интервал отправки метрик
 	if flags.Server.ReportInterval == defaultReportInterval && jsonConfig.ReportInterval != "" {
@@ -96,5 +151,85 @@ func ApplyJSONConfig(flags *Flags, jsonConfig *JSONConfig) error {
 		flags.PprofPort = jsonConfig.PprofPort
 	}
 
+	// Директория дисковой очереди метрик
+	if flags.Spool.Dir == "" && jsonConfig.SpoolDir != "" {
+		flags.Spool.Dir = jsonConfig.SpoolDir
+	}
+
+	// Максимальный размер дисковой очереди
+	if flags.Spool.MaxSize == defaultSpoolMaxSize && jsonConfig.SpoolMaxSize > 0 {
+		flags.Spool.MaxSize = jsonConfig.SpoolMaxSize
+	}
+
+	// Политика повторной отправки и выключатель
+	if err := applyRetryJSONConfig(flags, jsonConfig); err != nil {
+		return err
+	}
+
+	// Включенные коллекторы метрик
+	if len(flags.Collectors.Enabled) == 0 && len(jsonConfig.Collectors) > 0 {
+		flags.Collectors.Enabled = jsonConfig.Collectors
+	}
+
+	// Отключенные коллекторы метрик
+	if len(flags.Collectors.Disabled) == 0 && len(jsonConfig.DisabledCollectors) > 0 {
+		flags.Collectors.Disabled = jsonConfig.DisabledCollectors
+	}
+
+	// Отслеживаемые процессы
+	if len(flags.Processes) == 0 && len(jsonConfig.Processes) > 0 {
+		flags.Processes = jsonConfig.Processes
+	}
+
+	// Фильтры устройств и точек монтирования
+	for _, f := range []struct {
+		target *[]string
+		value  []string
+	}{
+		{&flags.Devices.Include, jsonConfig.DevicesInclude},
+		{&flags.Devices.Exclude, jsonConfig.DevicesExclude},
+		{&flags.Mounts.Include, jsonConfig.MountsInclude},
+		{&flags.Mounts.Exclude, jsonConfig.MountsExclude},
+	} {
+		if len(*f.target) == 0 && len(f.value) > 0 {
+			*f.target = f.value
+		}
+	}
+
+	return nil
+}
+
+// applyRetryJSONConfig применяет настройки политики повторов и выключателя из JSON-конфигурации.
+func applyRetryJSONConfig(flags *Flags, jsonConfig *JSONConfig) error {
+	if flags.Retry.MaxAttempts == retry.DefaultMaxAttempts && jsonConfig.RetryMaxAttempts > 0 {
+		flags.Retry.MaxAttempts = jsonConfig.RetryMaxAttempts
+	}
+	if flags.Retry.Jitter == retry.DefaultJitter && jsonConfig.RetryJitter > 0 {
+		flags.Retry.Jitter = jsonConfig.RetryJitter
+	}
+	if flags.Retry.BreakerThreshold == retry.DefaultBreakerThreshold && jsonConfig.BreakerThreshold > 0 {
+		flags.Retry.BreakerThreshold = jsonConfig.BreakerThreshold
+	}
+
+	for _, d := range []struct {
+		name   string
+		target *time.Duration
+		def    time.Duration
+		value  string
+	}{
+		{"retry_base_delay", &flags.Retry.BaseDelay, retry.DefaultBaseDelay, jsonConfig.RetryBaseDelay},
+		{"retry_max_delay", &flags.Retry.MaxDelay, retry.DefaultMaxDelay, jsonConfig.RetryMaxDelay},
+		{"breaker_open_timeout", &flags.Retry.BreakerOpenTimeout, retry.DefaultBreakerOpenTimeout, jsonConfig.BreakerOpenTimeout},
+	} {
+		if *d.target != d.def || d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s in config: %w", d.name, err)
+		}
+		*d.target = duration
+	}
+
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -22,7 +23,12 @@ func TestLoadJSONConfig(t *testing.T) {
 		"crypto_key": "/tmp/test-key.pem",
 		"rate_limit": 5,
 		"enable_pprof": true,
-		"pprof_port": "7070"
+		"pprof_port": "7070",
+		"spool_dir": "/tmp/agent-spool",
+		"spool_max_size": 1024,
+		"collectors": ["runtime", "system"],
+		"disabled_collectors": ["system"],
+		"processes": ["name:nginx", "pidfile:/run/app.pid"]
 	}`
 
 	err := os.WriteFile(configPath, []byte(configContent), 0o600)
@@ -39,6 +45,11 @@ func TestLoadJSONConfig(t *testing.T) {
 	assert.Equal(t, 5, config.RateLimit)
 	assert.True(t, config.EnablePprof)
 	assert.Equal(t, "7070", config.PprofPort)
+	assert.Equal(t, "/tmp/agent-spool", config.SpoolDir)
+	assert.Equal(t, int64(1024), config.SpoolMaxSize)
+	assert.Equal(t, []string{"runtime", "system"}, config.Collectors)
+	assert.Equal(t, []string{"system"}, config.DisabledCollectors)
+	assert.Equal(t, []string{"name:nginx", "pidfile:/run/app.pid"}, config.Processes)
 
 	// Тест 2: Пустой путь к файлу
 	config, err = LoadJSONConfig("")
@@ -70,6 +81,8 @@ func TestApplyJSONConfig(t *testing.T) {
 	flags.RateLimit = defaultRateLimit
 	flags.EnablePprof = false
 	flags.PprofPort = defaultPprofPort
+	flags.Spool.MaxSize = defaultSpoolMaxSize
+	flags.Transport = "http"
 
 	jsonConfig := &JSONConfig{
 		Address:        "localhost:9090",
@@ -79,6 +92,10 @@ func TestApplyJSONConfig(t *testing.T) {
 		RateLimit:      5,
 		EnablePprof:    true,
 		PprofPort:      "7070",
+		SpoolDir:       "/tmp/agent-spool",
+		SpoolMaxSize:   1024,
+		Collectors:     []string{"runtime"},
+		Transport:      "grpc",
 	}
 
 	err := ApplyJSONConfig(flags, jsonConfig)
@@ -91,6 +108,11 @@ func TestApplyJSONConfig(t *testing.T) {
 	assert.Equal(t, 5, flags.RateLimit)
 	assert.True(t, flags.EnablePprof)
 	assert.Equal(t, "7070", flags.PprofPort)
+	assert.Equal(t, "/tmp/agent-spool", flags.Spool.Dir)
+	assert.Equal(t, int64(1024), flags.Spool.MaxSize)
+	assert.Equal(t, []string{"runtime"}, flags.Collectors.Enabled)
+	assert.Empty(t, flags.Collectors.Disabled)
+	assert.Equal(t, "grpc", flags.Transport)
 
 	// Тест 2: Применение nil конфигурации
 	flags = &Flags{}
@@ -108,3 +130,31 @@ func TestApplyJSONConfig(t *testing.T) {
 	err = ApplyJSONConfig(flags, jsonConfig)
 	require.Error(t, err)
 }
+
+func TestApplyJSONConfig_Retry(t *testing.T) {
+	flags := &Flags{}
+	flags.Retry = defaultRetry()
+	flags.Retry.MaxAttempts = 2 // задано флагом, JSON не применяется
+
+	jsonConfig := &JSONConfig{
+		RetryMaxAttempts:   10,
+		RetryBaseDelay:     "200ms",
+		RetryMaxDelay:      "1m",
+		RetryJitter:        0.1,
+		BreakerThreshold:   8,
+		BreakerOpenTimeout: "5m",
+	}
+	require.NoError(t, ApplyJSONConfig(flags, jsonConfig))
+
+	assert.Equal(t, 2, flags.Retry.MaxAttempts)
+	assert.Equal(t, 200*time.Millisecond, flags.Retry.BaseDelay)
+	assert.Equal(t, time.Minute, flags.Retry.MaxDelay)
+	assert.InEpsilon(t, 0.1, flags.Retry.Jitter, 0.001)
+	assert.Equal(t, 8, flags.Retry.BreakerThreshold)
+	assert.Equal(t, 5*time.Minute, flags.Retry.BreakerOpenTimeout)
+
+	// Некорректный формат задержки
+	flags = &Flags{}
+	flags.Retry = defaultRetry()
+	require.Error(t, ApplyJSONConfig(flags, &JSONConfig{RetryMaxDelay: "invalid"}))
+}
@@ -6,6 +6,12 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maynagashev/go-metrics/internal/agent"
+	"github.com/maynagashev/go-metrics/internal/agent/collector"
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
 )
 
 const (
@@ -15,6 +21,7 @@ const (
 	minInterval            = 0.000001 // Минимально допустимый интервал в секундах.
 	defaultPprofPort       = "6060"
 	defaultAgentServerAddr = "localhost:8080"
+	defaultSpoolMaxSize    = 64 * 1024 * 1024 // 64 МБ
 )
 
 // Flags содержит флаги агента.
@@ -30,6 +37,34 @@ type Flags struct {
 	EnablePprof bool   // добавляем поле для профилирования
 	PprofPort   string // добавляем порт для pprof
 	ConfigFile  string // путь к файлу конфигурации в формате JSON
+	Spool       struct {
+		Dir     string // директория дисковой очереди, пустая строка отключает очередь
+		MaxSize int64  // максимальный размер дисковой очереди в байтах
+	}
+	// Режим отправки на несколько серверов, перечисленных через запятую в Server.Addr: failover или fanout
+	EndpointMode string
+	// Интервал проверки доступности приоритетных серверов в режиме failover
+	ProbeInterval time.Duration
+	// Протокол отправки метрик: http или grpc
+	Transport string
+	Retry     struct {
+		MaxAttempts        int           // максимальное количество попыток отправки пакета, включая первую
+		BaseDelay          time.Duration // задержка перед первым повтором
+		MaxDelay           time.Duration // максимальная задержка между попытками
+		Jitter             float64       // доля случайного разброса задержки от 0 до 1
+		BreakerThreshold   int           // количество ошибок подряд для размыкания выключателя, 0 отключает его
+		BreakerOpenTimeout time.Duration // время до пробного запроса после размыкания выключателя
+	}
+	Collectors struct {
+		Enabled  []string // включенные коллекторы, пустой список включает все
+		Disabled []string // отключенные коллекторы
+	}
+	// Отслеживаемые процессы: "pidfile:/путь", "name:имя" или "cmdline:регулярное выражение"
+	Processes []string
+	// Фильтр дисковых устройств и сетевых интерфейсов для коллекторов diskio и net
+	Devices collector.Filter
+	// Фильтр точек монтирования для коллектора filesystem
+	Mounts collector.Filter
 }
 
 // mustParseFlags обрабатывает аргументы командной строки
@@ -61,7 +96,7 @@ func registerCommandLineFlags(flags *Flags) {
 		&flags.Server.Addr,
 		"a",
 		defaultAgentServerAddr,
-		"address and port of the server send metrics to",
+		"address and port of the server send metrics to (several servers separated by commas)",
 	)
 	flag.Float64Var(
 		&flags.Server.ReportInterval,
@@ -91,6 +126,112 @@ func registerCommandLineFlags(flags *Flags) {
 	flag.BoolVar(&flags.EnablePprof, "pprof", false, "enable pprof profiling")
 	flag.StringVar(&flags.PprofPort, "pprof-port", defaultPprofPort, "port for pprof server")
 
+	flag.StringVar(
+		&flags.Spool.Dir,
+		"spool-dir",
+		"",
+		"директория для дисковой очереди метрик (если не указана, очередь хранится только в памяти)",
+	)
+	flag.Int64Var(
+		&flags.Spool.MaxSize,
+		"spool-max-size",
+		defaultSpoolMaxSize,
+		"максимальный размер дисковой очереди в байтах, при превышении удаляются самые старые пакеты",
+	)
+
+	flag.StringVar(
+		&flags.EndpointMode,
+		"endpoint-mode",
+		string(agent.ModeFailover),
+		"режим отправки на несколько серверов: failover (активный сервер с переключением на резервный) "+
+			"или fanout (отправка на все серверы)",
+	)
+	flag.DurationVar(
+		&flags.ProbeInterval,
+		"probe-interval",
+		agent.DefaultProbeInterval,
+		"интервал проверки доступности приоритетных серверов в режиме failover (0 отключает проверку)",
+	)
+
+	flag.StringVar(
+		&flags.Transport,
+		"transport",
+		string(agent.TransportHTTP),
+		"протокол отправки метрик: http (JSON API) или grpc (gRPC API, адреса серверов указывают на gRPC-порт)",
+	)
+
+	flag.IntVar(
+		&flags.Retry.MaxAttempts,
+		"retry-max-attempts",
+		retry.DefaultMaxAttempts,
+		"максимальное количество попыток отправки пакета метрик, включая первую",
+	)
+	flag.DurationVar(
+		&flags.Retry.BaseDelay,
+		"retry-base-delay",
+		retry.DefaultBaseDelay,
+		"задержка перед первым повтором отправки, удваивается с каждой попыткой",
+	)
+	flag.DurationVar(
+		&flags.Retry.MaxDelay,
+		"retry-max-delay",
+		retry.DefaultMaxDelay,
+		"максимальная задержка между попытками отправки, в том числе из заголовка Retry-After",
+	)
+	flag.Float64Var(
+		&flags.Retry.Jitter,
+		"retry-jitter",
+		retry.DefaultJitter,
+		"доля случайного разброса задержки между попытками от 0 до 1",
+	)
+	flag.IntVar(
+		&flags.Retry.BreakerThreshold,
+		"breaker-threshold",
+		retry.DefaultBreakerThreshold,
+		"количество неудачных отправок подряд, после которого запросы к серверу приостанавливаются (0 отключает)",
+	)
+	flag.DurationVar(
+		&flags.Retry.BreakerOpenTimeout,
+		"breaker-open-timeout",
+		retry.DefaultBreakerOpenTimeout,
+		"время, через которое после приостановки отправляется пробный запрос к серверу",
+	)
+
+	flag.Func(
+		"collectors",
+		"список включенных коллекторов метрик через запятую (по умолчанию все)",
+		func(v string) error {
+			flags.Collectors.Enabled = splitList(v)
+			return nil
+		},
+	)
+	flag.Func(
+		"disable-collectors",
+		"список отключенных коллекторов метрик через запятую",
+		func(v string) error {
+			flags.Collectors.Disabled = splitList(v)
+			return nil
+		},
+	)
+
+	flag.Func(
+		"process",
+		"отслеживаемый процесс: pidfile:/путь, name:имя или cmdline:регулярное выражение (можно указать несколько раз)",
+		func(v string) error {
+			flags.Processes = append(flags.Processes, v)
+			return nil
+		},
+	)
+
+	registerListFlag(&flags.Devices.Include, "devices-include",
+		"шаблоны разрешенных дисковых устройств и сетевых интерфейсов через запятую, например: sd*,eth*")
+	registerListFlag(&flags.Devices.Exclude, "devices-exclude",
+		"шаблоны исключаемых дисковых устройств и сетевых интерфейсов через запятую, например: loop*,lo")
+	registerListFlag(&flags.Mounts.Include, "mounts-include",
+		"шаблоны разрешенных точек монтирования через запятую, например: /,/home")
+	registerListFlag(&flags.Mounts.Exclude, "mounts-exclude",
+		"шаблоны исключаемых точек монтирования через запятую, например: /snap/*")
+
 	// Добавляем флаг для пути к файлу конфигурации
 	flag.StringVar(&flags.ConfigFile, "c", "", "путь к файлу конфигурации в формате JSON")
 	flag.StringVar(&flags.ConfigFile, "config", "", "путь к файлу конфигурации в формате JSON")
@@ -131,12 +272,97 @@ func applyEnvironmentVariables(flags *Flags) {
 		flags.RateLimit = l
 	}
 
+	if envSpoolDir, ok := os.LookupEnv("SPOOL_DIR"); ok {
+		flags.Spool.Dir = envSpoolDir
+	}
+	if envSpoolMaxSize, ok := os.LookupEnv("SPOOL_MAX_SIZE"); ok {
+		size, err := strconv.ParseInt(envSpoolMaxSize, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("error parsing env SPOOL_MAX_SIZE %s", err))
+		}
+		flags.Spool.MaxSize = size
+	}
+
+	if envEndpointMode, ok := os.LookupEnv("ENDPOINT_MODE"); ok {
+		flags.EndpointMode = envEndpointMode
+	}
+	if envProbeInterval, ok := os.LookupEnv("PROBE_INTERVAL"); ok {
+		d, err := time.ParseDuration(envProbeInterval)
+		if err != nil {
+			panic(fmt.Sprintf("error parsing env PROBE_INTERVAL %s", err))
+		}
+		flags.ProbeInterval = d
+	}
+
+	if envTransport, ok := os.LookupEnv("TRANSPORT"); ok {
+		flags.Transport = envTransport
+	}
+
+	applyRetryEnvironmentVariables(flags)
+
+	if envCollectors, ok := os.LookupEnv("COLLECTORS"); ok {
+		flags.Collectors.Enabled = splitList(envCollectors)
+	}
+	if envDisabledCollectors, ok := os.LookupEnv("DISABLE_COLLECTORS"); ok {
+		flags.Collectors.Disabled = splitList(envDisabledCollectors)
+	}
+
+	if envProcesses, ok := os.LookupEnv("PROCESSES"); ok {
+		flags.Processes = splitList(envProcesses)
+	}
+	for env, target := range map[string]*[]string{
+		"DEVICES_INCLUDE": &flags.Devices.Include,
+		"DEVICES_EXCLUDE": &flags.Devices.Exclude,
+		"MOUNTS_INCLUDE":  &flags.Mounts.Include,
+		"MOUNTS_EXCLUDE":  &flags.Mounts.Exclude,
+	} {
+		if v, ok := os.LookupEnv(env); ok {
+			*target = splitList(v)
+		}
+	}
+
 	// Если передан путь к файлу конфигурации в параметрах окружения, используем его
 	if envConfigFile, ok := os.LookupEnv("CONFIG"); ok {
 		flags.ConfigFile = envConfigFile
 	}
 }
 
+// applyRetryEnvironmentVariables применяет переменные окружения политики повторов.
+func applyRetryEnvironmentVariables(flags *Flags) {
+	for env, target := range map[string]*int{
+		"RETRY_MAX_ATTEMPTS": &flags.Retry.MaxAttempts,
+		"BREAKER_THRESHOLD":  &flags.Retry.BreakerThreshold,
+	} {
+		if v, ok := os.LookupEnv(env); ok {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				panic(fmt.Sprintf("error parsing env %s %s", env, err))
+			}
+			*target = i
+		}
+	}
+	for env, target := range map[string]*time.Duration{
+		"RETRY_BASE_DELAY":     &flags.Retry.BaseDelay,
+		"RETRY_MAX_DELAY":      &flags.Retry.MaxDelay,
+		"BREAKER_OPEN_TIMEOUT": &flags.Retry.BreakerOpenTimeout,
+	} {
+		if v, ok := os.LookupEnv(env); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				panic(fmt.Sprintf("error parsing env %s %s", env, err))
+			}
+			*target = d
+		}
+	}
+	if envJitter, ok := os.LookupEnv("RETRY_JITTER"); ok {
+		j, err := strconv.ParseFloat(envJitter, 64)
+		if err != nil {
+			panic(fmt.Sprintf("error parsing env RETRY_JITTER %s", err))
+		}
+		flags.Retry.Jitter = j
+	}
+}
+
 // applyJSONConfig загружает и применяет JSON-конфигурацию.
 func applyJSONConfig(flags *Flags) {
 	// Загружаем конфигурацию из JSON-файла, если он указан
@@ -161,6 +387,18 @@ func validateFlags(flags *Flags) {
 	if flags.RateLimit < 1 {
 		panic("RateLimit should be greater than 0")
 	}
+	if err := retryPolicy(flags).Validate(); err != nil {
+		panic(err.Error())
+	}
+	if _, err := agent.ParseEndpointMode(flags.EndpointMode); err != nil {
+		panic(err.Error())
+	}
+	if _, err := agent.ParseTransport(flags.Transport); err != nil {
+		panic(err.Error())
+	}
+	if len(splitList(flags.Server.Addr)) == 0 {
+		panic("server address should not be empty")
+	}
 
 	// Устанавливаем минимальные допустимые значения для интервалов
 	if flags.Server.ReportInterval < minInterval {
@@ -170,3 +408,32 @@ func validateFlags(flags *Flags) {
 		flags.Server.PollInterval = minInterval
 	}
 }
+
+// retryPolicy возвращает политику повторной отправки, заданную флагами.
+func retryPolicy(flags *Flags) retry.Policy {
+	return retry.Policy{
+		MaxAttempts: flags.Retry.MaxAttempts,
+		BaseDelay:   flags.Retry.BaseDelay,
+		MaxDelay:    flags.Retry.MaxDelay,
+		Jitter:      flags.Retry.Jitter,
+	}
+}
+
+// registerListFlag регистрирует флаг со списком значений через запятую.
+func registerListFlag(target *[]string, name, usage string) {
+	flag.Func(name, usage, func(v string) error {
+		*target = splitList(v)
+		return nil
+	})
+}
+
+// splitList разбирает список значений, разделенных запятыми, пропуская пустые элементы.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -4,8 +4,12 @@ import (
 	"flag"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/maynagashev/go-metrics/internal/agent"
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
 )
 
 func TestMustParseFlags(t *testing.T) {
@@ -37,9 +41,14 @@ func TestMustParseFlags(t *testing.T) {
 					ReportInterval: 10.0,
 					PollInterval:   2.0,
 				},
-				RateLimit:   3,
-				EnablePprof: false,
-				PprofPort:   "6060",
+				RateLimit:     3,
+				EnablePprof:   false,
+				PprofPort:     "6060",
+				Spool:         defaultSpool(),
+				Retry:         defaultRetry(),
+				EndpointMode:  "failover",
+				Transport:     "http",
+				ProbeInterval: agent.DefaultProbeInterval,
 			},
 		},
 		{
@@ -63,9 +72,14 @@ func TestMustParseFlags(t *testing.T) {
 					ReportInterval: 5.0,
 					PollInterval:   1.0,
 				},
-				RateLimit:   5,
-				EnablePprof: true,
-				PprofPort:   "6061",
+				RateLimit:     5,
+				EnablePprof:   true,
+				PprofPort:     "6061",
+				Spool:         defaultSpool(),
+				Retry:         defaultRetry(),
+				EndpointMode:  "failover",
+				Transport:     "http",
+				ProbeInterval: agent.DefaultProbeInterval,
 			},
 		},
 		{
@@ -88,11 +102,159 @@ func TestMustParseFlags(t *testing.T) {
 					ReportInterval: 15.0,
 					PollInterval:   3.0,
 				},
-				PrivateKey:  "test-key",
-				RateLimit:   10,
-				EnablePprof: false,
-				PprofPort:   "6060",
+				PrivateKey:    "test-key",
+				RateLimit:     10,
+				EnablePprof:   false,
+				PprofPort:     "6060",
+				Spool:         defaultSpool(),
+				Retry:         defaultRetry(),
+				EndpointMode:  "failover",
+				Transport:     "http",
+				ProbeInterval: agent.DefaultProbeInterval,
+			},
+		},
+		{
+			name: "spool settings from env",
+			args: []string{"app", "-spool-max-size", "2048"},
+			env: map[string]string{
+				"SPOOL_DIR": "/tmp/agent-spool",
+			},
+			expected: func() Flags {
+				f := Flags{RateLimit: 3, PprofPort: "6060"}
+				f.Server.Addr = "localhost:8080"
+				f.Server.ReportInterval = 10.0
+				f.Server.PollInterval = 2.0
+				f.Spool.Dir = "/tmp/agent-spool"
+				f.Spool.MaxSize = 2048
+				f.Retry = defaultRetry()
+				f.EndpointMode, f.ProbeInterval = "failover", agent.DefaultProbeInterval
+				f.Transport = "http"
+				return f
+			}(),
+		},
+		{
+			name: "collectors lists",
+			args: []string{"app", "-collectors", "runtime, system,", "-disable-collectors", "system"},
+			expected: func() Flags {
+				f := Flags{RateLimit: 3, PprofPort: "6060", Spool: defaultSpool()}
+				f.Server.Addr = "localhost:8080"
+				f.Server.ReportInterval = 10.0
+				f.Server.PollInterval = 2.0
+				f.Collectors.Enabled = []string{"runtime", "system"}
+				f.Collectors.Disabled = []string{"system"}
+				f.Retry = defaultRetry()
+				f.EndpointMode, f.ProbeInterval = "failover", agent.DefaultProbeInterval
+				f.Transport = "http"
+				return f
+			}(),
+		},
+		{
+			name: "device and mount filters",
+			args: []string{"app", "-devices-exclude", "loop*,lo", "-mounts-include", "/"},
+			env: map[string]string{
+				"MOUNTS_EXCLUDE": "/snap/*",
+			},
+			expected: func() Flags {
+				f := Flags{RateLimit: 3, PprofPort: "6060", Spool: defaultSpool()}
+				f.Server.Addr = "localhost:8080"
+				f.Server.ReportInterval = 10.0
+				f.Server.PollInterval = 2.0
+				f.Devices.Exclude = []string{"loop*", "lo"}
+				f.Mounts.Include = []string{"/"}
+				f.Mounts.Exclude = []string{"/snap/*"}
+				f.Retry = defaultRetry()
+				f.EndpointMode, f.ProbeInterval = "failover", agent.DefaultProbeInterval
+				f.Transport = "http"
+				return f
+			}(),
+		},
+		{
+			name: "retry settings",
+			args: []string{"app", "-retry-max-attempts", "6", "-retry-base-delay", "500ms", "-breaker-threshold", "0"},
+			env: map[string]string{
+				"RETRY_MAX_DELAY":      "1m",
+				"RETRY_JITTER":         "0.5",
+				"BREAKER_OPEN_TIMEOUT": "2m",
+			},
+			expected: func() Flags {
+				f := Flags{RateLimit: 3, PprofPort: "6060", Spool: defaultSpool()}
+				f.Server.Addr = "localhost:8080"
+				f.Server.ReportInterval = 10.0
+				f.Server.PollInterval = 2.0
+				f.Retry.MaxAttempts = 6
+				f.Retry.BaseDelay = 500 * time.Millisecond
+				f.Retry.MaxDelay = time.Minute
+				f.Retry.Jitter = 0.5
+				f.Retry.BreakerThreshold = 0
+				f.Retry.BreakerOpenTimeout = 2 * time.Minute
+				f.EndpointMode, f.ProbeInterval = "failover", agent.DefaultProbeInterval
+				f.Transport = "http"
+				return f
+			}(),
+		},
+		{
+			name: "multiple endpoints",
+			args: []string{"app", "-a", "localhost:8080,localhost:8081", "-endpoint-mode", "fanout"},
+			env: map[string]string{
+				"PROBE_INTERVAL": "30s",
+			},
+			expected: func() Flags {
+				f := Flags{RateLimit: 3, PprofPort: "6060", Spool: defaultSpool(), Retry: defaultRetry()}
+				f.Server.Addr = "localhost:8080,localhost:8081"
+				f.Server.ReportInterval = 10.0
+				f.Server.PollInterval = 2.0
+				f.EndpointMode = "fanout"
+				f.ProbeInterval = 30 * time.Second
+				f.Transport = "http"
+				return f
+			}(),
+		},
+		{
+			name: "grpc transport from env",
+			args: []string{"app", "-a", "localhost:3200"},
+			env: map[string]string{
+				"TRANSPORT": "grpc",
+			},
+			expected: func() Flags {
+				f := Flags{RateLimit: 3, PprofPort: "6060", Spool: defaultSpool(), Retry: defaultRetry()}
+				f.Server.Addr = "localhost:3200"
+				f.Server.ReportInterval = 10.0
+				f.Server.PollInterval = 2.0
+				f.EndpointMode, f.ProbeInterval = "failover", agent.DefaultProbeInterval
+				f.Transport = "grpc"
+				return f
+			}(),
+		},
+		{
+			name:      "unknown transport",
+			args:      []string{"app", "-transport", "quic"},
+			wantPanic: true,
+		},
+		{
+			name:      "unknown endpoint mode",
+			args:      []string{"app", "-endpoint-mode", "roundrobin"},
+			wantPanic: true,
+		},
+		{
+			name: "invalid retry delay env",
+			args: []string{"app"},
+			env: map[string]string{
+				"RETRY_BASE_DELAY": "soon",
+			},
+			wantPanic: true,
+		},
+		{
+			name:      "retry max attempts less than 1",
+			args:      []string{"app", "-retry-max-attempts", "0"},
+			wantPanic: true,
+		},
+		{
+			name: "invalid spool max size env",
+			args: []string{"app"},
+			env: map[string]string{
+				"SPOOL_MAX_SIZE": "invalid",
 			},
+			wantPanic: true,
 		},
 		{
 			name: "invalid report interval env",
@@ -136,9 +298,14 @@ func TestMustParseFlags(t *testing.T) {
 					ReportInterval: minInterval,
 					PollInterval:   minInterval,
 				},
-				RateLimit:   3,
-				EnablePprof: false,
-				PprofPort:   "6060",
+				RateLimit:     3,
+				EnablePprof:   false,
+				PprofPort:     "6060",
+				Spool:         defaultSpool(),
+				Retry:         defaultRetry(),
+				EndpointMode:  "failover",
+				Transport:     "http",
+				ProbeInterval: agent.DefaultProbeInterval,
 			},
 		},
 	}
@@ -176,3 +343,32 @@ func TestMustParseFlags(t *testing.T) {
 		})
 	}
 }
+
+// defaultSpool возвращает настройки дисковой очереди по умолчанию.
+func defaultSpool() struct {
+	Dir     string
+	MaxSize int64
+} {
+	s := Flags{}.Spool
+	s.MaxSize = defaultSpoolMaxSize
+	return s
+}
+
+// defaultRetry возвращает настройки политики повторов и выключателя по умолчанию.
+func defaultRetry() struct {
+	MaxAttempts        int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	Jitter             float64
+	BreakerThreshold   int
+	BreakerOpenTimeout time.Duration
+} {
+	r := Flags{}.Retry
+	r.MaxAttempts = retry.DefaultMaxAttempts
+	r.BaseDelay = retry.DefaultBaseDelay
+	r.MaxDelay = retry.DefaultMaxDelay
+	r.Jitter = retry.DefaultJitter
+	r.BreakerThreshold = retry.DefaultBreakerThreshold
+	r.BreakerOpenTimeout = retry.DefaultBreakerOpenTimeout
+	return r
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/maynagashev/go-metrics/internal/agent"
+	"github.com/maynagashev/go-metrics/internal/agent/spool"
 	"github.com/maynagashev/go-metrics/pkg/crypto"
 )
 
@@ -54,10 +55,41 @@ func main() {
 		slog.Info("loaded public key for encryption", "path", flags.CryptoKey)
 	}
 
-	serverURL := "http://" + flags.Server.Addr
+	// Первый сервер списка основной, остальные резервные
+	transport, _ := agent.ParseTransport(flags.Transport) // протокол проверен в validateFlags
+	serverURLs := make([]string, 0, 1)
+	for _, addr := range splitList(flags.Server.Addr) {
+		serverURLs = append(serverURLs, string(transport)+"://"+addr)
+	}
+	mode, _ := agent.ParseEndpointMode(flags.EndpointMode) // режим проверен в validateFlags
 	pollInterval := time.Duration(flags.Server.PollInterval * float64(time.Second))
 	reportInterval := time.Duration(flags.Server.ReportInterval * float64(time.Second))
 
+	// Формируем набор коллекторов с учетом списков включения/отключения
+	registry, err := buildRegistry(flags)
+	if err != nil {
+		slog.Error("invalid collectors configuration", "error", err)
+		os.Exit(1)
+	}
+	opts := []agent.Option{
+		agent.WithCollectors(registry),
+		agent.WithRetryPolicy(retryPolicy(&flags)),
+		agent.WithBreaker(flags.Retry.BreakerThreshold, flags.Retry.BreakerOpenTimeout),
+		agent.WithEndpoints(mode, serverURLs[1:]...),
+		agent.WithProbeInterval(flags.ProbeInterval),
+		agent.WithTransport(transport),
+	}
+
+	// Открываем дисковую очередь, если указана директория
+	if flags.Spool.Dir != "" {
+		sp, err := spool.Open(flags.Spool.Dir, flags.Spool.MaxSize)
+		if err != nil {
+			slog.Error("failed to open spool", "error", err, "dir", flags.Spool.Dir)
+			os.Exit(1)
+		}
+		opts = append(opts, agent.WithSpool(sp))
+	}
+
 	// Создаем контекст с отменой для graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -68,12 +100,13 @@ func main() {
 
 	// Запускаем агента
 	a := agent.New(
-		serverURL,
+		serverURLs[0],
 		pollInterval,
 		reportInterval,
 		flags.PrivateKey,
 		flags.RateLimit,
 		publicKey,
+		opts...,
 	)
 
 	// Запускаем горутину для обработки сигналов
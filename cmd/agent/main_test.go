@@ -90,6 +90,7 @@ func TestMain(t *testing.T) {
 		privateKey string,
 		rateLimit int,
 		publicKey *rsa.PublicKey,
+		_ ...agent.Option,
 	) agent.Agent {
 		// Проверяем, что параметры переданы правильно
 		assert.Equal(t, "http://localhost:9090", serverURL)
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/internal/server/storage/pgstorage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/sqlitestorage"
+)
+
+// defaultBatchSize количество метрик в одном вызове UpdateMetrics при копировании.
+const defaultBatchSize = 500
+
+// Префиксы описания хранилища в параметрах -from и -to. Описание без префикса — путь к файлу
+// хранилища в памяти (снимок и журнал изменений, как при запуске сервера с флагом -f).
+const (
+	postgresPrefix   = "postgres://"
+	postgresqlPrefix = "postgresql://"
+	sqlitePrefix     = "sqlite:"
+	filePrefix       = "file:"
+)
+
+// copyOptions параметры копирования метрик между хранилищами.
+type copyOptions struct {
+	from string
+	to   string
+	// Количество метрик в одном пакете обновления.
+	batchSize int
+	// Только прочитать источник, не изменяя хранилище назначения.
+	dryRun bool
+	// Прибавлять значения counter к значениям в хранилище назначения, а не заменять их.
+	merge bool
+	// Пути к директориям с миграциями PostgreSQL и SQLite.
+	migrationsPath       string
+	sqliteMigrationsPath string
+}
+
+// copyStats результат копирования.
+type copyStats struct {
+	gauges   int
+	counters int
+}
+
+// runCopy разбирает аргументы подкоманды copy и копирует метрики между хранилищами.
+func runCopy(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	var opts copyOptions
+	var overwrite bool
+	fs.StringVar(&opts.from, "from", "",
+		"Хранилище-источник: postgres://..., sqlite:<путь> или путь к файлу хранилища в памяти")
+	fs.StringVar(&opts.to, "to", "",
+		"Хранилище назначения: postgres://..., sqlite:<путь> или путь к файлу хранилища в памяти")
+	fs.IntVar(&opts.batchSize, "batch-size", defaultBatchSize, "Количество метрик в одном пакете обновления")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "Только прочитать источник, не изменяя хранилище назначения")
+	fs.BoolVar(&overwrite, "overwrite", false,
+		"Заменять значения counter в хранилище назначения значениями источника (по умолчанию)")
+	fs.BoolVar(&opts.merge, "merge", false,
+		"Прибавлять значения counter источника к значениям в хранилище назначения")
+	fs.StringVar(&opts.migrationsPath, "migrations-path", "migrations/server",
+		"Путь к директории с миграциями PostgreSQL")
+	fs.StringVar(&opts.sqliteMigrationsPath, "sqlite-migrations-path", "migrations/sqlite",
+		"Путь к директории с миграциями SQLite")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	switch {
+	case opts.from == "" || opts.to == "":
+		return errors.New("не указаны хранилища: -from <источник> -to <назначение>")
+	case opts.from == opts.to:
+		return errors.New("источник и назначение совпадают")
+	case overwrite && opts.merge:
+		return errors.New("флаги -overwrite и -merge несовместимы")
+	case opts.batchSize <= 0:
+		return errors.New("размер пакета должен быть положительным: -batch-size")
+	}
+	return copyBetween(ctx, opts, zap.NewNop())
+}
+
+// copyBetween открывает хранилища, копирует метрики и проверяет результат.
+// В режиме dry-run хранилище назначения не открывается, так как открытие может его изменить
+// (например, применить миграции или создать файл).
+func copyBetween(ctx context.Context, opts copyOptions, log *zap.Logger) error {
+	src, err := openRepository(ctx, opts.from, true, opts, log)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть источник: %w", err)
+	}
+	defer src.Close()
+
+	var dst storage.Repository
+	if !opts.dryRun {
+		if dst, err = openRepository(ctx, opts.to, false, opts, log); err != nil {
+			return fmt.Errorf("не удалось открыть хранилище назначения: %w", err)
+		}
+	}
+
+	slog.Info("Копирование метрик...", "from", opts.from, "to", opts.to,
+		"merge", opts.merge, "dry_run", opts.dryRun)
+	c := &copier{src: src, dst: dst, opts: opts, expected: make(map[metricKey]float64)}
+	stats, err := c.run(ctx)
+	if err == nil && dst != nil {
+		err = c.verify(ctx, stats)
+	}
+	if dst != nil {
+		// Хранилище в памяти сохраняет снимок метрик при закрытии.
+		if closeErr := dst.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("не удалось закрыть хранилище назначения: %w", closeErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		slog.Info("Пробный запуск завершен, хранилище назначения не изменялось.",
+			"gauges", stats.gauges, "counters", stats.counters)
+		return nil
+	}
+	slog.Info("Метрики скопированы.", "gauges", stats.gauges, "counters", stats.counters)
+	return nil
+}
+
+// openRepository открывает хранилище по описанию spec. Файл хранилища в памяти, открытый
+// как источник, только читается; как назначение — сохраняется при закрытии хранилища.
+func openRepository(
+	ctx context.Context,
+	spec string,
+	source bool,
+	opts copyOptions,
+	log *zap.Logger,
+) (storage.Repository, error) {
+	switch {
+	case strings.HasPrefix(spec, postgresPrefix), strings.HasPrefix(spec, postgresqlPrefix):
+		cfg := &app.Config{Database: app.DatabaseConfig{DSN: spec, MigrationsPath: opts.migrationsPath}}
+		return pgstorage.New(ctx, cfg, log)
+	case strings.HasPrefix(spec, sqlitePrefix):
+		path := strings.TrimPrefix(spec, sqlitePrefix)
+		if source {
+			if _, err := os.Stat(path); err != nil {
+				return nil, err
+			}
+		}
+		cfg := &app.Config{SQLite: app.SQLiteConfig{Path: path, MigrationsPath: opts.sqliteMigrationsPath}}
+		return sqlitestorage.New(ctx, cfg, log)
+	}
+
+	path := strings.TrimPrefix(spec, filePrefix)
+	if source {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		return memory.Load(path, log)
+	}
+	cfg := &app.Config{FileStoragePath: path, Restore: true, Fsync: app.FsyncNever}
+	ms, err := memory.New(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// metricKey идентификатор метрики в хранилище.
+type metricKey struct {
+	mType metrics.MetricType
+	name  string
+}
+
+// copier копирует метрики из src в dst пакетами и запоминает ожидаемые значения для проверки.
+type copier struct {
+	src  storage.Repository
+	dst  storage.Repository
+	opts copyOptions
+	// Ожидаемые значения метрик в хранилище назначения после копирования.
+	expected map[metricKey]float64
+	batch    []metrics.Metric
+}
+
+// run передает метрики источника в хранилище назначения пакетами по batchSize метрик.
+func (c *copier) run(ctx context.Context) (copyStats, error) {
+	var stats copyStats
+	add := func(m metrics.Metric) error {
+		switch m.MType {
+		case metrics.TypeGauge:
+			stats.gauges++
+		case metrics.TypeCounter:
+			stats.counters++
+		default:
+			return fmt.Errorf("неизвестный тип метрики %s: %s", m.Name, m.MType)
+		}
+		c.batch = append(c.batch, m)
+		if len(c.batch) >= c.opts.batchSize {
+			return c.flush(ctx)
+		}
+		return nil
+	}
+
+	var err error
+	if streamer, ok := c.src.(storage.MetricsStreamer); ok {
+		err = streamer.StreamMetrics(ctx, add)
+	} else {
+		var items []metrics.Metric
+		if items, err = c.src.GetMetrics(ctx); err != nil {
+			return stats, err
+		}
+		for _, m := range items {
+			if err = add(m); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = c.flush(ctx)
+	}
+	return stats, err
+}
+
+// flush записывает накопленный пакет. Значения counter передаются в UpdateMetrics как приращения,
+// поэтому при замене приращение вычисляется от текущего значения в хранилище назначения.
+func (c *copier) flush(ctx context.Context) error {
+	if len(c.batch) == 0 {
+		return nil
+	}
+	defer func() { c.batch = c.batch[:0] }()
+	if c.dst == nil {
+		return nil
+	}
+
+	items := make([]metrics.Metric, 0, len(c.batch))
+	for _, m := range c.batch {
+		key := metricKey{mType: m.MType, name: m.Name}
+		if m.MType == metrics.TypeGauge {
+			c.expected[key] = *m.Value
+			items = append(items, m)
+			continue
+		}
+
+		total := *m.Delta
+		current, err := c.dst.GetCounter(ctx, m.Name)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("не удалось прочитать счетчик %s: %w", m.Name, err)
+		}
+		delta := total - int64(current)
+		if c.opts.merge {
+			delta = total
+		}
+		c.expected[key] = float64(int64(current) + delta)
+		items = append(items, metrics.Metric{Name: m.Name, MType: m.MType, Delta: &delta})
+	}
+
+	if err := c.dst.UpdateMetrics(ctx, items); err != nil {
+		return fmt.Errorf("не удалось записать пакет метрик: %w", err)
+	}
+	slog.Info("Пакет метрик записан.", "metrics", len(items))
+	return nil
+}
+
+// verify сверяет количество скопированных метрик с источником и значения метрик в хранилище назначения.
+func (c *copier) verify(ctx context.Context, stats copyStats) error {
+	copied := stats.gauges + stats.counters
+	total, err := c.src.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if copied != total {
+		return fmt.Errorf("скопировано %d метрик из %d в источнике", copied, total)
+	}
+
+	var missing, mismatched int
+	for key, want := range c.expected {
+		got, getErr := c.dst.GetMetric(ctx, key.mType, key.name)
+		if errors.Is(getErr, storage.ErrNotFound) {
+			missing++
+			continue
+		}
+		if getErr != nil {
+			return getErr
+		}
+		value := float64(0)
+		if key.mType == metrics.TypeGauge {
+			value = *got.Value
+		} else {
+			value = float64(*got.Delta)
+		}
+		if math.Abs(value-want) > 1e-9*math.Max(1, math.Abs(want)) {
+			mismatched++
+		}
+	}
+	if missing > 0 || mismatched > 0 {
+		return fmt.Errorf("проверка не пройдена: отсутствует %d, не совпадает %d из %d метрик",
+			missing, mismatched, copied)
+	}
+
+	destination, err := c.dst.Count(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("Проверка пройдена.", "source", copied, "destination", destination)
+	return nil
+}
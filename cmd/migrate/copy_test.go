@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/internal/server/storage/sqlitestorage"
+)
+
+const sqliteMigrations = "../../migrations/sqlite"
+
+// writeFileStore сохраняет метрики в файл хранилища в памяти.
+func writeFileStore(t *testing.T, path string, items ...metrics.Metric) {
+	ms, err := memory.New(&app.Config{FileStoragePath: path, Restore: true}, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, ms.UpdateMetrics(context.Background(), items))
+	require.NoError(t, ms.Close())
+}
+
+func copyArgs(from, to string, extra ...string) []string {
+	args := []string{"-from", from, "-to", to, "-batch-size", "2", "-sqlite-migrations-path", sqliteMigrations}
+	return append(args, extra...)
+}
+
+func openSQLite(t *testing.T, path string) *sqlitestorage.SQLiteStorage {
+	cfg := &app.Config{SQLite: app.SQLiteConfig{Path: path, MigrationsPath: sqliteMigrations}}
+	s, err := sqlitestorage.New(context.Background(), cfg, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func counter(t *testing.T, repo storage.Repository, name string) storage.Counter {
+	v, err := repo.GetCounter(context.Background(), name)
+	require.NoError(t, err, "counter %s not found", name)
+	return v
+}
+
+func TestRunCopy_FileToSQLite(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "metrics.json")
+	dst := filepath.Join(dir, "metrics.db")
+	writeFileStore(t, src,
+		*metrics.NewCounter("requests", 10),
+		*metrics.NewCounter("errors", 2),
+		*metrics.NewGauge("load", 0.5),
+	)
+	ctx := context.Background()
+
+	require.NoError(t, runCopy(ctx, copyArgs(src, "sqlite:"+dst)))
+
+	// Повторное копирование с заменой не удваивает счетчики
+	require.NoError(t, runCopy(ctx, copyArgs(src, "sqlite:"+dst, "-overwrite")))
+
+	s := openSQLite(t, dst)
+	count, err := s.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, storage.Counter(10), counter(t, s, "requests"))
+	assert.Equal(t, storage.Counter(2), counter(t, s, "errors"))
+	load, err := s.GetGauge(ctx, "load")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, float64(load), 1e-9)
+}
+
+func TestRunCopy_Merge(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "source.json")
+	dst := filepath.Join(dir, "destination.json")
+	writeFileStore(t, src, *metrics.NewCounter("requests", 10))
+	writeFileStore(t, dst, *metrics.NewCounter("requests", 5), *metrics.NewGauge("load", 1))
+	ctx := context.Background()
+
+	require.NoError(t, runCopy(ctx, copyArgs(src, dst, "-merge")))
+
+	ms, err := memory.Load(dst, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(15), counter(t, ms, "requests"))
+	count, err := ms.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestRunCopy_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "metrics.json")
+	dst := filepath.Join(dir, "metrics.db")
+	writeFileStore(t, src, *metrics.NewCounter("requests", 10))
+
+	require.NoError(t, runCopy(context.Background(), copyArgs(src, "sqlite:"+dst, "-dry-run")))
+
+	// Хранилище назначения не создается
+	_, err := os.Stat(dst)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRunCopy_InvalidArgs(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "metrics.json")
+	writeFileStore(t, src, *metrics.NewCounter("requests", 10))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no destination", args: []string{"-from", src}},
+		{name: "same storage", args: copyArgs(src, src)},
+		{name: "overwrite and merge", args: copyArgs(src, "sqlite:"+filepath.Join(dir, "m.db"), "-overwrite", "-merge")},
+		{name: "missing source", args: copyArgs(filepath.Join(dir, "missing.json"), "sqlite:"+filepath.Join(dir, "m.db"))},
+		{name: "invalid batch size", args: copyArgs(src, "sqlite:"+filepath.Join(dir, "m.db"), "-batch-size", "0")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, runCopy(ctx, tt.args))
+		})
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -44,6 +46,43 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRun_Commands(t *testing.T) {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "metrics.db")
+
+	require.NoError(t, run(dsn, sqliteMigrations, "status"))
+	require.NoError(t, run(dsn, sqliteMigrations, "up", "1"))
+	require.NoError(t, run(dsn, sqliteMigrations, "up"))
+	require.NoError(t, run(dsn, sqliteMigrations, "down"))
+	require.NoError(t, run(dsn, sqliteMigrations, "goto", "2"))
+	require.NoError(t, run(dsn, sqliteMigrations, "force", "2"))
+
+	invalid := [][]string{
+		{"up", "0"},
+		{"down", "-1"},
+		{"goto"},
+		{"force", "x"},
+		{"status", "1"},
+		{"up", "1", "2"},
+		{"unknown"},
+	}
+	for _, args := range invalid {
+		require.Error(t, run(dsn, sqliteMigrations, args...), args)
+	}
+}
+
+func TestRun_Create(t *testing.T) {
+	dir := t.TempDir()
+
+	// Для создания миграции подключение к БД не требуется
+	require.NoError(t, run("", dir, "create", "init"))
+	require.NoError(t, run("", dir, "create", "add_index"))
+	assert.FileExists(t, filepath.Join(dir, "1_init.up.sql"))
+	assert.FileExists(t, filepath.Join(dir, "2_add_index.down.sql"))
+
+	require.Error(t, run("", dir, "create"))
+	require.Error(t, run("", dir, "create", "add-index"))
+}
+
 func TestMain_Integration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
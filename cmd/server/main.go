@@ -1,7 +1,8 @@
 // Package main реализует HTTP-сервер для сбора и хранения метрик.
 //
-// Сервер поддерживает хранение метрик в PostgreSQL или в памяти. Выбор хранилища
-// определяется наличием параметров подключения к БД (флаг -d или переменная DATABASE_DSN).
+// Сервер поддерживает хранение метрик в PostgreSQL, во встроенной базе SQLite или в памяти.
+// Выбор хранилища определяется наличием параметров подключения к БД (флаг -d или переменная
+// DATABASE_DSN), затем пути к файлу SQLite (флаг -sqlite или переменная SQLITE_PATH).
 //
 // # Поддерживаемые типы метрик
 //
@@ -13,13 +14,21 @@
 //   - POST /update - обновление одиночной метрики
 //   - POST /updates/ - пакетное обновление метрик
 //   - POST /value - получение значения метрики
-//   - GET /ping - проверка подключения к БД
+//   - GET /ping - проверка готовности хранилища метрик
+//   - GET /healthz - проверка живости процесса
+//   - GET /readyz - проверка готовности сервера к приему запросов (503 во время остановки)
 //   - GET / - получение всех метрик (текстовый формат)
+//   - GET /stream - поток обновлений метрик (Server-Sent Events или WebSocket)
+//   - GET /api/v1/query_range - значения метрики за период (при включенной истории, флаг -history)
+//
+// Дополнительно сервер может принимать метрики по протоколам Graphite plaintext (TCP, флаг -graphite-addr)
+// и StatsD (UDP, флаг -statsd-addr), а также предоставлять gRPC API (флаг -grpc-addr).
 //
 // # Конфигурация
 //
 // Сервер поддерживает настройку через флаги командной строки и переменные окружения:
 //   - DATABASE_DSN - строка подключения к PostgreSQL
+//   - SQLITE_PATH - путь к файлу базы данных SQLite
 //   - STORE_INTERVAL - интервал сохранения метрик (для in-memory хранилища)
 //   - FILE_STORAGE_PATH - путь к файлу для сохранения метрик
 //   - RESTORE - восстанавливать ли метрики из файла при старте
@@ -36,6 +45,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	//nolint:gosec // G108: pprof is used intentionally for debugging and profiling
 	_ "net/http/pprof"
@@ -43,10 +53,18 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/grpcserver"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/health"
+	"github.com/maynagashev/go-metrics/internal/server/listeners/aggregator"
+	"github.com/maynagashev/go-metrics/internal/server/listeners/graphite"
+	"github.com/maynagashev/go-metrics/internal/server/listeners/statsd"
+	"github.com/maynagashev/go-metrics/internal/server/pubsub"
+	"github.com/maynagashev/go-metrics/internal/server/retention"
 	"github.com/maynagashev/go-metrics/internal/server/router"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
 	"github.com/maynagashev/go-metrics/internal/server/storage/pgstorage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/sqlitestorage"
 )
 
 // Глобальные переменные для информации о сборке.
@@ -100,9 +118,38 @@ func main() {
 		}
 	}()
 
-	handlers := router.New(cfg, repo, log)
+	// Подписчики GET /stream отключаются в начале остановки сервера
+	hub := pubsub.NewHub(pubsub.DefaultBufferSize)
+	server.RegisterOnShutdown(hub.Close)
+
+	// После сигнала остановки /readyz отвечает 503, чтобы балансировщик перестал направлять запросы
+	ready := health.New(map[string]health.Check{"storage": repo.Ping}, log)
+	server.RegisterOnDrain(ready.Shutdown)
+
+	handlers := router.New(cfg, repo, log, hub, ready)
+
+	// Прореживание истории выполняется в фоне, пока работает сервер
+	compactor, compactorErr := initCompactor(cfg, repo, log)
+	if compactorErr != nil {
+		log.Error("failed to init history compaction", zap.Error(compactorErr))
+		panic(compactorErr)
+	}
+	if compactor != nil {
+		server.RegisterTask(compactor.Run)
+	}
+
+	// Устаревшие ключи идемпотентности удаляются в фоне, а не при применении пакетов
+	if purge := initRequestsPurge(cfg, repo, log); purge != nil {
+		server.RegisterTask(purge)
+	}
+
+	listeners, listenersErr := initListeners(cfg, repo, log)
+	if listenersErr != nil {
+		log.Error("failed to init listeners", zap.Error(listenersErr))
+		panic(listenersErr)
+	}
 
-	server.Start(log, handlers)
+	server.Start(log, handlers, listeners...)
 
 	log.Debug("server stopped")
 }
@@ -117,7 +164,107 @@ func initStorage(cfg *app.Config, log *zap.Logger) (storage.Repository, error) {
 		return pg, nil
 	}
 
-	return memory.New(cfg, log), nil
+	// Если указан SQLITE_PATH или флаг -sqlite, то используем встроенную базу SQLite.
+	if cfg.IsSQLiteEnabled() {
+		db, err := sqlitestorage.New(context.Background(), cfg, log)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+
+	ms, err := memory.New(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// initCompactor создает задачу прореживания истории, если история включена и задана политика хранения.
+func initCompactor(cfg *app.Config, repo storage.Repository, log *zap.Logger) (*retention.Compactor, error) {
+	if !cfg.IsHistoryEnabled() {
+		return nil, nil //nolint:nilnil // без истории прореживание не требуется
+	}
+	tiers, err := retention.ParsePolicy(cfg.Retention)
+	if err != nil {
+		return nil, err
+	}
+	history, ok := repo.(storage.HistoryCompactor)
+	if len(tiers) == 0 || !ok {
+		return nil, nil //nolint:nilnil // политика хранения не задана
+	}
+	return retention.New(history, tiers, cfg.GetCompactionInterval(), log), nil
+}
+
+// initRequestsPurge создает задачу удаления устаревших ключей идемпотентности, если хранилище
+// не удаляет их само. Ключи удаляются с периодом, равным их времени хранения.
+func initRequestsPurge(cfg *app.Config, repo storage.Repository, log *zap.Logger) func(ctx context.Context) {
+	purger, ok := repo.(storage.AppliedRequestsPurger)
+	if !ok {
+		return nil
+	}
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(cfg.GetIdempotencyTTL())
+		defer ticker.Stop()
+		for {
+			purged, err := purger.PurgeAppliedRequests(ctx)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				log.Error("failed to purge idempotency keys", zap.Error(err))
+			case purged > 0:
+				log.Debug("idempotency keys purged", zap.Int64("purged", purged))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// initListeners создает gRPC API и приемники метрик Graphite и StatsD, если заданы их адреса.
+// Приемники Graphite и StatsD передают значения в общий агрегатор, который останавливается последним,
+// чтобы записать в хранилище значения, полученные до остановки приемников.
+func initListeners(cfg *app.Config, repo storage.Repository, log *zap.Logger) ([]app.Listener, error) {
+	var listeners []app.Listener
+	if cfg.GRPCAddr != "" {
+		l, err := grpcserver.Listen(cfg.GRPCAddr, cfg, repo, log)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if cfg.GraphiteAddr == "" && cfg.StatsDAddr == "" {
+		return listeners, nil
+	}
+
+	agg := aggregator.New(repo, log, cfg.GetIngestFlushInterval())
+	if cfg.GraphiteAddr != "" {
+		l, err := graphite.Listen(cfg.GraphiteAddr, agg, log)
+		if err != nil {
+			shutdownListeners(append(listeners, agg))
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if cfg.StatsDAddr != "" {
+		l, err := statsd.Listen(cfg.StatsDAddr, agg, log)
+		if err != nil {
+			// Агрегатор останавливается после приемника Graphite, чтобы записать полученные им значения.
+			shutdownListeners(append(listeners, agg))
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return append(listeners, agg), nil
+}
+
+// shutdownListeners останавливает уже созданные приемники, если создать следующий не удалось.
+func shutdownListeners(listeners []app.Listener) {
+	for _, l := range listeners {
+		_ = l.Shutdown(context.Background())
+	}
 }
 
 func initLogger() *zap.Logger {
@@ -2,14 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
 	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
 )
 
 func TestPrintVersion(t *testing.T) {
@@ -91,3 +95,42 @@ func TestInitStorage(t *testing.T) {
 	// We can't easily test the PostgreSQL path without a real database,
 	// so we'll skip that test case
 }
+
+// purgerStorage хранилище в памяти, удаляющее ключи идемпотентности по запросу.
+type purgerStorage struct {
+	*memory.MemStorage
+	purges chan struct{}
+}
+
+func (s *purgerStorage) PurgeAppliedRequests(context.Context) (int64, error) {
+	select {
+	case s.purges <- struct{}{}:
+	default:
+	}
+	return 1, nil
+}
+
+func TestInitRequestsPurge(t *testing.T) {
+	cfg := &app.Config{IdempotencyTTL: time.Millisecond}
+	ms, err := memory.New(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	// Хранилище в памяти удаляет устаревшие ключи само
+	assert.Nil(t, initRequestsPurge(cfg, ms, zap.NewNop()))
+
+	repo := &purgerStorage{MemStorage: ms, purges: make(chan struct{}, 1)}
+	purge := initRequestsPurge(cfg, repo, zap.NewNop())
+	require.NotNil(t, purge)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		purge(ctx)
+	}()
+	// Ключи удаляются при запуске задачи и затем периодически
+	<-repo.purges
+	<-repo.purges
+	cancel()
+	<-done
+}
@@ -5,16 +5,20 @@ import (
 	"crypto/rsa"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 
+	"github.com/maynagashev/go-metrics/internal/agent/collector"
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
+	"github.com/maynagashev/go-metrics/internal/agent/spool"
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/pkg/random"
 )
 
-// Количество попыток отправки запроса на сервер при возникновении ошибок.
-const maxSendRetries = 3
+// Максимальное время финальной отправки метрик при завершении работы, включая повторы.
+const finalSendTimeout = 10 * time.Second
 
 // Agent представляет собой интерфейс для сбора и отправки метрик на сервер.
 // Реализует функционал сбора runtime метрик и дополнительных системных метрик,
@@ -69,6 +73,34 @@ type agent struct {
 	resultQueue chan Result
 	// Канал для сигнала остановки
 	stopCh chan struct{}
+	// Дисковая очередь пакетов метрик, nil если не используется.
+	spool *spool.Spool
+	// Реестр источников метрик, опрашиваемых в runPolls.
+	registry *collector.Registry
+	// Последние gauge метрики каждого коллектора.
+	snapshots map[string][]metrics.Metric
+	// Время последнего опроса каждого коллектора.
+	lastCollected map[string]time.Time
+	// Приращения счетчиков пакетов, ожидающих результата отправки, по номеру пакета.
+	inFlight map[uint64]map[string]int64
+	// Номер последнего подготовленного пакета.
+	nextBatchID uint64
+	// Политика повторной отправки пакетов.
+	retryPolicy retry.Policy
+	// Параметры выключателей серверов, threshold 0 отключает выключатели.
+	breakerThreshold   int
+	breakerOpenTimeout time.Duration
+	// Серверы для отправки метрик: основной ServerURL и дополнительные extraURLs.
+	endpoints []*endpoint
+	extraURLs []string
+	// Режим отправки на несколько серверов.
+	mode EndpointMode
+	// Индекс активного сервера в режиме failover.
+	active atomic.Int32
+	// Интервал проверки доступности приоритетных серверов в режиме failover.
+	probeInterval time.Duration
+	// Протокол отправки метрик на сервер.
+	transport Transport
 }
 
 // New создает новый экземпляр агента.
@@ -81,8 +113,9 @@ var New = func(
 	privateKey string,
 	rateLimit int,
 	publicKey *rsa.PublicKey,
+	opts ...Option,
 ) Agent {
-	return &agent{
+	a := &agent{
 		ServerURL:          url,
 		PollInterval:       pollInterval,
 		ReportInterval:     reportInterval,
@@ -98,7 +131,22 @@ var New = func(
 		sendQueue:          make(chan Job, rateLimit),
 		resultQueue:        make(chan Result, rateLimit),
 		stopCh:             make(chan struct{}),
+		registry:           collector.Default(),
+		snapshots:          make(map[string][]metrics.Metric),
+		lastCollected:      make(map[string]time.Time),
+		inFlight:           make(map[uint64]map[string]int64),
+		retryPolicy:        retry.DefaultPolicy(),
+		breakerThreshold:   retry.DefaultBreakerThreshold,
+		breakerOpenTimeout: retry.DefaultBreakerOpenTimeout,
+		mode:               ModeFailover,
+		probeInterval:      DefaultProbeInterval,
+		transport:          TransportHTTP,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.initEndpoints()
+	return a
 }
 
 // IsRequestSigningEnabled возвращает true, если задан приватный ключ и агент должен отправлять хэш на его основе.
@@ -123,11 +171,22 @@ func (a *agent) Run(ctx context.Context) {
 		"send_hash", a.IsRequestSigningEnabled(),
 		"encryption_enabled", a.IsEncryptionEnabled(),
 		"rate_limit", a.RateLimit,
+		"spool_enabled", a.spool != nil,
+		"retry_max_attempts", a.retryPolicy.MaxAttempts,
+		"breaker_threshold", a.breakerThreshold,
+		"endpoints", a.endpointURLs(),
+		"endpoint_mode", a.mode,
+		"transport", a.transport,
+		"collectors", a.registry.Names(),
 	)
 	// Горутина для сбора метрик (с интервалом PollInterval).
 	go a.runPolls(ctx)
 	// Горутина для добавления задач в очередь на отправку, с интервалом ReportInterval.
 	go a.runReports(ctx)
+	// Горутина для возврата на приоритетный сервер в режиме failover.
+	if a.mode == ModeFailover && len(a.endpoints) > 1 && a.probeInterval > 0 {
+		go a.runProbes(ctx)
+	}
 
 	// Запуск worker pool для отправки метрик.
 	for i := range a.RateLimit {
@@ -161,25 +220,10 @@ func (a *agent) Shutdown() {
 	slog.Info("Stop channel closed")
 
 	// Отправляем последние собранные метрики
-	metrics := a.GetMetrics()
-	if len(metrics) > 0 {
-		slog.Info("Sending final metrics batch before shutdown", "count", len(metrics))
-
-		// Пытаемся отправить метрики напрямую
-		err := a.sendMetrics(metrics, -1) // -1 означает, что это финальная отправка
-		if err != nil {
-			slog.Error("Failed to send final metrics directly", "error", err)
-
-			// Если не удалось отправить напрямую, пробуем через очередь
-			select {
-			case a.sendQueue <- Job{Metrics: metrics}:
-				slog.Info("Final metrics queued for sending")
-			default:
-				slog.Error("Failed to queue final metrics, queue might be full")
-			}
-		} else {
-			slog.Info("Final metrics sent successfully")
-		}
+	job := a.prepareBatch()
+	if len(job.Metrics) > 0 {
+		slog.Info("Sending final metrics batch before shutdown", "count", len(job.Metrics))
+		a.sendFinalMetrics(job)
 	} else {
 		slog.Info("No metrics to send before shutdown")
 	}
@@ -191,9 +235,56 @@ func (a *agent) Shutdown() {
 	// Ожидаем завершения всех горутин
 	slog.Info("Waiting for all goroutines to finish")
 	a.wg.Wait()
+	a.closeGRPC()
 	slog.Info("======= GRACEFUL SHUTDOWN COMPLETED =======")
 }
 
+// sendFinalMetrics отправляет последний пакет метрик при завершении работы.
+// Если включена дисковая очередь, пакет предварительно сохраняется на диск
+// и в случае неудачи будет отправлен после перезапуска агента.
+func (a *agent) sendFinalMetrics(job Job) {
+	var spoolID uint64
+	if a.spool != nil {
+		id, err := a.spool.Append(job.RequestID, job.Metrics)
+		if err != nil {
+			slog.Error("Failed to spool final metrics", "error", err)
+		} else {
+			spoolID = id
+			// Доставкой сохраненного пакета теперь занимается дисковая очередь.
+			a.completeBatch(job.BatchID, nil)
+			job.BatchID = 0
+		}
+	}
+
+	// Пытаемся отправить метрики напрямую, ограничивая время ожидания повторов
+	ctx, cancel := context.WithTimeout(context.Background(), finalSendTimeout)
+	defer cancel()
+	err := a.sendMetrics(ctx, job, -1) // -1 означает, что это финальная отправка
+	if err == nil {
+		a.completeBatch(job.BatchID, nil)
+		if spoolID != 0 {
+			a.spool.Ack(spoolID)
+		}
+		slog.Info("Final metrics sent successfully")
+		return
+	}
+	slog.Error("Failed to send final metrics directly", "error", err)
+
+	if spoolID != 0 {
+		slog.Info("Final metrics kept in spool for sending after restart", "spool_id", spoolID)
+		return
+	}
+
+	// Если не удалось отправить напрямую, пробуем через очередь
+	select {
+	case a.sendQueue <- job:
+		slog.Info("Final metrics queued for sending")
+	default:
+		a.completeBatch(job.BatchID, err)
+		slog.Error("Failed to queue final metrics, queue might be full")
+	}
+}
+
 // runPolls собирает сведения из системы в отдельной горутине.
 func (a *agent) runPolls(ctx context.Context) {
 	a.wg.Add(1)
@@ -206,29 +297,7 @@ func (a *agent) runPolls(ctx context.Context) {
 	for {
 		select {
 		case <-a.pollTicker.C:
-			pollStart := time.Now()
-			slog.Debug("Starting metrics collection cycle")
-
-			a.mu.Lock()
-			// Перезаписываем метрики свежими показаниями
-			a.ResetMetrics()
-			a.CollectRuntimeMetrics()
-			a.CollectAdditionalMetrics()
-
-			// Увеличиваем счетчик PollCount на 1.
-			a.counters["PollCount"]++
-			// Добавляем обновляемое рандомное значение по условию.
-			a.gauges["RandomValue"] = random.GenerateRandomFloat64()
-
-			metricsCount := len(a.gauges) + len(a.counters)
-			pollDuration := time.Since(pollStart)
-
-			// Логируем текущее значение счетчика PollCount в консоль для наглядности работы.
-			slog.Info("Metrics collection completed",
-				"poll_count", a.counters["PollCount"],
-				"metrics_count", metricsCount,
-				"duration_ms", pollDuration.Milliseconds())
-			a.mu.Unlock()
+			a.poll(ctx)
 		case <-ctx.Done():
 			slog.Info("Stopping polls due to context cancellation")
 			return
@@ -239,6 +308,36 @@ func (a *agent) runPolls(ctx context.Context) {
 	}
 }
 
+// poll выполняет один цикл опроса: собирает метрики коллекторов и увеличивает PollCount.
+func (a *agent) poll(ctx context.Context) {
+	pollStart := time.Now()
+	slog.Debug("Starting metrics collection cycle")
+
+	// Опрашиваем коллекторы без блокировки, чтобы не задерживать подготовку отправки.
+	a.runCollectors(ctx)
+
+	a.mu.Lock()
+	// Перезаписываем gauge метрики свежими показаниями коллекторов
+	a.rebuildGauges()
+	// Добавляем метрики состояния серверов
+	a.storeEndpointGauges()
+
+	// Увеличиваем счетчик PollCount на 1.
+	a.counters["PollCount"]++
+	// Добавляем обновляемое рандомное значение по условию.
+	a.gauges["RandomValue"] = random.GenerateRandomFloat64()
+
+	metricsCount := len(a.gauges) + len(a.counters)
+	pollDuration := time.Since(pollStart)
+
+	// Логируем текущее значение счетчика PollCount в консоль для наглядности работы.
+	slog.Info("Metrics collection completed",
+		"poll_count", a.counters["PollCount"],
+		"metrics_count", metricsCount,
+		"duration_ms", pollDuration.Milliseconds())
+	a.mu.Unlock()
+}
+
 // Создает задачи по отправке метрик в очереди задач на отправку.
 func (a *agent) runReports(ctx context.Context) {
 	a.wg.Add(1)
@@ -254,16 +353,11 @@ func (a *agent) runReports(ctx context.Context) {
 			reportStart := time.Now()
 			slog.Debug("Starting metrics report cycle")
 
-			metrics := a.GetMetrics()
-			metricsCount := len(metrics)
-
-			if metricsCount > 0 {
-				slog.Info("Sending metrics to queue",
-					"metrics_count", metricsCount,
-					"queue_size", len(a.sendQueue))
-				a.sendQueue <- Job{Metrics: metrics}
+			job := a.prepareBatch()
+			if a.spool != nil {
+				a.enqueueSpooled(job)
 			} else {
-				slog.Debug("No metrics to send in this report cycle")
+				a.enqueue(job)
 			}
 
 			reportDuration := time.Since(reportStart)
@@ -279,7 +373,46 @@ func (a *agent) runReports(ctx context.Context) {
 	}
 }
 
-// GetMetrics считывает текущие метрики из агента.
+// enqueue добавляет пакет метрик в очередь задач на отправку.
+func (a *agent) enqueue(job Job) {
+	if len(job.Metrics) == 0 {
+		slog.Debug("No metrics to send in this report cycle")
+		return
+	}
+	slog.Info("Sending metrics to queue",
+		"metrics_count", len(job.Metrics),
+		"queue_size", len(a.sendQueue))
+	a.sendQueue <- job
+}
+
+// enqueueSpooled сохраняет пакет метрик в дисковую очередь и отправляет в очередь задач
+// самые старые неотправленные сегменты, сколько помещается в свободную часть очереди.
+// Приращения счетчиков сохраненного пакета списываются сразу: пакет будет повторно
+// отправляться из дисковой очереди до успешного ответа сервера.
+func (a *agent) enqueueSpooled(job Job) {
+	if len(job.Metrics) > 0 {
+		if _, err := a.spool.Append(job.RequestID, job.Metrics); err != nil {
+			// Диск недоступен, отправляем пакет без сохранения, чтобы не потерять его сразу.
+			slog.Error("Failed to append metrics to spool, sending without spooling", "error", err)
+			a.enqueue(job)
+			return
+		}
+		a.completeBatch(job.BatchID, nil)
+	}
+
+	free := cap(a.sendQueue) - len(a.sendQueue)
+	entries := a.spool.Pending(free)
+	slog.Info("Sending spooled metrics to queue",
+		"jobs", len(entries),
+		"spool_segments", a.spool.Len(),
+		"spool_size_bytes", a.spool.Size(),
+		"queue_size", len(a.sendQueue))
+	for _, entry := range entries {
+		a.sendQueue <- Job{Metrics: entry.Metrics, SpoolID: entry.ID, RequestID: entry.RequestID}
+	}
+}
+
+// GetMetrics считывает текущие метрики из агента, не изменяя накопленные счетчики.
 func (a *agent) GetMetrics() []*metrics.Metric {
 	startTime := time.Now()
 	slog.Debug("Starting metrics preparation for sending")
@@ -318,11 +451,8 @@ func (a *agent) GetMetrics() []*metrics.Metric {
 	for name, value := range a.counters {
 		items = append(items, metrics.NewCounter(name, value))
 	}
-	// Обнуляем счетчик PollCount сразу как только подготовили его к отправке.
-	// Из минусов: счетчик PollCount будет обнулен, даже если отправка метрик не удалась.
-	// Другой вариант: обнулять счетчик PollCount только после успешной отправки метрик.
-	a.counters["PollCount"] = 0
-	slog.Debug("Reset poll count to zero")
+	// Счетчики здесь не обнуляются: приращения переносятся в пакет при подготовке отправки
+	// и списываются только после подтверждения успешной отправки (см. prepareBatch).
 
 	a.mu.Unlock()
 
@@ -30,9 +30,10 @@ func TestAgent_collectRuntimeMetrics(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			a.ResetMetrics()
 			a.CollectRuntimeMetrics()
+			// Помимо прежних 27 метрик runtime.MemStats отправляются все показатели runtime/metrics.
 			got := len(a.GetMetrics())
-			if got != tt.want {
-				t.Errorf("CollectRuntimeMetrics() = %v, want %v", got, tt.want)
+			if got < tt.want {
+				t.Errorf("CollectRuntimeMetrics() = %v, want at least %v", got, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// prepareBatch готовит пакет метрик к отправке.
+//
+// Gauge метрики копируются как есть, а накопленные приращения счетчиков переносятся
+// в пакет: агент продолжает накапливать новые приращения с нуля, а приращения пакета
+// хранятся отдельно до получения результата отправки. Так один и тот же прирост
+// не попадает в два пакета, даже если предыдущий пакет еще отправляется.
+func (a *agent) prepareBatch() Job {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	items := make([]*metrics.Metric, 0, len(a.gauges)+len(a.counters))
+	for name, value := range a.gauges {
+		items = append(items, metrics.NewGauge(name, value))
+	}
+	if len(a.counters) == 0 {
+		return Job{Metrics: items, RequestID: newRequestID()}
+	}
+
+	for name, value := range a.counters {
+		items = append(items, metrics.NewCounter(name, value))
+	}
+
+	a.nextBatchID++
+	a.inFlight[a.nextBatchID] = a.counters
+	a.counters = make(map[string]int64)
+
+	slog.Debug("Counter deltas reserved for batch",
+		"batch_id", a.nextBatchID,
+		"poll_count", a.inFlight[a.nextBatchID]["PollCount"],
+		"in_flight_batches", len(a.inFlight))
+
+	return Job{Metrics: items, BatchID: a.nextBatchID, RequestID: newRequestID()}
+}
+
+// requestIDSize размер ключа идемпотентности пакета в байтах.
+const requestIDSize = 16
+
+// newRequestID создает случайный ключ идемпотентности пакета метрик.
+// Если генератор случайных чисел недоступен, возвращает пустую строку и пакет отправляется без ключа.
+func newRequestID() string {
+	b := make([]byte, requestIDSize)
+	if _, err := rand.Read(b); err != nil {
+		slog.Error("Failed to generate request ID", "error", err)
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// completeBatch завершает пакет по результату отправки. При успехе приращения счетчиков
+// пакета окончательно списываются, при ошибке возвращаются в агент и попадут в следующий пакет.
+func (a *agent) completeBatch(batchID uint64, sendErr error) {
+	if batchID == 0 {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	deltas, ok := a.inFlight[batchID]
+	if !ok {
+		return
+	}
+	delete(a.inFlight, batchID)
+
+	if sendErr == nil {
+		return
+	}
+	for name, delta := range deltas {
+		a.counters[name] += delta
+	}
+	slog.Info("Counter deltas merged back after failed send",
+		"batch_id", batchID,
+		"poll_count", deltas["PollCount"],
+		"pending_poll_count", a.counters["PollCount"])
+}
+
+// Вспомогательные функции для тестирования
+
+// PollOnce - вспомогательная функция для тестирования, которая выполняет один цикл опроса метрик.
+func PollOnce(a Agent) {
+	if agentImpl, ok := a.(*agent); ok {
+		agentImpl.poll(context.Background())
+	}
+}
+
+// ReportOnce - вспомогательная функция для тестирования, которая готовит пакет метрик,
+// отправляет его и обрабатывает результат так же, как воркер и коллектор результатов.
+func ReportOnce(a Agent) error {
+	agentImpl, ok := a.(*agent)
+	if !ok {
+		return errors.New("invalid agent implementation")
+	}
+	job := agentImpl.prepareBatch()
+	err := agentImpl.sendMetrics(context.Background(), job, 0)
+	agentImpl.handleResult(Result{Job: job, Error: err})
+	return err
+}
@@ -0,0 +1,135 @@
+package agent_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/agent"
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// pollCountServer тестовый сервер, суммирующий принятые приращения PollCount.
+type pollCountServer struct {
+	mu       sync.Mutex
+	fail     bool
+	received int64
+}
+
+func (s *pollCountServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	body, err := gzipDecode(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var items []metrics.Metric
+	if err = json.Unmarshal(body, &items); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, m := range items {
+		if m.Name == "PollCount" && m.Delta != nil {
+			s.received += *m.Delta
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *pollCountServer) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func (s *pollCountServer) total() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received
+}
+
+func TestAgent_PollCountResetOnAck(t *testing.T) {
+	srv := &pollCountServer{}
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	a := agent.New(server.URL, time.Hour, time.Hour, "", 1, nil)
+
+	// Отправка не удалась: приращения возвращаются в агент и попадают в следующий пакет.
+	srv.setFail(true)
+	for range 3 {
+		agent.PollOnce(a)
+	}
+	require.Error(t, agent.ReportOnce(a))
+	assert.Equal(t, int64(0), srv.total())
+	assert.Equal(t, int64(3), pollCount(a.GetMetrics()))
+
+	// Успешная отправка списывает приращения.
+	srv.setFail(false)
+	for range 2 {
+		agent.PollOnce(a)
+	}
+	require.NoError(t, agent.ReportOnce(a))
+	assert.Equal(t, int64(5), srv.total())
+	assert.Equal(t, int64(0), pollCount(a.GetMetrics()))
+
+	// Повторная отправка без новых опросов не увеличивает счетчик на сервере.
+	require.NoError(t, agent.ReportOnce(a))
+	assert.Equal(t, int64(5), srv.total())
+}
+
+func TestAgent_GetMetricsDoesNotResetCounters(t *testing.T) {
+	a := agent.New("http://localhost:8080", time.Hour, time.Hour, "", 1, nil)
+
+	agent.PollOnce(a)
+	assert.Equal(t, int64(1), pollCount(a.GetMetrics()))
+	assert.Equal(t, int64(1), pollCount(a.GetMetrics()))
+}
+
+// pollCount возвращает значение PollCount из списка метрик или 0, если метрики нет.
+func pollCount(items []*metrics.Metric) int64 {
+	for _, m := range items {
+		if m.Name == "PollCount" && m.Delta != nil {
+			return *m.Delta
+		}
+	}
+	return 0
+}
+
+func TestAgent_BatchIdempotencyKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(metrics.IdempotencyKeyHeader))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	a := agent.New(server.URL, time.Hour, time.Hour, "", 1, nil)
+	for range 2 {
+		agent.PollOnce(a)
+		require.NoError(t, agent.ReportOnce(a))
+	}
+
+	// Каждый пакет получает собственный ключ идемпотентности.
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, keys, 2)
+	assert.NotEmpty(t, keys[0])
+	assert.NotEmpty(t, keys[1])
+	assert.NotEqual(t, keys[0], keys[1])
+}
@@ -2,12 +2,13 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"runtime"
+	"time"
 
-	"github.com/shirou/gopsutil/cpu"
-	"github.com/shirou/gopsutil/mem"
+	"github.com/maynagashev/go-metrics/internal/agent/collector"
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 )
 
 // Константы для конвертации единиц измерения.
@@ -22,93 +23,135 @@ func (a *agent) ResetMetrics() {
 	slog.Debug("Resetting metrics before collection")
 	a.gauges = make(map[string]float64)
 	a.counters = make(map[string]int64)
+	a.snapshots = make(map[string][]metrics.Metric)
 }
 
+// CollectRuntimeMetrics собирает метрики времени выполнения с помощью коллектора runtime.
 func (a *agent) CollectRuntimeMetrics() {
 	// Проверяем сигнал остановки перед сбором метрик
-	select {
-	case <-a.stopCh:
+	if a.isStopping() {
 		slog.Info("Shutdown signal received, skipping runtime metrics collection")
 		return
-	default:
-		// Продолжаем выполнение
 	}
-
-	slog.Debug("Starting runtime metrics collection")
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-
-	a.gauges["Alloc"] = float64(m.Alloc)
-	a.gauges["BuckHashSys"] = float64(m.BuckHashSys)
-	a.gauges["Frees"] = float64(m.Frees)
-	a.gauges["GCCPUFraction"] = m.GCCPUFraction
-	a.gauges["GCSys"] = float64(m.GCSys)
-	a.gauges["HeapAlloc"] = float64(m.HeapAlloc)
-	a.gauges["HeapIdle"] = float64(m.HeapIdle)
-	a.gauges["HeapInuse"] = float64(m.HeapInuse)
-	a.gauges["HeapObjects"] = float64(m.HeapObjects)
-	a.gauges["HeapReleased"] = float64(m.HeapReleased)
-	a.gauges["HeapSys"] = float64(m.HeapSys)
-	a.gauges["LastGC"] = float64(m.LastGC)
-	a.gauges["Lookups"] = float64(m.Lookups)
-	a.gauges["MCacheInuse"] = float64(m.MCacheInuse)
-	a.gauges["MCacheSys"] = float64(m.MCacheSys)
-	a.gauges["MSpanInuse"] = float64(m.MSpanInuse)
-	a.gauges["MSpanSys"] = float64(m.MSpanSys)
-	a.gauges["Mallocs"] = float64(m.Mallocs)
-	a.gauges["NextGC"] = float64(m.NextGC)
-	a.gauges["NumForcedGC"] = float64(m.NumForcedGC)
-	a.gauges["NumGC"] = float64(m.NumGC)
-	a.gauges["OtherSys"] = float64(m.OtherSys)
-	a.gauges["PauseTotalNs"] = float64(m.PauseTotalNs)
-	a.gauges["StackInuse"] = float64(m.StackInuse)
-	a.gauges["StackSys"] = float64(m.StackSys)
-	a.gauges["Sys"] = float64(m.Sys)
-	a.gauges["TotalAlloc"] = float64(m.TotalAlloc)
-
-	slog.Debug("Runtime metrics collection completed",
-		"metrics_count", len(a.gauges),
-		"heap_alloc_mb", float64(m.HeapAlloc)/BytesInMB,
-		"sys_mb", float64(m.Sys)/BytesInMB)
+	a.collectDirect(collector.NewRuntime())
 }
 
-// CollectAdditionalMetrics собирает дополнительные метрики системы.
+// CollectAdditionalMetrics собирает дополнительные метрики системы с помощью коллектора system.
 func (a *agent) CollectAdditionalMetrics() {
 	// Проверяем сигнал остановки перед сбором метрик
-	select {
-	case <-a.stopCh:
+	if a.isStopping() {
 		slog.Info("Shutdown signal received, skipping additional metrics collection")
 		return
-	default:
-		// Продолжаем выполнение
 	}
+	a.collectDirect(collector.NewSystem())
+}
 
-	slog.Debug("Starting additional system metrics collection")
-
-	v, err := mem.VirtualMemory()
+// collectDirect опрашивает коллектор и сразу записывает результат в метрики агента.
+func (a *agent) collectDirect(c collector.Collector) {
+	items, err := safeCollect(context.Background(), c)
 	if err != nil {
-		slog.Error("Failed to collect virtual memory metrics", "error", err)
-		return
+		slog.Error("Collector failed", "collector", c.Name(), "error", err)
 	}
-	a.gauges["TotalMemory"] = float64(v.Total)
-	a.gauges["FreeMemory"] = float64(v.Free)
+	for _, m := range items {
+		a.store(m)
+	}
+}
 
-	slog.Debug("Memory metrics collected",
-		"total_memory_gb", float64(v.Total)/BytesInGB,
-		"free_memory_gb", float64(v.Free)/BytesInGB,
-		"used_percent", v.UsedPercent)
+// runCollectors опрашивает коллекторы реестра, у которых подошел интервал сбора,
+// и сохраняет полученные метрики. Ошибка или паника одного коллектора не прерывает
+// опрос остальных.
+func (a *agent) runCollectors(ctx context.Context) {
+	now := time.Now()
+	for _, c := range a.registry.Collectors() {
+		if ctx.Err() != nil || a.isStopping() {
+			slog.Info("Shutdown signal received, skipping remaining collectors")
+			return
+		}
 
-	c, err := cpu.Percent(0, true)
-	if err != nil {
-		slog.Error("Failed to collect CPU metrics", "error", err)
-		return
+		name := c.Name()
+		if last, ok := a.lastCollected[name]; ok && now.Sub(last) < c.PollInterval() {
+			continue
+		}
+		a.lastCollected[name] = now
+
+		start := time.Now()
+		items, err := safeCollect(ctx, c)
+		duration := time.Since(start)
+		if err != nil {
+			slog.Error("Collector failed",
+				"collector", name,
+				"error", err,
+				"metrics_count", len(items),
+				"duration_ms", duration.Milliseconds())
+		} else {
+			slog.Debug("Collector completed",
+				"collector", name,
+				"metrics_count", len(items),
+				"duration_ms", duration.Milliseconds())
+		}
+
+		a.mu.Lock()
+		a.applyCollected(name, items)
+		a.mu.Unlock()
+	}
+}
+
+// applyCollected сохраняет результат коллектора: gauge метрики заменяют предыдущий снимок коллектора,
+// приращения счетчиков суммируются до отправки. Вызывается под блокировкой.
+func (a *agent) applyCollected(name string, items []metrics.Metric) {
+	gauges := make([]metrics.Metric, 0, len(items))
+	for _, m := range items {
+		if m.MType == metrics.TypeCounter {
+			a.store(m)
+			continue
+		}
+		gauges = append(gauges, m)
 	}
+	a.snapshots[name] = gauges
+}
+
+// rebuildGauges пересобирает gauge метрики агента из последних снимков коллекторов,
+// чтобы исчезнувшие серии (например, завершившиеся процессы) не отправлялись повторно.
+// Вызывается под блокировкой.
+func (a *agent) rebuildGauges() {
+	a.gauges = make(map[string]float64, len(a.gauges))
+	for _, items := range a.snapshots {
+		for _, m := range items {
+			a.store(m)
+		}
+	}
+}
 
-	cpuCount := 0
-	for i, percent := range c {
-		a.gauges[fmt.Sprintf("CPUutilization%d", i+1)] = percent
-		cpuCount++
+// store записывает метрику в хранилище агента: gauge перезаписывается, counter суммируется.
+func (a *agent) store(m metrics.Metric) {
+	switch m.MType {
+	case metrics.TypeGauge:
+		if m.Value != nil {
+			a.gauges[m.Name] = *m.Value
+		}
+	case metrics.TypeCounter:
+		if m.Delta != nil {
+			a.counters[m.Name] += *m.Delta
+		}
 	}
+}
+
+// isStopping возвращает true, если получен сигнал остановки агента.
+func (a *agent) isStopping() bool {
+	select {
+	case <-a.stopCh:
+		return true
+	default:
+		return false
+	}
+}
 
-	slog.Debug("CPU metrics collection completed", "cpu_count", cpuCount)
+// safeCollect вызывает коллектор, преобразуя панику в ошибку.
+func safeCollect(ctx context.Context, c collector.Collector) (items []metrics.Metric, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("collector %s panicked: %v", c.Name(), r)
+		}
+	}()
+	return c.Collect(ctx)
 }
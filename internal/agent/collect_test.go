@@ -1,14 +1,24 @@
 package agent_test
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/maynagashev/go-metrics/internal/agent"
+	"github.com/maynagashev/go-metrics/internal/agent/collector"
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 )
 
@@ -205,3 +215,66 @@ func TestAgent_IsEncryptionEnabled(t *testing.T) {
 	// Тест с публичным ключом требует создания ключа, что сложно в тестах
 	// Поэтому просто проверяем, что метод существует
 }
+
+// failingCollector имитирует коллектор, который завершается паникой или ошибкой.
+type failingCollector struct {
+	name  string
+	panic bool
+}
+
+func (c failingCollector) Name() string                { return c.name }
+func (c failingCollector) PollInterval() time.Duration { return 0 }
+func (c failingCollector) Collect(_ context.Context) ([]metrics.Metric, error) {
+	if c.panic {
+		panic("collector failure")
+	}
+	return nil, errors.New("collector failure")
+}
+
+func TestAgent_CollectorErrorIsolation(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := gzipDecode(r.Body)
+		if err == nil {
+			var items []metrics.Metric
+			if json.Unmarshal(body, &items) == nil {
+				mu.Lock()
+				for _, m := range items {
+					received[m.Name] = true
+				}
+				mu.Unlock()
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	registry, err := collector.NewRegistry(
+		failingCollector{name: "panicking", panic: true},
+		failingCollector{name: "failing"},
+		collector.NewRuntime(),
+	)
+	require.NoError(t, err)
+
+	a := agent.New(server.URL, 10*time.Millisecond, time.Hour, "", 1, nil, agent.WithCollectors(registry))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	a.Run(ctx)
+
+	// Метрики исправного коллектора отправлены, несмотря на сбои соседних коллекторов.
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, received["HeapAlloc"])
+	assert.True(t, received["PollCount"])
+}
+
+func gzipDecode(r io.Reader) ([]byte, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,121 @@
+// Package collector определяет интерфейс источников метрик агента и реестр для их регистрации.
+//
+// Каждый источник (коллектор) собирает свой набор метрик независимо от остальных:
+// ошибка или паника в одном коллекторе не влияет на сбор метрик другими.
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// Collector источник метрик агента.
+type Collector interface {
+	// Name возвращает уникальное имя коллектора, используется в настройках включения/отключения.
+	Name() string
+
+	// PollInterval возвращает интервал сбора метрик коллектором.
+	// Нулевое значение означает сбор на каждом цикле опроса агента.
+	PollInterval() time.Duration
+
+	// Collect собирает метрики. При ошибке может вернуть частичный набор метрик.
+	Collect(ctx context.Context) ([]metrics.Metric, error)
+}
+
+// ErrDuplicateCollector возвращается при регистрации коллектора с уже занятым именем.
+var ErrDuplicateCollector = errors.New("collector already registered")
+
+// Registry упорядоченный набор коллекторов агента.
+type Registry struct {
+	collectors []Collector
+}
+
+// NewRegistry создает реестр с указанными коллекторами.
+func NewRegistry(collectors ...Collector) (*Registry, error) {
+	r := &Registry{}
+	for _, c := range collectors {
+		if err := r.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register добавляет коллектор в реестр.
+func (r *Registry) Register(c Collector) error {
+	for _, existing := range r.collectors {
+		if existing.Name() == c.Name() {
+			return fmt.Errorf("%w: %s", ErrDuplicateCollector, c.Name())
+		}
+	}
+	r.collectors = append(r.collectors, c)
+	return nil
+}
+
+// Collectors возвращает зарегистрированные коллекторы в порядке регистрации.
+func (r *Registry) Collectors() []Collector {
+	return r.collectors
+}
+
+// Names возвращает имена зарегистрированных коллекторов.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+// Filter возвращает новый реестр, содержащий только включенные коллекторы.
+// Пустой список enabled означает, что включены все коллекторы,
+// коллекторы из списка disabled исключаются в любом случае.
+// Возвращает ошибку, если в списках указано неизвестное имя коллектора.
+func (r *Registry) Filter(enabled, disabled []string) (*Registry, error) {
+	known := make(map[string]struct{}, len(r.collectors))
+	for _, c := range r.collectors {
+		known[c.Name()] = struct{}{}
+	}
+
+	toSet := func(names []string) (map[string]struct{}, error) {
+		set := make(map[string]struct{}, len(names))
+		for _, name := range names {
+			if _, ok := known[name]; !ok {
+				return nil, fmt.Errorf("unknown collector: %s", name)
+			}
+			set[name] = struct{}{}
+		}
+		return set, nil
+	}
+
+	enabledSet, err := toSet(enabled)
+	if err != nil {
+		return nil, err
+	}
+	disabledSet, err := toSet(disabled)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := &Registry{}
+	for _, c := range r.collectors {
+		if _, ok := disabledSet[c.Name()]; ok {
+			continue
+		}
+		if _, ok := enabledSet[c.Name()]; len(enabledSet) > 0 && !ok {
+			continue
+		}
+		filtered.collectors = append(filtered.collectors, c)
+	}
+	return filtered, nil
+}
+
+// Default возвращает реестр со встроенными коллекторами агента.
+func Default() *Registry {
+	return &Registry{
+		collectors: []Collector{NewRuntime(), NewSystem()},
+	}
+}
@@ -0,0 +1,139 @@
+package collector_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/agent/collector"
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+type stubCollector struct {
+	name string
+}
+
+func (c stubCollector) Name() string                { return c.name }
+func (c stubCollector) PollInterval() time.Duration { return 0 }
+func (c stubCollector) Collect(_ context.Context) ([]metrics.Metric, error) {
+	return []metrics.Metric{*metrics.NewGauge(c.name, 1)}, nil
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r, err := collector.NewRegistry(stubCollector{"a"}, stubCollector{"b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, r.Names())
+
+	err = r.Register(stubCollector{"a"})
+	require.ErrorIs(t, err, collector.ErrDuplicateCollector)
+	assert.Len(t, r.Collectors(), 2)
+}
+
+func TestRegistry_Filter(t *testing.T) {
+	r, err := collector.NewRegistry(stubCollector{"a"}, stubCollector{"b"}, stubCollector{"c"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		enabled  []string
+		disabled []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "all enabled by default", want: []string{"a", "b", "c"}},
+		{name: "enabled list", enabled: []string{"c", "a"}, want: []string{"a", "c"}},
+		{name: "disabled list", disabled: []string{"b"}, want: []string{"a", "c"}},
+		{name: "disabled wins", enabled: []string{"a", "b"}, disabled: []string{"b"}, want: []string{"a"}},
+		{name: "unknown enabled", enabled: []string{"x"}, wantErr: true},
+		{name: "unknown disabled", disabled: []string{"x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, filterErr := r.Filter(tt.enabled, tt.disabled)
+			if tt.wantErr {
+				require.Error(t, filterErr)
+				return
+			}
+			require.NoError(t, filterErr)
+			assert.Equal(t, tt.want, filtered.Names())
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	assert.Equal(t, []string{collector.RuntimeName, collector.SystemName}, collector.Default().Names())
+}
+
+func TestRuntime_Collect(t *testing.T) {
+	items, err := collector.NewRuntime().Collect(context.Background())
+	require.NoError(t, err)
+
+	values := make(map[string]float64, len(items))
+	for _, m := range items {
+		assert.Equal(t, metrics.TypeGauge, m.MType)
+		require.NotNil(t, m.Value)
+		assert.False(t, math.IsNaN(*m.Value), m.Name)
+		values[m.Name] = *m.Value
+	}
+
+	// Прежние имена метрик runtime.MemStats сохраняются.
+	legacy := []string{
+		"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys", "HeapAlloc", "HeapIdle",
+		"HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "LastGC", "Lookups", "MCacheInuse",
+		"MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC",
+		"OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc",
+	}
+	for _, name := range legacy {
+		assert.Contains(t, values, name)
+	}
+	assert.Positive(t, values["HeapAlloc"])
+	assert.Positive(t, values["Sys"])
+	assert.GreaterOrEqual(t, values["HeapSys"], values["HeapInuse"])
+
+	// Показатели runtime/metrics и квантили гистограмм.
+	assert.Contains(t, values, "go_gc_heap_allocs_bytes")
+	assert.Positive(t, values["go_sched_goroutines_goroutines"])
+	for _, suffix := range []string{"_p50", "_p90", "_p99", "_max"} {
+		assert.Contains(t, values, "go_sched_latencies_seconds"+suffix)
+		assert.Contains(t, values, "go_gc_pauses_seconds"+suffix)
+	}
+	assert.LessOrEqual(t, values["go_sched_latencies_seconds_p50"], values["go_sched_latencies_seconds_p99"])
+}
+
+func TestRuntime_CollectAfterGC(t *testing.T) {
+	c := collector.NewRuntime()
+	runtime.GC()
+	items, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	values := make(map[string]float64, len(items))
+	for _, m := range items {
+		values[m.Name] = *m.Value
+	}
+	assert.GreaterOrEqual(t, values["NumGC"], 1.0)
+	assert.GreaterOrEqual(t, values["NumForcedGC"], 1.0)
+	assert.Positive(t, values["LastGC"])
+	assert.Positive(t, values["go_gc_pauses_seconds_max"])
+}
+
+func TestSystem_Collect(t *testing.T) {
+	items, err := collector.NewSystem().Collect(context.Background())
+	require.NoError(t, err)
+
+	names := make(map[string]bool, len(items))
+	for _, m := range items {
+		names[m.Name] = true
+	}
+	assert.True(t, names["TotalMemory"])
+	assert.True(t, names["FreeMemory"])
+	for i := range runtime.NumCPU() {
+		assert.True(t, names[fmt.Sprintf("CPUutilization%d", i+1)])
+	}
+}
@@ -0,0 +1,39 @@
+package collector
+
+import "github.com/maynagashev/go-metrics/internal/contracts/metrics"
+
+// deltaTracker вычисляет приращения накопительных счетчиков с момента предыдущего опроса,
+// чтобы отправлять их на сервер как counter метрики, которые сервер суммирует.
+type deltaTracker struct {
+	last map[string]uint64
+}
+
+func newDeltaTracker() *deltaTracker {
+	return &deltaTracker{last: make(map[string]uint64)}
+}
+
+// observe запоминает текущее значение счетчика и добавляет в items counter метрику с приращением.
+// При первом наблюдении приращение не отправляется, так как точка отсчета неизвестна.
+// Если значение уменьшилось (счетчик был сброшен), приращением считается текущее значение.
+func (t *deltaTracker) observe(items []metrics.Metric, name string, value uint64) []metrics.Metric {
+	prev, ok := t.last[name]
+	t.last[name] = value
+	if !ok {
+		return items
+	}
+
+	delta := value - prev
+	if value < prev {
+		delta = value
+	}
+	return append(items, *metrics.NewCounter(name, int64(delta))) //nolint:gosec // приращение за опрос не превышает int64
+}
+
+// forget удаляет сохраненные значения счетчиков, которые не наблюдались в текущем опросе.
+func (t *deltaTracker) forget(seen map[string]struct{}) {
+	for name := range t.last {
+		if _, ok := seen[name]; !ok {
+			delete(t.last, name)
+		}
+	}
+}
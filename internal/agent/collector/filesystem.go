@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/disk"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// FilesystemName имя коллектора использования файловых систем.
+const FilesystemName = "filesystem"
+
+// Filesystem собирает метрики использования файловых систем по точкам монтирования.
+type Filesystem struct {
+	mounts Filter
+}
+
+// NewFilesystem создает коллектор использования файловых систем с фильтром точек монтирования.
+func NewFilesystem(mounts Filter) *Filesystem {
+	return &Filesystem{mounts: mounts}
+}
+
+// Name возвращает имя коллектора.
+func (c *Filesystem) Name() string {
+	return FilesystemName
+}
+
+// PollInterval возвращает интервал сбора, метрики собираются на каждом цикле опроса.
+func (c *Filesystem) PollInterval() time.Duration {
+	return 0
+}
+
+// Collect собирает gauge метрики FilesystemTotal_<точка>, FilesystemUsed_<точка>,
+// FilesystemFree_<точка> и FilesystemUsedPercent_<точка> для физических разделов.
+// Ошибка чтения одного раздела не мешает сбору метрик остальных.
+func (c *Filesystem) Collect(ctx context.Context) ([]metrics.Metric, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	var items []metrics.Metric
+	var errs []error
+	seen := make(map[string]struct{}, len(partitions))
+	for _, p := range partitions {
+		if !c.mounts.Allows(p.Mountpoint) {
+			continue
+		}
+		// Один и тот же раздел может быть смонтирован несколько раз.
+		if _, ok := seen[p.Mountpoint]; ok {
+			continue
+		}
+		seen[p.Mountpoint] = struct{}{}
+
+		usage, usageErr := disk.UsageWithContext(ctx, p.Mountpoint)
+		if usageErr != nil {
+			errs = append(errs, fmt.Errorf("failed to collect usage of %s: %w", p.Mountpoint, usageErr))
+			continue
+		}
+
+		suffix := "_" + mountName(p.Mountpoint)
+		items = append(items,
+			*metrics.NewGauge("FilesystemTotal"+suffix, float64(usage.Total)),
+			*metrics.NewGauge("FilesystemUsed"+suffix, float64(usage.Used)),
+			*metrics.NewGauge("FilesystemFree"+suffix, float64(usage.Free)),
+			*metrics.NewGauge("FilesystemUsedPercent"+suffix, usage.UsedPercent),
+		)
+	}
+
+	return items, errors.Join(errs...)
+}
+
+// mountName преобразует точку монтирования в часть имени метрики: "/" -> "root", "/var/lib" -> "var_lib".
+func mountName(mountpoint string) string {
+	trimmed := strings.Trim(mountpoint, "/")
+	if trimmed == "" {
+		return "root"
+	}
+	return sanitizeName(trimmed)
+}
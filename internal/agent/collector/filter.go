@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Filter отбирает устройства или точки монтирования по шаблонам имен
+// в формате filepath.Match (например, "loop*" или "/snap/*").
+// Символ "*" не захватывает разделитель "/", поэтому "/snap/*" не совпадает с "/snap/a/b".
+type Filter struct {
+	// Include шаблоны разрешенных имен, пустой список разрешает все имена.
+	Include []string
+	// Exclude шаблоны исключаемых имен, имеют приоритет над Include.
+	Exclude []string
+}
+
+// Validate проверяет корректность шаблонов фильтра.
+func (f Filter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Allows возвращает true, если имя проходит фильтр.
+func (f Filter) Allows(name string) bool {
+	if matchAny(f.Exclude, name) {
+		return false
+	}
+	return len(f.Include) == 0 || matchAny(f.Include, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
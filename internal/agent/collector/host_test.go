@@ -0,0 +1,108 @@
+package collector_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/agent/collector"
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+func TestFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter collector.Filter
+		value  string
+		want   bool
+	}{
+		{name: "empty filter", value: "sda", want: true},
+		{name: "included", filter: collector.Filter{Include: []string{"sd*"}}, value: "sda", want: true},
+		{name: "not included", filter: collector.Filter{Include: []string{"sd*"}}, value: "nvme0n1", want: false},
+		{name: "excluded", filter: collector.Filter{Exclude: []string{"loop*"}}, value: "loop0", want: false},
+		{
+			name:   "exclude wins",
+			filter: collector.Filter{Include: []string{"/snap/*"}, Exclude: []string{"/snap/core*"}},
+			value:  "/snap/core20",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Allows(tt.value))
+		})
+	}
+
+	require.Error(t, collector.Filter{Include: []string{"[invalid"}}.Validate())
+	require.NoError(t, collector.Filter{Include: []string{"sd*"}, Exclude: []string{"lo"}}.Validate())
+}
+
+func TestLoad_Collect(t *testing.T) {
+	items, err := collector.NewLoad().Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"LoadAverage1", "LoadAverage5", "LoadAverage15"}, names(items))
+}
+
+func TestSwap_Collect(t *testing.T) {
+	items, err := collector.NewSwap().Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SwapTotal", "SwapUsed", "SwapFree", "SwapUsedPercent"}, names(items))
+}
+
+func TestFilesystem_Collect(t *testing.T) {
+	items, _ := collector.NewFilesystem(collector.Filter{}).Collect(context.Background())
+	for _, m := range items {
+		assert.Equal(t, metrics.TypeGauge, m.MType)
+		assert.True(t, strings.HasPrefix(m.Name, "Filesystem"), m.Name)
+	}
+
+	items, err := collector.NewFilesystem(collector.Filter{Include: []string{"/non-existent-mount"}}).
+		Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestNet_CollectDeltas(t *testing.T) {
+	c := collector.NewNet(collector.Filter{Include: []string{"lo"}})
+
+	// Первый опрос задает точку отсчета, приращения не отправляются.
+	items, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	items, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	for _, m := range items {
+		assert.Equal(t, metrics.TypeCounter, m.MType)
+		assert.True(t, strings.HasSuffix(m.Name, "_lo"), m.Name)
+		assert.GreaterOrEqual(t, *m.Delta, int64(0))
+	}
+}
+
+func TestDiskIO_CollectDeltas(t *testing.T) {
+	c := collector.NewDiskIO(collector.Filter{})
+
+	items, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	items, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	for _, m := range items {
+		assert.Equal(t, metrics.TypeCounter, m.MType)
+		assert.True(t, strings.HasPrefix(m.Name, "Disk"), m.Name)
+		assert.GreaterOrEqual(t, *m.Delta, int64(0))
+	}
+}
+
+func names(items []metrics.Metric) []string {
+	result := make([]string, 0, len(items))
+	for _, m := range items {
+		result = append(result, m.Name)
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/load"
+	"github.com/shirou/gopsutil/mem"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+const (
+	// LoadName имя коллектора средней загрузки системы.
+	LoadName = "load"
+	// SwapName имя коллектора метрик файла подкачки.
+	SwapName = "swap"
+)
+
+// Load собирает среднюю загрузку системы за 1, 5 и 15 минут.
+type Load struct{}
+
+// NewLoad создает коллектор средней загрузки системы.
+func NewLoad() *Load {
+	return &Load{}
+}
+
+// Name возвращает имя коллектора.
+func (c *Load) Name() string {
+	return LoadName
+}
+
+// PollInterval возвращает интервал сбора, метрики собираются на каждом цикле опроса.
+func (c *Load) PollInterval() time.Duration {
+	return 0
+}
+
+// Collect собирает gauge метрики LoadAverage1, LoadAverage5 и LoadAverage15.
+func (c *Load) Collect(ctx context.Context) ([]metrics.Metric, error) {
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect load average: %w", err)
+	}
+	return []metrics.Metric{
+		*metrics.NewGauge("LoadAverage1", avg.Load1),
+		*metrics.NewGauge("LoadAverage5", avg.Load5),
+		*metrics.NewGauge("LoadAverage15", avg.Load15),
+	}, nil
+}
+
+// Swap собирает метрики использования файла подкачки.
+type Swap struct{}
+
+// NewSwap создает коллектор метрик файла подкачки.
+func NewSwap() *Swap {
+	return &Swap{}
+}
+
+// Name возвращает имя коллектора.
+func (c *Swap) Name() string {
+	return SwapName
+}
+
+// PollInterval возвращает интервал сбора, метрики собираются на каждом цикле опроса.
+func (c *Swap) PollInterval() time.Duration {
+	return 0
+}
+
+// Collect собирает gauge метрики SwapTotal, SwapUsed, SwapFree и SwapUsedPercent.
+func (c *Swap) Collect(ctx context.Context) ([]metrics.Metric, error) {
+	swap, err := mem.SwapMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect swap metrics: %w", err)
+	}
+	return []metrics.Metric{
+		*metrics.NewGauge("SwapTotal", float64(swap.Total)),
+		*metrics.NewGauge("SwapUsed", float64(swap.Used)),
+		*metrics.NewGauge("SwapFree", float64(swap.Free)),
+		*metrics.NewGauge("SwapUsedPercent", swap.UsedPercent),
+	}, nil
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/net"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// NetName имя коллектора сетевых счетчиков.
+const NetName = "net"
+
+// Net собирает счетчики сетевых интерфейсов: байты, пакеты, ошибки и отброшенные пакеты.
+// Счетчики отправляются как приращения с момента предыдущего опроса.
+type Net struct {
+	interfaces Filter
+	deltas     *deltaTracker
+}
+
+// NewNet создает коллектор сетевых счетчиков с фильтром интерфейсов.
+func NewNet(interfaces Filter) *Net {
+	return &Net{interfaces: interfaces, deltas: newDeltaTracker()}
+}
+
+// Name возвращает имя коллектора.
+func (c *Net) Name() string {
+	return NetName
+}
+
+// PollInterval возвращает интервал сбора, метрики собираются на каждом цикле опроса.
+func (c *Net) PollInterval() time.Duration {
+	return 0
+}
+
+// Collect собирает counter метрики NetBytesSent_<интерфейс>, NetBytesRecv_<интерфейс>,
+// NetPacketsSent_<интерфейс>, NetPacketsRecv_<интерфейс>, NetErrIn_<интерфейс>,
+// NetErrOut_<интерфейс>, NetDropIn_<интерфейс> и NetDropOut_<интерфейс>.
+func (c *Net) Collect(ctx context.Context) ([]metrics.Metric, error) {
+	counters, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect network counters: %w", err)
+	}
+
+	var items []metrics.Metric
+	seen := make(map[string]struct{})
+	for _, stat := range counters {
+		if !c.interfaces.Allows(stat.Name) {
+			continue
+		}
+		suffix := "_" + sanitizeName(stat.Name)
+		for name, value := range map[string]uint64{
+			"NetBytesSent" + suffix:   stat.BytesSent,
+			"NetBytesRecv" + suffix:   stat.BytesRecv,
+			"NetPacketsSent" + suffix: stat.PacketsSent,
+			"NetPacketsRecv" + suffix: stat.PacketsRecv,
+			"NetErrIn" + suffix:       stat.Errin,
+			"NetErrOut" + suffix:      stat.Errout,
+			"NetDropIn" + suffix:      stat.Dropin,
+			"NetDropOut" + suffix:     stat.Dropout,
+		} {
+			seen[name] = struct{}{}
+			items = c.deltas.observe(items, name, value)
+		}
+	}
+	c.deltas.forget(seen)
+
+	return items, nil
+}
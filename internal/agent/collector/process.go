@@ -0,0 +1,333 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// ProcessName имя коллектора метрик отдельных процессов.
+const ProcessName = "process"
+
+// TargetKind способ выбора отслеживаемых процессов.
+type TargetKind string
+
+const (
+	// TargetPIDFile процесс, PID которого записан в файле.
+	TargetPIDFile TargetKind = "pidfile"
+	// TargetName процессы с точным совпадением имени.
+	TargetName TargetKind = "name"
+	// TargetCmdline процессы, командная строка которых соответствует регулярному выражению.
+	TargetCmdline TargetKind = "cmdline"
+)
+
+// ProcessTarget описание отслеживаемых процессов.
+type ProcessTarget struct {
+	Kind  TargetKind
+	Value string
+	re    *regexp.Regexp
+}
+
+// ParseProcessTarget разбирает описание цели в формате "pidfile:/run/app.pid",
+// "name:nginx" или "cmdline:regexp". Строка без префикса считается именем процесса.
+func ParseProcessTarget(spec string) (ProcessTarget, error) {
+	kind, value, found := strings.Cut(spec, ":")
+	if !found {
+		kind, value = string(TargetName), spec
+	}
+	if value == "" {
+		return ProcessTarget{}, fmt.Errorf("empty process target: %q", spec)
+	}
+
+	target := ProcessTarget{Kind: TargetKind(kind), Value: value}
+	switch target.Kind {
+	case TargetPIDFile, TargetName:
+	case TargetCmdline:
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return ProcessTarget{}, fmt.Errorf("invalid cmdline regexp %q: %w", value, err)
+		}
+		target.re = re
+	default:
+		return ProcessTarget{}, fmt.Errorf("unknown process target kind %q in %q", kind, spec)
+	}
+	return target, nil
+}
+
+// processStats суммарные показатели процессов с одинаковым именем.
+type processStats struct {
+	count      int
+	rss        uint64
+	cpuPercent float64
+	numFDs     int64
+	numThreads int64
+	readBytes  uint64
+	writeBytes uint64
+	readCount  uint64
+	writeCount uint64
+}
+
+// cachedProcess процесс, сохраняемый между опросами для расчета загрузки CPU.
+type cachedProcess struct {
+	proc       *process.Process
+	createTime int64
+}
+
+// Process собирает метрики выбранных процессов: RSS, загрузку CPU, количество
+// открытых файловых дескрипторов, потоков и счетчики ввода-вывода.
+// Метрики процессов с одинаковым именем суммируются, имя метрики начинается с имени процесса.
+type Process struct {
+	targets []ProcessTarget
+	cache   map[int32]cachedProcess
+}
+
+// NewProcess создает коллектор метрик процессов для указанных целей.
+func NewProcess(targets []ProcessTarget) *Process {
+	return &Process{
+		targets: targets,
+		cache:   make(map[int32]cachedProcess),
+	}
+}
+
+// Name возвращает имя коллектора.
+func (c *Process) Name() string {
+	return ProcessName
+}
+
+// PollInterval возвращает интервал сбора, метрики процессов собираются на каждом цикле опроса.
+func (c *Process) PollInterval() time.Duration {
+	return 0
+}
+
+// Collect собирает метрики процессов. Процессы, завершившиеся между опросами, пропускаются,
+// а появившиеся заново подхватываются на следующем опросе.
+func (c *Process) Collect(ctx context.Context) ([]metrics.Metric, error) {
+	pids, resolveErr := c.resolve(ctx)
+
+	stats := make(processStatsMap)
+	// Для целей по имени показываем нулевое количество процессов, даже если они не запущены.
+	for _, t := range c.targets {
+		if t.Kind == TargetName {
+			stats[sanitizeName(t.Value)] = &processStats{}
+		}
+	}
+
+	seen := make(map[int32]struct{}, len(pids))
+	for _, pid := range pids {
+		p, ok := c.lookup(ctx, pid)
+		if !ok {
+			continue
+		}
+		seen[pid] = struct{}{}
+
+		name, err := p.NameWithContext(ctx)
+		if err != nil {
+			slog.Debug("process disappeared during collection", "pid", pid, "error", err)
+			continue
+		}
+		key := sanitizeName(name)
+		if stats[key] == nil {
+			stats[key] = &processStats{}
+		}
+		if !sampleProcess(ctx, p, stats[key]) {
+			slog.Debug("process disappeared during collection", "pid", pid, "name", name)
+		}
+	}
+
+	// Забываем процессы, которые больше не отслеживаются.
+	for pid := range c.cache {
+		if _, ok := seen[pid]; !ok {
+			delete(c.cache, pid)
+		}
+	}
+
+	return stats.toMetrics(), resolveErr
+}
+
+// resolve возвращает PID процессов, соответствующих целям коллектора.
+func (c *Process) resolve(ctx context.Context) ([]int32, error) {
+	unique := make(map[int32]struct{})
+	var errs []error
+
+	needScan := false
+	for _, t := range c.targets {
+		if t.Kind != TargetPIDFile {
+			needScan = true
+			continue
+		}
+		pid, err := readPIDFile(t.Value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		unique[pid] = struct{}{}
+	}
+
+	if needScan {
+		procs, err := process.ProcessesWithContext(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list processes: %w", err))
+		}
+		for _, p := range procs {
+			if c.matches(ctx, p) {
+				unique[p.Pid] = struct{}{}
+			}
+		}
+	}
+
+	pids := make([]int32, 0, len(unique))
+	for pid := range unique {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+
+	return pids, errors.Join(errs...)
+}
+
+// matches проверяет, соответствует ли процесс одной из целей по имени или командной строке.
+func (c *Process) matches(ctx context.Context, p *process.Process) bool {
+	var name, cmdline string
+	var nameErr, cmdlineErr error
+	nameLoaded, cmdlineLoaded := false, false
+
+	for _, t := range c.targets {
+		switch t.Kind {
+		case TargetName:
+			if !nameLoaded {
+				name, nameErr = p.NameWithContext(ctx)
+				nameLoaded = true
+			}
+			if nameErr == nil && name == t.Value {
+				return true
+			}
+		case TargetCmdline:
+			if !cmdlineLoaded {
+				cmdline, cmdlineErr = p.CmdlineWithContext(ctx)
+				cmdlineLoaded = true
+			}
+			if cmdlineErr == nil && t.re.MatchString(cmdline) {
+				return true
+			}
+		case TargetPIDFile:
+		}
+	}
+	return false
+}
+
+// lookup возвращает процесс из кэша или создает новый. Если PID был переиспользован
+// другим процессом, кэш обновляется. Возвращает false, если процесс уже завершился.
+func (c *Process) lookup(ctx context.Context, pid int32) (*process.Process, bool) {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		delete(c.cache, pid)
+		return nil, false
+	}
+	createTime, err := p.CreateTimeWithContext(ctx)
+	if err != nil {
+		delete(c.cache, pid)
+		return nil, false
+	}
+
+	if cached, ok := c.cache[pid]; ok && cached.createTime == createTime {
+		return cached.proc, true
+	}
+	c.cache[pid] = cachedProcess{proc: p, createTime: createTime}
+	return p, true
+}
+
+// sampleProcess добавляет показатели процесса к статистике.
+// Возвращает false, если процесс завершился до снятия показателей.
+func sampleProcess(ctx context.Context, p *process.Process, s *processStats) bool {
+	mem, err := p.MemoryInfoWithContext(ctx)
+	if err != nil {
+		return false
+	}
+	s.count++
+	s.rss += mem.RSS
+
+	// Остальные показатели могут быть недоступны из-за прав доступа, пропускаем их без ошибки.
+	if percent, percentErr := p.PercentWithContext(ctx, 0); percentErr == nil {
+		s.cpuPercent += percent
+	}
+	if fds, fdsErr := p.NumFDsWithContext(ctx); fdsErr == nil {
+		s.numFDs += int64(fds)
+	}
+	if threads, threadsErr := p.NumThreadsWithContext(ctx); threadsErr == nil {
+		s.numThreads += int64(threads)
+	}
+	if io, ioErr := p.IOCountersWithContext(ctx); ioErr == nil {
+		s.readBytes += io.ReadBytes
+		s.writeBytes += io.WriteBytes
+		s.readCount += io.ReadCount
+		s.writeCount += io.WriteCount
+	}
+	return true
+}
+
+// processStatsMap статистика процессов по именам.
+type processStatsMap map[string]*processStats
+
+// toMetrics преобразует статистику процессов в gauge метрики вида <имя процесса>_<показатель>.
+func (stats processStatsMap) toMetrics() []metrics.Metric {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]metrics.Metric, 0, len(stats)*9) //nolint:gomnd // количество показателей процесса
+	for _, name := range names {
+		s := stats[name]
+		items = append(items, *metrics.NewGauge(name+"_Count", float64(s.count)))
+		if s.count == 0 {
+			continue
+		}
+		items = append(items,
+			*metrics.NewGauge(name+"_RSS", float64(s.rss)),
+			*metrics.NewGauge(name+"_CPUPercent", s.cpuPercent),
+			*metrics.NewGauge(name+"_NumFDs", float64(s.numFDs)),
+			*metrics.NewGauge(name+"_NumThreads", float64(s.numThreads)),
+			*metrics.NewGauge(name+"_IOReadBytes", float64(s.readBytes)),
+			*metrics.NewGauge(name+"_IOWriteBytes", float64(s.writeBytes)),
+			*metrics.NewGauge(name+"_IOReadCount", float64(s.readCount)),
+			*metrics.NewGauge(name+"_IOWriteCount", float64(s.writeCount)),
+		)
+	}
+	return items
+}
+
+// readPIDFile читает PID процесса из файла.
+func readPIDFile(path string) (int32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read pid file: %w", err)
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid in file %s: %w", path, err)
+	}
+	return int32(pid), nil
+}
+
+// sanitizeName заменяет в имени процесса символы, недопустимые в имени метрики.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,153 @@
+package collector_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/shirou/gopsutil/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/agent/collector"
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+func TestParseProcessTarget(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantKind collector.TargetKind
+		wantVal  string
+		wantErr  bool
+	}{
+		{spec: "nginx", wantKind: collector.TargetName, wantVal: "nginx"},
+		{spec: "name:postgres", wantKind: collector.TargetName, wantVal: "postgres"},
+		{spec: "pidfile:/run/app.pid", wantKind: collector.TargetPIDFile, wantVal: "/run/app.pid"},
+		{spec: "cmdline:java.*-jar app", wantKind: collector.TargetCmdline, wantVal: "java.*-jar app"},
+		{spec: "cmdline:(", wantErr: true},
+		{spec: "unknown:value", wantErr: true},
+		{spec: "name:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			target, err := collector.ParseProcessTarget(tt.spec)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKind, target.Kind)
+			assert.Equal(t, tt.wantVal, target.Value)
+		})
+	}
+}
+
+func metricsByName(items []metrics.Metric) map[string]metrics.Metric {
+	byName := make(map[string]metrics.Metric, len(items))
+	for _, m := range items {
+		byName[m.Name] = m
+	}
+	return byName
+}
+
+func writePIDFile(t *testing.T, pid int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.pid")
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"), 0o600))
+	return path
+}
+
+func TestProcess_CollectSelf(t *testing.T) {
+	self, err := process.NewProcess(int32(os.Getpid()))
+	require.NoError(t, err)
+	name, err := self.Name()
+	require.NoError(t, err)
+
+	target, err := collector.ParseProcessTarget("pidfile:" + writePIDFile(t, os.Getpid()))
+	require.NoError(t, err)
+	c := collector.NewProcess([]collector.ProcessTarget{target})
+
+	items, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	byName := metricsByName(items)
+	prefix := sanitize(name)
+	require.Contains(t, byName, prefix+"_Count")
+	assert.InDelta(t, 1.0, *byName[prefix+"_Count"].Value, 0.001)
+	require.Contains(t, byName, prefix+"_RSS")
+	assert.Positive(t, *byName[prefix+"_RSS"].Value)
+	assert.Contains(t, byName, prefix+"_NumThreads")
+	assert.Contains(t, byName, prefix+"_CPUPercent")
+}
+
+func TestProcess_NameTargetNotRunning(t *testing.T) {
+	target, err := collector.ParseProcessTarget("name:definitely-not-running-process")
+	require.NoError(t, err)
+	c := collector.NewProcess([]collector.ProcessTarget{target})
+
+	items, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	byName := metricsByName(items)
+	require.Len(t, byName, 1)
+	assert.InDelta(t, 0.0, *byName["definitely_not_running_process_Count"].Value, 0.001)
+}
+
+func TestProcess_DisappearsAndReappears(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "sleep.pid")
+	target, err := collector.ParseProcessTarget("pidfile:" + pidFile)
+	require.NoError(t, err)
+	c := collector.NewProcess([]collector.ProcessTarget{target})
+
+	start := func() *exec.Cmd {
+		cmd := exec.Command("sleep", "30")
+		require.NoError(t, cmd.Start())
+		require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0o600))
+		return cmd
+	}
+	stop := func(cmd *exec.Cmd) {
+		require.NoError(t, cmd.Process.Kill())
+		_ = cmd.Wait()
+	}
+
+	cmd := start()
+	items, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, metricsByName(items), "sleep_RSS")
+
+	// Процесс завершился, PID-файл остался: метрик нет, паники нет.
+	stop(cmd)
+	items, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	// Процесс запущен заново с новым PID.
+	cmd = start()
+	defer stop(cmd)
+	items, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, metricsByName(items), "sleep_RSS")
+}
+
+func TestProcess_MissingPIDFile(t *testing.T) {
+	target, err := collector.ParseProcessTarget("pidfile:/non/existent.pid")
+	require.NoError(t, err)
+
+	items, err := collector.NewProcess([]collector.ProcessTarget{target}).Collect(context.Background())
+	require.Error(t, err)
+	assert.Empty(t, items)
+}
+
+func sanitize(name string) string {
+	out := []rune(name)
+	for i, r := range out {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
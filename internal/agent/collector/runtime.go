@@ -0,0 +1,226 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"runtime/debug"
+	rtmetrics "runtime/metrics"
+	"strings"
+	"time"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// RuntimeName имя коллектора runtime метрик.
+const RuntimeName = "runtime"
+
+const (
+	bytesInKB = 1024
+	bytesInMB = bytesInKB * 1024
+	bytesInGB = bytesInMB * 1024
+)
+
+// runtimeMetricPrefix префикс имен метрик, полученных напрямую из runtime/metrics.
+const runtimeMetricPrefix = "go_"
+
+// histogramQuantiles квантили, в которые разворачиваются гистограммы runtime/metrics.
+//
+//nolint:gochecknoglobals // неизменяемая таблица
+var histogramQuantiles = []struct {
+	suffix string
+	q      float64
+}{
+	{"_p50", 0.5},
+	{"_p90", 0.9},
+	{"_p99", 0.99},
+	{"_max", 1},
+}
+
+// legacyGauges таблица соответствия прежних имен метрик из runtime.MemStats
+// сумме показателей runtime/metrics, сохраняет обратную совместимость имен.
+//
+//nolint:gochecknoglobals // неизменяемая таблица
+var legacyGauges = []struct {
+	name    string
+	sources []string
+}{
+	{"Alloc", []string{"/memory/classes/heap/objects:bytes"}},
+	{"BuckHashSys", []string{"/memory/classes/profiling/buckets:bytes"}},
+	{"Frees", []string{"/gc/heap/frees:objects", "/gc/heap/tiny/allocs:objects"}},
+	{"GCSys", []string{"/memory/classes/metadata/other:bytes"}},
+	{"HeapAlloc", []string{"/memory/classes/heap/objects:bytes"}},
+	{"HeapIdle", []string{"/memory/classes/heap/released:bytes", "/memory/classes/heap/free:bytes"}},
+	{"HeapInuse", []string{"/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes"}},
+	{"HeapObjects", []string{"/gc/heap/objects:objects"}},
+	{"HeapReleased", []string{"/memory/classes/heap/released:bytes"}},
+	{"HeapSys", []string{
+		"/memory/classes/heap/objects:bytes",
+		"/memory/classes/heap/unused:bytes",
+		"/memory/classes/heap/free:bytes",
+		"/memory/classes/heap/released:bytes",
+	}},
+	{"MCacheInuse", []string{"/memory/classes/metadata/mcache/inuse:bytes"}},
+	{"MCacheSys", []string{"/memory/classes/metadata/mcache/inuse:bytes", "/memory/classes/metadata/mcache/free:bytes"}},
+	{"MSpanInuse", []string{"/memory/classes/metadata/mspan/inuse:bytes"}},
+	{"MSpanSys", []string{"/memory/classes/metadata/mspan/inuse:bytes", "/memory/classes/metadata/mspan/free:bytes"}},
+	{"Mallocs", []string{"/gc/heap/allocs:objects", "/gc/heap/tiny/allocs:objects"}},
+	{"NextGC", []string{"/gc/heap/goal:bytes"}},
+	{"NumForcedGC", []string{"/gc/cycles/forced:gc-cycles"}},
+	{"NumGC", []string{"/gc/cycles/total:gc-cycles"}},
+	{"OtherSys", []string{"/memory/classes/other:bytes"}},
+	{"StackInuse", []string{"/memory/classes/heap/stacks:bytes"}},
+	{"StackSys", []string{"/memory/classes/heap/stacks:bytes", "/memory/classes/os-stacks:bytes"}},
+	{"Sys", []string{"/memory/classes/total:bytes"}},
+	{"TotalAlloc", []string{"/gc/heap/allocs:bytes"}},
+}
+
+// Runtime собирает метрики среды выполнения Go из пакета runtime/metrics.
+//
+// В отличие от runtime.ReadMemStats чтение runtime/metrics не останавливает программу.
+// Отправляются все поддерживаемые показатели под именами вида go_gc_heap_allocs_bytes,
+// гистограммы (задержки планировщика, паузы GC и т.д.) разворачиваются в gauge метрики
+// квантилей _p50, _p90, _p99 и _max. Дополнительно отправляются прежние 27 метрик
+// runtime.MemStats (HeapAlloc, NumGC и т.д.), вычисленные по таблице соответствия.
+type Runtime struct {
+	samples []rtmetrics.Sample
+	gcStats debug.GCStats
+}
+
+// NewRuntime создает коллектор runtime метрик.
+func NewRuntime() *Runtime {
+	descs := rtmetrics.All()
+	samples := make([]rtmetrics.Sample, len(descs))
+	for i, d := range descs {
+		samples[i].Name = d.Name
+	}
+	return &Runtime{samples: samples}
+}
+
+// Name возвращает имя коллектора.
+func (c *Runtime) Name() string {
+	return RuntimeName
+}
+
+// PollInterval возвращает интервал сбора, runtime метрики собираются на каждом цикле опроса.
+func (c *Runtime) PollInterval() time.Duration {
+	return 0
+}
+
+// Collect собирает runtime метрики.
+func (c *Runtime) Collect(_ context.Context) ([]metrics.Metric, error) {
+	slog.Debug("Starting runtime metrics collection")
+	rtmetrics.Read(c.samples)
+
+	values := make(map[string]float64, len(c.samples))
+	items := make([]metrics.Metric, 0, len(c.samples)+len(legacyGauges)+len(histogramQuantiles)*8)
+	for _, s := range c.samples {
+		name := runtimeMetricName(s.Name)
+		switch s.Value.Kind() {
+		case rtmetrics.KindUint64:
+			v := float64(s.Value.Uint64())
+			values[s.Name] = v
+			items = append(items, *metrics.NewGauge(name, v))
+		case rtmetrics.KindFloat64:
+			v := s.Value.Float64()
+			values[s.Name] = v
+			items = append(items, *metrics.NewGauge(name, v))
+		case rtmetrics.KindFloat64Histogram:
+			h := s.Value.Float64Histogram()
+			for _, hq := range histogramQuantiles {
+				items = append(items, *metrics.NewGauge(name+hq.suffix, histogramQuantile(h, hq.q)))
+			}
+		case rtmetrics.KindBad:
+			// Показатель не поддерживается текущей версией Go.
+		}
+	}
+
+	items = append(items, c.legacyMetrics(values)...)
+
+	slog.Debug("Runtime metrics collection completed",
+		"metrics_count", len(items),
+		"heap_alloc_mb", values["/memory/classes/heap/objects:bytes"]/bytesInMB,
+		"sys_mb", values["/memory/classes/total:bytes"]/bytesInMB)
+
+	return items, nil
+}
+
+// legacyMetrics вычисляет прежние метрики runtime.MemStats по показателям runtime/metrics.
+func (c *Runtime) legacyMetrics(values map[string]float64) []metrics.Metric {
+	items := make([]metrics.Metric, 0, len(legacyGauges)+4) //nolint:gomnd // метрики вне таблицы
+	for _, g := range legacyGauges {
+		var sum float64
+		for _, source := range g.sources {
+			sum += values[source]
+		}
+		items = append(items, *metrics.NewGauge(g.name, sum))
+	}
+
+	// Доля процессорного времени, затраченного на GC, от всего доступного программе времени.
+	var gcCPUFraction float64
+	if total := values["/cpu/classes/total:cpu-seconds"]; total > 0 {
+		gcCPUFraction = values["/cpu/classes/gc/total:cpu-seconds"] / total
+	}
+
+	// Время последней сборки и суммарные паузы GC недоступны в runtime/metrics,
+	// debug.ReadGCStats читает их без остановки программы.
+	debug.ReadGCStats(&c.gcStats)
+	var lastGC float64
+	if !c.gcStats.LastGC.IsZero() {
+		lastGC = float64(c.gcStats.LastGC.UnixNano())
+	}
+
+	return append(items,
+		*metrics.NewGauge("GCCPUFraction", gcCPUFraction),
+		*metrics.NewGauge("LastGC", lastGC),
+		*metrics.NewGauge("PauseTotalNs", float64(c.gcStats.PauseTotal.Nanoseconds())),
+		// Поле Lookups в runtime.MemStats всегда равно нулю, отправляем его для совместимости.
+		*metrics.NewGauge("Lookups", 0),
+	)
+}
+
+// runtimeMetricName преобразует имя показателя runtime/metrics в имя метрики:
+// "/gc/heap/allocs:bytes" -> "go_gc_heap_allocs_bytes".
+func runtimeMetricName(name string) string {
+	return runtimeMetricPrefix + strings.Map(func(r rune) rune {
+		switch r {
+		case '/', ':', '-', '.':
+			return '_'
+		default:
+			return r
+		}
+	}, strings.TrimPrefix(name, "/"))
+}
+
+// histogramQuantile оценивает квантиль q гистограммы как границу корзины,
+// в которой накопленное количество наблюдений достигает доли q.
+// Для пустой гистограммы возвращает 0.
+func histogramQuantile(h *rtmetrics.Float64Histogram, q float64) float64 {
+	var total uint64
+	for _, count := range h.Counts {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	threshold := uint64(math.Ceil(q * float64(total)))
+	if threshold == 0 {
+		threshold = 1
+	}
+
+	var cumulative uint64
+	for i, count := range h.Counts {
+		cumulative += count
+		if cumulative < threshold {
+			continue
+		}
+		// Buckets содержит len(Counts)+1 границ, крайние могут быть бесконечными.
+		upper := h.Buckets[i+1]
+		if math.IsInf(upper, 1) {
+			return h.Buckets[i]
+		}
+		return upper
+	}
+	return h.Buckets[len(h.Buckets)-1]
+}
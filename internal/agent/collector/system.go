@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// SystemName имя коллектора системных метрик памяти и CPU.
+const SystemName = "system"
+
+// System собирает метрики памяти и загрузки CPU с помощью gopsutil.
+type System struct{}
+
+// NewSystem создает коллектор системных метрик.
+func NewSystem() *System {
+	return &System{}
+}
+
+// Name возвращает имя коллектора.
+func (c *System) Name() string {
+	return SystemName
+}
+
+// PollInterval возвращает интервал сбора, системные метрики собираются на каждом цикле опроса.
+func (c *System) PollInterval() time.Duration {
+	return 0
+}
+
+// Collect собирает метрики памяти (TotalMemory, FreeMemory) и загрузки каждого CPU (CPUutilizationN).
+func (c *System) Collect(ctx context.Context) ([]metrics.Metric, error) {
+	slog.Debug("Starting additional system metrics collection")
+
+	v, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect virtual memory metrics: %w", err)
+	}
+	items := []metrics.Metric{
+		*metrics.NewGauge("TotalMemory", float64(v.Total)),
+		*metrics.NewGauge("FreeMemory", float64(v.Free)),
+	}
+
+	slog.Debug("Memory metrics collected",
+		"total_memory_gb", float64(v.Total)/bytesInGB,
+		"free_memory_gb", float64(v.Free)/bytesInGB,
+		"used_percent", v.UsedPercent)
+
+	percents, err := cpu.PercentWithContext(ctx, 0, true)
+	if err != nil {
+		return items, fmt.Errorf("failed to collect CPU metrics: %w", err)
+	}
+	for i, percent := range percents {
+		items = append(items, *metrics.NewGauge(fmt.Sprintf("CPUutilization%d", i+1), percent))
+	}
+
+	slog.Debug("CPU metrics collection completed", "cpu_count", len(percents))
+
+	return items, nil
+}
@@ -0,0 +1,266 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
+	"github.com/maynagashev/go-metrics/internal/contracts/metricspb"
+)
+
+// EndpointMode режим отправки метрик на несколько серверов.
+type EndpointMode string
+
+const (
+	// ModeFailover пакеты отправляются на активный сервер, при его недоступности агент
+	// переключается на следующий и периодически проверяет, не восстановились ли предыдущие.
+	ModeFailover EndpointMode = "failover"
+	// ModeFanout каждый пакет отправляется на все серверы.
+	ModeFanout EndpointMode = "fanout"
+)
+
+// DefaultProbeInterval интервал проверки доступности приоритетных серверов в режиме failover.
+const DefaultProbeInterval = 10 * time.Second
+
+// ParseEndpointMode разбирает название режима отправки, пустая строка означает failover.
+func ParseEndpointMode(s string) (EndpointMode, error) {
+	switch mode := EndpointMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "", ModeFailover:
+		return ModeFailover, nil
+	case ModeFanout:
+		return ModeFanout, nil
+	default:
+		return "", fmt.Errorf("unknown endpoint mode %q, expected %q or %q", s, ModeFailover, ModeFanout)
+	}
+}
+
+// endpoint сервер, на который отправляются метрики, с собственным выключателем и статистикой отправок.
+type endpoint struct {
+	url     string
+	breaker *retry.Breaker
+	// Количество успешно и неуспешно отправленных пакетов.
+	sent   atomic.Uint64
+	failed atomic.Uint64
+	// Результат последней отправки или проверки доступности.
+	up atomic.Bool
+	// Соединение и клиент gRPC API сервера, используются при отправке по gRPC.
+	grpcConn   *grpc.ClientConn
+	grpcClient metricspb.MetricsClient
+	// Ошибка создания клиента gRPC, возвращается при каждой отправке.
+	grpcErr error
+}
+
+func newEndpoint(url string, threshold int, openTimeout time.Duration) *endpoint {
+	ep := &endpoint{url: url}
+	if threshold > 0 {
+		ep.breaker = retry.NewBreaker(threshold, openTimeout)
+	}
+	ep.up.Store(true)
+	return ep
+}
+
+// initEndpoints создает список серверов: основной сервер агента и дополнительные из WithEndpoints.
+func (a *agent) initEndpoints() {
+	urls := append([]string{a.ServerURL}, a.extraURLs...)
+	a.endpoints = make([]*endpoint, 0, len(urls))
+	for _, url := range urls {
+		ep := newEndpoint(url, a.breakerThreshold, a.breakerOpenTimeout)
+		if a.transport == TransportGRPC {
+			ep.dialGRPC()
+		}
+		a.endpoints = append(a.endpoints, ep)
+	}
+}
+
+// endpointURLs возвращает адреса всех серверов.
+func (a *agent) endpointURLs() []string {
+	urls := make([]string, 0, len(a.endpoints))
+	for _, ep := range a.endpoints {
+		urls = append(urls, ep.url)
+	}
+	return urls
+}
+
+// sendFailover отправляет пакет на активный сервер. Если сервер недоступен после всех повторов,
+// пакет отправляется на следующий сервер списка, и при успехе тот становится активным.
+// Постоянные ошибки (например, 400) не приводят к переключению: пакет отклонит и другой сервер.
+func (a *agent) sendFailover(ctx context.Context, job Job, workerID int) error {
+	active := int(a.active.Load())
+	errs := make([]error, 0, len(a.endpoints))
+	for i := range a.endpoints {
+		idx := (active + i) % len(a.endpoints)
+		ep := a.endpoints[idx]
+
+		err := a.sendTo(ctx, ep, job, workerID)
+		if err == nil {
+			if idx != active {
+				a.switchEndpoint(active, idx, "send failed on active endpoint")
+			}
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", ep.url, err))
+
+		if ctx.Err() != nil {
+			break
+		}
+		if retriable, _ := retry.Classify(err); !retriable && !errors.Is(err, retry.ErrCircuitOpen) {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendFanout отправляет пакет на все серверы параллельно. Пакет считается доставленным,
+// если его принял хотя бы один сервер: повтор пакета с новыми приращениями счетчиков
+// увеличил бы значения на серверах, уже принявших пакет.
+func (a *agent) sendFanout(ctx context.Context, job Job, workerID int) error {
+	errs := make([]error, len(a.endpoints))
+	var wg sync.WaitGroup
+	for i, ep := range a.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.sendTo(ctx, ep, job, workerID); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", ep.url, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) == len(a.endpoints) {
+		return errors.Join(failed...)
+	}
+	if len(failed) > 0 {
+		slog.Warn("metrics batch delivered partially",
+			"workerID", workerID,
+			"delivered", len(a.endpoints)-len(failed),
+			"failed", len(failed),
+			"error", errors.Join(failed...))
+	}
+	return nil
+}
+
+// switchEndpoint делает активным сервер с индексом to, если активным все еще остается from.
+func (a *agent) switchEndpoint(from, to int, reason string) {
+	if !a.active.CompareAndSwap(int32(from), int32(to)) { //nolint:gosec // количество серверов невелико
+		return
+	}
+	slog.Warn("switched active endpoint",
+		"from", a.endpoints[from].url,
+		"to", a.endpoints[to].url,
+		"reason", reason)
+}
+
+// runProbes периодически проверяет доступность серверов с более высоким приоритетом,
+// чем активный, и возвращается на первый доступный из них.
+func (a *agent) runProbes(ctx context.Context) {
+	a.wg.Add(1)
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(a.probeInterval)
+	defer ticker.Stop()
+
+	slog.Info("Endpoint probe routine started", "probe_interval", a.probeInterval)
+	for {
+		select {
+		case <-ticker.C:
+			a.probe(ctx)
+		case <-ctx.Done():
+			return
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// probe проверяет серверы с более высоким приоритетом, чем активный, запросом GET /ping.
+func (a *agent) probe(ctx context.Context) {
+	active := int(a.active.Load())
+	for i := range active {
+		ep := a.endpoints[i]
+		if err := a.ping(ctx, ep); err != nil {
+			ep.up.Store(false)
+			slog.Debug("endpoint probe failed", "url", ep.url, "error", err)
+			continue
+		}
+		ep.up.Store(true)
+		ep.breaker.Success()
+		a.switchEndpoint(active, i, "higher priority endpoint is available again")
+		return
+	}
+}
+
+// ping проверяет доступность сервера. Сервер считается доступным, если ответил
+// любым статусом, кроме временной недоступности: при недоступном хранилище сервер отвечает на /ping 503.
+func (a *agent) ping(ctx context.Context, ep *endpoint) error {
+	if a.transport == TransportGRPC {
+		return a.pingGRPC(ctx, ep)
+	}
+	res, err := a.client.R().SetContext(ctx).Get(ep.url + "/ping")
+	if err != nil {
+		return err
+	}
+	if code := res.StatusCode(); retry.IsRetriableStatus(code) {
+		return retry.NewStatusError(code, res.Header().Get("Retry-After"))
+	}
+	return nil
+}
+
+// storeEndpointGauges записывает метрики состояния серверов в хранилище агента:
+// AgentEndpointUp_<сервер>, AgentEndpointActive_<сервер>, AgentEndpointSent_<сервер>
+// и AgentEndpointFailed_<сервер>. Вызывается под блокировкой.
+func (a *agent) storeEndpointGauges() {
+	active := int(a.active.Load())
+	for i, ep := range a.endpoints {
+		suffix := "_" + endpointMetricSuffix(ep.url)
+		a.gauges["AgentEndpointUp"+suffix] = boolGauge(ep.up.Load())
+		a.gauges["AgentEndpointActive"+suffix] = boolGauge(a.mode == ModeFanout || i == active)
+		a.gauges["AgentEndpointSent"+suffix] = float64(ep.sent.Load())
+		a.gauges["AgentEndpointFailed"+suffix] = float64(ep.failed.Load())
+	}
+}
+
+// endpointMetricSuffix возвращает адрес сервера без схемы в виде, допустимом в имени метрики.
+func endpointMetricSuffix(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, url)
+}
+
+func boolGauge(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// Вспомогательные функции для тестирования
+
+// ProbeOnce - вспомогательная функция для тестирования, которая выполняет одну проверку доступности серверов.
+func ProbeOnce(ctx context.Context, a Agent) {
+	if agentImpl, ok := a.(*agent); ok {
+		agentImpl.probe(ctx)
+	}
+}
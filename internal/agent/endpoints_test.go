@@ -0,0 +1,138 @@
+package agent_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/agent"
+)
+
+// endpointServer тестовый сервер метрик, который можно сделать недоступным.
+type endpointServer struct {
+	*httptest.Server
+	down    atomic.Bool
+	updates atomic.Int32
+}
+
+func newEndpointServer(t *testing.T) *endpointServer {
+	t.Helper()
+	s := &endpointServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/updates" {
+			s.updates.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestParseEndpointMode(t *testing.T) {
+	mode, err := agent.ParseEndpointMode("")
+	require.NoError(t, err)
+	assert.Equal(t, agent.ModeFailover, mode)
+
+	mode, err = agent.ParseEndpointMode(" FanOut ")
+	require.NoError(t, err)
+	assert.Equal(t, agent.ModeFanout, mode)
+
+	_, err = agent.ParseEndpointMode("roundrobin")
+	require.Error(t, err)
+}
+
+func TestAgent_Failover(t *testing.T) {
+	primary := newEndpointServer(t)
+	backup := newEndpointServer(t)
+
+	a := agent.New(primary.URL, time.Hour, time.Hour, "", 1, nil,
+		agent.WithRetryPolicy(fastRetryPolicy(2)),
+		agent.WithEndpoints(agent.ModeFailover, backup.URL))
+
+	// Основной сервер доступен: резервный не используется.
+	agent.PollOnce(a)
+	require.NoError(t, agent.ReportOnce(a))
+	assert.Equal(t, int32(1), primary.updates.Load())
+	assert.Equal(t, int32(0), backup.updates.Load())
+
+	// Основной сервер недоступен: пакет уходит на резервный, и тот становится активным.
+	primary.down.Store(true)
+	agent.PollOnce(a)
+	require.NoError(t, agent.ReportOnce(a))
+	assert.Equal(t, int32(1), backup.updates.Load())
+
+	primary.down.Store(false)
+	agent.PollOnce(a)
+	require.NoError(t, agent.ReportOnce(a))
+	assert.Equal(t, int32(1), primary.updates.Load(), "активный сервер закрепляется до проверки доступности")
+	assert.Equal(t, int32(2), backup.updates.Load())
+
+	// Проверка доступности возвращает агент на основной сервер.
+	agent.ProbeOnce(context.Background(), a)
+	agent.PollOnce(a)
+	require.NoError(t, agent.ReportOnce(a))
+	assert.Equal(t, int32(2), primary.updates.Load())
+	assert.Equal(t, int32(2), backup.updates.Load())
+}
+
+func TestAgent_FailoverAllDown(t *testing.T) {
+	primary := newEndpointServer(t)
+	backup := newEndpointServer(t)
+	primary.down.Store(true)
+	backup.down.Store(true)
+
+	a := agent.New(primary.URL, time.Hour, time.Hour, "", 1, nil,
+		agent.WithRetryPolicy(fastRetryPolicy(1)),
+		agent.WithEndpoints(agent.ModeFailover, backup.URL))
+	agent.PollOnce(a)
+
+	require.Error(t, agent.ReportOnce(a))
+	assert.Equal(t, int64(1), pollCount(a.GetMetrics()), "приращения остаются в агенте")
+}
+
+func TestAgent_Fanout(t *testing.T) {
+	first := newEndpointServer(t)
+	second := newEndpointServer(t)
+
+	a := agent.New(first.URL, time.Hour, time.Hour, "", 1, nil,
+		agent.WithRetryPolicy(fastRetryPolicy(1)),
+		agent.WithEndpoints(agent.ModeFanout, second.URL))
+
+	agent.PollOnce(a)
+	require.NoError(t, agent.ReportOnce(a))
+	assert.Equal(t, int32(1), first.updates.Load())
+	assert.Equal(t, int32(1), second.updates.Load())
+
+	// Пакет доставлен, если его принял хотя бы один сервер.
+	second.down.Store(true)
+	agent.PollOnce(a)
+	require.NoError(t, agent.ReportOnce(a))
+	assert.Equal(t, int32(2), first.updates.Load())
+
+	// Статистика отправок по серверам доступна в метриках агента.
+	agent.PollOnce(a)
+	gauges := make(map[string]float64)
+	for _, m := range a.GetMetrics() {
+		if m.Value != nil {
+			gauges[m.Name] = *m.Value
+		}
+	}
+	firstSuffix := "_" + strings.NewReplacer(".", "_", ":", "_").Replace(strings.TrimPrefix(first.URL, "http://"))
+	secondSuffix := "_" + strings.NewReplacer(".", "_", ":", "_").Replace(strings.TrimPrefix(second.URL, "http://"))
+	assert.InDelta(t, 2, gauges["AgentEndpointSent"+firstSuffix], 0)
+	assert.InDelta(t, 1, gauges["AgentEndpointUp"+firstSuffix], 0)
+	assert.InDelta(t, 1, gauges["AgentEndpointSent"+secondSuffix], 0)
+	assert.InDelta(t, 1, gauges["AgentEndpointFailed"+secondSuffix], 0)
+	assert.InDelta(t, 0, gauges["AgentEndpointUp"+secondSuffix], 0)
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/maynagashev/go-metrics/internal/agent/collector"
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
+	"github.com/maynagashev/go-metrics/internal/agent/spool"
+)
+
+// Option дополнительная настройка агента, передаваемая в New.
+type Option func(a *agent)
+
+// WithSpool включает дисковую очередь: пакеты метрик сохраняются на диск до отправки
+// и удаляются только после успешного ответа сервера.
+func WithSpool(s *spool.Spool) Option {
+	return func(a *agent) {
+		a.spool = s
+	}
+}
+
+// WithCollectors задает реестр коллекторов, опрашиваемых агентом.
+// По умолчанию используются встроенные коллекторы runtime и system.
+func WithCollectors(r *collector.Registry) Option {
+	return func(a *agent) {
+		a.registry = r
+	}
+}
+
+// WithRetryPolicy задает политику повторной отправки пакетов метрик.
+// По умолчанию используется retry.DefaultPolicy().
+func WithRetryPolicy(p retry.Policy) Option {
+	return func(a *agent) {
+		a.retryPolicy = p
+	}
+}
+
+// WithBreaker задает параметры автоматических выключателей серверов: после threshold
+// неудачных отправок подряд запросы к серверу приостанавливаются на openTimeout.
+// threshold 0 отключает выключатели.
+func WithBreaker(threshold int, openTimeout time.Duration) Option {
+	return func(a *agent) {
+		a.breakerThreshold = threshold
+		a.breakerOpenTimeout = openTimeout
+	}
+}
+
+// WithEndpoints добавляет резервные серверы к основному и задает режим отправки на них.
+// Адреса указываются со схемой, например http://localhost:8081.
+func WithEndpoints(mode EndpointMode, urls ...string) Option {
+	return func(a *agent) {
+		a.mode = mode
+		a.extraURLs = urls
+	}
+}
+
+// WithTransport задает протокол отправки метрик. При отправке по gRPC адреса серверов
+// указывают на gRPC API, например grpc://localhost:3200. По умолчанию используется HTTP.
+func WithTransport(t Transport) Option {
+	return func(a *agent) {
+		a.transport = t
+	}
+}
+
+// WithProbeInterval задает интервал проверки доступности приоритетных серверов в режиме failover.
+// 0 отключает проверки: агент возвращается на основной сервер только при отказе резервного.
+func WithProbeInterval(d time.Duration) Option {
+	return func(a *agent) {
+		a.probeInterval = d
+	}
+}
@@ -0,0 +1,143 @@
+package retry
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBreakerThreshold количество неудачных отправок подряд, после которого выключатель размыкается.
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerOpenTimeout время, через которое разомкнутый выключатель пропускает пробный запрос.
+	DefaultBreakerOpenTimeout = 30 * time.Second
+)
+
+// ErrCircuitOpen возвращается, когда выключатель разомкнут и запрос к серверу не выполняется.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState состояние выключателя.
+type BreakerState int
+
+const (
+	// StateClosed запросы выполняются как обычно.
+	StateClosed BreakerState = iota
+	// StateOpen сервер считается недоступным, запросы не выполняются.
+	StateOpen
+	// StateHalfOpen выполняется единственный пробный запрос.
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker автоматический выключатель запросов к серверу.
+//
+// После Threshold неудачных отправок подряд выключатель размыкается и запросы не выполняются
+// в течение OpenTimeout. Затем пропускается один пробный запрос: при успехе выключатель
+// замыкается, при ошибке снова размыкается на OpenTimeout.
+type Breaker struct {
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+// NewBreaker создает выключатель. Если threshold <= 0, выключатель никогда не размыкается.
+func NewBreaker(threshold int, openTimeout time.Duration) *Breaker {
+	return &Breaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		now:         time.Now,
+	}
+}
+
+// Allow возвращает true, если запрос к серверу можно выполнить.
+func (b *Breaker) Allow() bool {
+	if b == nil || b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = StateHalfOpen
+		slog.Info("circuit breaker half-open, probing server")
+		return true
+	case StateHalfOpen:
+		// Пробный запрос уже выполняется, остальные ждут его результата.
+		return false
+	default:
+		return true
+	}
+}
+
+// Success отмечает успешную отправку и замыкает выключатель.
+func (b *Breaker) Success() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateClosed {
+		slog.Info("circuit breaker closed, server is available again")
+	}
+	b.state = StateClosed
+	b.failures = 0
+}
+
+// Failure отмечает неудачную отправку. Размыкает выключатель после threshold ошибок подряд
+// или после неудачного пробного запроса.
+func (b *Breaker) Failure() {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		if b.state != StateOpen {
+			slog.Warn("circuit breaker opened, pausing requests to server",
+				"failures", b.failures,
+				"open_timeout", b.openTimeout)
+		}
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// State возвращает текущее состояние выключателя.
+func (b *Breaker) State() BreakerState {
+	if b == nil {
+		return StateClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
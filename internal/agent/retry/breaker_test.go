@@ -0,0 +1,71 @@
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
+)
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	b := retry.NewBreaker(3, time.Hour)
+
+	for range 2 {
+		assert.True(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, retry.StateClosed, b.State())
+
+	// Успешная отправка сбрасывает счетчик ошибок подряд.
+	b.Success()
+	for range 2 {
+		b.Failure()
+	}
+	assert.Equal(t, retry.StateClosed, b.State())
+
+	b.Failure()
+	assert.Equal(t, retry.StateOpen, b.State())
+	assert.False(t, b.Allow())
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	const openTimeout = 20 * time.Millisecond
+	b := retry.NewBreaker(1, openTimeout)
+
+	b.Failure()
+	assert.False(t, b.Allow())
+
+	// После паузы пропускается только один пробный запрос.
+	time.Sleep(2 * openTimeout)
+	assert.True(t, b.Allow())
+	assert.Equal(t, retry.StateHalfOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// Неудачная проба снова размыкает выключатель.
+	b.Failure()
+	assert.Equal(t, retry.StateOpen, b.State())
+	assert.False(t, b.Allow())
+
+	// Успешная проба замыкает выключатель.
+	time.Sleep(2 * openTimeout)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.Equal(t, retry.StateClosed, b.State())
+	assert.True(t, b.Allow())
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := retry.NewBreaker(0, time.Hour)
+	for range 10 {
+		b.Failure()
+	}
+	assert.True(t, b.Allow())
+
+	var nilBreaker *retry.Breaker
+	assert.True(t, nilBreaker.Allow())
+	nilBreaker.Failure()
+	nilBreaker.Success()
+	assert.Equal(t, retry.StateClosed, nilBreaker.State())
+}
@@ -0,0 +1,199 @@
+// Package retry реализует политику повторной отправки метрик агентом: классификацию ошибок,
+// экспоненциальную задержку со случайным разбросом, поддержку заголовка Retry-After
+// и автоматический выключатель (circuit breaker), который прекращает запросы к недоступному серверу.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultMaxAttempts количество попыток отправки пакета по умолчанию (первая попытка и 3 повтора).
+	DefaultMaxAttempts = 4
+	// DefaultBaseDelay задержка перед первым повтором по умолчанию.
+	DefaultBaseDelay = time.Second
+	// DefaultMaxDelay максимальная задержка между попытками по умолчанию.
+	DefaultMaxDelay = 30 * time.Second
+	// DefaultJitter доля случайного разброса задержки по умолчанию.
+	DefaultJitter = 0.2
+
+	backoffMultiplier = 2
+)
+
+// Policy параметры повторной отправки запросов.
+type Policy struct {
+	// MaxAttempts максимальное количество попыток отправки, включая первую.
+	MaxAttempts int
+	// BaseDelay задержка перед первым повтором, далее удваивается с каждой попыткой.
+	BaseDelay time.Duration
+	// MaxDelay максимальная задержка между попытками, в том числе заданная сервером в Retry-After.
+	MaxDelay time.Duration
+	// Jitter доля случайного разброса задержки от 0 до 1: при 0.2 задержка 10s превращается в 8s..12s.
+	Jitter float64
+}
+
+// DefaultPolicy возвращает политику повторов по умолчанию.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: DefaultMaxAttempts,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+		Jitter:      DefaultJitter,
+	}
+}
+
+// Validate проверяет корректность параметров политики.
+func (p Policy) Validate() error {
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("retry max attempts should be greater than 0, got %d", p.MaxAttempts)
+	}
+	if p.BaseDelay < 0 || p.MaxDelay < 0 {
+		return errors.New("retry delays should not be negative")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("retry jitter should be between 0 and 1, got %v", p.Jitter)
+	}
+	return nil
+}
+
+// Backoff возвращает задержку перед повтором с номером attempt (начиная с 1):
+// BaseDelay * 2^(attempt-1) со случайным разбросом, но не больше MaxDelay.
+func (p Policy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(p.BaseDelay) * math.Pow(backoffMultiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		//nolint:gosec // криптостойкий генератор для разброса задержек не требуется
+		delay *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+// Delay возвращает задержку перед повтором с учетом заданной сервером в Retry-After:
+// используется большая из двух задержек, ограниченная MaxDelay.
+func (p Policy) Delay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := p.Backoff(attempt)
+	if retryAfter > delay {
+		delay = retryAfter
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// StatusError ошибка ответа сервера с неуспешным кодом статуса.
+type StatusError struct {
+	StatusCode int
+	// RetryAfter задержка из заголовка Retry-After, 0 если заголовок не передан.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// NewStatusError создает ошибку по коду статуса и значению заголовка Retry-After.
+func NewStatusError(statusCode int, retryAfter string) *StatusError {
+	d, _ := ParseRetryAfter(retryAfter, time.Now())
+	return &StatusError{StatusCode: statusCode, RetryAfter: d}
+}
+
+// ParseRetryAfter разбирает значение заголовка Retry-After: количество секунд или дату в формате HTTP.
+// Возвращает false, если значение пустое или некорректное.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// IsRetriableStatus возвращает true для кодов статуса, при которых запрос имеет смысл повторить:
+// сервер перегружен, временно недоступен или не дождался запроса.
+func IsRetriableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Classify определяет, можно ли повторить запрос после ошибки, и возвращает задержку,
+// запрошенную сервером в заголовке Retry-After (0, если сервер ее не указал).
+func Classify(err error) (bool, time.Duration) {
+	if err == nil {
+		return false, 0
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return IsRetriableStatus(statusErr.StatusCode), statusErr.RetryAfter
+	}
+
+	// Проверяем, является ли ошибка общей ошибкой сети и таймаутом
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true, 0
+	}
+
+	// Ошибки установки соединения и обрыв соединения сервером
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true, 0
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true, 0
+	}
+
+	return false, 0
+}
+
+// Sleep ожидает указанное время или отмену контекста.
+// Возвращает ошибку контекста, если ожидание было прервано.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
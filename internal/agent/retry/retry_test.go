@@ -0,0 +1,134 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
+)
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := retry.Policy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	assert.Equal(t, time.Second, p.Backoff(1))
+	assert.Equal(t, 2*time.Second, p.Backoff(2))
+	assert.Equal(t, 4*time.Second, p.Backoff(3))
+	assert.Equal(t, 5*time.Second, p.Backoff(4), "задержка ограничена MaxDelay")
+	assert.Equal(t, 5*time.Second, p.Backoff(50))
+}
+
+func TestPolicy_BackoffJitter(t *testing.T) {
+	p := retry.Policy{MaxAttempts: 5, BaseDelay: 10 * time.Second, MaxDelay: time.Minute, Jitter: 0.2}
+
+	for range 100 {
+		d := p.Backoff(1)
+		assert.GreaterOrEqual(t, d, 8*time.Second)
+		assert.LessOrEqual(t, d, 12*time.Second)
+	}
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	p := retry.Policy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	assert.Equal(t, time.Second, p.Delay(1, 0))
+	assert.Equal(t, 7*time.Second, p.Delay(1, 7*time.Second), "используется задержка из Retry-After")
+	assert.Equal(t, 10*time.Second, p.Delay(1, time.Hour), "Retry-After ограничен MaxDelay")
+	assert.Equal(t, 4*time.Second, p.Delay(3, time.Second), "Retry-After не уменьшает задержку")
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	require.NoError(t, retry.DefaultPolicy().Validate())
+
+	tests := []struct {
+		name   string
+		policy retry.Policy
+	}{
+		{"zero attempts", retry.Policy{MaxAttempts: 0}},
+		{"negative delay", retry.Policy{MaxAttempts: 1, BaseDelay: -time.Second}},
+		{"jitter above one", retry.Policy{MaxAttempts: 1, Jitter: 1.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.policy.Validate())
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"empty", "", 0, false},
+		{"seconds", "120", 2 * time.Minute, true},
+		{"negative seconds", "-1", 0, false},
+		{"http date", now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{"date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"invalid", "soon", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := retry.ParseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		retriable  bool
+		retryAfter time.Duration
+	}{
+		{"nil", nil, false, 0},
+		{"regular error", errors.New("boom"), false, 0},
+		{"too many requests", &retry.StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second}, true, time.Second},
+		{"service unavailable", &retry.StatusError{StatusCode: http.StatusServiceUnavailable}, true, 0},
+		{"gateway timeout", &retry.StatusError{StatusCode: http.StatusGatewayTimeout}, true, 0},
+		{"bad request", &retry.StatusError{StatusCode: http.StatusBadRequest}, false, 0},
+		{"internal server error", &retry.StatusError{StatusCode: http.StatusInternalServerError}, false, 0},
+		{"wrapped status", fmt.Errorf("send: %w", &retry.StatusError{StatusCode: http.StatusBadGateway}), true, 0},
+		{"op error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true, 0},
+		{"unexpected eof", fmt.Errorf("post: %w", io.ErrUnexpectedEOF), true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retriable, retryAfter := retry.Classify(tt.err)
+			assert.Equal(t, tt.retriable, retriable)
+			assert.Equal(t, tt.retryAfter, retryAfter)
+		})
+	}
+}
+
+func TestNewStatusError(t *testing.T) {
+	err := retry.NewStatusError(http.StatusServiceUnavailable, "3")
+	assert.Equal(t, http.StatusServiceUnavailable, err.StatusCode)
+	assert.Equal(t, 3*time.Second, err.RetryAfter)
+	assert.Equal(t, "unexpected status code: 503", err.Error())
+}
+
+func TestSleep(t *testing.T) {
+	require.NoError(t, retry.Sleep(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	err := retry.Sleep(ctx, time.Hour)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -1,92 +1,139 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
-	"net"
 	"net/http"
-	"time"
 
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/pkg/crypto"
 	"github.com/maynagashev/go-metrics/pkg/middleware/gzip"
 	"github.com/maynagashev/go-metrics/pkg/sign"
 )
 
-const backoffFactor = 2
-
 // Отправка очередного списка метрик из очереди на отправку, с помощью воркеров.
-func (a *agent) sendMetrics(items []*metrics.Metric, workerID int) error {
-	// Отправляем все метрики пачкой на новый маршрут /updates
-	// Ошибки подключения при отправке метрик можно повторить, но не более 3-х раз (retriable errors)
-	for i := 0; i <= maxSendRetries; i++ {
-		// Пауза перед повторной отправкой
-		if i > 0 {
-			//nolint:gomnd // количество секунд для паузы зависит от номера попытки
-			sleepSeconds := i*backoffFactor - 1 // 1, 3, 5, 7, 9, 11, ...
-			slog.Info(
-				fmt.Sprintf("retrying to send metrics (try=%d) in %d seconds", sleepSeconds, i),
-				"workerID", workerID,
-			)
-			time.Sleep(time.Duration(sleepSeconds) * time.Second)
+// В зависимости от режима пакет отправляется на активный сервер с переключением
+// на резервный при недоступности (failover) или на все серверы сразу (fanout).
+func (a *agent) sendMetrics(ctx context.Context, job Job, workerID int) error {
+	if a.mode == ModeFanout {
+		return a.sendFanout(ctx, job, workerID)
+	}
+	return a.sendFailover(ctx, job, workerID)
+}
+
+// sendTo отправляет пакет на один сервер.
+// Все попытки отправки пакета используют один ключ идемпотентности, поэтому повтор после таймаута,
+// когда сервер уже применил пакет, не увеличивает счетчики на сервере второй раз.
+//
+// Повторяются только временные ошибки: сетевые сбои и ответы 408, 429, 502, 503, 504.
+// Пауза между попытками растет экспоненциально со случайным разбросом, но не меньше
+// запрошенной сервером в заголовке Retry-After. Ожидание прерывается отменой контекста.
+// Если сервер недоступен продолжительное время, его выключатель размыкается и пакеты
+// на этот сервер не отправляются до пробного запроса.
+func (a *agent) sendTo(ctx context.Context, ep *endpoint, job Job, workerID int) error {
+	err := a.sendWithRetries(ctx, ep, job, workerID)
+	if err == nil {
+		ep.sent.Add(1)
+		ep.up.Store(true)
+		return nil
+	}
+
+	ep.failed.Add(1)
+	if retriable, _ := retry.Classify(err); retriable || errors.Is(err, retry.ErrCircuitOpen) {
+		ep.up.Store(false)
+	}
+	slog.Error("failed to send metrics to endpoint",
+		"workerID", workerID,
+		"url", ep.url,
+		"sent", ep.sent.Load(),
+		"failed", ep.failed.Load(),
+		"error", err)
+	return err
+}
+
+// sendWithRetries выполняет попытки отправки пакета на сервер согласно политике повторов.
+func (a *agent) sendWithRetries(ctx context.Context, ep *endpoint, job Job, workerID int) error {
+	items := job.Metrics
+	policy := a.retryPolicy
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if !ep.breaker.Allow() {
+			slog.Warn("circuit breaker is open, skipping send", "workerID", workerID, "url", ep.url)
+			if lastErr != nil {
+				return errors.Join(retry.ErrCircuitOpen, lastErr)
+			}
+			return retry.ErrCircuitOpen
 		}
 
-		err := a.makeUpdatesRequest(items, i, workerID)
+		err := a.sendBatch(ctx, ep, job, attempt-1, workerID)
 		// Если нет ошибок выходим из цикла и функции
 		if err == nil {
+			ep.breaker.Success()
 			return nil
 		}
+		lastErr = err
 
 		// Логируем ошибку
 		slog.Error(
-			fmt.Sprintf("failed to send metrics (try=%d): %s", i, err),
+			fmt.Sprintf("failed to send metrics (try=%d): %s", attempt-1, err),
 			"workerID",
 			workerID,
+			"url",
+			ep.url,
 			"metrics",
 			items,
 		)
 
-		// Если ошибка не retriable, то выходим из цикла и функции, иначе продолжаем попытки
-		if !a.isRetriableSendError(err) {
+		// Если ошибка не retriable, то выходим из цикла и функции, иначе продолжаем попытки.
+		// Сервер, ответивший постоянной ошибкой, доступен, поэтому выключатель не размыкается.
+		retriable, retryAfter := retry.Classify(err)
+		if !retriable {
+			ep.breaker.Success()
 			slog.Debug("non-retriable error, stopping retries", "workerID", workerID, "err", err)
 			return err
 		}
+		ep.breaker.Failure()
+
+		if attempt == policy.MaxAttempts {
+			break
+		}
+
+		// Пауза перед повторной отправкой
+		delay := policy.Delay(attempt, retryAfter)
+		slog.Info(
+			fmt.Sprintf("retrying to send metrics (try=%d) in %s", attempt, delay),
+			"workerID", workerID,
+			"url", ep.url,
+			"retry_after", retryAfter,
+		)
+		if sleepErr := retry.Sleep(ctx, delay); sleepErr != nil {
+			return fmt.Errorf("retry interrupted: %w", errors.Join(sleepErr, lastErr))
+		}
 	}
 
-	return fmt.Errorf("failed to send metrics after %d retries", maxSendRetries)
+	return fmt.Errorf("failed to send metrics after %d attempts: %w", policy.MaxAttempts, lastErr)
 }
 
 // Проверяет, является ли ошибка временной и можно ли повторить запрос.
 func (a *agent) isRetriableSendError(err error) bool {
 	slog.Debug(fmt.Sprintf("isRetriableSendError: %#v", err))
-
-	// Проверяем, является ли ошибка общей ошибкой сети, временной или таймаутом
-	var netErr net.Error
-	if errors.As(err, &netErr) {
-		slog.Debug(fmt.Sprintf("isRetriableSendError => AS net.Error: %#v", netErr))
-		// Проверяем, является ли ошибка временной
-		if netErr.Timeout() {
-			return true
-		}
-	}
-
-	// Проверяем, является ли ошибка ошибкой сети
-	var opErr *net.OpError
-	if errors.As(err, &opErr) {
-		slog.Debug("isRetriableSendError => AS net.OpError", "err", err)
-		return true
-	}
-
-	// Если ошибка не является временной, возвращаем false
-	return false
+	retriable, _ := retry.Classify(err)
+	return retriable
 }
 
 // При ошибках подключения запрос можно повторить, но не более 3-х раз (retriable errors).
-func (a *agent) makeUpdatesRequest(items []*metrics.Metric, try int, workerID int) error {
+func (a *agent) makeUpdatesRequest(serverURL string, job Job, try int, workerID int) error {
 	var err error
-	url := fmt.Sprintf("%s/updates", a.ServerURL)
+	items := job.Metrics
+	url := fmt.Sprintf("%s/updates", serverURL)
 	slog.Info(
 		fmt.Sprintf("sending metrics batch (try=%d)", try),
 		"workerID",
@@ -101,6 +148,9 @@ func (a *agent) makeUpdatesRequest(items []*metrics.Metric, try int, workerID in
 	req := a.client.R()
 	req.Debug = true // Включаем отладочный режим, чтобы видеть все детали запроса, в частности, использование сжатия
 	req.SetHeader("Content-Type", "application/json")
+	if job.RequestID != "" {
+		req.SetHeader(metrics.IdempotencyKeyHeader, job.RequestID)
+	}
 
 	// Преобразуем метрики в JSON
 	bytesBody, err := json.Marshal(items)
@@ -144,7 +194,7 @@ func (a *agent) makeUpdatesRequest(items []*metrics.Metric, try int, workerID in
 
 	// Обрабатываем ответ сервера
 	if res.StatusCode() != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", res.StatusCode())
+		return retry.NewStatusError(res.StatusCode(), res.Header().Get("Retry-After"))
 	}
 
 	return nil
@@ -164,7 +214,7 @@ func IsRetriableSendError(err error) bool {
 func SendMetrics(a Agent, metrics []*metrics.Metric, workerID int) error {
 	// Мы можем получить доступ к методам конкретного типа только через приведение типа
 	if agentImpl, ok := a.(*agent); ok {
-		return agentImpl.makeUpdatesRequest(metrics, 0, workerID)
+		return agentImpl.makeUpdatesRequest(agentImpl.ServerURL, Job{Metrics: metrics}, 0, workerID)
 	}
 	return errors.New("invalid agent implementation")
 }
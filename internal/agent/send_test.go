@@ -5,6 +5,8 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/maynagashev/go-metrics/internal/agent"
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 )
 
@@ -201,3 +204,98 @@ func TestMakeUpdatesRequest_WithSigning(t *testing.T) {
 	err := agent.SendMetrics(a, metrics, 1)
 	require.NoError(t, err)
 }
+
+// fastRetryPolicy политика повторов без заметных пауз для тестов.
+func fastRetryPolicy(attempts int) retry.Policy {
+	return retry.Policy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+}
+
+func TestSendMetrics_RetriesOnServiceUnavailable(t *testing.T) {
+	var calls atomic.Int32
+	var keys sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys.Store(r.Header.Get(metrics.IdempotencyKeyHeader), struct{}{})
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	a := agent.New(server.URL, time.Hour, time.Hour, "", 1, nil,
+		agent.WithRetryPolicy(fastRetryPolicy(4)))
+	agent.PollOnce(a)
+
+	require.NoError(t, agent.ReportOnce(a))
+	assert.Equal(t, int32(3), calls.Load())
+
+	// Все попытки отправки пакета используют один ключ идемпотентности.
+	var count int
+	keys.Range(func(_, _ any) bool { count++; return true })
+	assert.Equal(t, 1, count)
+}
+
+func TestSendMetrics_HonorsRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	a := agent.New(server.URL, time.Hour, time.Hour, "", 1, nil,
+		agent.WithRetryPolicy(retry.Policy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second}))
+	agent.PollOnce(a)
+
+	start := time.Now()
+	require.NoError(t, agent.ReportOnce(a))
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestSendMetrics_NoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	a := agent.New(server.URL, time.Hour, time.Hour, "", 1, nil,
+		agent.WithRetryPolicy(fastRetryPolicy(4)))
+	agent.PollOnce(a)
+
+	err := agent.ReportOnce(a)
+	var statusErr *retry.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestSendMetrics_CircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	a := agent.New(server.URL, time.Hour, time.Hour, "", 1, nil,
+		agent.WithRetryPolicy(fastRetryPolicy(2)),
+		agent.WithBreaker(2, time.Hour))
+	agent.PollOnce(a)
+
+	require.Error(t, agent.ReportOnce(a))
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Выключатель разомкнут: запросы к серверу не выполняются, приращения остаются в агенте.
+	require.ErrorIs(t, agent.ReportOnce(a), retry.ErrCircuitOpen)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, int64(1), pollCount(a.GetMetrics()))
+}
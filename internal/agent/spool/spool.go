@@ -0,0 +1,302 @@
+// Package spool реализует дисковую очередь (write-ahead spool) для пакетов метрик агента.
+//
+// Каждый пакет метрик записывается в отдельный файл-сегмент до отправки на сервер
+// и удаляется только после подтверждения успешной отправки. Сегменты, оставшиеся
+// на диске после перезапуска агента, повторно отправляются в порядке их создания.
+// Общий размер очереди ограничен: при превышении лимита удаляются самые старые сегменты.
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+const (
+	segmentExt = ".seg"
+	tmpExt     = ".tmp"
+	dirPerm    = 0o750
+	filePerm   = 0o600
+)
+
+// Entry пакет метрик, прочитанный из сегмента очереди.
+type Entry struct {
+	// ID порядковый номер сегмента, используется для подтверждения отправки.
+	ID uint64
+	// RequestID ключ идемпотентности пакета, сохраняется вместе с метриками,
+	// чтобы повторная отправка после перезапуска не применялась сервером дважды.
+	RequestID string
+	// Metrics метрики, сохраненные в сегменте.
+	Metrics []*metrics.Metric
+}
+
+// segmentData содержимое файла-сегмента.
+type segmentData struct {
+	RequestID string            `json:"request_id,omitempty"`
+	Metrics   []*metrics.Metric `json:"metrics"`
+}
+
+type segment struct {
+	id   uint64
+	size int64
+}
+
+// Spool дисковая очередь пакетов метрик.
+type Spool struct {
+	dir     string
+	maxSize int64
+
+	mu       sync.Mutex
+	segments []segment // сегменты в порядке возрастания ID
+	inFlight map[uint64]struct{}
+	size     int64
+	nextID   uint64
+}
+
+// Open открывает (или создает) очередь в указанной директории и загружает
+// список сегментов, оставшихся с предыдущего запуска.
+// Если maxSize <= 0, размер очереди не ограничивается.
+func Open(dir string, maxSize int64) (*Spool, error) {
+	if dir == "" {
+		return nil, errors.New("spool directory not specified")
+	}
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{
+		dir:      dir,
+		maxSize:  maxSize,
+		inFlight: make(map[uint64]struct{}),
+		nextID:   1,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	slog.Info("spool opened",
+		"dir", dir,
+		"segments", len(s.segments),
+		"size_bytes", s.size,
+		"max_size_bytes", maxSize)
+
+	return s, nil
+}
+
+// load читает содержимое директории и восстанавливает список сегментов.
+func (s *Spool) load() error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		name := f.Name()
+
+		// Недописанные временные файлы остаются после аварийного завершения, удаляем их.
+		if strings.HasSuffix(name, tmpExt) {
+			if rmErr := os.Remove(filepath.Join(s.dir, name)); rmErr != nil {
+				slog.Error("failed to remove stale spool file", "file", name, "error", rmErr)
+			}
+			continue
+		}
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		id, parseErr := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if parseErr != nil {
+			slog.Warn("skipping unexpected file in spool directory", "file", name)
+			continue
+		}
+		info, infoErr := f.Info()
+		if infoErr != nil {
+			return fmt.Errorf("failed to stat spool segment %s: %w", name, infoErr)
+		}
+
+		s.segments = append(s.segments, segment{id: id, size: info.Size()})
+		s.size += info.Size()
+		if id >= s.nextID {
+			s.nextID = id + 1
+		}
+	}
+
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].id < s.segments[j].id })
+
+	return nil
+}
+
+// Append сохраняет пакет метрик с ключом идемпотентности в новый сегмент и возвращает его ID.
+// Запись атомарна: данные пишутся во временный файл, который затем переименовывается.
+func (s *Spool) Append(requestID string, items []*metrics.Metric) (uint64, error) {
+	data, err := json.Marshal(segmentData{RequestID: requestID, Metrics: items})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode spool segment: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	path := s.segmentPath(id)
+	if err = writeFileSync(path+tmpExt, data); err != nil {
+		return 0, err
+	}
+	if err = os.Rename(path+tmpExt, path); err != nil {
+		return 0, fmt.Errorf("failed to commit spool segment: %w", err)
+	}
+
+	s.nextID++
+	s.segments = append(s.segments, segment{id: id, size: int64(len(data))})
+	s.size += int64(len(data))
+
+	s.evict()
+
+	return id, nil
+}
+
+// Pending возвращает до limit самых старых сегментов, которые еще не находятся в отправке,
+// и помечает их как отправляемые. Поврежденные сегменты удаляются из очереди.
+func (s *Spool) Pending(limit int) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, limit)
+	var corrupted []uint64
+	for _, seg := range s.segments {
+		if len(entries) >= limit {
+			break
+		}
+		if _, ok := s.inFlight[seg.id]; ok {
+			continue
+		}
+
+		data, err := s.read(seg.id)
+		if err != nil {
+			slog.Error("dropping unreadable spool segment", "id", seg.id, "error", err)
+			corrupted = append(corrupted, seg.id)
+			continue
+		}
+
+		s.inFlight[seg.id] = struct{}{}
+		entries = append(entries, Entry{ID: seg.id, RequestID: data.RequestID, Metrics: data.Metrics})
+	}
+
+	for _, id := range corrupted {
+		s.remove(id)
+	}
+
+	return entries
+}
+
+// Ack удаляет сегмент после успешной отправки метрик на сервер.
+func (s *Spool) Ack(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, id)
+	s.remove(id)
+}
+
+// Release возвращает сегмент в очередь после неудачной отправки,
+// чтобы он был отправлен повторно при следующем вызове Pending.
+func (s *Spool) Release(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, id)
+}
+
+// Len возвращает количество сегментов в очереди.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.segments)
+}
+
+// Size возвращает суммарный размер сегментов очереди в байтах.
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.size
+}
+
+// evict удаляет самые старые сегменты, пока размер очереди превышает лимит.
+// Самый новый сегмент не удаляется никогда. Вызывается под блокировкой.
+func (s *Spool) evict() {
+	if s.maxSize <= 0 {
+		return
+	}
+	for s.size > s.maxSize && len(s.segments) > 1 {
+		oldest := s.segments[0]
+		slog.Warn("spool size limit exceeded, evicting oldest segment",
+			"id", oldest.id,
+			"size_bytes", s.size,
+			"max_size_bytes", s.maxSize)
+		delete(s.inFlight, oldest.id)
+		s.remove(oldest.id)
+	}
+}
+
+// remove удаляет сегмент с диска и из списка. Вызывается под блокировкой.
+func (s *Spool) remove(id uint64) {
+	for i, seg := range s.segments {
+		if seg.id != id {
+			continue
+		}
+		err := os.Remove(s.segmentPath(id))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Error("failed to remove spool segment", "id", id, "error", err)
+		}
+		s.size -= seg.size
+		s.segments = append(s.segments[:i], s.segments[i+1:]...)
+		return
+	}
+}
+
+func (s *Spool) read(id uint64) (segmentData, error) {
+	var data segmentData
+	raw, err := os.ReadFile(s.segmentPath(id))
+	if err != nil {
+		return data, err
+	}
+	if err = json.Unmarshal(raw, &data); err != nil {
+		return data, err
+	}
+	return data, nil
+}
+
+func (s *Spool) segmentPath(id uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// writeFileSync записывает данные в файл и сбрасывает их на диск.
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync spool segment: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,141 @@
+package spool_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/agent/spool"
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+func batch(name string, value float64) []*metrics.Metric {
+	return []*metrics.Metric{metrics.NewGauge(name, value)}
+}
+
+func TestSpool_AppendAckRelease(t *testing.T) {
+	s, err := spool.Open(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	id1, err := s.Append("req-1", batch("first", 1))
+	require.NoError(t, err)
+	id2, err := s.Append("req-2", batch("second", 2))
+	require.NoError(t, err)
+	assert.Less(t, id1, id2)
+	assert.Equal(t, 2, s.Len())
+
+	// Сегменты отдаются в порядке создания.
+	entries := s.Pending(10)
+	require.Len(t, entries, 2)
+	assert.Equal(t, id1, entries[0].ID)
+	assert.Equal(t, "first", entries[0].Metrics[0].Name)
+	assert.Equal(t, "req-1", entries[0].RequestID)
+	assert.Equal(t, id2, entries[1].ID)
+	assert.Equal(t, "req-2", entries[1].RequestID)
+
+	// Отправляемые сегменты повторно не выдаются.
+	assert.Empty(t, s.Pending(10))
+
+	// После подтверждения сегмент удаляется, после ошибки возвращается в очередь.
+	s.Ack(id1)
+	s.Release(id2)
+	assert.Equal(t, 1, s.Len())
+
+	entries = s.Pending(10)
+	require.Len(t, entries, 1)
+	assert.Equal(t, id2, entries[0].ID)
+}
+
+func TestSpool_PendingLimit(t *testing.T) {
+	s, err := spool.Open(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	for i := range 3 {
+		_, err = s.Append("", batch("m", float64(i)))
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, s.Pending(2), 2)
+	assert.Len(t, s.Pending(2), 1)
+	assert.Empty(t, s.Pending(0))
+}
+
+func TestSpool_ReplayAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := spool.Open(dir, 0)
+	require.NoError(t, err)
+	id1, err := s.Append("", batch("first", 1))
+	require.NoError(t, err)
+	_, err = s.Append("", batch("second", 2))
+	require.NoError(t, err)
+	s.Ack(id1)
+
+	// Имитируем недописанный временный файл после аварийного завершения.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000099.seg.tmp"), []byte("{"), 0o600))
+
+	reopened, err := spool.Open(dir, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reopened.Len())
+
+	entries := reopened.Pending(10)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "second", entries[0].Metrics[0].Name)
+
+	// Новые сегменты продолжают нумерацию.
+	id3, err := reopened.Append("", batch("third", 3))
+	require.NoError(t, err)
+	assert.Greater(t, id3, entries[0].ID)
+
+	_, err = os.Stat(filepath.Join(dir, "00000000000000000099.seg.tmp"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSpool_EvictsOldest(t *testing.T) {
+	dir := t.TempDir()
+
+	probe, err := spool.Open(filepath.Join(dir, "probe"), 0)
+	require.NoError(t, err)
+	_, err = probe.Append("", batch("m", 1))
+	require.NoError(t, err)
+	segmentSize := probe.Size()
+
+	// Лимит вмещает ровно два сегмента.
+	s, err := spool.Open(filepath.Join(dir, "spool"), 2*segmentSize)
+	require.NoError(t, err)
+	for i := range 4 {
+		_, err = s.Append("", batch("m", float64(i)))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, s.Len())
+	assert.LessOrEqual(t, s.Size(), 2*segmentSize)
+
+	entries := s.Pending(10)
+	require.Len(t, entries, 2)
+	assert.InDelta(t, 2.0, *entries[0].Metrics[0].Value, 0.001)
+	assert.InDelta(t, 3.0, *entries[1].Metrics[0].Value, 0.001)
+}
+
+func TestSpool_DropsCorruptedSegment(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000001.seg"), []byte("not json"), 0o600))
+
+	s, err := spool.Open(dir, 0)
+	require.NoError(t, err)
+	_, err = s.Append("", batch("valid", 1))
+	require.NoError(t, err)
+
+	entries := s.Pending(10)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "valid", entries[0].Metrics[0].Name)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestOpen_EmptyDir(t *testing.T) {
+	_, err := spool.Open("", 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+
+	"github.com/maynagashev/go-metrics/internal/agent/retry"
+	"github.com/maynagashev/go-metrics/internal/contracts/metricspb"
+	"github.com/maynagashev/go-metrics/pkg/crypto"
+	"github.com/maynagashev/go-metrics/pkg/sign"
+)
+
+// Transport протокол отправки метрик на сервер.
+type Transport string
+
+const (
+	// TransportHTTP пакеты отправляются запросом POST /updates JSON API.
+	TransportHTTP Transport = "http"
+	// TransportGRPC пакеты отправляются вызовом UpdateMetrics gRPC API.
+	TransportGRPC Transport = "grpc"
+)
+
+// grpcRequestTimeout максимальное время одного вызова gRPC API.
+const grpcRequestTimeout = 10 * time.Second
+
+// ParseTransport разбирает название протокола отправки, пустая строка означает http.
+func ParseTransport(s string) (Transport, error) {
+	switch t := Transport(strings.ToLower(strings.TrimSpace(s))); t {
+	case "", TransportHTTP:
+		return TransportHTTP, nil
+	case TransportGRPC:
+		return TransportGRPC, nil
+	default:
+		return "", fmt.Errorf("unknown transport %q, expected %q or %q", s, TransportHTTP, TransportGRPC)
+	}
+}
+
+// grpcTarget возвращает адрес gRPC API сервера без схемы, например localhost:3200.
+func grpcTarget(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		return url[i+3:]
+	}
+	return url
+}
+
+// dialGRPC создает соединение с gRPC API сервера. Соединение устанавливается при первом вызове.
+func (ep *endpoint) dialGRPC() {
+	conn, err := grpc.NewClient(grpcTarget(ep.url), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		ep.grpcErr = fmt.Errorf("grpc client: %w", err)
+		return
+	}
+	ep.grpcConn = conn
+	ep.grpcClient = metricspb.NewMetricsClient(conn)
+}
+
+// closeGRPC закрывает соединения с gRPC API серверов.
+func (a *agent) closeGRPC() {
+	for _, ep := range a.endpoints {
+		if ep.grpcConn == nil {
+			continue
+		}
+		if err := ep.grpcConn.Close(); err != nil {
+			slog.Error("failed to close grpc connection", "url", ep.url, "error", err)
+		}
+	}
+}
+
+// sendBatch выполняет одну попытку отправки пакета по выбранному протоколу.
+func (a *agent) sendBatch(ctx context.Context, ep *endpoint, job Job, try int, workerID int) error {
+	if a.transport == TransportGRPC {
+		return a.makeGRPCRequest(ctx, ep, job, try, workerID)
+	}
+	return a.makeUpdatesRequest(ep.url, job, try, workerID)
+}
+
+// makeGRPCRequest отправляет пакет вызовом UpdateMetrics. Подпись, шифрование и ключ
+// идемпотентности передаются в полях запроса, сжатие выполняется средствами gRPC.
+func (a *agent) makeGRPCRequest(ctx context.Context, ep *endpoint, job Job, try int, workerID int) error {
+	if ep.grpcErr != nil {
+		return ep.grpcErr
+	}
+	slog.Info(
+		fmt.Sprintf("sending metrics batch over grpc (try=%d)", try),
+		"workerID", workerID,
+		"url", ep.url,
+		"metrics", job.Metrics,
+	)
+
+	items := make([]*metricspb.Metric, 0, len(job.Metrics))
+	for _, m := range job.Metrics {
+		items = append(items, metricspb.FromMetric(*m))
+	}
+	req := &metricspb.UpdateMetricsRequest{Metrics: items, IdempotencyKey: job.RequestID}
+
+	batch, err := metricspb.MarshalBatch(items)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+	if a.IsRequestSigningEnabled() {
+		req.Hash = sign.ComputeHMACSHA256(batch, a.PrivateKey)
+	}
+	if a.IsEncryptionEnabled() {
+		slog.Debug("encrypting data before sending", "workerID", workerID)
+		encrypted, err := crypto.EncryptLargeData(a.PublicKey, batch)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt data: %w", err)
+		}
+		req.Metrics, req.EncryptedMetrics = nil, encrypted
+	}
+
+	var opts []grpc.CallOption
+	if a.SendCompressedData {
+		opts = append(opts, grpc.UseCompressor(gzip.Name))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, grpcRequestTimeout)
+	defer cancel()
+	if _, err := ep.grpcClient.UpdateMetrics(ctx, req, opts...); err != nil {
+		return grpcStatusError(err)
+	}
+	return nil
+}
+
+// pingGRPC проверяет доступность gRPC API сервера запросом несуществующей метрики.
+// Сервер считается доступным, если ответил любым статусом, кроме временной недоступности.
+func (a *agent) pingGRPC(ctx context.Context, ep *endpoint) error {
+	if ep.grpcErr != nil {
+		return ep.grpcErr
+	}
+	ctx, cancel := context.WithTimeout(ctx, grpcRequestTimeout)
+	defer cancel()
+	_, err := ep.grpcClient.GetMetric(ctx, &metricspb.GetMetricRequest{})
+	if err == nil {
+		return nil
+	}
+	err = grpcStatusError(err)
+	if retriable, _ := retry.Classify(err); retriable {
+		return err
+	}
+	return nil
+}
+
+// grpcStatusError преобразует ошибку вызова gRPC в retry.StatusError с аналогичным кодом HTTP,
+// чтобы политика повторов и выключатели работали так же, как при отправке по HTTP.
+func grpcStatusError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var code int
+	switch st.Code() {
+	case codes.Unavailable:
+		code = http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		code = http.StatusGatewayTimeout
+	case codes.ResourceExhausted:
+		code = http.StatusTooManyRequests
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		code = http.StatusBadRequest
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.Unauthenticated:
+		code = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	case codes.Unimplemented:
+		code = http.StatusNotImplemented
+	case codes.Canceled:
+		return err
+	default:
+		code = http.StatusInternalServerError
+	}
+	return fmt.Errorf("%w: %s", retry.NewStatusError(code, ""), st.Message())
+}
@@ -0,0 +1,69 @@
+package agent_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/agent"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/grpcserver"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+)
+
+func TestParseTransport(t *testing.T) {
+	tr, err := agent.ParseTransport("")
+	require.NoError(t, err)
+	assert.Equal(t, agent.TransportHTTP, tr)
+
+	tr, err = agent.ParseTransport(" GRPC ")
+	require.NoError(t, err)
+	assert.Equal(t, agent.TransportGRPC, tr)
+
+	_, err = agent.ParseTransport("quic")
+	require.Error(t, err)
+}
+
+func TestAgent_GRPCTransport(t *testing.T) {
+	const signKey = "secret"
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cfg := &app.Config{PrivateKey: signKey, PrivateRSAKey: privateKey}
+	st, err := memory.New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	l, err := grpcserver.Listen("127.0.0.1:0", cfg, st, zap.NewNop())
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- l.Serve() }()
+
+	a := agent.New("grpc://"+l.Addr().String(), time.Hour, time.Hour, signKey, 1, &privateKey.PublicKey,
+		agent.WithRetryPolicy(fastRetryPolicy(1)),
+		agent.WithTransport(agent.TransportGRPC))
+
+	agent.PollOnce(a)
+	require.NoError(t, agent.ReportOnce(a))
+
+	ctx := context.Background()
+	c, err := st.GetCounter(ctx, "PollCount")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, c)
+
+	// Остановленный сервер недоступен: ошибка временная, приращения остаются в агенте.
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, l.Shutdown(shutdownCtx))
+	require.NoError(t, <-served)
+
+	agent.PollOnce(a)
+	err = agent.ReportOnce(a)
+	require.Error(t, err)
+	assert.True(t, agent.IsRetriableSendError(err))
+	assert.Equal(t, int64(1), pollCount(a.GetMetrics()))
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 
@@ -10,6 +11,12 @@ import (
 // Job структура для задания воркерам.
 type Job struct {
 	Metrics []*metrics.Metric
+	// SpoolID номер сегмента дисковой очереди, 0 если задача не сохранена на диск.
+	SpoolID uint64
+	// BatchID номер пакета с зарезервированными приращениями счетчиков, 0 если счетчиков в пакете нет.
+	BatchID uint64
+	// RequestID ключ идемпотентности пакета, одинаковый для всех попыток отправки пакета.
+	RequestID string
 }
 
 // Result структура для результата выполнения задания.
@@ -25,6 +32,17 @@ func (a *agent) worker(id int) {
 		a.wg.Done()
 	}()
 
+	// Контекст отменяется сигналом остановки, чтобы прервать ожидание между повторами отправки.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-a.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	slog.Debug(fmt.Sprintf("worker %d started", id))
 	// По мере поступления задач в очередь отправляем их на сервер (читаем из канала очередную запись текущим воркером).
 	for {
@@ -39,7 +57,7 @@ func (a *agent) worker(id int) {
 				"workerID",
 				id,
 			)
-			err := a.sendMetrics(job.Metrics, id)
+			err := a.sendMetrics(ctx, job, id)
 			// Отправляем результат выполнения задачи (ошибку, если была) в очередь результатов,
 			// которые потом разбирает коллектор.
 			select {
@@ -75,15 +93,32 @@ func (a *agent) collector() {
 				slog.Info("Result queue closed, collector exiting")
 				return
 			}
-			if result.Error != nil {
-				wrappedError := fmt.Errorf("collector: %w", result.Error)
-				slog.Error(wrappedError.Error(), "error", wrappedError)
-			} else {
-				slog.Info("Metrics sent successfully", "count", len(result.Job.Metrics))
-			}
+			a.handleResult(result)
 		case <-a.stopCh:
 			slog.Info("Stop signal received, collector exiting")
 			return
 		}
 	}
 }
+
+// handleResult обрабатывает результат отправки: подтверждает сегмент дисковой очереди
+// и списывает приращения счетчиков пакета при успехе, при ошибке возвращает сегмент
+// в очередь для повторной отправки, а приращения счетчиков — в агент.
+func (a *agent) handleResult(result Result) {
+	a.completeBatch(result.Job.BatchID, result.Error)
+
+	if result.Error != nil {
+		wrappedError := fmt.Errorf("collector: %w", result.Error)
+		slog.Error(wrappedError.Error(), "error", wrappedError)
+		if a.spool != nil && result.Job.SpoolID != 0 {
+			a.spool.Release(result.Job.SpoolID)
+			slog.Info("Metrics kept in spool for retry", "spool_id", result.Job.SpoolID)
+		}
+		return
+	}
+
+	slog.Info("Metrics sent successfully", "count", len(result.Job.Metrics))
+	if a.spool != nil && result.Job.SpoolID != 0 {
+		a.spool.Ack(result.Job.SpoolID)
+	}
+}
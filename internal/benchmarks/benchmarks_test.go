@@ -25,7 +25,11 @@ import (
 func newTestStorage() *memory.MemStorage {
 	logger := zap.NewNop()
 	cfg := &app.Config{}
-	return memory.New(cfg, logger)
+	ms, err := memory.New(cfg, logger)
+	if err != nil {
+		panic(err)
+	}
+	return ms
 }
 
 // Это помогает оценить скорость работы in-memory хранилища и выявить потенциальные узкие места.
@@ -45,8 +49,8 @@ func BenchmarkStorageOperations(b *testing.B) {
 		if err != nil {
 			b.Fatal(err)
 		}
-		_, found := store.GetMetric(ctx, metric.MType, metric.Name)
-		if !found {
+		_, err = store.GetMetric(ctx, metric.MType, metric.Name)
+		if err != nil {
 			b.Fatal("metric not found")
 		}
 	}
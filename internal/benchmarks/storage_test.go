@@ -0,0 +1,179 @@
+package benchmarks_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// benchStorage операции хранилища, которые сравниваются в бенчмарках.
+type benchStorage interface {
+	UpdateMetric(ctx context.Context, metric metrics.Metric) error
+	GetMetric(ctx context.Context, mType metrics.MetricType, id string) (metrics.Metric, error)
+	GetMetrics(ctx context.Context) ([]metrics.Metric, error)
+}
+
+// globalLockStorage прежняя реализация хранилища в памяти — два общих словаря, — дополненная
+// одной блокировкой на все хранилище, без которой ее нельзя использовать из нескольких горутин.
+type globalLockStorage struct {
+	mu       sync.RWMutex
+	gauges   storage.Gauges
+	counters storage.Counters
+}
+
+func newGlobalLockStorage() *globalLockStorage {
+	return &globalLockStorage{gauges: make(storage.Gauges), counters: make(storage.Counters)}
+}
+
+func (s *globalLockStorage) UpdateMetric(_ context.Context, metric metrics.Metric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch metric.MType {
+	case metrics.TypeGauge:
+		s.gauges[metric.Name] = storage.Gauge(*metric.Value)
+	case metrics.TypeCounter:
+		s.counters[metric.Name] += storage.Counter(*metric.Delta)
+	}
+	return nil
+}
+
+func (s *globalLockStorage) GetMetric(
+	_ context.Context,
+	mType metrics.MetricType,
+	id string,
+) (metrics.Metric, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if mType == metrics.TypeCounter {
+		v, ok := s.counters[id]
+		if !ok {
+			return metrics.Metric{}, storage.ErrNotFound
+		}
+		return metrics.Metric{Name: id, MType: mType, Delta: (*int64)(&v)}, nil
+	}
+	v, ok := s.gauges[id]
+	if !ok {
+		return metrics.Metric{}, storage.ErrNotFound
+	}
+	return metrics.Metric{Name: id, MType: mType, Value: (*float64)(&v)}, nil
+}
+
+func (s *globalLockStorage) GetMetrics(_ context.Context) ([]metrics.Metric, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	items := make([]metrics.Metric, 0, len(s.gauges)+len(s.counters))
+	for id, value := range s.gauges {
+		items = append(items, metrics.Metric{Name: id, MType: metrics.TypeGauge, Value: (*float64)(&value)})
+	}
+	for id, value := range s.counters {
+		items = append(items, metrics.Metric{Name: id, MType: metrics.TypeCounter, Delta: (*int64)(&value)})
+	}
+	return items, nil
+}
+
+// benchStorages реализации хранилища для сравнения.
+func benchStorages() []struct {
+	name  string
+	store func() benchStorage
+} {
+	return []struct {
+		name  string
+		store func() benchStorage
+	}{
+		{name: "sharded", store: func() benchStorage { return newTestStorage() }},
+		{name: "global-lock", store: func() benchStorage { return newGlobalLockStorage() }},
+	}
+}
+
+// benchMetrics возвращает n обновлений counter с разными именами.
+func benchMetrics(n int) []metrics.Metric {
+	items := make([]metrics.Metric, n)
+	for i := range items {
+		items[i] = *metrics.NewCounter(fmt.Sprintf("metric_%d", i), 1)
+	}
+	return items
+}
+
+// Измеряет пропускную способность одновременных обновлений разных метрик.
+func BenchmarkStorageParallelUpdates(b *testing.B) {
+	items := benchMetrics(1000)
+	for _, bs := range benchStorages() {
+		b.Run(bs.name, func(b *testing.B) {
+			store := bs.store()
+			ctx := context.Background()
+			var next atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := next.Add(1) % uint64(len(items))
+					if err := store.UpdateMetric(ctx, items[i]); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// Измеряет смешанную нагрузку: на каждые девять чтений метрики приходится одно обновление.
+func BenchmarkStorageParallelMixed(b *testing.B) {
+	items := benchMetrics(1000)
+	for _, bs := range benchStorages() {
+		b.Run(bs.name, func(b *testing.B) {
+			store := bs.store()
+			ctx := context.Background()
+			for _, m := range items {
+				_ = store.UpdateMetric(ctx, m)
+			}
+			var next atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := next.Add(1)
+					m := items[n%uint64(len(items))]
+					if n%10 == 0 {
+						_ = store.UpdateMetric(ctx, m)
+						continue
+					}
+					if _, err := store.GetMetric(ctx, m.MType, m.Name); err != nil {
+						b.Error("metric not found")
+					}
+				}
+			})
+		})
+	}
+}
+
+// Измеряет получение списка всех метрик, например для страницы метрик или экспорта Prometheus,
+// при редких обновлениях.
+func BenchmarkStorageGetMetrics(b *testing.B) {
+	items := benchMetrics(1000)
+	for _, bs := range benchStorages() {
+		b.Run(bs.name, func(b *testing.B) {
+			store := bs.store()
+			ctx := context.Background()
+			for _, m := range items {
+				_ = store.UpdateMetric(ctx, m)
+			}
+			var next atomic.Uint64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := next.Add(1)
+					if n%100 == 0 {
+						_ = store.UpdateMetric(ctx, items[n%uint64(len(items))])
+						continue
+					}
+					if got, _ := store.GetMetrics(ctx); len(got) != len(items) {
+						b.Error("unexpected metrics count")
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package metrics
+
+const (
+	// IdempotencyKeyHeader заголовок запроса с ключом идемпотентности пакета метрик.
+	// Повторная отправка пакета с тем же ключом не применяется сервером повторно.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader заголовок ответа, равный "true", если пакет с переданным
+	// ключом уже был применен ранее и повторно не применялся.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// MaxIdempotencyKeyLength максимальная длина ключа идемпотентности.
+	MaxIdempotencyKeyLength = 128
+)
@@ -0,0 +1,37 @@
+// Package metricspb содержит сообщения и описание gRPC-сервиса metrics.v1.Metrics.
+//
+// Сообщения и сервис (metrics.pb.go, metrics_grpc.pb.go) генерируются из схемы metrics.proto
+// командой make proto, в этом файле — преобразования между сообщениями и метриками хранилища.
+package metricspb
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// FromMetric преобразует метрику хранилища в сообщение.
+func FromMetric(m metrics.Metric) *Metric {
+	return &Metric{Id: m.Name, Type: string(m.MType), Delta: m.Delta, Value: m.Value}
+}
+
+// ToMetric преобразует сообщение в метрику хранилища.
+func (x *Metric) ToMetric() metrics.Metric {
+	return metrics.Metric{Name: x.GetId(), MType: metrics.MetricType(x.GetType()), Delta: x.Delta, Value: x.Value}
+}
+
+// MarshalBatch сериализует метрики как сообщение MetricsBatch.
+// По этому представлению вычисляется подпись пакета и оно же шифруется. Сериализация
+// детерминированная, поэтому сервер проверяет подпись по повторно сериализованному пакету.
+func MarshalBatch(items []*Metric) ([]byte, error) {
+	return proto.MarshalOptions{Deterministic: true}.Marshal(&MetricsBatch{Metrics: items})
+}
+
+// UnmarshalBatch разбирает сообщение MetricsBatch.
+func UnmarshalBatch(b []byte) ([]*Metric, error) {
+	batch := &MetricsBatch{}
+	if err := proto.Unmarshal(b, batch); err != nil {
+		return nil, err
+	}
+	return batch.GetMetrics(), nil
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -21,10 +22,25 @@ const (
 	DataSavingTimeout   = 5 * time.Second // Время ожидания сохранения данных при shutdown
 )
 
+// Listener дополнительный приемник метрик (например, Graphite или StatsD),
+// который работает вместе с HTTP-сервером и останавливается при graceful shutdown.
+type Listener interface {
+	// Serve принимает данные до вызова Shutdown. Ошибка означает аварийное прекращение приема.
+	Serve() error
+	// Shutdown прекращает прием и дожидается обработки уже принятых данных.
+	Shutdown(ctx context.Context) error
+}
+
 // Server представляет собой HTTP-сервер для сбора метрик.
 // Обрабатывает запросы от агентов и сохраняет метрики в хранилище.
 type Server struct {
 	cfg *Config
+	// Функции, вызываемые сразу после получения сигнала остановки.
+	onDrain []func()
+	// Функции, вызываемые в начале graceful shutdown HTTP-сервера.
+	onShutdown []func()
+	// Фоновые задачи, выполняемые во время работы сервера.
+	tasks []func(ctx context.Context)
 }
 
 // New создает новый экземпляр сервера с указанной конфигурацией.
@@ -34,10 +50,32 @@ func New(cfg *Config) *Server {
 	}
 }
 
-// Start запускает HTTP-сервер с указанным обработчиком и логгером.
-// Настраивает таймауты и другие параметры сервера.
-// Обрабатывает сигналы SIGTERM, SIGINT, SIGQUIT для graceful shutdown.
-func (s *Server) Start(log *zap.Logger, handler http.Handler) {
+// RegisterOnDrain регистрирует функцию, которая вызывается сразу после получения сигнала остановки,
+// до задержки Config.ShutdownDelay и остановки HTTP-сервера, например чтобы /readyz начал отвечать 503.
+func (s *Server) RegisterOnDrain(f func()) {
+	s.onDrain = append(s.onDrain, f)
+}
+
+// RegisterOnShutdown регистрирует функцию, которая вызывается в начале graceful shutdown
+// HTTP-сервера, например для завершения долгих потоковых ответов, которых shutdown иначе дожидался бы.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.onShutdown = append(s.onShutdown, f)
+}
+
+// RegisterTask регистрирует фоновую задачу, которая запускается вместе с HTTP-сервером.
+// При graceful shutdown контекст задачи отменяется, и сервер дожидается ее завершения.
+func (s *Server) RegisterTask(task func(ctx context.Context)) {
+	s.tasks = append(s.tasks, task)
+}
+
+// Start запускает HTTP-сервер с указанным обработчиком и логгером, а также дополнительные
+// приемники метрик listeners. Настраивает таймауты и другие параметры сервера.
+// Обрабатывает сигналы SIGTERM, SIGINT, SIGQUIT для graceful shutdown: после сигнала вызываются
+// функции RegisterOnDrain и сервер продолжает принимать запросы в течение Config.ShutdownDelay,
+// чтобы балансировщик нагрузки успел перестать направлять на него трафик. После остановки
+// HTTP-сервера приемники останавливаются в порядке передачи, затем сервер дожидается
+// завершения фоновых задач, зарегистрированных через RegisterTask.
+func (s *Server) Start(log *zap.Logger, handler http.Handler, listeners ...Listener) {
 	log.Info("starting server", zap.Any("config", s.cfg))
 
 	httpServer := &http.Server{
@@ -48,9 +86,12 @@ func (s *Server) Start(log *zap.Logger, handler http.Handler) {
 		WriteTimeout: DefaultWriteTimeout,
 		IdleTimeout:  DefaultIdleTimeout,
 	}
+	for _, f := range s.onShutdown {
+		httpServer.RegisterOnShutdown(f)
+	}
 
-	// Канал для получения ошибок от запущенного сервера
-	serverErrors := make(chan error, 1)
+	// Канал для получения ошибок от запущенного сервера и приемников
+	serverErrors := make(chan error, 1+len(listeners))
 
 	// Запускаем сервер в отдельной горутине
 	go func() {
@@ -58,6 +99,26 @@ func (s *Server) Start(log *zap.Logger, handler http.Handler) {
 		serverErrors <- httpServer.ListenAndServe()
 	}()
 
+	for _, l := range listeners {
+		go func() {
+			if err := l.Serve(); err != nil {
+				serverErrors <- err
+			}
+		}()
+	}
+
+	// Запускаем фоновые задачи
+	tasksCtx, cancelTasks := context.WithCancel(context.Background())
+	defer cancelTasks()
+	var tasks sync.WaitGroup
+	for _, task := range s.tasks {
+		tasks.Add(1)
+		go func() {
+			defer tasks.Done()
+			task(tasksCtx)
+		}()
+	}
+
 	// Канал для получения сигналов от ОС
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
@@ -69,10 +130,26 @@ func (s *Server) Start(log *zap.Logger, handler http.Handler) {
 	case sig := <-shutdown:
 		log.Info("shutdown signal received", zap.String("signal", sig.String()))
 
+		// Сообщаем о предстоящей остановке и даем балансировщику время снять сервер с нагрузки.
+		// Повторный сигнал прерывает ожидание.
+		for _, f := range s.onDrain {
+			f()
+		}
+		if s.cfg.ShutdownDelay > 0 {
+			log.Info("draining before shutdown", zap.Duration("delay", s.cfg.ShutdownDelay))
+			select {
+			case <-time.After(s.cfg.ShutdownDelay):
+			case <-shutdown:
+			}
+		}
+
 		// Создаем контекст с таймаутом для graceful shutdown
 		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
 		defer cancel()
 
+		// Фоновые задачи останавливаются параллельно с остановкой приема запросов
+		cancelTasks()
+
 		// Сначала останавливаем прием новых запросов
 		if err := httpServer.Shutdown(ctx); err != nil {
 			log.Error("server shutdown failed", zap.Error(err))
@@ -83,6 +160,25 @@ func (s *Server) Start(log *zap.Logger, handler http.Handler) {
 			log.Info("server shutdown completed")
 		}
 
+		// Останавливаем дополнительные приемники метрик
+		for _, l := range listeners {
+			if err := l.Shutdown(ctx); err != nil {
+				log.Error("listener shutdown failed", zap.Error(err))
+			}
+		}
+
+		// Дожидаемся завершения фоновых задач
+		tasksDone := make(chan struct{})
+		go func() {
+			tasks.Wait()
+			close(tasksDone)
+		}()
+		select {
+		case <-tasksDone:
+		case <-ctx.Done():
+			log.Error("background tasks did not stop before shutdown timeout")
+		}
+
 		// Даем время на завершение текущих операций и сохранение данных
 		select {
 		case <-ctx.Done():
@@ -84,6 +84,15 @@ func TestConfig_IsSyncStore(t *testing.T) {
 	assert.False(t, config.IsSyncStore())
 }
 
+func TestConfig_GetFsyncPolicy(t *testing.T) {
+	// Без явной политики выбор зависит от режима сохранения
+	assert.Equal(t, app.FsyncAlways, (&app.Config{StoreInterval: 0}).GetFsyncPolicy())
+	assert.Equal(t, app.FsyncInterval, (&app.Config{StoreInterval: 300}).GetFsyncPolicy())
+
+	// Явно заданная политика имеет приоритет
+	assert.Equal(t, app.FsyncNever, (&app.Config{StoreInterval: 0, Fsync: app.FsyncNever}).GetFsyncPolicy())
+}
+
 func TestConfig_GetStoreInterval(t *testing.T) {
 	config := &app.Config{
 		StoreInterval: 300,
@@ -4,6 +4,7 @@ import (
 	"crypto/rsa"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/maynagashev/go-metrics/pkg/crypto"
 )
@@ -18,8 +19,11 @@ type Config struct {
 	FileStoragePath string
 	// Загружать или нет ранее сохраненные метрики из файла.
 	Restore bool
+	// Политика сброса журнала изменений на диск, пустое значение — выбор по StoreInterval.
+	Fsync string
 	// Параметры базы данных
 	Database DatabaseConfig
+	SQLite   SQLiteConfig
 	// Приватный ключ для подписи метрик.
 	PrivateKey string
 	// Включить профилирование через pprof
@@ -28,6 +32,32 @@ type Config struct {
 	PrivateRSAKey *rsa.PrivateKey
 	// Конфигурационный файл
 	ConfigFile string
+	// Время хранения ключей идемпотентности примененных пакетов метрик.
+	IdempotencyTTL time.Duration
+	// Суффиксы имен рядов Prometheus remote write, которые сохраняются как counter.
+	RemoteWriteCounterSuffixes []string
+	// Атрибуты ресурса OTLP, которыми дополняется имя метрики.
+	OTLPResourceAttributes []string
+	// Адрес TCP-порта приема метрик Graphite plaintext, пустое значение отключает прием.
+	GraphiteAddr string
+	// Адрес UDP-порта приема метрик StatsD, пустое значение отключает прием.
+	StatsDAddr string
+	// Адрес TCP-порта gRPC API, пустое значение отключает gRPC API.
+	GRPCAddr string
+	// Окно агрегации значений Graphite и StatsD перед записью в хранилище.
+	IngestFlushInterval time.Duration
+	// Сохранять историю значений метрик.
+	History bool
+	// Количество значений истории одной метрики в хранилище в памяти.
+	HistorySize int
+	// Уровни хранения истории в формате resolution:retention через запятую,
+	// пустое значение отключает прореживание и удаление истории.
+	Retention string
+	// Период прореживания и удаления устаревшей истории.
+	CompactionInterval time.Duration
+	// Задержка остановки HTTP-сервера после сигнала, в течение которой /readyz отвечает 503,
+	// а запросы продолжают обрабатываться.
+	ShutdownDelay time.Duration
 }
 
 // DatabaseConfig содержит настройки подключения к базе данных.
@@ -38,19 +68,45 @@ type DatabaseConfig struct {
 	MigrationsPath string
 }
 
+// SQLiteConfig содержит настройки встроенного хранилища SQLite.
+type SQLiteConfig struct {
+	// Path путь к файлу базы данных SQLite.
+	Path string
+	// MigrationsPath путь к директории с миграциями SQLite.
+	MigrationsPath string
+}
+
 func NewConfig(flags *Flags) *Config {
 	cfg := &Config{
 		Addr:            flags.Server.Addr,
 		StoreInterval:   flags.Server.StoreInterval,
 		FileStoragePath: flags.Server.FileStoragePath,
 		Restore:         flags.Server.Restore,
+		Fsync:           flags.Server.Fsync,
 		Database: DatabaseConfig{
 			DSN:            flags.Database.DSN,
 			MigrationsPath: flags.Database.MigrationsPath,
 		},
+		SQLite: SQLiteConfig{
+			Path:           flags.SQLite.Path,
+			MigrationsPath: flags.SQLite.MigrationsPath,
+		},
 		PrivateKey:  flags.PrivateKey,
 		EnablePprof: flags.Server.EnablePprof,
 		ConfigFile:  flags.ConfigFile,
+
+		IdempotencyTTL:             flags.Server.IdempotencyTTL,
+		RemoteWriteCounterSuffixes: flags.Server.RemoteWriteCounterSuffixes,
+		OTLPResourceAttributes:     flags.Server.OTLPResourceAttributes,
+		GraphiteAddr:               flags.Server.GraphiteAddr,
+		StatsDAddr:                 flags.Server.StatsDAddr,
+		GRPCAddr:                   flags.Server.GRPCAddr,
+		IngestFlushInterval:        flags.Server.IngestFlushInterval,
+		History:                    flags.Server.History,
+		HistorySize:                flags.Server.HistorySize,
+		Retention:                  flags.Server.Retention,
+		CompactionInterval:         flags.Server.CompactionInterval,
+		ShutdownDelay:              flags.Server.ShutdownDelay,
 	}
 
 	// Load private key for decryption if provided
@@ -87,6 +143,18 @@ func (cfg *Config) IsSyncStore() bool {
 	return cfg.StoreInterval == 0
 }
 
+// GetFsyncPolicy возвращает политику сброса журнала изменений на диск. Если политика не задана,
+// при синхронном сохранении журнал сбрасывается после каждого обновления, иначе раз в секунду.
+func (cfg *Config) GetFsyncPolicy() string {
+	if cfg.Fsync != "" {
+		return cfg.Fsync
+	}
+	if cfg.IsSyncStore() {
+		return FsyncAlways
+	}
+	return FsyncInterval
+}
+
 // GetStoreInterval возвращает интервал сохранения метрик на сервере в секундах.
 func (cfg *Config) GetStoreInterval() int {
 	return cfg.StoreInterval
@@ -97,11 +165,75 @@ func (cfg *Config) IsDatabaseEnabled() bool {
 	return cfg.Database.DSN != ""
 }
 
+// IsSQLiteEnabled возвращает true, если указан путь к базе данных SQLite.
+func (cfg *Config) IsSQLiteEnabled() bool {
+	return cfg.SQLite.Path != ""
+}
+
 // IsRequestSigningEnabled включена ли проверка подписи метрик.
 func (cfg *Config) IsRequestSigningEnabled() bool {
 	return cfg.PrivateKey != ""
 }
 
+// GetIdempotencyTTL возвращает время хранения ключей идемпотентности,
+// если значение не задано, используется значение по умолчанию.
+func (cfg *Config) GetIdempotencyTTL() time.Duration {
+	if cfg.IdempotencyTTL <= 0 {
+		return defaultIdempotencyTTL
+	}
+	return cfg.IdempotencyTTL
+}
+
+// GetRemoteWriteCounterSuffixes возвращает суффиксы имен counter рядов remote write,
+// если они не заданы, используется значение по умолчанию.
+func (cfg *Config) GetRemoteWriteCounterSuffixes() []string {
+	if len(cfg.RemoteWriteCounterSuffixes) == 0 {
+		return DefaultRemoteWriteCounterSuffixes()
+	}
+	return cfg.RemoteWriteCounterSuffixes
+}
+
+// GetOTLPResourceAttributes возвращает атрибуты ресурса OTLP, которыми дополняется имя метрики,
+// если они не заданы, используется значение по умолчанию.
+func (cfg *Config) GetOTLPResourceAttributes() []string {
+	if len(cfg.OTLPResourceAttributes) == 0 {
+		return DefaultOTLPResourceAttributes()
+	}
+	return cfg.OTLPResourceAttributes
+}
+
+// GetIngestFlushInterval возвращает окно агрегации значений Graphite и StatsD,
+// если значение не задано, используется значение по умолчанию.
+func (cfg *Config) GetIngestFlushInterval() time.Duration {
+	if cfg.IngestFlushInterval <= 0 {
+		return defaultIngestFlushInterval
+	}
+	return cfg.IngestFlushInterval
+}
+
+// IsHistoryEnabled возвращает true, если хранилище сохраняет историю значений метрик.
+func (cfg *Config) IsHistoryEnabled() bool {
+	return cfg.History
+}
+
+// GetHistorySize возвращает количество значений истории одной метрики в памяти,
+// если значение не задано, используется значение по умолчанию.
+func (cfg *Config) GetHistorySize() int {
+	if cfg.HistorySize <= 0 {
+		return defaultHistorySize
+	}
+	return cfg.HistorySize
+}
+
+// GetCompactionInterval возвращает период прореживания истории,
+// если значение не задано, используется значение по умолчанию.
+func (cfg *Config) GetCompactionInterval() time.Duration {
+	if cfg.CompactionInterval <= 0 {
+		return defaultCompactionInterval
+	}
+	return cfg.CompactionInterval
+}
+
 // IsEncryptionEnabled возвращает true, если включено шифрование.
 func (cfg *Config) IsEncryptionEnabled() bool {
 	return cfg.PrivateRSAKey != nil
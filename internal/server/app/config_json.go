@@ -27,6 +27,41 @@ func DefaultStoreInterval() int {
 	return defaultStoreInterval
 }
 
+// DefaultIdempotencyTTL возвращает время хранения ключей идемпотентности по умолчанию.
+func DefaultIdempotencyTTL() time.Duration {
+	return defaultIdempotencyTTL
+}
+
+// DefaultRemoteWriteCounterSuffixes возвращает суффиксы имен counter рядов remote write по умолчанию.
+func DefaultRemoteWriteCounterSuffixes() []string {
+	return []string{"_total"}
+}
+
+// DefaultOTLPResourceAttributes возвращает атрибуты ресурса OTLP, которыми по умолчанию дополняется имя метрики.
+func DefaultOTLPResourceAttributes() []string {
+	return []string{"service.name"}
+}
+
+// DefaultIngestFlushInterval возвращает окно агрегации значений Graphite и StatsD по умолчанию.
+func DefaultIngestFlushInterval() time.Duration {
+	return defaultIngestFlushInterval
+}
+
+// DefaultHistorySize возвращает количество значений истории одной метрики в памяти по умолчанию.
+func DefaultHistorySize() int {
+	return defaultHistorySize
+}
+
+// DefaultRetention возвращает уровни хранения истории по умолчанию.
+func DefaultRetention() string {
+	return defaultRetention
+}
+
+// DefaultCompactionInterval возвращает период прореживания истории по умолчанию.
+func DefaultCompactionInterval() time.Duration {
+	return defaultCompactionInterval
+}
+
 // DefaultFileStoragePath возвращает путь к файлу хранения метрик по умолчанию.
 func DefaultFileStoragePath() string {
 	return defaultFileStoragePath
@@ -38,9 +73,35 @@ type JSONConfig struct {
 	Restore       bool   `json:"restore"`        // Восстанавливать метрики из файла при старте
 	StoreInterval string `json:"store_interval"` // Интервал сохранения метрик в виде строки (например, "1s")
 	StoreFile     string `json:"store_file"`     // Путь к файлу для хранения метрик
+	Fsync         string `json:"fsync"`          // Политика сброса журнала изменений на диск
 	DatabaseDSN   string `json:"database_dsn"`   // Строка подключения к базе данных
+	SQLitePath    string `json:"sqlite_path"`    // Путь к файлу базы данных SQLite
 	CryptoKey     string `json:"crypto_key"`     // Путь к файлу с приватным ключом для расшифровки
 	EnablePprof   bool   `json:"enable_pprof"`   // Включить профилирование через pprof
+	// Время хранения ключей идемпотентности пакетов метрик (например, "10m")
+	IdempotencyTTL string `json:"idempotency_ttl"`
+	// Суффиксы имен рядов Prometheus remote write, которые сохраняются как counter
+	RemoteWriteCounterSuffixes []string `json:"remote_write_counter_suffixes"`
+	// Атрибуты ресурса OTLP, которыми дополняется имя метрики
+	OTLPResourceAttributes []string `json:"otlp_resource_attributes"`
+	// Адрес TCP-порта приема метрик Graphite plaintext (например, ":2003")
+	GraphiteAddress string `json:"graphite_address"`
+	// Адрес UDP-порта приема метрик StatsD (например, ":8125")
+	StatsDAddress string `json:"statsd_address"`
+	// Адрес TCP-порта gRPC API (например, ":3200")
+	GRPCAddress string `json:"grpc_address"`
+	// Окно агрегации значений Graphite и StatsD (например, "10s")
+	IngestFlushInterval string `json:"ingest_flush_interval"`
+	// Сохранять историю значений метрик
+	History bool `json:"history"`
+	// Количество значений истории одной метрики в памяти
+	HistorySize int `json:"history_size"`
+	// Уровни хранения истории (например, "raw:24h,1m:30d,1h:365d")
+	Retention string `json:"retention"`
+	// Период прореживания и удаления устаревшей истории (например, "5m")
+	CompactionInterval string `json:"compaction_interval"`
+	// Задержка остановки HTTP-сервера после сигнала (например, "10s")
+	ShutdownDelay string `json:"shutdown_delay"`
 }
 
 // LoadJSONConfig загружает конфигурацию из JSON-файла.
@@ -88,11 +149,83 @@ func ApplyJSONConfig(flags *Flags, jsonConfig *JSONConfig) error {
 		flags.Server.StoreInterval = int(duration.Seconds())
 	}
 
+	// Время хранения ключей идемпотентности
+	if flags.Server.IdempotencyTTL == defaultIdempotencyTTL && jsonConfig.IdempotencyTTL != "" {
+		ttl, ttlErr := time.ParseDuration(jsonConfig.IdempotencyTTL)
+		if ttlErr != nil {
+			return fmt.Errorf("invalid idempotency_ttl in config: %w", ttlErr)
+		}
+		flags.Server.IdempotencyTTL = ttl
+	}
+
+	// Суффиксы имен counter рядов remote write
+	if len(flags.Server.RemoteWriteCounterSuffixes) == 0 && len(jsonConfig.RemoteWriteCounterSuffixes) > 0 {
+		flags.Server.RemoteWriteCounterSuffixes = jsonConfig.RemoteWriteCounterSuffixes
+	}
+
+	// Атрибуты ресурса OTLP
+	if len(flags.Server.OTLPResourceAttributes) == 0 && len(jsonConfig.OTLPResourceAttributes) > 0 {
+		flags.Server.OTLPResourceAttributes = jsonConfig.OTLPResourceAttributes
+	}
+
+	// Адреса приема метрик Graphite и StatsD
+	if flags.Server.GraphiteAddr == "" && jsonConfig.GraphiteAddress != "" {
+		flags.Server.GraphiteAddr = jsonConfig.GraphiteAddress
+	}
+	if flags.Server.StatsDAddr == "" && jsonConfig.StatsDAddress != "" {
+		flags.Server.StatsDAddr = jsonConfig.StatsDAddress
+	}
+
+	// Окно агрегации значений Graphite и StatsD
+	if flags.Server.IngestFlushInterval == defaultIngestFlushInterval && jsonConfig.IngestFlushInterval != "" {
+		interval, intervalErr := time.ParseDuration(jsonConfig.IngestFlushInterval)
+		if intervalErr != nil {
+			return fmt.Errorf("invalid ingest_flush_interval in config: %w", intervalErr)
+		}
+		flags.Server.IngestFlushInterval = interval
+	}
+
+	// Адрес gRPC API
+	if flags.Server.GRPCAddr == "" && jsonConfig.GRPCAddress != "" {
+		flags.Server.GRPCAddr = jsonConfig.GRPCAddress
+	}
+
+	// История значений метрик
+	if !flags.Server.History && jsonConfig.History {
+		flags.Server.History = jsonConfig.History
+	}
+	if flags.Server.HistorySize == defaultHistorySize && jsonConfig.HistorySize > 0 {
+		flags.Server.HistorySize = jsonConfig.HistorySize
+	}
+
+	if flags.Server.Retention == defaultRetention && jsonConfig.Retention != "" {
+		flags.Server.Retention = jsonConfig.Retention
+	}
+	if flags.Server.CompactionInterval == defaultCompactionInterval && jsonConfig.CompactionInterval != "" {
+		interval, intervalErr := time.ParseDuration(jsonConfig.CompactionInterval)
+		if intervalErr != nil {
+			return fmt.Errorf("invalid compaction_interval in config: %w", intervalErr)
+		}
+		flags.Server.CompactionInterval = interval
+	}
+	if flags.Server.ShutdownDelay == 0 && jsonConfig.ShutdownDelay != "" {
+		delay, delayErr := time.ParseDuration(jsonConfig.ShutdownDelay)
+		if delayErr != nil {
+			return fmt.Errorf("invalid shutdown_delay in config: %w", delayErr)
+		}
+		flags.Server.ShutdownDelay = delay
+	}
+
 	// Путь к файлу для хранения метрик
 	if flags.Server.FileStoragePath == defaultFileStoragePath && jsonConfig.StoreFile != "" {
 		flags.Server.FileStoragePath = jsonConfig.StoreFile
 	}
 
+	// Политика сброса журнала изменений на диск
+	if flags.Server.Fsync == "" && jsonConfig.Fsync != "" {
+		flags.Server.Fsync = jsonConfig.Fsync
+	}
+
 	// Восстанавливать метрики из файла при старте
 	if flags.Server.Restore && !jsonConfig.Restore {
 		flags.Server.Restore = jsonConfig.Restore
@@ -103,6 +236,11 @@ func ApplyJSONConfig(flags *Flags, jsonConfig *JSONConfig) error {
 		flags.Database.DSN = jsonConfig.DatabaseDSN
 	}
 
+	// Путь к файлу базы данных SQLite
+	if flags.SQLite.Path == "" && jsonConfig.SQLitePath != "" {
+		flags.SQLite.Path = jsonConfig.SQLitePath
+	}
+
 	// Путь к файлу с приватным ключом для расшифровки
 	if flags.CryptoKey == "" && jsonConfig.CryptoKey != "" {
 		flags.CryptoKey = jsonConfig.CryptoKey
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -110,3 +111,123 @@ func TestApplyJSONConfig(t *testing.T) {
 	err = app.ApplyJSONConfig(flags, jsonConfig)
 	require.Error(t, err)
 }
+
+func TestApplyJSONConfig_IdempotencyTTL(t *testing.T) {
+	flags := &app.Flags{}
+	flags.Server.IdempotencyTTL = app.DefaultIdempotencyTTL()
+
+	err := app.ApplyJSONConfig(flags, &app.JSONConfig{IdempotencyTTL: "30m"})
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, flags.Server.IdempotencyTTL)
+
+	// Значение, заданное флагом или переменной окружения, не переопределяется.
+	flags.Server.IdempotencyTTL = time.Minute
+	err = app.ApplyJSONConfig(flags, &app.JSONConfig{IdempotencyTTL: "30m"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, flags.Server.IdempotencyTTL)
+
+	flags.Server.IdempotencyTTL = app.DefaultIdempotencyTTL()
+	err = app.ApplyJSONConfig(flags, &app.JSONConfig{IdempotencyTTL: "invalid"})
+	require.Error(t, err)
+}
+
+func TestApplyJSONConfig_Listeners(t *testing.T) {
+	flags := &app.Flags{}
+	flags.Server.IngestFlushInterval = app.DefaultIngestFlushInterval()
+
+	err := app.ApplyJSONConfig(flags, &app.JSONConfig{
+		GraphiteAddress:     ":2003",
+		StatsDAddress:       ":8125",
+		GRPCAddress:         ":3200",
+		IngestFlushInterval: "5s",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ":3200", flags.Server.GRPCAddr)
+	assert.Equal(t, ":2003", flags.Server.GraphiteAddr)
+	assert.Equal(t, ":8125", flags.Server.StatsDAddr)
+	assert.Equal(t, 5*time.Second, flags.Server.IngestFlushInterval)
+
+	// Адрес, заданный флагом или переменной окружения, не переопределяется.
+	flags.Server.GraphiteAddr = ":12003"
+	err = app.ApplyJSONConfig(flags, &app.JSONConfig{GraphiteAddress: ":2003"})
+	require.NoError(t, err)
+	assert.Equal(t, ":12003", flags.Server.GraphiteAddr)
+
+	flags.Server.IngestFlushInterval = app.DefaultIngestFlushInterval()
+	err = app.ApplyJSONConfig(flags, &app.JSONConfig{IngestFlushInterval: "soon"})
+	require.Error(t, err)
+}
+
+func TestApplyJSONConfig_History(t *testing.T) {
+	flags := &app.Flags{}
+	flags.Server.HistorySize = app.DefaultHistorySize()
+
+	err := app.ApplyJSONConfig(flags, &app.JSONConfig{History: true, HistorySize: 100})
+	require.NoError(t, err)
+	assert.True(t, flags.Server.History)
+	assert.Equal(t, 100, flags.Server.HistorySize)
+
+	// Размер, заданный флагом или переменной окружения, не переопределяется.
+	flags.Server.HistorySize = 50
+	err = app.ApplyJSONConfig(flags, &app.JSONConfig{HistorySize: 100})
+	require.NoError(t, err)
+	assert.Equal(t, 50, flags.Server.HistorySize)
+
+	cfg := app.NewConfig(flags)
+	assert.True(t, cfg.IsHistoryEnabled())
+	assert.Equal(t, 50, cfg.GetHistorySize())
+	assert.Equal(t, app.DefaultHistorySize(), (&app.Config{}).GetHistorySize())
+}
+
+func TestApplyJSONConfig_Retention(t *testing.T) {
+	flags := &app.Flags{}
+	flags.Server.Retention = app.DefaultRetention()
+	flags.Server.CompactionInterval = app.DefaultCompactionInterval()
+
+	err := app.ApplyJSONConfig(flags, &app.JSONConfig{Retention: "raw:1h", CompactionInterval: "30s"})
+	require.NoError(t, err)
+	assert.Equal(t, "raw:1h", flags.Server.Retention)
+	assert.Equal(t, 30*time.Second, flags.Server.CompactionInterval)
+
+	flags.Server.CompactionInterval = app.DefaultCompactionInterval()
+	err = app.ApplyJSONConfig(flags, &app.JSONConfig{CompactionInterval: "often"})
+	require.Error(t, err)
+
+	assert.Equal(t, app.DefaultCompactionInterval(), (&app.Config{}).GetCompactionInterval())
+}
+
+func TestApplyJSONConfig_ShutdownDelay(t *testing.T) {
+	flags := &app.Flags{}
+	err := app.ApplyJSONConfig(flags, &app.JSONConfig{ShutdownDelay: "15s"})
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Second, flags.Server.ShutdownDelay)
+
+	// Значение из флага или переменной окружения имеет приоритет над файлом конфигурации.
+	flags.Server.ShutdownDelay = 5 * time.Second
+	err = app.ApplyJSONConfig(flags, &app.JSONConfig{ShutdownDelay: "15s"})
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, flags.Server.ShutdownDelay)
+
+	flags.Server.ShutdownDelay = 0
+	err = app.ApplyJSONConfig(flags, &app.JSONConfig{ShutdownDelay: "soon"})
+	require.Error(t, err)
+}
+
+func TestApplyJSONConfig_SQLite(t *testing.T) {
+	flags := &app.Flags{}
+	flags.SQLite.MigrationsPath = "migrations/sqlite"
+
+	err := app.ApplyJSONConfig(flags, &app.JSONConfig{SQLitePath: "/var/lib/metrics/metrics.db"})
+	require.NoError(t, err)
+
+	cfg := app.NewConfig(flags)
+	assert.True(t, cfg.IsSQLiteEnabled())
+	assert.Equal(t, "/var/lib/metrics/metrics.db", cfg.SQLite.Path)
+	assert.Equal(t, "migrations/sqlite", cfg.SQLite.MigrationsPath)
+
+	// Путь, заданный флагом или переменной окружения, не переопределяется.
+	flags.SQLite.Path = "/tmp/metrics.db"
+	err = app.ApplyJSONConfig(flags, &app.JSONConfig{SQLitePath: "/var/lib/metrics/metrics.db"})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/metrics.db", flags.SQLite.Path)
+}
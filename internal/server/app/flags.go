@@ -3,11 +3,35 @@ package app
 import (
 	"errors"
 	"flag"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
-const defaultStoreInterval = 300
+const (
+	defaultStoreInterval  = 300
+	defaultIdempotencyTTL = 10 * time.Minute
+
+	defaultIngestFlushInterval = 10 * time.Second
+
+	// Размер истории одной метрики в памяти: сутки значений при отправке раз в 10 секунд.
+	defaultHistorySize = 8640
+	// Политика хранения истории: исходные значения сутки, минутные агрегаты 30 дней, часовые год.
+	defaultRetention          = "raw:24h,1m:30d,1h:365d"
+	defaultCompactionInterval = 5 * time.Minute
+)
+
+// Политики сброса журнала изменений хранилища в памяти на диск (fsync).
+const (
+	// FsyncAlways сбрасывать журнал после каждого обновления.
+	FsyncAlways = "always"
+	// FsyncInterval сбрасывать журнал раз в секунду.
+	FsyncInterval = "interval"
+	// FsyncNever не сбрасывать журнал явно, оставляя запись на диск операционной системе.
+	FsyncNever = "never"
+)
 
 // Flags содержит все флаги сервера.
 type Flags struct {
@@ -19,8 +43,34 @@ type Flags struct {
 		FileStoragePath string
 		// Загружать или нет ранее сохраненные метрики из файла
 		Restore bool
+		// Политика сброса журнала изменений на диск: always, interval или never
+		Fsync string
 		// Включить профилирование через pprof
 		EnablePprof bool
+		// Время хранения ключей идемпотентности примененных пакетов метрик
+		IdempotencyTTL time.Duration
+		// Суффиксы имен рядов Prometheus remote write, которые сохраняются как counter
+		RemoteWriteCounterSuffixes []string
+		// Атрибуты ресурса OTLP, которыми дополняется имя метрики
+		OTLPResourceAttributes []string
+		// Адрес TCP-порта приема метрик Graphite plaintext
+		GraphiteAddr string
+		// Адрес UDP-порта приема метрик StatsD
+		StatsDAddr string
+		// Окно агрегации значений Graphite и StatsD
+		IngestFlushInterval time.Duration
+		// Адрес TCP-порта gRPC API
+		GRPCAddr string
+		// Сохранять историю значений метрик
+		History bool
+		// Количество значений истории одной метрики в памяти
+		HistorySize int
+		// Уровни хранения истории в формате resolution:retention через запятую
+		Retention string
+		// Период прореживания и удаления устаревшей истории
+		CompactionInterval time.Duration
+		// Задержка остановки HTTP-сервера после сигнала, в течение которой /readyz отвечает 503
+		ShutdownDelay time.Duration
 	}
 
 	Database struct {
@@ -30,6 +80,13 @@ type Flags struct {
 		MigrationsPath string
 	}
 
+	SQLite struct {
+		// Путь к файлу базы данных SQLite
+		Path string
+		// Путь к директории с миграциями SQLite
+		MigrationsPath string
+	}
+
 	PrivateKey string
 	CryptoKey  string // Path to the private key file for decryption
 	ConfigFile string // Путь к файлу конфигурации в формате JSON
@@ -56,6 +113,10 @@ func ParseFlags() (*Flags, error) {
 		return nil, err
 	}
 
+	if err := validateFlags(&flags); err != nil {
+		return nil, err
+	}
+
 	return &flags, nil
 }
 
@@ -84,6 +145,13 @@ func registerCommandLineFlags(flags *Flags) {
 	)
 	// Регистрируем переменную flagRestore как аргумент -r со значением false по умолчанию.
 	flag.BoolVar(&flags.Server.Restore, "r", true, "Восстанавливать метрики из файла при старте?")
+	flag.StringVar(
+		&flags.Server.Fsync,
+		"fsync",
+		"",
+		"Политика сброса журнала изменений на диск: always, interval или never "+
+			"(по умолчанию always при -i 0, иначе interval)",
+	)
 
 	// Добавляем флаг профилирования
 	flag.BoolVar(
@@ -93,6 +161,88 @@ func registerCommandLineFlags(flags *Flags) {
 		"enable pprof profiling with /debug/pprof routes",
 	)
 
+	flag.DurationVar(
+		&flags.Server.IdempotencyTTL,
+		"idempotency-ttl",
+		defaultIdempotencyTTL,
+		"Время хранения ключей идемпотентности примененных пакетов метрик",
+	)
+
+	flag.Func(
+		"remote-write-counter-suffixes",
+		"Суффиксы имен рядов Prometheus remote write через запятую, которые сохраняются как counter (по умолчанию _total)",
+		func(v string) error {
+			flags.Server.RemoteWriteCounterSuffixes = splitList(v)
+			return nil
+		},
+	)
+
+	flag.Func(
+		"otlp-resource-attributes",
+		"Атрибуты ресурса OTLP через запятую, которыми дополняется имя метрики (по умолчанию service.name)",
+		func(v string) error {
+			flags.Server.OTLPResourceAttributes = splitList(v)
+			return nil
+		},
+	)
+
+	flag.StringVar(
+		&flags.Server.GraphiteAddr,
+		"graphite-addr",
+		"",
+		"Адрес TCP-порта приема метрик Graphite plaintext, например :2003 (пусто — прием отключен)",
+	)
+	flag.StringVar(
+		&flags.Server.StatsDAddr,
+		"statsd-addr",
+		"",
+		"Адрес UDP-порта приема метрик StatsD, например :8125 (пусто — прием отключен)",
+	)
+	flag.DurationVar(
+		&flags.Server.IngestFlushInterval,
+		"ingest-flush-interval",
+		defaultIngestFlushInterval,
+		"Окно агрегации значений Graphite и StatsD перед записью в хранилище",
+	)
+
+	flag.StringVar(
+		&flags.Server.GRPCAddr,
+		"grpc-addr",
+		"",
+		"Адрес TCP-порта gRPC API, например :3200 (пусто — gRPC API отключен)",
+	)
+
+	flag.BoolVar(
+		&flags.Server.History,
+		"history",
+		false,
+		"Сохранять историю значений метрик для запросов GET /api/v1/query_range",
+	)
+	flag.IntVar(
+		&flags.Server.HistorySize,
+		"history-size",
+		defaultHistorySize,
+		"Количество последних значений каждой метрики в истории хранилища в памяти",
+	)
+	flag.StringVar(
+		&flags.Server.Retention,
+		"retention",
+		defaultRetention,
+		"Уровни хранения истории resolution:retention через запятую (пусто — без ограничений)",
+	)
+	flag.DurationVar(
+		&flags.Server.CompactionInterval,
+		"compaction-interval",
+		defaultCompactionInterval,
+		"Период прореживания и удаления устаревшей истории",
+	)
+	flag.DurationVar(
+		&flags.Server.ShutdownDelay,
+		"shutdown-delay",
+		0,
+		"Задержка остановки HTTP-сервера после сигнала, чтобы балансировщик успел снять сервер с нагрузки",
+	)
+
 	// Адрес подключения к БД PostgresSQL, по умолчанию пустое значение (не подключаемся к БД).
 	flag.StringVar(
 		&flags.Database.DSN,
@@ -106,6 +256,14 @@ func registerCommandLineFlags(flags *Flags) {
 		"migrations/server",
 		"Путь к директории с миграциями")
 
+	// Путь к файлу базы данных SQLite, по умолчанию пустое значение (SQLite не используется).
+	flag.StringVar(&flags.SQLite.Path, "sqlite", "", "Путь к файлу базы данных SQLite")
+	// Путь к директории с миграциями SQLite относительно корня проекта, по умолчанию "migrations/sqlite".
+	flag.StringVar(&flags.SQLite.MigrationsPath,
+		"sqlite-migrations-path",
+		"migrations/sqlite",
+		"Путь к директории с миграциями SQLite")
+
 	flag.StringVar(&flags.PrivateKey, "k", "", "Приватный ключ для подписи запросов к серверу")
 	flag.StringVar(
 		&flags.CryptoKey,
@@ -148,11 +306,91 @@ func applyEnvironmentVariables(flags *Flags) error {
 		flags.Server.Restore = restore
 	}
 
+	if envFsync, ok := os.LookupEnv("FSYNC"); ok {
+		flags.Server.Fsync = envFsync
+	}
+
+	if envIdempotencyTTL := os.Getenv("IDEMPOTENCY_TTL"); envIdempotencyTTL != "" {
+		ttl, err := time.ParseDuration(envIdempotencyTTL)
+		if err != nil {
+			return err
+		}
+		flags.Server.IdempotencyTTL = ttl
+	}
+
+	if envSuffixes, ok := os.LookupEnv("REMOTE_WRITE_COUNTER_SUFFIXES"); ok {
+		flags.Server.RemoteWriteCounterSuffixes = splitList(envSuffixes)
+	}
+
+	if envAttributes, ok := os.LookupEnv("OTLP_RESOURCE_ATTRIBUTES"); ok {
+		flags.Server.OTLPResourceAttributes = splitList(envAttributes)
+	}
+
+	if envGraphiteAddr, ok := os.LookupEnv("GRAPHITE_ADDRESS"); ok {
+		flags.Server.GraphiteAddr = envGraphiteAddr
+	}
+
+	if envStatsDAddr, ok := os.LookupEnv("STATSD_ADDRESS"); ok {
+		flags.Server.StatsDAddr = envStatsDAddr
+	}
+
+	if envFlushInterval := os.Getenv("INGEST_FLUSH_INTERVAL"); envFlushInterval != "" {
+		interval, err := time.ParseDuration(envFlushInterval)
+		if err != nil {
+			return err
+		}
+		flags.Server.IngestFlushInterval = interval
+	}
+
+	if envGRPCAddr, ok := os.LookupEnv("GRPC_ADDRESS"); ok {
+		flags.Server.GRPCAddr = envGRPCAddr
+	}
+
+	if envHistory, ok := os.LookupEnv("HISTORY"); ok {
+		history, err := strconv.ParseBool(envHistory)
+		if err != nil {
+			return err
+		}
+		flags.Server.History = history
+	}
+
+	if envHistorySize := os.Getenv("HISTORY_SIZE"); envHistorySize != "" {
+		size, err := strconv.Atoi(envHistorySize)
+		if err != nil {
+			return err
+		}
+		flags.Server.HistorySize = size
+	}
+
+	if envRetention, ok := os.LookupEnv("RETENTION"); ok {
+		flags.Server.Retention = envRetention
+	}
+
+	if envCompactionInterval := os.Getenv("COMPACTION_INTERVAL"); envCompactionInterval != "" {
+		interval, err := time.ParseDuration(envCompactionInterval)
+		if err != nil {
+			return err
+		}
+		flags.Server.CompactionInterval = interval
+	}
+
+	if envShutdownDelay := os.Getenv("SHUTDOWN_DELAY"); envShutdownDelay != "" {
+		delay, err := time.ParseDuration(envShutdownDelay)
+		if err != nil {
+			return err
+		}
+		flags.Server.ShutdownDelay = delay
+	}
+
 	// Если переданы параметры БД в параметрах окружения, используем их
 	if envDatabaseDSN, ok := os.LookupEnv("DATABASE_DSN"); ok {
 		flags.Database.DSN = envDatabaseDSN
 	}
 
+	if envSQLitePath, ok := os.LookupEnv("SQLITE_PATH"); ok {
+		flags.SQLite.Path = envSQLitePath
+	}
+
 	// Если передан ключ в параметрах окружения, используем его
 	if envPrivateKey, ok := os.LookupEnv("KEY"); ok {
 		flags.PrivateKey = envPrivateKey
@@ -186,3 +424,32 @@ func loadAndApplyJSONConfig(flags *Flags) error {
 	// Применяем настройки из JSON-конфигурации (с более низким приоритетом)
 	return ApplyJSONConfig(flags, jsonConfig)
 }
+
+// validateFlags проверяет значения флагов, которые не проверяются при разборе.
+func validateFlags(flags *Flags) error {
+	switch flags.Server.Fsync {
+	case "", FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return fmt.Errorf("invalid fsync policy %q, expected %s, %s or %s",
+			flags.Server.Fsync, FsyncAlways, FsyncInterval, FsyncNever)
+	}
+	if flags.Server.ShutdownDelay < 0 {
+		return fmt.Errorf("invalid shutdown delay %s, expected non-negative duration", flags.Server.ShutdownDelay)
+	}
+	// SQLite используется, только если не задана база PostgreSQL, и не хранит историю значений метрик.
+	if flags.Server.History && flags.SQLite.Path != "" && flags.Database.DSN == "" {
+		return errors.New("history is not supported with SQLite storage, use PostgreSQL or in-memory storage")
+	}
+	return nil
+}
+
+// splitList разбирает список значений, разделенных запятыми, пропуская пустые элементы.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -31,6 +31,42 @@ func TestParseFlags(t *testing.T) {
 	assert.Equal(t, 10, flags.Server.StoreInterval)
 	assert.Equal(t, "/tmp/test.db", flags.Server.FileStoragePath)
 	assert.False(t, flags.Server.Restore)
+	assert.Equal(t, app.DefaultIdempotencyTTL(), flags.Server.IdempotencyTTL)
+}
+
+func TestParseFlags_Fsync(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"cmd", "-fsync", app.FsyncNever}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags, err := app.ParseFlags()
+	require.NoError(t, err)
+	assert.Equal(t, app.FsyncNever, flags.Server.Fsync)
+
+	// Неизвестная политика отклоняется
+	os.Args = []string{"cmd", "-fsync", "sometimes"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	_, err = app.ParseFlags()
+	require.Error(t, err)
+}
+
+func TestParseFlags_HistoryWithSQLite(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	// SQLite не хранит историю значений метрик
+	os.Args = []string{"cmd", "-sqlite", "/tmp/metrics.db", "-history"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	_, err := app.ParseFlags()
+	require.ErrorContains(t, err, "history is not supported with SQLite")
+
+	// При заданной базе PostgreSQL SQLite не используется
+	os.Args = []string{"cmd", "-sqlite", "/tmp/metrics.db", "-history", "-d", "postgres://localhost/metrics"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags, err := app.ParseFlags()
+	require.NoError(t, err)
+	assert.True(t, flags.Server.History)
 }
 
 func TestRegisterCommandLineFlags(t *testing.T) {
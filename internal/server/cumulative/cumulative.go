@@ -0,0 +1,145 @@
+// Package cumulative преобразует счетчики, передаваемые нарастающим итогом (Prometheus remote write,
+// OTLP с кумулятивной агрегацией), в приращения, которые накапливает хранилище сервера.
+package cumulative
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+const (
+	// DefaultMaxSeries количество счетчиков, накопленные значения которых хранит Tracker.
+	DefaultMaxSeries = 100_000
+	// IdleTTL время, после которого не обновлявшийся счетчик может быть забыт при превышении
+	// количества счетчиков. Забытый счетчик при следующей встрече снова берет значение из хранилища.
+	IdleTTL = time.Hour
+)
+
+// series состояние счетчика источника.
+type series struct {
+	// Последнее накопленное значение, по уменьшению которого определяется сброс счетчика.
+	total float64
+	// Часть накопленного значения, уже сохраненная в хранилище целыми приращениями.
+	// Дробный остаток total - accounted учитывается в следующих приращениях.
+	accounted float64
+	// Время последнего сохранения значения.
+	seen time.Time
+}
+
+// Tracker запоминает последние накопленные значения счетчиков. Методы можно вызывать
+// из нескольких горутин.
+type Tracker struct {
+	st        storage.Repository
+	maxSeries int
+	mu        sync.Mutex
+	series    map[string]series
+}
+
+// New создает Tracker, который при первой встрече счетчика берет предыдущее значение из хранилища
+// и хранит значения не более чем DefaultMaxSeries счетчиков.
+func New(st storage.Repository) *Tracker {
+	return NewWithLimit(st, DefaultMaxSeries)
+}
+
+// NewWithLimit создает Tracker, который хранит значения не более чем maxSeries счетчиков.
+func NewWithLimit(st storage.Repository, maxSeries int) *Tracker {
+	return &Tracker{st: st, maxSeries: maxSeries, series: make(map[string]series)}
+}
+
+// Batch вычисляет приращения счетчиков одного запроса. Новые накопленные значения сохраняются
+// в Tracker только вызовом Commit после успешной записи приращений в хранилище, поэтому
+// при повторе неудачного запроса приращения вычисляются заново и не теряются.
+type Batch struct {
+	t       *Tracker
+	pending map[string]series
+}
+
+// Batch начинает вычисление приращений одного запроса.
+func (t *Tracker) Batch() *Batch {
+	return &Batch{t: t, pending: make(map[string]series)}
+}
+
+// Delta возвращает целое приращение счетчика name относительно предыдущего накопленного значения.
+// Для счетчика, впервые встреченного после запуска сервера, предыдущим значением считается
+// сохраненное в хранилище. Уменьшение значения означает сброс счетчика (перезапуск источника),
+// тогда приращением считается само значение. Дробная часть приращения переносится в следующие.
+func (b *Batch) Delta(ctx context.Context, name string, total float64) int64 {
+	prev, ok := b.pending[name]
+	if !ok {
+		prev, ok = b.t.load(ctx, name)
+	}
+
+	next := series{total: total}
+	if ok && total >= prev.total {
+		next.accounted = prev.accounted
+	}
+	delta := math.Floor(total - next.accounted)
+	next.accounted += delta
+	b.pending[name] = next
+	return int64(delta)
+}
+
+// Commit сохраняет накопленные значения счетчиков запроса. Вызывается после успешной записи
+// приращений в хранилище.
+func (b *Batch) Commit() {
+	if len(b.pending) == 0 {
+		return
+	}
+	now := time.Now()
+
+	t := b.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name, s := range b.pending {
+		s.seen = now
+		t.series[name] = s
+	}
+	if len(t.series) > t.maxSeries {
+		t.evict(now)
+	}
+}
+
+// load возвращает сохраненное состояние счетчика или, если счетчик еще не встречался,
+// состояние по значению из хранилища. Хранилище читается без блокировки Tracker.
+func (t *Tracker) load(ctx context.Context, name string) (series, bool) {
+	t.mu.Lock()
+	s, ok := t.series[name]
+	t.mu.Unlock()
+	if ok {
+		return s, true
+	}
+
+	stored, err := t.st.GetCounter(ctx, name)
+	if err != nil {
+		return series{}, false
+	}
+	return series{total: float64(stored), accounted: float64(stored)}, true
+}
+
+// evict забывает счетчики, не обновлявшиеся дольше IdleTTL, а если их количество все еще
+// превышает предел — давно не обновлявшиеся счетчики, пока не останется 90% от предела.
+// Вызывается под блокировкой.
+func (t *Tracker) evict(now time.Time) {
+	for name, s := range t.series {
+		if now.Sub(s.seen) > IdleTTL {
+			delete(t.series, name)
+		}
+	}
+	if len(t.series) <= t.maxSeries {
+		return
+	}
+
+	names := make([]string, 0, len(t.series))
+	for name := range t.series {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return t.series[names[i]].seen.Before(t.series[names[j]].seen) })
+	for _, name := range names[:len(names)-t.maxSeries*9/10] {
+		delete(t.series, name)
+	}
+}
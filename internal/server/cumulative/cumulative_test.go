@@ -0,0 +1,87 @@
+package cumulative_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/cumulative"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+)
+
+func TestBatch_Delta(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop(), storage.Gauges{}, storage.Counters{"stored": 10})
+	require.NoError(t, err)
+	tr := cumulative.New(st)
+	ctx := context.Background()
+
+	delta := func(name string, total float64) int64 {
+		b := tr.Batch()
+		d := b.Delta(ctx, name, total)
+		b.Commit()
+		return d
+	}
+
+	// Предыдущее значение нового счетчика берется из хранилища.
+	assert.Equal(t, int64(5), delta("stored", 15))
+	assert.Equal(t, int64(0), delta("stored", 15))
+	assert.Equal(t, int64(3), delta("stored", 18))
+	// Сброс счетчика источника.
+	assert.Equal(t, int64(2), delta("stored", 2))
+
+	// Счетчика нет в хранилище: приращение равно значению.
+	assert.Equal(t, int64(7), delta("new", 7))
+	assert.Equal(t, int64(1), delta("new", 8))
+
+	// Дробные части накапливаются и не теряются.
+	assert.Equal(t, int64(0), delta("fraction", 0.4))
+	assert.Equal(t, int64(0), delta("fraction", 0.8))
+	assert.Equal(t, int64(1), delta("fraction", 1.2))
+	assert.Equal(t, int64(1), delta("fraction", 2.5))
+}
+
+func TestBatch_WithoutCommit(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop(), storage.Gauges{}, storage.Counters{"c": 10})
+	require.NoError(t, err)
+	tr := cumulative.New(st)
+	ctx := context.Background()
+
+	// Несколько значений счетчика в одном запросе.
+	b := tr.Batch()
+	assert.Equal(t, int64(5), b.Delta(ctx, "c", 15))
+	assert.Equal(t, int64(5), b.Delta(ctx, "c", 20))
+
+	// Запрос не записан в хранилище: следующий запрос снова считает приращение от сохраненного значения.
+	b = tr.Batch()
+	assert.Equal(t, int64(10), b.Delta(ctx, "c", 20))
+	b.Commit()
+	assert.Equal(t, int64(0), tr.Batch().Delta(ctx, "c", 20))
+}
+
+func TestTracker_MaxSeries(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	tr := cumulative.NewWithLimit(st, 10)
+	ctx := context.Background()
+
+	b := tr.Batch()
+	b.Delta(ctx, "old", 5)
+	b.Commit()
+	time.Sleep(time.Millisecond)
+	for i := range 10 {
+		b = tr.Batch()
+		b.Delta(ctx, fmt.Sprintf("c%d", i), 5)
+		b.Commit()
+	}
+
+	// Давно не обновлявшийся счетчик забыт: его предыдущим значением снова считается значение из хранилища.
+	assert.Equal(t, int64(5), tr.Batch().Delta(ctx, "old", 5))
+	assert.Equal(t, int64(0), tr.Batch().Delta(ctx, "c9", 5))
+}
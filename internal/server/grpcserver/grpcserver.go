@@ -0,0 +1,82 @@
+// Package grpcserver реализует gRPC API сервера метрик (сервис metrics.v1.Metrics).
+//
+// Сервис повторяет JSON API: UpdateMetrics и StreamUpdates соответствуют POST /updates,
+// GetMetric — POST /value, ListMetrics возвращает все метрики хранилища. Подпись HMAC-SHA256,
+// расшифровка RSA и логирование выполняются перехватчиками, аналогичными middleware HTTP-сервера.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	// Регистрирует сжатие gzip для запросов агента.
+	_ "google.golang.org/grpc/encoding/gzip"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metricspb"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// NewServer создает gRPC-сервер с зарегистрированным сервисом метрик и перехватчиками.
+func NewServer(cfg *app.Config, st storage.Repository, log *zap.Logger) *grpc.Server {
+	c := &cryptoInterceptor{cfg: cfg, log: log}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(loggingUnaryInterceptor(log), c.unary),
+		grpc.ChainStreamInterceptor(loggingStreamInterceptor(log), c.stream),
+	)
+	metricspb.RegisterMetricsServer(srv, NewService(st, log))
+	return srv
+}
+
+// Listener gRPC-сервер, работающий вместе с HTTP-сервером.
+type Listener struct {
+	srv *grpc.Server
+	ln  net.Listener
+	log *zap.Logger
+}
+
+// Listen открывает TCP-порт addr для gRPC API.
+func Listen(addr string, cfg *app.Config, st storage.Repository, log *zap.Logger) (*Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("grpc listener: %w", err)
+	}
+	return &Listener{srv: NewServer(cfg, st, log), ln: ln, log: log}, nil
+}
+
+// Addr возвращает адрес, на котором принимаются соединения.
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// Serve обрабатывает запросы до вызова Shutdown.
+func (l *Listener) Serve() error {
+	l.log.Info("grpc server is listening", zap.String("addr", l.Addr().String()))
+	if err := l.srv.Serve(l.ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("grpc listener: %w", err)
+	}
+	return nil
+}
+
+// Shutdown прекращает прием новых вызовов и дожидается завершения текущих.
+// Если контекст истекает раньше, оставшиеся вызовы прерываются.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		l.srv.GracefulStop()
+		// Порт мог быть открыт без вызова Serve, если сервер не был запущен.
+		_ = l.ln.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		l.srv.Stop()
+		return ctx.Err()
+	}
+}
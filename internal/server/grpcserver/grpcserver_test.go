@@ -0,0 +1,217 @@
+package grpcserver_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/contracts/metricspb"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/grpcserver"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/pkg/crypto"
+	"github.com/maynagashev/go-metrics/pkg/sign"
+)
+
+// startServer запускает gRPC API на свободном порту и возвращает клиент к нему.
+func startServer(t *testing.T, cfg *app.Config) (metricspb.MetricsClient, *memory.MemStorage) {
+	t.Helper()
+	st, err := memory.New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	l, err := grpcserver.Listen("127.0.0.1:0", cfg, st, zap.NewNop())
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- l.Serve() }()
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, l.Shutdown(ctx))
+		require.NoError(t, <-served)
+	})
+	return metricspb.NewMetricsClient(conn), st
+}
+
+func batch(items ...*metrics.Metric) []*metricspb.Metric {
+	result := make([]*metricspb.Metric, 0, len(items))
+	for _, m := range items {
+		result = append(result, metricspb.FromMetric(*m))
+	}
+	return result
+}
+
+func TestService_UpdateGetList(t *testing.T) {
+	client, _ := startServer(t, &app.Config{})
+	ctx := context.Background()
+
+	resp, err := client.UpdateMetrics(ctx, &metricspb.UpdateMetricsRequest{
+		Metrics: batch(metrics.NewGauge("Alloc", 1.5), metrics.NewCounter("PollCount", 2)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), resp.Updated)
+
+	m, err := client.GetMetric(ctx, &metricspb.GetMetricRequest{Id: "PollCount", Type: "counter"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *m.Delta)
+
+	_, err = client.GetMetric(ctx, &metricspb.GetMetricRequest{Id: "Missing", Type: "gauge"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetMetric(ctx, &metricspb.GetMetricRequest{Id: "Alloc", Type: "histogram"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	list, err := client.ListMetrics(ctx, &metricspb.ListMetricsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Metrics, 2)
+	assert.Equal(t, "PollCount", list.Metrics[0].GetId())
+	assert.Equal(t, "Alloc", list.Metrics[1].GetId())
+}
+
+func TestService_UpdateMetricsIdempotency(t *testing.T) {
+	client, st := startServer(t, &app.Config{})
+	ctx := context.Background()
+	req := &metricspb.UpdateMetricsRequest{
+		Metrics:        batch(metrics.NewCounter("PollCount", 5)),
+		IdempotencyKey: "batch-1",
+	}
+
+	_, err := client.UpdateMetrics(ctx, req)
+	require.NoError(t, err)
+	resp, err := client.UpdateMetrics(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), resp.Replayed)
+
+	c, _ := st.GetCounter(ctx, "PollCount")
+	assert.EqualValues(t, 5, c)
+}
+
+func TestService_StreamUpdates(t *testing.T) {
+	client, st := startServer(t, &app.Config{})
+	ctx := context.Background()
+
+	stream, err := client.StreamUpdates(ctx)
+	require.NoError(t, err)
+	for range 3 {
+		require.NoError(t, stream.Send(&metricspb.UpdateMetricsRequest{
+			Metrics: batch(metrics.NewCounter("PollCount", 1), metrics.NewGauge("Alloc", 3)),
+		}))
+	}
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(6), resp.Updated)
+
+	c, _ := st.GetCounter(ctx, "PollCount")
+	assert.EqualValues(t, 3, c)
+}
+
+func marshalBatch(t *testing.T, items []*metricspb.Metric) []byte {
+	t.Helper()
+	b, err := metricspb.MarshalBatch(items)
+	require.NoError(t, err)
+	return b
+}
+
+func TestCryptoInterceptor_Signature(t *testing.T) {
+	const key = "secret"
+	client, st := startServer(t, &app.Config{PrivateKey: key})
+	ctx := context.Background()
+
+	items := batch(metrics.NewGauge("Alloc", 7))
+	hash := sign.ComputeHMACSHA256(marshalBatch(t, items), key)
+
+	var header metadata.MD
+	resp, err := client.UpdateMetrics(ctx,
+		&metricspb.UpdateMetricsRequest{Metrics: items, Hash: hash},
+		grpc.Header(&header))
+	require.NoError(t, err)
+
+	// Ответ подписан тем же ключом.
+	respBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(resp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{sign.ComputeHMACSHA256(respBytes, key)}, header.Get(grpcserver.HashMetadataKey))
+
+	_, err = client.UpdateMetrics(ctx, &metricspb.UpdateMetricsRequest{
+		Metrics: batch(metrics.NewGauge("Alloc", 8)),
+		Hash:    hash,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	g, _ := st.GetGauge(ctx, "Alloc")
+	assert.InDelta(t, 7.0, float64(g), 0)
+}
+
+func TestCryptoInterceptor_Encryption(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	client, st := startServer(t, &app.Config{PrivateRSAKey: privateKey})
+	ctx := context.Background()
+
+	encrypted, err := crypto.EncryptLargeData(&privateKey.PublicKey,
+		marshalBatch(t, batch(metrics.NewCounter("PollCount", 4))))
+	require.NoError(t, err)
+
+	// Зашифрованные пакеты принимаются и в потоке.
+	stream, err := client.StreamUpdates(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&metricspb.UpdateMetricsRequest{EncryptedMetrics: encrypted}))
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), resp.Updated)
+
+	c, _ := st.GetCounter(ctx, "PollCount")
+	assert.EqualValues(t, 4, c)
+
+	_, err = client.UpdateMetrics(ctx, &metricspb.UpdateMetricsRequest{EncryptedMetrics: []byte("garbage")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestCryptoInterceptor_EncryptionNotConfigured(t *testing.T) {
+	client, _ := startServer(t, &app.Config{})
+	_, err := client.UpdateMetrics(context.Background(),
+		&metricspb.UpdateMetricsRequest{EncryptedMetrics: []byte("data")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestNewServer_StandardServices(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+
+	// На сервере с сервисом метрик работают и стандартные сервисы gRPC
+	srv := grpcserver.NewServer(&app.Config{}, st, zap.NewNop())
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	_, err = metricspb.NewMetricsClient(conn).ListMetrics(context.Background(), &metricspb.ListMetricsRequest{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,178 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metricspb"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/pkg/crypto"
+	"github.com/maynagashev/go-metrics/pkg/sign"
+)
+
+// HashMetadataKey ключ метаданных ответа с подписью HMAC-SHA256 сериализованного сообщения,
+// аналог заголовка HashSHA256 HTTP API.
+//
+//nolint:gochecknoglobals // производное от sign.HeaderKey значение
+var HashMetadataKey = strings.ToLower(sign.HeaderKey)
+
+// loggingUnaryInterceptor логирует каждый вызов, аналогично middleware logger HTTP-сервера.
+func loggingUnaryInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		t1 := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, log, info.FullMethod, t1, err)
+		return resp, err
+	}
+}
+
+// loggingStreamInterceptor логирует каждый потоковый вызов после его завершения.
+func loggingStreamInterceptor(log *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		t1 := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), log, info.FullMethod, t1, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, log *zap.Logger, method string, start time.Time, err error) {
+	remote := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+	}
+	log.Info("request completed",
+		zap.String("method", method),
+		zap.String("remote_addr", remote),
+		zap.String("code", status.Code(err).String()),
+		zap.Error(err),
+		zap.String("duration", time.Since(start).String()),
+	)
+}
+
+// cryptoInterceptor расшифровывает пакеты метрик, проверяет их подпись и подписывает ответы,
+// аналогично middleware crypto HTTP-сервера.
+type cryptoInterceptor struct {
+	cfg *app.Config
+	log *zap.Logger
+}
+
+func (c *cryptoInterceptor) unary(
+	ctx context.Context,
+	req any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if err := c.processRequest(req); err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if md := c.responseHash(resp); md != nil {
+		if hdrErr := grpc.SetHeader(ctx, md); hdrErr != nil {
+			c.log.Error("failed to sign response", zap.Error(hdrErr))
+		}
+	}
+	return resp, nil
+}
+
+func (c *cryptoInterceptor) stream(
+	srv any,
+	ss grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	return handler(srv, &cryptoServerStream{ServerStream: ss, c: c})
+}
+
+// cryptoServerStream обрабатывает каждый полученный пакет и подписывает ответ потока.
+type cryptoServerStream struct {
+	grpc.ServerStream
+	c *cryptoInterceptor
+}
+
+func (s *cryptoServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.c.processRequest(m)
+}
+
+func (s *cryptoServerStream) SendMsg(m any) error {
+	if md := s.c.responseHash(m); md != nil {
+		if err := s.ServerStream.SetHeader(md); err != nil {
+			s.c.log.Error("failed to sign response", zap.Error(err))
+		}
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// processRequest расшифровывает пакет метрик, если он передан в зашифрованном виде,
+// и проверяет подпись пакета, если она передана и на сервере задан ключ.
+func (c *cryptoInterceptor) processRequest(req any) error {
+	r, ok := req.(*metricspb.UpdateMetricsRequest)
+	if !ok {
+		return nil
+	}
+
+	if len(r.EncryptedMetrics) > 0 {
+		if !c.cfg.IsEncryptionEnabled() {
+			c.log.Error("received encrypted data but server has no private key configured")
+			return status.Error(codes.InvalidArgument, "Server is not configured for encryption")
+		}
+		decrypted, err := crypto.DecryptLargeData(c.cfg.PrivateRSAKey, r.EncryptedMetrics)
+		if err != nil {
+			c.log.Error("failed to decrypt data", zap.Error(err))
+			return status.Error(codes.InvalidArgument, "Failed to decrypt request body")
+		}
+		items, err := metricspb.UnmarshalBatch(decrypted)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "decrypted metrics: %v", err)
+		}
+		r.Metrics, r.EncryptedMetrics = items, nil
+	}
+
+	if !c.cfg.IsRequestSigningEnabled() || r.Hash == "" {
+		return nil
+	}
+	// Подпись вычисляется по детерминированной сериализации пакета, поэтому пакет
+	// сериализуется заново, а не берется в полученном виде.
+	batch, err := metricspb.MarshalBatch(r.Metrics)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "metrics: %v", err)
+	}
+	if _, err = sign.VerifyHMACSHA256(batch, c.cfg.PrivateKey, r.Hash); err != nil {
+		c.log.Error("failed to verify request signature", zap.Error(err))
+		return status.Error(codes.InvalidArgument, "Invalid request signature")
+	}
+	return nil
+}
+
+// responseHash возвращает метаданные с подписью сериализованного ответа
+// или nil, если подпись не требуется.
+func (c *cryptoInterceptor) responseHash(resp any) metadata.MD {
+	if !c.cfg.IsRequestSigningEnabled() {
+		return nil
+	}
+	m, ok := resp.(proto.Message)
+	if !ok {
+		return nil
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		c.log.Error("failed to marshal response for signing", zap.Error(err))
+		return nil
+	}
+	return metadata.Pairs(HashMetadataKey, sign.ComputeHMACSHA256(b, c.cfg.PrivateKey))
+}
@@ -0,0 +1,151 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/contracts/metricspb"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// Service реализация сервиса metrics.v1.Metrics поверх хранилища метрик.
+type Service struct {
+	metricspb.UnimplementedMetricsServer
+
+	st  storage.Repository
+	log *zap.Logger
+}
+
+// NewService создает сервис метрик.
+func NewService(st storage.Repository, log *zap.Logger) *Service {
+	return &Service{st: st, log: log}
+}
+
+// UpdateMetrics применяет пакет метрик.
+func (s *Service) UpdateMetrics(
+	ctx context.Context,
+	req *metricspb.UpdateMetricsRequest,
+) (*metricspb.UpdateMetricsResponse, error) {
+	resp := &metricspb.UpdateMetricsResponse{}
+	if err := s.apply(ctx, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// StreamUpdates применяет пакеты метрик из потока по мере получения.
+// После ошибки очередного пакета поток прерывается, уже примененные пакеты не откатываются.
+func (s *Service) StreamUpdates(
+	stream grpc.ClientStreamingServer[metricspb.UpdateMetricsRequest, metricspb.UpdateMetricsResponse],
+) error {
+	resp := &metricspb.UpdateMetricsResponse{}
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+		if err = s.apply(stream.Context(), req, resp); err != nil {
+			return err
+		}
+	}
+}
+
+// GetMetric возвращает значение метрики.
+func (s *Service) GetMetric(ctx context.Context, req *metricspb.GetMetricRequest) (*metricspb.Metric, error) {
+	mType := metrics.MetricType(req.Type)
+	if mType != metrics.TypeGauge && mType != metrics.TypeCounter {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown metric type %q", req.Type)
+	}
+	m, err := s.st.GetMetric(ctx, mType, req.GetId())
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "metric %s %q not found", req.GetType(), req.GetId())
+	}
+	if err != nil {
+		return nil, storageError(err)
+	}
+	return metricspb.FromMetric(m), nil
+}
+
+// ListMetrics возвращает все метрики хранилища, упорядоченные по типу и имени.
+func (s *Service) ListMetrics(ctx context.Context, _ *metricspb.ListMetricsRequest) (*metricspb.ListMetricsResponse, error) {
+	items, err := s.st.GetMetrics(ctx)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].MType != items[j].MType {
+			return items[i].MType < items[j].MType
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	resp := &metricspb.ListMetricsResponse{Metrics: make([]*metricspb.Metric, 0, len(items))}
+	for _, m := range items {
+		resp.Metrics = append(resp.Metrics, metricspb.FromMetric(m))
+	}
+	return resp, nil
+}
+
+// apply применяет пакет метрик и учитывает результат в resp. Если передан ключ идемпотентности
+// и хранилище поддерживает однократное применение пакетов, повторный пакет не применяется.
+func (s *Service) apply(
+	ctx context.Context,
+	req *metricspb.UpdateMetricsRequest,
+	resp *metricspb.UpdateMetricsResponse,
+) error {
+	if len(req.IdempotencyKey) > metrics.MaxIdempotencyKeyLength {
+		return status.Error(codes.InvalidArgument, "idempotency key is too long")
+	}
+
+	items := make([]metrics.Metric, 0, len(req.Metrics))
+	for _, m := range req.Metrics {
+		items = append(items, m.ToMetric())
+	}
+
+	var (
+		duplicate bool
+		err       error
+	)
+	if idempotent, ok := s.st.(storage.IdempotentRepository); ok && req.IdempotencyKey != "" {
+		duplicate, err = idempotent.UpdateMetricsOnce(ctx, req.IdempotencyKey, items)
+	} else {
+		err = s.st.UpdateMetrics(ctx, items)
+	}
+	if err != nil {
+		return storageError(err)
+	}
+
+	if duplicate {
+		s.log.Info("Metrics batch already applied, skipping", zap.String("idempotency_key", req.IdempotencyKey))
+		resp.Replayed++
+		return nil
+	}
+	resp.Updated += uint32(len(items)) //nolint:gosec // размер пакета ограничен размером сообщения gRPC
+	return nil
+}
+
+// storageError преобразует ошибку хранилища в статус gRPC: InvalidArgument для storage.ErrInvalidMetric,
+// NotFound для storage.ErrNotFound, Unavailable для storage.ErrUnavailable и Internal для остальных ошибок.
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrInvalidMetric):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,120 @@
+// Package health реализует проверки живости и готовности сервера для балансировщиков нагрузки
+// и оркестраторов: GET /healthz отвечает, пока процесс работает, GET /readyz проверяет компоненты,
+// без которых сервер не может обрабатывать запросы, и отвечает 503 во время graceful shutdown.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CheckTimeout время, за которое должны завершиться проверки компонентов одного запроса /readyz.
+const CheckTimeout = 2 * time.Second
+
+// Состояния сервера и его компонентов в ответах /healthz и /readyz.
+const (
+	StatusUp           = "up"
+	StatusDown         = "down"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check проверяет готовность компонента, ошибка означает, что компонент не готов.
+type Check func(ctx context.Context) error
+
+// Component состояние компонента в ответе /readyz.
+type Component struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report ответ /healthz и /readyz.
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Health состояние готовности сервера. Методы можно вызывать из нескольких горутин.
+type Health struct {
+	checks map[string]Check
+	names  []string
+	log    *zap.Logger
+	// Получен сигнал остановки, сервер больше не принимает новую нагрузку.
+	shuttingDown atomic.Bool
+}
+
+// New создает проверку готовности сервера по компонентам checks, ключ — имя компонента в ответе /readyz.
+func New(checks map[string]Check, log *zap.Logger) *Health {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &Health{checks: checks, names: names, log: log}
+}
+
+// Shutdown переводит сервер в состояние остановки: после вызова /readyz отвечает 503.
+func (h *Health) Shutdown() {
+	if !h.shuttingDown.Swap(true) {
+		h.log.Info("readiness probe switched to shutting down")
+	}
+}
+
+// Liveness возвращает обработчик GET /healthz. Он не проверяет компоненты и отвечает 200,
+// пока процесс способен обрабатывать запросы.
+func (h *Health) Liveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, Report{Status: StatusUp}, http.StatusOK)
+	}
+}
+
+// Readiness возвращает обработчик GET /readyz. Он отвечает 200, если все компоненты готовы,
+// и 503, если хотя бы один компонент не готов или сервер останавливается.
+func (h *Health) Readiness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.shuttingDown.Load() {
+			writeReport(w, Report{Status: StatusShuttingDown}, http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), CheckTimeout)
+		defer cancel()
+
+		report, ready := h.check(ctx)
+		if !ready {
+			writeReport(w, report, http.StatusServiceUnavailable)
+			return
+		}
+		writeReport(w, report, http.StatusOK)
+	}
+}
+
+// check проверяет все компоненты и возвращает их состояние, а также признак готовности сервера.
+func (h *Health) check(ctx context.Context) (Report, bool) {
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(h.checks))}
+	ready := true
+	for _, name := range h.names {
+		if err := h.checks[name](ctx); err != nil {
+			h.log.Warn("readiness check failed", zap.String("component", name), zap.Error(err))
+			report.Components[name] = Component{Status: StatusDown, Error: err.Error()}
+			report.Status = StatusDown
+			ready = false
+			continue
+		}
+		report.Components[name] = Component{Status: StatusUp}
+	}
+	return report, ready
+}
+
+func writeReport(w http.ResponseWriter, report Report, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	// Ответ проверки не должен кэшироваться промежуточными прокси.
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(report)
+}
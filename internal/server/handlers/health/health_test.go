@@ -0,0 +1,118 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/server/handlers/health"
+)
+
+func serve(t *testing.T, handler http.HandlerFunc) (int, health.Report) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var report health.Report
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	return rr.Code, report
+}
+
+func TestLiveness(t *testing.T) {
+	h := health.New(map[string]health.Check{
+		"storage": func(context.Context) error { return errors.New("connection refused") },
+	}, zap.NewNop())
+
+	// Живость не зависит от состояния компонентов и остановки сервера
+	code, report := serve(t, h.Liveness())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.Report{Status: health.StatusUp}, report)
+
+	h.Shutdown()
+	code, _ = serve(t, h.Liveness())
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]health.Check
+		wantCode   int
+		wantReport health.Report
+	}{
+		{
+			name: "all components ready",
+			checks: map[string]health.Check{
+				"storage": func(context.Context) error { return nil },
+			},
+			wantCode: http.StatusOK,
+			wantReport: health.Report{
+				Status:     health.StatusUp,
+				Components: map[string]health.Component{"storage": {Status: health.StatusUp}},
+			},
+		},
+		{
+			name: "component not ready",
+			checks: map[string]health.Check{
+				"storage": func(context.Context) error { return errors.New("connection refused") },
+				"queue":   func(context.Context) error { return nil },
+			},
+			wantCode: http.StatusServiceUnavailable,
+			wantReport: health.Report{
+				Status: health.StatusDown,
+				Components: map[string]health.Component{
+					"storage": {Status: health.StatusDown, Error: "connection refused"},
+					"queue":   {Status: health.StatusUp},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, report := serve(t, health.New(tt.checks, zap.NewNop()).Readiness())
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantReport, report)
+		})
+	}
+}
+
+func TestReadiness_ShuttingDown(t *testing.T) {
+	called := false
+	h := health.New(map[string]health.Check{
+		"storage": func(context.Context) error {
+			called = true
+			return nil
+		},
+	}, zap.NewNop())
+	h.Shutdown()
+
+	code, report := serve(t, h.Readiness())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.Report{Status: health.StatusShuttingDown}, report)
+	// Во время остановки компоненты не проверяются
+	assert.False(t, called)
+}
+
+func TestReadiness_CheckTimeout(t *testing.T) {
+	h := health.New(map[string]health.Check{
+		"storage": func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			require.True(t, ok, "check must be called with deadline")
+			assert.LessOrEqual(t, time.Until(deadline), health.CheckTimeout)
+			return nil
+		},
+	}, zap.NewNop())
+
+	code, _ := serve(t, h.Readiness())
+	assert.Equal(t, http.StatusOK, code)
+}
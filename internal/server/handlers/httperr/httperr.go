@@ -0,0 +1,24 @@
+// Package httperr преобразует ошибки хранилища метрик в коды ответов HTTP-обработчиков.
+package httperr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// Status возвращает код ответа HTTP для ошибки хранилища: 400 для storage.ErrInvalidMetric,
+// 404 для storage.ErrNotFound, 503 для storage.ErrUnavailable и 500 для остальных ошибок.
+func Status(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrInvalidMetric):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,31 @@
+package httperr_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "invalid metric", err: fmt.Errorf("%w: gauge value is nil", storage.ErrInvalidMetric), want: http.StatusBadRequest},
+		{name: "not found", err: storage.ErrNotFound, want: http.StatusNotFound},
+		{name: "wrapped unavailable", err: fmt.Errorf("query: %w", storage.ErrUnavailable), want: http.StatusServiceUnavailable},
+		{name: "other error", err: errors.New("syntax error"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, httperr.Status(tt.err))
+		})
+	}
+}
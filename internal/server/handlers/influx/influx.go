@@ -0,0 +1,178 @@
+// Package influx реализует прием метрик в формате InfluxDB line protocol (POST /write).
+//
+// Каждое поле строки превращается в отдельную метрику с именем <measurement>_<поле>
+// (для поля value только <measurement>), дополненным тегами строки. Целые поля (суффикс i)
+// и беззнаковые (суффикс u) сохраняются как counter: значение прибавляется к счетчику.
+// Поля с плавающей точкой и логические поля (1 или 0) сохраняются как gauge, строковые поля пропускаются.
+package influx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
+	"github.com/maynagashev/go-metrics/internal/server/metricname"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+const (
+	// MaxBodySize максимальный размер тела запроса после распаковки.
+	MaxBodySize = 32 << 20
+	// valueField имя поля, которое не добавляется к имени метрики.
+	valueField = "value"
+)
+
+// LineError ошибка разбора строки запроса.
+type LineError struct {
+	// Номер строки, начиная с 1.
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse тело ответа, если часть строк не удалось разобрать. Корректные строки запроса
+// при этом сохраняются.
+type ErrorResponse struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Errors  []LineError `json:"errors"`
+}
+
+// New возвращает обработчик, который сохраняет метрики всех корректных строк запроса
+// одним вызовом UpdateMetrics. Возвращает 204, если все строки разобраны, и 400 с описанием
+// ошибок по строкам, если часть строк содержит ошибки.
+func New(st storage.Repository, log *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > MaxBodySize {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		b := newBatch(time.Now().UnixNano())
+		var lineErrors []LineError
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(make([]byte, 0, 64*1024), MaxBodySize)
+		for lineNum := 1; scanner.Scan(); lineNum++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			p, parseErr := parseLine(line)
+			if parseErr != nil {
+				lineErrors = append(lineErrors, LineError{Line: lineNum, Message: parseErr.Error()})
+				continue
+			}
+			b.add(p)
+		}
+		if err = scanner.Err(); err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		items := b.metrics()
+		if len(items) > 0 {
+			if err = st.UpdateMetrics(r.Context(), items); err != nil {
+				log.Error("failed to store line protocol metrics", zap.Error(err))
+				http.Error(w, "failed to store metrics", httperr.Status(err))
+				return
+			}
+		}
+
+		log.Debug("line protocol request applied",
+			zap.Int("metrics", len(items)),
+			zap.Int("invalid_lines", len(lineErrors)))
+		if len(lineErrors) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		messages := make([]string, 0, len(lineErrors))
+		for _, le := range lineErrors {
+			messages = append(messages, fmt.Sprintf("line %d: %s", le.Line, le.Message))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{
+			Code:    "invalid",
+			Message: "partial write: " + strings.Join(messages, "; "),
+			Errors:  lineErrors,
+		})
+	}
+}
+
+// gaugeValue значение gauge и метка времени строки, из которой оно получено.
+type gaugeValue struct {
+	value     float64
+	timestamp int64
+}
+
+// batch метрики, собранные из строк одного запроса.
+type batch struct {
+	now      int64
+	gauges   map[string]gaugeValue
+	counters map[string]int64
+}
+
+func newBatch(now int64) *batch {
+	return &batch{now: now, gauges: make(map[string]gaugeValue), counters: make(map[string]int64)}
+}
+
+// add добавляет поля строки. Для gauge сохраняется значение с наибольшей меткой времени,
+// строки без метки времени считаются полученными в момент запроса.
+func (b *batch) add(p point) {
+	ts := b.now
+	if p.HasTimestamp {
+		ts = p.Timestamp
+	}
+	for _, f := range p.Fields {
+		base := p.Measurement
+		if f.Key != valueField {
+			base += "_" + f.Key
+		}
+		name := metricname.WithLabels(metricname.Sanitize(base), p.Tags)
+
+		switch f.Kind {
+		case kindInteger, kindUnsigned:
+			b.counters[name] += f.Int
+		case kindFloat, kindBool:
+			if prev, ok := b.gauges[name]; ok && prev.timestamp > ts {
+				continue
+			}
+			b.gauges[name] = gaugeValue{value: f.Float, timestamp: ts}
+		case kindString:
+			// Строковые значения не представимы метриками сервера.
+		}
+	}
+}
+
+// metrics возвращает метрики пакета, упорядоченные по имени.
+func (b *batch) metrics() []metrics.Metric {
+	items := make([]metrics.Metric, 0, len(b.gauges)+len(b.counters))
+	for name, g := range b.gauges {
+		items = append(items, *metrics.NewGauge(name, g.value))
+	}
+	for name, delta := range b.counters {
+		items = append(items, *metrics.NewCounter(name, delta))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].MType < items[j].MType
+	})
+	return items
+}
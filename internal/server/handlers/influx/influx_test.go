@@ -0,0 +1,111 @@
+package influx_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/influx"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+)
+
+func write(t *testing.T, st storage.Repository, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/write", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	influx.New(st, zap.NewNop())(rec, req)
+	return rec
+}
+
+func TestHandler_ValidLines(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop(), storage.Gauges{}, storage.Counters{"jobs_processed_host_a": 10})
+	require.NoError(t, err)
+
+	body := strings.Join([]string{
+		"# комментарий",
+		"cpu,host=a,region=eu-west usage_user=12.5,usage_system=3 1700000000000000000",
+		"cpu,host=a,region=eu-west usage_user=10.5 1600000000000000000",
+		"",
+		"jobs,host=a processed=5i,ok=true,note=\"done, \\\"fast\\\"\"",
+		"jobs,host=a processed=2u",
+		"temperature value=21.5",
+		`disk\ io,path=C:\\data,dev\=x=sda reads=1i`,
+	}, "\n")
+
+	rec := write(t, st, body)
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	ctx := context.Background()
+	gauges := map[string]float64{
+		// Для gauge сохраняется значение строки с наибольшей меткой времени.
+		"cpu_usage_user_host_a_region_eu_west":   12.5,
+		"cpu_usage_system_host_a_region_eu_west": 3,
+		"jobs_ok_host_a":                         1,
+		"temperature":                            21.5,
+	}
+	for name, want := range gauges {
+		v, err := st.GetGauge(ctx, name)
+		require.NoError(t, err, name)
+		assert.InDelta(t, want, float64(v), 1e-9, name)
+	}
+
+	c, err := st.GetCounter(ctx, "jobs_processed_host_a")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(17), c)
+
+	c, err = st.GetCounter(ctx, "disk_io_reads_dev_x_sda_path_C__data")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(1), c)
+
+	// Строковые поля пропускаются.
+	_, err = st.GetGauge(ctx, "jobs_note_host_a")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestHandler_PartialWrite(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+
+	body := strings.Join([]string{
+		"mem used=1.5",
+		"mem",
+		"mem,host used=1",
+		"mem free=abc",
+		"mem total=1.5i",
+		`mem label="unterminated`,
+		"mem used=1 notatimestamp",
+		"mem free=2",
+	}, "\n")
+
+	rec := write(t, st, body)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp influx.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "invalid", resp.Code)
+
+	lines := make([]int, 0, len(resp.Errors))
+	for _, e := range resp.Errors {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []int{2, 3, 4, 5, 6, 7}, lines)
+	assert.Contains(t, resp.Message, "line 4: field \"free\": invalid value \"abc\"")
+
+	// Корректные строки сохранены.
+	ctx := context.Background()
+	v, err := st.GetGauge(ctx, "mem_used")
+	require.NoError(t, err)
+	assert.InDelta(t, 1.5, float64(v), 1e-9)
+	v, err = st.GetGauge(ctx, "mem_free")
+	require.NoError(t, err)
+	assert.InDelta(t, 2.0, float64(v), 1e-9)
+}
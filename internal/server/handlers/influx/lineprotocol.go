@@ -0,0 +1,210 @@
+package influx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// fieldKind тип значения поля line protocol.
+type fieldKind int
+
+const (
+	kindFloat fieldKind = iota
+	kindInteger
+	kindUnsigned
+	kindBool
+	kindString
+)
+
+// field поле точки. Для целых и беззнаковых значений заполняется Int, для остальных числовых Float.
+type field struct {
+	Key   string
+	Kind  fieldKind
+	Float float64
+	Int   int64
+}
+
+// point строка line protocol: measurement,tag=value field=value timestamp.
+type point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      []field
+	Timestamp   int64
+	// HasTimestamp false, если метка времени в строке не указана.
+	HasTimestamp bool
+}
+
+var (
+	errMissingMeasurement = errors.New("missing measurement")
+	errMissingFields      = errors.New("missing fields")
+)
+
+// parseLine разбирает строку line protocol. Строка не должна содержать перевод строки,
+// пустые строки и комментарии пропускаются вызывающим кодом.
+//
+// Экранирование: в measurement обратной косой чертой экранируются запятая и пробел, в ключах
+// и значениях тегов и в ключах полей дополнительно знак равенства, в строковых значениях полей
+// двойная кавычка и обратная косая черта.
+func parseLine(line string) (point, error) {
+	var p point
+
+	measurement, rest, delim := scanToken(line, ", ", false)
+	if measurement == "" {
+		return p, errMissingMeasurement
+	}
+	p.Measurement = measurement
+
+	if delim == ',' {
+		p.Tags = make(map[string]string)
+		for delim == ',' {
+			var key, value string
+			key, rest, delim = scanToken(rest, "=", true)
+			if delim != '=' || key == "" {
+				return p, fmt.Errorf("invalid tag %q: expected key=value", key)
+			}
+			value, rest, delim = scanToken(rest, ", ", true)
+			if value == "" {
+				return p, fmt.Errorf("missing value of tag %q", key)
+			}
+			p.Tags[key] = value
+		}
+	}
+	if delim != ' ' {
+		return p, errMissingFields
+	}
+
+	rest = strings.TrimLeft(rest, " ")
+	if rest == "" {
+		return p, errMissingFields
+	}
+	for {
+		var key string
+		key, rest, delim = scanToken(rest, "=", true)
+		if delim != '=' || key == "" {
+			return p, fmt.Errorf("invalid field %q: expected key=value", key)
+		}
+
+		var (
+			f   field
+			err error
+		)
+		f, rest, delim, err = scanFieldValue(rest)
+		if err != nil {
+			return p, fmt.Errorf("field %q: %w", key, err)
+		}
+		f.Key = key
+		p.Fields = append(p.Fields, f)
+
+		if delim != ',' {
+			break
+		}
+	}
+
+	if delim == ' ' {
+		ts := strings.TrimSpace(rest)
+		if ts != "" {
+			v, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return p, fmt.Errorf("invalid timestamp %q", ts)
+			}
+			p.Timestamp, p.HasTimestamp = v, true
+		}
+	}
+	return p, nil
+}
+
+// scanToken читает s до первого неэкранированного разделителя из delims. Возвращает
+// токен без экранирования, остаток строки после разделителя и сам разделитель (0 в конце строки).
+// Если escapeEquals равен true, экранированным может быть и знак равенства.
+func scanToken(s, delims string, escapeEquals bool) (string, string, byte) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			next := s[i+1]
+			if next == ',' || next == ' ' || next == '\\' || (escapeEquals && next == '=') {
+				b.WriteByte(next)
+				i++
+				continue
+			}
+		}
+		if strings.IndexByte(delims, c) >= 0 {
+			return b.String(), s[i+1:], c
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), "", 0
+}
+
+// scanFieldValue читает значение поля до запятой или пробела и определяет его тип.
+func scanFieldValue(s string) (field, string, byte, error) {
+	var f field
+	if strings.HasPrefix(s, `"`) {
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+				b.WriteByte(s[i+1])
+				i++
+				continue
+			}
+			if c == '"' {
+				f.Kind = kindString
+				rest := s[i+1:]
+				if rest == "" {
+					return f, "", 0, nil
+				}
+				if rest[0] != ',' && rest[0] != ' ' {
+					return f, "", 0, errors.New("unexpected characters after string value")
+				}
+				return f, rest[1:], rest[0], nil
+			}
+			b.WriteByte(c)
+		}
+		return f, "", 0, errors.New("unterminated string value")
+	}
+
+	end := strings.IndexAny(s, ", ")
+	raw, rest, delim := s, "", byte(0)
+	if end >= 0 {
+		raw, rest, delim = s[:end], s[end+1:], s[end]
+	}
+	if raw == "" {
+		return f, "", 0, errors.New("missing value")
+	}
+
+	var err error
+	switch {
+	case strings.HasSuffix(raw, "i"):
+		f.Kind = kindInteger
+		f.Int, err = strconv.ParseInt(strings.TrimSuffix(raw, "i"), 10, 64)
+	case strings.HasSuffix(raw, "u"):
+		var u uint64
+		f.Kind = kindUnsigned
+		u, err = strconv.ParseUint(strings.TrimSuffix(raw, "u"), 10, 64)
+		if err == nil && u > math.MaxInt64 {
+			err = errors.New("unsigned value out of range")
+		}
+		f.Int = int64(u) //nolint:gosec // диапазон проверен выше
+	default:
+		switch raw {
+		case "t", "T", "true", "True", "TRUE":
+			f.Kind, f.Float = kindBool, 1
+		case "f", "F", "false", "False", "FALSE":
+			f.Kind, f.Float = kindBool, 0
+		default:
+			f.Kind = kindFloat
+			f.Float, err = strconv.ParseFloat(raw, 64)
+			if err == nil && (math.IsNaN(f.Float) || math.IsInf(f.Float, 0)) {
+				err = errors.New("non-finite value")
+			}
+		}
+	}
+	if err != nil {
+		return f, "", 0, fmt.Errorf("invalid value %q", raw)
+	}
+	return f, rest, delim, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 )
 
@@ -15,7 +16,11 @@ func New(st storage.Repository) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 
 		// Возвращаем метрики в формате JSON архива
-		metrics := st.GetMetrics(r.Context())
+		metrics, err := st.GetMetrics(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), httperr.Status(err))
+			return
+		}
 		jsonData, err := json.Marshal(metrics)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -81,7 +81,8 @@ func TestJSONIndexHandler_EmptyStorage(t *testing.T) {
 	require.NoError(t, err)
 
 	// Create an empty storage
-	storage := memory.New(&app.Config{}, logger)
+	storage, err := memory.New(&app.Config{}, logger)
+	require.NoError(t, err)
 
 	// Create the handler
 	handler := index.New(storage)
@@ -116,7 +117,8 @@ func setupTestStorage(t *testing.T) *memory.MemStorage {
 	logger, err := zap.NewDevelopment()
 	require.NoError(t, err)
 
-	storage := memory.New(&app.Config{}, logger)
+	storage, err := memory.New(&app.Config{}, logger)
+	require.NoError(t, err)
 	ctx := context.Background()
 
 	// Add a gauge metric
@@ -1,17 +1,12 @@
-// Package ping реализует обработчик для проверки соединения с базой данных.
+// Package ping реализует обработчик для проверки соединения с хранилищем метрик.
 // Предоставляет эндпоинт для проверки работоспособности системы.
 package ping
 
 import (
 	"context"
-	"errors"
 	"net/http"
 
-	"go.uber.org/zap"
-
-	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
-	"github.com/maynagashev/go-metrics/internal/server/app"
-	"github.com/maynagashev/go-metrics/internal/server/storage/pgstorage"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
 	"github.com/maynagashev/go-metrics/pkg/response"
 )
 
@@ -20,39 +15,20 @@ type Response struct {
 }
 
 type Storage interface {
-	GetMetrics(ctx context.Context) []metrics.Metric
+	Ping(ctx context.Context) error
 }
 
-// Handle логика обработчика ping с указанной базой данных, чтобы можно было провести тестирование моком.
-func Handle(storage Storage) http.HandlerFunc {
+// New возвращает обработчик ping, который проверяет готовность хранилища метрик,
+// используемого сервером. Если хранилище недоступно, возвращает 503, при других ошибках — 500.
+func New(st Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		// Проверяем подключение сделав запрос к базе данных.
-		_ = storage.GetMetrics(r.Context())
-
-		response.OK(w, "pong")
-	}
-}
-
-// New создает подключение к базе данных из конфига и возвращает обработчик запроса.
-func New(config *app.Config, log *zap.Logger) http.HandlerFunc {
-	// Если не используется PostgreSQL, то возвращаем обработчик, который возвращает ошибку.
-	if !config.IsDatabaseEnabled() {
-		return func(w http.ResponseWriter, _ *http.Request) {
-			response.Error(w, errors.New("не указана база данных"), http.StatusInternalServerError)
+		if err := st.Ping(r.Context()); err != nil {
+			response.Error(w, err, httperr.Status(err))
+			return
 		}
-	}
 
-	// Создаем экземпляр хранилища на основе PostgreSQL, здесь создается подключение и накатываются миграции.
-	db, err := pgstorage.New(context.Background(), config, log)
-	// Если не удалось создать хранилище, то возвращаем обработчик, который возвращает ошибку.
-	if err != nil {
-		return func(w http.ResponseWriter, _ *http.Request) {
-			response.Error(w, err, http.StatusInternalServerError)
-		}
+		response.OK(w, "pong")
 	}
-
-	// Запускаем обработчик запроса с созданным хранилищем.
-	return Handle(db)
 }
@@ -5,6 +5,7 @@ package update
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -12,6 +13,7 @@ import (
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 	"github.com/maynagashev/go-metrics/pkg/response"
 	"github.com/maynagashev/go-metrics/pkg/sign"
@@ -41,20 +43,23 @@ func New(cfg *app.Config, strg storage.Repository, log *zap.Logger) http.Handler
 
 		// Конвертируем локальную структуру в структуру из контракта
 		metric := metrics.Metric(requestedMetric)
-		err = strg.UpdateMetric(r.Context(), metric)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if err = strg.UpdateMetric(r.Context(), metric); err != nil {
+			http.Error(w, err.Error(), httperr.Status(err))
 			return
 		}
 
 		var resMessage string
 
 		// Получаем значение метрики из хранилища
-		m, ok := strg.GetMetric(r.Context(), metric.MType, metric.Name)
-		if ok {
+		m, err := strg.GetMetric(r.Context(), metric.MType, metric.Name)
+		switch {
+		case err == nil:
 			resMessage = fmt.Sprintf("metric %s updated, result: %s", metric.String(), m.String())
-		} else {
+		case errors.Is(err, storage.ErrNotFound):
 			resMessage = fmt.Sprintf("metric %s not found", metric.String())
+		default:
+			http.Error(w, err.Error(), httperr.Status(err))
+			return
 		}
 
 		// Логируем ответ для отладки
@@ -24,6 +24,11 @@ import (
 
 // TestUpdateHandler is testing the plain update handler, not the JSON update handler.
 func TestUpdateHandler(t *testing.T) {
+	newStorage := func() storage.Repository {
+		st, err := memory.New(&app.Config{}, zap.NewNop())
+		require.NoError(t, err)
+		return st
+	}
 	type want struct {
 		code        int
 		contentType string
@@ -37,7 +42,7 @@ func TestUpdateHandler(t *testing.T) {
 		{
 			name:    "update gauge",
 			target:  "/update/gauge/test_gauge/1.1",
-			storage: memory.New(&app.Config{}, zap.NewNop()),
+			storage: newStorage(),
 			want: want{
 				code:        200,
 				contentType: "text/plain",
@@ -46,7 +51,7 @@ func TestUpdateHandler(t *testing.T) {
 		{
 			name:    "update counter",
 			target:  "/update/counter/test_counter/1",
-			storage: memory.New(&app.Config{}, zap.NewNop()),
+			storage: newStorage(),
 			want: want{
 				code:        200,
 				contentType: "text/plain",
@@ -55,7 +60,7 @@ func TestUpdateHandler(t *testing.T) {
 		{
 			name:    "invalid metrics type",
 			target:  "/update/invalid/test_counter/1",
-			storage: memory.New(&app.Config{}, zap.NewNop()),
+			storage: newStorage(),
 			want: want{
 				code:        400,
 				contentType: "text/plain; charset=utf-8",
@@ -64,7 +69,7 @@ func TestUpdateHandler(t *testing.T) {
 		{
 			name:    "invalid url",
 			target:  "/update/gauge/1",
-			storage: memory.New(&app.Config{}, zap.NewNop()),
+			storage: newStorage(),
 			want: want{
 				code:        404,
 				contentType: "text/plain; charset=utf-8",
@@ -96,7 +101,8 @@ func TestJSONUpdateHandler_Gauge(t *testing.T) {
 	// Создаем хранилище
 	cfg := &app.Config{}
 	logger := zap.NewNop()
-	repo := memory.New(cfg, logger)
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Создаем обработчик
 	handler := jsonupdate.New(cfg, repo, logger)
@@ -126,8 +132,8 @@ func TestJSONUpdateHandler_Gauge(t *testing.T) {
 	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 
 	// Проверяем, что метрика была сохранена в хранилище
-	savedMetric, ok := repo.GetMetric(context.Background(), metrics.TypeGauge, "test_gauge")
-	assert.True(t, ok)
+	savedMetric, err := repo.GetMetric(context.Background(), metrics.TypeGauge, "test_gauge")
+	assert.NoError(t, err)
 	assert.InDelta(t, value, *savedMetric.Value, 0.0001)
 }
 
@@ -135,7 +141,8 @@ func TestJSONUpdateHandler_Counter(t *testing.T) {
 	// Создаем хранилище
 	cfg := &app.Config{}
 	logger := zap.NewNop()
-	repo := memory.New(cfg, logger)
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Создаем обработчик
 	handler := jsonupdate.New(cfg, repo, logger)
@@ -165,8 +172,8 @@ func TestJSONUpdateHandler_Counter(t *testing.T) {
 	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 
 	// Проверяем, что метрика была сохранена в хранилище
-	savedMetric, ok := repo.GetMetric(context.Background(), metrics.TypeCounter, "test_counter")
-	assert.True(t, ok)
+	savedMetric, err := repo.GetMetric(context.Background(), metrics.TypeCounter, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, delta, *savedMetric.Delta)
 
 	// Обновляем метрику еще раз
@@ -176,8 +183,8 @@ func TestJSONUpdateHandler_Counter(t *testing.T) {
 	handler(rr, req)
 
 	// Проверяем, что значение метрики увеличилось
-	savedMetric, ok = repo.GetMetric(context.Background(), metrics.TypeCounter, "test_counter")
-	assert.True(t, ok)
+	savedMetric, err = repo.GetMetric(context.Background(), metrics.TypeCounter, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, delta*2, *savedMetric.Delta)
 }
 
@@ -185,7 +192,8 @@ func TestJSONUpdateHandler_InvalidJSON(t *testing.T) {
 	// Создаем хранилище
 	cfg := &app.Config{}
 	logger := zap.NewNop()
-	repo := memory.New(cfg, logger)
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Создаем обработчик
 	handler := jsonupdate.New(cfg, repo, logger)
@@ -213,7 +221,8 @@ func TestJSONUpdateHandler_WithSignature(t *testing.T) {
 		PrivateKey: privateKey,
 	}
 	logger := zap.NewNop()
-	repo := memory.New(cfg, logger)
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Создаем обработчик
 	handler := jsonupdate.New(cfg, repo, logger)
@@ -247,8 +256,8 @@ func TestJSONUpdateHandler_WithSignature(t *testing.T) {
 	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 
 	// Проверяем, что метрика была сохранена в хранилище
-	savedMetric, ok := repo.GetMetric(context.Background(), metrics.TypeGauge, "test_gauge")
-	assert.True(t, ok)
+	savedMetric, err := repo.GetMetric(context.Background(), metrics.TypeGauge, "test_gauge")
+	assert.NoError(t, err)
 	assert.InDelta(t, value, *savedMetric.Value, 0.0001)
 }
 
@@ -259,7 +268,8 @@ func TestJSONUpdateHandler_InvalidSignature(t *testing.T) {
 		PrivateKey: privateKey,
 	}
 	logger := zap.NewNop()
-	repo := memory.New(cfg, logger)
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Создаем обработчик
 	handler := jsonupdate.New(cfg, repo, logger)
@@ -5,17 +5,22 @@ package updates
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"go.uber.org/zap"
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 	"github.com/maynagashev/go-metrics/pkg/response"
 	sign "github.com/maynagashev/go-metrics/pkg/sign"
 )
 
+var errIdempotencyKeyTooLong = errors.New("idempotency key is too long")
+
 // NewBulkUpdate возвращает http.HandlerFunc, который обновляет множество метрик в хранилище.
 // Метрики передаются в теле запроса в формате JSON.
 func NewBulkUpdate(cfg *app.Config, st storage.Repository, log *zap.Logger) http.HandlerFunc {
@@ -40,15 +45,29 @@ func NewBulkUpdate(cfg *app.Config, st storage.Repository, log *zap.Logger) http
 			return
 		}
 
+		// Проверяем ключ идемпотентности пакета, если он передан.
+		key := r.Header.Get(metrics.IdempotencyKeyHeader)
+		if len(key) > metrics.MaxIdempotencyKeyLength {
+			response.Error(w, errIdempotencyKeyTooLong, http.StatusBadRequest)
+			return
+		}
+
 		// Обновляем метрики в хранилище.
-		err = st.UpdateMetrics(r.Context(), metricsToUpdate)
+		duplicate, err := updateMetrics(r, st, key, metricsToUpdate)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), httperr.Status(err))
 			return
 		}
 
-		// Отправляем успешный ответ.
-		w.WriteHeader(http.StatusOK)
+		if key != "" {
+			w.Header().Set(metrics.IdempotentReplayedHeader, strconv.FormatBool(duplicate))
+		}
+
+		if duplicate {
+			log.Info("Metrics batch already applied, skipping", zap.String("idempotency_key", key))
+			response.OK(w, "Metrics batch already applied")
+			return
+		}
 
 		// Логируем ответ для отладки
 		log.Info("Metrics updated successfully")
@@ -58,6 +77,21 @@ func NewBulkUpdate(cfg *app.Config, st storage.Repository, log *zap.Logger) http
 	}
 }
 
+// updateMetrics обновляет метрики в хранилище. Если передан ключ идемпотентности
+// и хранилище поддерживает однократное применение пакетов, повторный пакет не применяется.
+// Возвращает true, если пакет уже был применен ранее.
+func updateMetrics(
+	r *http.Request,
+	st storage.Repository,
+	key string,
+	items []metrics.Metric,
+) (bool, error) {
+	if idempotent, ok := st.(storage.IdempotentRepository); ok && key != "" {
+		return idempotent.UpdateMetricsOnce(r.Context(), key, items)
+	}
+	return false, st.UpdateMetrics(r.Context(), items)
+}
+
 // validateRequest проверяет запрос на валидность.
 func validateRequest(r *http.Request, log *zap.Logger, cfg *app.Config) (string, error) {
 	buf := new(bytes.Buffer)
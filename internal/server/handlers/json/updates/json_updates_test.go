@@ -4,18 +4,24 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/internal/server/app"
 	"github.com/maynagashev/go-metrics/internal/server/handlers/json/updates"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
 	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/mocks"
 	"github.com/maynagashev/go-metrics/pkg/sign"
 )
 
@@ -23,7 +29,8 @@ func TestNewBulkUpdate_Success(t *testing.T) {
 	// Создаем хранилище
 	cfg := &app.Config{}
 	logger := zap.NewNop()
-	repo := memory.New(cfg, logger)
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Создаем обработчик
 	handler := updates.NewBulkUpdate(cfg, repo, logger)
@@ -57,16 +64,16 @@ func TestNewBulkUpdate_Success(t *testing.T) {
 	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 
 	// Проверяем, что метрики были сохранены в хранилище
-	savedGaugeMetric, ok := repo.GetMetric(context.Background(), metrics.TypeGauge, "test_gauge")
-	assert.True(t, ok)
+	savedGaugeMetric, err := repo.GetMetric(context.Background(), metrics.TypeGauge, "test_gauge")
+	assert.NoError(t, err)
 	assert.InDelta(t, gaugeValue, *savedGaugeMetric.Value, 0.0001)
 
-	savedCounterMetric, ok := repo.GetMetric(
+	savedCounterMetric, err := repo.GetMetric(
 		context.Background(),
 		metrics.TypeCounter,
 		"test_counter",
 	)
-	assert.True(t, ok)
+	assert.NoError(t, err)
 	assert.Equal(t, counterValue, *savedCounterMetric.Delta)
 }
 
@@ -74,7 +81,8 @@ func TestNewBulkUpdate_InvalidJSON(t *testing.T) {
 	// Создаем хранилище
 	cfg := &app.Config{}
 	logger := zap.NewNop()
-	repo := memory.New(cfg, logger)
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Создаем обработчик
 	handler := updates.NewBulkUpdate(cfg, repo, logger)
@@ -103,7 +111,8 @@ func TestNewBulkUpdate_WithSignature(t *testing.T) {
 		PrivateKey: privateKey,
 	}
 	logger := zap.NewNop()
-	repo := memory.New(cfg, logger)
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Создаем обработчик
 	handler := updates.NewBulkUpdate(cfg, repo, logger)
@@ -141,16 +150,16 @@ func TestNewBulkUpdate_WithSignature(t *testing.T) {
 	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 
 	// Проверяем, что метрики были сохранены в хранилище
-	savedGaugeMetric, ok := repo.GetMetric(context.Background(), metrics.TypeGauge, "test_gauge")
-	assert.True(t, ok)
+	savedGaugeMetric, err := repo.GetMetric(context.Background(), metrics.TypeGauge, "test_gauge")
+	assert.NoError(t, err)
 	assert.InDelta(t, gaugeValue, *savedGaugeMetric.Value, 0.0001)
 
-	savedCounterMetric, ok := repo.GetMetric(
+	savedCounterMetric, err := repo.GetMetric(
 		context.Background(),
 		metrics.TypeCounter,
 		"test_counter",
 	)
-	assert.True(t, ok)
+	assert.NoError(t, err)
 	assert.Equal(t, counterValue, *savedCounterMetric.Delta)
 }
 
@@ -161,7 +170,8 @@ func TestNewBulkUpdate_InvalidSignature(t *testing.T) {
 		PrivateKey: privateKey,
 	}
 	logger := zap.NewNop()
-	repo := memory.New(cfg, logger)
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Создаем обработчик
 	handler := updates.NewBulkUpdate(cfg, repo, logger)
@@ -191,3 +201,104 @@ func TestNewBulkUpdate_InvalidSignature(t *testing.T) {
 	// Проверяем код ответа
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
 }
+
+func TestNewBulkUpdate_Idempotent(t *testing.T) {
+	cfg := &app.Config{}
+	logger := zap.NewNop()
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
+	handler := updates.NewBulkUpdate(cfg, repo, logger)
+
+	body, err := json.Marshal([]metrics.Metric{*metrics.NewCounter("test_counter", 10)})
+	require.NoError(t, err)
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(metrics.IdempotencyKeyHeader, key)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	// Первый пакет применяется.
+	rr := send("batch-1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "false", rr.Header().Get(metrics.IdempotentReplayedHeader))
+
+	// Повтор того же пакета возвращает 200, но не применяется повторно.
+	rr = send("batch-1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get(metrics.IdempotentReplayedHeader))
+	assert.Contains(t, rr.Body.String(), "already applied")
+
+	counter, err := repo.GetCounter(context.Background(), "test_counter")
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, counter)
+
+	// Слишком длинный ключ отклоняется.
+	rr = send(strings.Repeat("k", metrics.MaxIdempotencyKeyLength+1))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestNewBulkUpdate_WithoutIdempotencyKey(t *testing.T) {
+	cfg := &app.Config{}
+	logger := zap.NewNop()
+	repo, err := memory.New(cfg, logger)
+	require.NoError(t, err)
+	handler := updates.NewBulkUpdate(cfg, repo, logger)
+
+	body, err := json.Marshal([]metrics.Metric{*metrics.NewCounter("test_counter", 10)})
+	require.NoError(t, err)
+
+	// Без ключа каждый пакет применяется, как и раньше.
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBuffer(body))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get(metrics.IdempotentReplayedHeader))
+	}
+
+	counter, _ := repo.GetCounter(context.Background(), "test_counter")
+	assert.EqualValues(t, 20, counter)
+}
+
+func TestNewBulkUpdate_StorageErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{
+			name:     "invalid metric",
+			err:      fmt.Errorf("%w: gauge value is nil", storage.ErrInvalidMetric),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "storage unavailable",
+			err:      fmt.Errorf("%w: connection refused", storage.ErrUnavailable),
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "storage failure",
+			err:      errors.New("disk I/O error"),
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewRepository(t)
+			repo.On("UpdateMetrics", mock.Anything, mock.Anything).Return(tt.err)
+			handler := updates.NewBulkUpdate(&app.Config{}, repo, zap.NewNop())
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/",
+				strings.NewReader(`[{"id":"PollCount","type":"counter","delta":1}]`))
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			assert.Equal(t, tt.wantCode, rr.Code)
+		})
+	}
+}
@@ -4,17 +4,19 @@ package value
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 	"github.com/maynagashev/go-metrics/pkg/sign"
 )
 
 // New хэндлер для получения значения метрики с сервера в ответ на запрос `POST /value`.
-func New(cfg *app.Config, storage storage.Repository) http.HandlerFunc {
+func New(cfg *app.Config, st storage.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		var err error
@@ -25,11 +27,15 @@ func New(cfg *app.Config, storage storage.Repository) http.HandlerFunc {
 		}
 
 		// Получаем значение метрики из хранилища
-		metric, ok := storage.GetMetric(r.Context(), requestMetric.MType, requestMetric.Name)
-		if !ok {
+		metric, err := st.GetMetric(r.Context(), requestMetric.MType, requestMetric.Name)
+		if errors.Is(err, storage.ErrNotFound) {
 			http.Error(w, fmt.Sprintf("%s not found", metric.String()), http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			http.Error(w, err.Error(), httperr.Status(err))
+			return
+		}
 
 		// Отправляем json ответ с метрикой
 		encodedBody, err := json.Marshal(metric)
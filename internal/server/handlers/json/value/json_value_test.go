@@ -91,7 +91,8 @@ func TestJSONValueHandler(t *testing.T) {
 
 			// Create a test storage
 			cfg := &app.Config{}
-			storage := memory.New(cfg, logger)
+			storage, err := memory.New(cfg, logger)
+			require.NoError(t, err)
 
 			// Add the test metric to the storage if needed
 			if tt.setupMetric != nil {
@@ -148,7 +149,8 @@ func TestJSONValueHandler_InvalidJSON(t *testing.T) {
 
 	// Create a test storage
 	cfg := &app.Config{}
-	storage := memory.New(cfg, logger)
+	storage, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Create the handler
 	handler := value.New(cfg, storage)
@@ -215,7 +217,8 @@ func setupTestStorage(t *testing.T) *memory.MemStorage {
 	require.NoError(t, err)
 
 	cfg := &app.Config{}
-	storage := memory.New(cfg, logger)
+	storage, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 	ctx := context.Background()
 
 	// Add a gauge metric
@@ -248,7 +251,8 @@ func TestJSONValueHandler_MetricNotFound(t *testing.T) {
 
 	// Create a test storage
 	cfg := &app.Config{}
-	storage := memory.New(cfg, logger)
+	storage, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Create the handler
 	handler := value.New(cfg, storage)
@@ -283,7 +287,8 @@ func TestJSONValueHandler_WithSignature(t *testing.T) {
 	cfg := &app.Config{
 		PrivateKey: "test-key",
 	}
-	storage := memory.New(cfg, logger)
+	storage, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 
 	// Add a test metric to the storage
 	gaugeValue := 42.0
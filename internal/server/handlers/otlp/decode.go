@@ -0,0 +1,318 @@
+package otlp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Номера полей сообщений opentelemetry.proto.metrics.v1 и opentelemetry.proto.common.v1,
+// которые читает сервер. Остальные поля (exemplars, flags, метаданные) пропускаются.
+const (
+	fieldRequestResourceMetrics protowire.Number = 1
+
+	fieldResourceMetricsResource     protowire.Number = 1
+	fieldResourceMetricsScopeMetrics protowire.Number = 2
+	fieldResourceAttributes          protowire.Number = 1
+	fieldScopeMetricsMetrics         protowire.Number = 2
+
+	fieldMetricName                 protowire.Number = 1
+	fieldMetricGauge                protowire.Number = 5
+	fieldMetricSum                  protowire.Number = 7
+	fieldMetricHistogram            protowire.Number = 9
+	fieldMetricExponentialHistogram protowire.Number = 10
+	fieldMetricSummary              protowire.Number = 11
+
+	fieldDataPoints  protowire.Number = 1
+	fieldTemporality protowire.Number = 2
+	fieldIsMonotonic protowire.Number = 3
+
+	fieldNumberTime       protowire.Number = 3
+	fieldNumberAsDouble   protowire.Number = 4
+	fieldNumberAsInt      protowire.Number = 6
+	fieldNumberAttributes protowire.Number = 7
+
+	fieldHistogramTime           protowire.Number = 3
+	fieldHistogramCount          protowire.Number = 4
+	fieldHistogramSum            protowire.Number = 5
+	fieldHistogramBucketCounts   protowire.Number = 6
+	fieldHistogramExplicitBounds protowire.Number = 7
+	fieldHistogramAttributes     protowire.Number = 9
+
+	fieldKeyValueKey   protowire.Number = 1
+	fieldKeyValueValue protowire.Number = 2
+
+	fieldAnyString protowire.Number = 1
+	fieldAnyBool   protowire.Number = 2
+	fieldAnyInt    protowire.Number = 3
+	fieldAnyDouble protowire.Number = 4
+)
+
+var errInvalidMessage = errors.New("invalid protobuf message")
+
+// field поле сообщения protobuf: для полей с длиной содержимое в Bytes, для остальных значение в Raw.
+type field struct {
+	Num   protowire.Number
+	Type  protowire.Type
+	Bytes []byte
+	Raw   uint64
+}
+
+// decodeProto разбирает сообщение ExportMetricsServiceRequest.
+func decodeProto(b []byte) ([]resourceMetrics, error) {
+	var result []resourceMetrics
+	err := walk(b, func(f field) error {
+		if f.Num != fieldRequestResourceMetrics || f.Type != protowire.BytesType {
+			return nil
+		}
+		rm, err := decodeResourceMetrics(f.Bytes)
+		if err != nil {
+			return fmt.Errorf("resource_metrics: %w", err)
+		}
+		result = append(result, rm)
+		return nil
+	})
+	return result, err
+}
+
+func decodeResourceMetrics(b []byte) (resourceMetrics, error) {
+	var rm resourceMetrics
+	err := walk(b, func(f field) error {
+		if f.Type != protowire.BytesType {
+			return nil
+		}
+		switch f.Num {
+		case fieldResourceMetricsResource:
+			return walk(f.Bytes, func(rf field) error {
+				if rf.Num != fieldResourceAttributes || rf.Type != protowire.BytesType {
+					return nil
+				}
+				return appendAttribute(&rm.Attributes, rf.Bytes)
+			})
+		case fieldResourceMetricsScopeMetrics:
+			return walk(f.Bytes, func(sf field) error {
+				if sf.Num != fieldScopeMetricsMetrics || sf.Type != protowire.BytesType {
+					return nil
+				}
+				m, err := decodeMetric(sf.Bytes)
+				if err != nil {
+					return fmt.Errorf("metric: %w", err)
+				}
+				rm.Metrics = append(rm.Metrics, m)
+				return nil
+			})
+		}
+		return nil
+	})
+	return rm, err
+}
+
+func decodeMetric(b []byte) (metric, error) {
+	var m metric
+	err := walk(b, func(f field) error {
+		if f.Type != protowire.BytesType {
+			return nil
+		}
+		switch f.Num {
+		case fieldMetricName:
+			m.Name = string(f.Bytes)
+		case fieldMetricGauge:
+			m.Kind = kindGauge
+			return decodeNumberData(&m, f.Bytes)
+		case fieldMetricSum:
+			m.Kind = kindSum
+			return decodeNumberData(&m, f.Bytes)
+		case fieldMetricHistogram:
+			m.Kind = kindHistogram
+			return decodeHistogramData(&m, f.Bytes)
+		case fieldMetricExponentialHistogram:
+			m.Kind = kindExponentialHistogram
+			return countDataPoints(&m, f.Bytes)
+		case fieldMetricSummary:
+			m.Kind = kindSummary
+			return countDataPoints(&m, f.Bytes)
+		}
+		return nil
+	})
+	return m, err
+}
+
+// countDataPoints подсчитывает точки данных неподдерживаемого вида.
+func countDataPoints(m *metric, b []byte) error {
+	return walk(b, func(f field) error {
+		if f.Num == fieldDataPoints && f.Type == protowire.BytesType {
+			m.Unsupported++
+		}
+		return nil
+	})
+}
+
+// decodeNumberData разбирает сообщения Gauge и Sum.
+func decodeNumberData(m *metric, b []byte) error {
+	return walk(b, func(f field) error {
+		switch {
+		case f.Num == fieldDataPoints && f.Type == protowire.BytesType:
+			p, err := decodeNumberPoint(f.Bytes)
+			if err != nil {
+				return fmt.Errorf("data_points: %w", err)
+			}
+			m.Numbers = append(m.Numbers, p)
+		case f.Num == fieldTemporality && f.Type == protowire.VarintType:
+			m.Temporality = int(f.Raw) //nolint:gosec // значение enum
+		case f.Num == fieldIsMonotonic && f.Type == protowire.VarintType:
+			m.Monotonic = f.Raw != 0
+		}
+		return nil
+	})
+}
+
+func decodeNumberPoint(b []byte) (numberPoint, error) {
+	var p numberPoint
+	err := walk(b, func(f field) error {
+		switch {
+		case f.Num == fieldNumberAttributes && f.Type == protowire.BytesType:
+			return appendAttribute(&p.Attributes, f.Bytes)
+		case f.Num == fieldNumberTime && f.Type == protowire.Fixed64Type:
+			p.TimeUnixNano = f.Raw
+		case f.Num == fieldNumberAsDouble && f.Type == protowire.Fixed64Type:
+			p.Value, p.HasValue = math.Float64frombits(f.Raw), true
+		case f.Num == fieldNumberAsInt && f.Type == protowire.Fixed64Type:
+			p.Value, p.HasValue = float64(int64(f.Raw)), true //nolint:gosec // sfixed64
+		}
+		return nil
+	})
+	return p, err
+}
+
+// decodeHistogramData разбирает сообщение Histogram.
+func decodeHistogramData(m *metric, b []byte) error {
+	return walk(b, func(f field) error {
+		switch {
+		case f.Num == fieldDataPoints && f.Type == protowire.BytesType:
+			p, err := decodeHistogramPoint(f.Bytes)
+			if err != nil {
+				return fmt.Errorf("data_points: %w", err)
+			}
+			m.Histograms = append(m.Histograms, p)
+		case f.Num == fieldTemporality && f.Type == protowire.VarintType:
+			m.Temporality = int(f.Raw) //nolint:gosec // значение enum
+		}
+		return nil
+	})
+}
+
+func decodeHistogramPoint(b []byte) (histogramPoint, error) {
+	var p histogramPoint
+	err := walk(b, func(f field) error {
+		switch {
+		case f.Num == fieldHistogramAttributes && f.Type == protowire.BytesType:
+			return appendAttribute(&p.Attributes, f.Bytes)
+		case f.Num == fieldHistogramTime && f.Type == protowire.Fixed64Type:
+			p.TimeUnixNano = f.Raw
+		case f.Num == fieldHistogramCount && f.Type == protowire.Fixed64Type:
+			p.Count = f.Raw
+		case f.Num == fieldHistogramSum && f.Type == protowire.Fixed64Type:
+			p.Sum, p.HasSum = math.Float64frombits(f.Raw), true
+		case f.Num == fieldHistogramBucketCounts:
+			return appendFixed64(f, func(v uint64) { p.BucketCounts = append(p.BucketCounts, v) })
+		case f.Num == fieldHistogramExplicitBounds:
+			return appendFixed64(f, func(v uint64) {
+				p.ExplicitBounds = append(p.ExplicitBounds, math.Float64frombits(v))
+			})
+		}
+		return nil
+	})
+	return p, err
+}
+
+// appendFixed64 передает в fn значения повторяющегося поля fixed64 или double,
+// записанного упакованным (packed) или по одному значению.
+func appendFixed64(f field, fn func(uint64)) error {
+	switch f.Type {
+	case protowire.Fixed64Type:
+		fn(f.Raw)
+	case protowire.BytesType:
+		b := f.Bytes
+		for len(b) > 0 {
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return errInvalidMessage
+			}
+			fn(v)
+			b = b[n:]
+		}
+	}
+	return nil
+}
+
+// appendAttribute разбирает сообщение KeyValue и добавляет атрибут со скалярным значением.
+func appendAttribute(attrs *[]attribute, b []byte) error {
+	var (
+		a   attribute
+		set bool
+	)
+	err := walk(b, func(f field) error {
+		switch {
+		case f.Num == fieldKeyValueKey && f.Type == protowire.BytesType:
+			a.Key = string(f.Bytes)
+		case f.Num == fieldKeyValueValue && f.Type == protowire.BytesType:
+			return walk(f.Bytes, func(vf field) error {
+				switch {
+				case vf.Num == fieldAnyString && vf.Type == protowire.BytesType:
+					a.Value, set = string(vf.Bytes), true
+				case vf.Num == fieldAnyBool && vf.Type == protowire.VarintType:
+					a.Value, set = strconv.FormatBool(vf.Raw != 0), true
+				case vf.Num == fieldAnyInt && vf.Type == protowire.VarintType:
+					a.Value, set = strconv.FormatInt(int64(vf.Raw), 10), true //nolint:gosec // int64
+				case vf.Num == fieldAnyDouble && vf.Type == protowire.Fixed64Type:
+					a.Value, set = strconv.FormatFloat(math.Float64frombits(vf.Raw), 'g', -1, 64), true
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err == nil && set && a.Key != "" {
+		*attrs = append(*attrs, a)
+	}
+	return err
+}
+
+// walk обходит поля сообщения и передает их в fn.
+func walk(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errInvalidMessage
+		}
+		b = b[n:]
+
+		f := field{Num: num, Type: typ}
+		switch typ {
+		case protowire.BytesType:
+			f.Bytes, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			f.Raw, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.Raw, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.Raw = uint64(v)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return errInvalidMessage
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package otlp
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// gaugeLockStripes количество блокировок, между которыми распределяются имена gauge.
+const gaugeLockStripes = 64
+
+// gaugeLocks блокировки gauge, к которым прибавляются дельты. Значение gauge читается из хранилища
+// и записывается обратно, поэтому одновременные запросы с одним рядом должны выполняться по очереди.
+// Имена распределяются по фиксированному набору блокировок по хешу.
+type gaugeLocks struct {
+	stripes [gaugeLockStripes]sync.Mutex
+}
+
+// lock захватывает блокировки всех имен и возвращает функцию их освобождения.
+// Блокировки захватываются в порядке номеров, чтобы запросы с пересекающимися именами не ждали друг друга вечно.
+func (l *gaugeLocks) lock(names []string) func() {
+	seen := make(map[int]struct{}, len(names))
+	stripes := make([]int, 0, len(names))
+	for _, name := range names {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(name))
+		i := int(h.Sum32() % gaugeLockStripes)
+		if _, ok := seen[i]; !ok {
+			seen[i] = struct{}{}
+			stripes = append(stripes, i)
+		}
+	}
+	sort.Ints(stripes)
+
+	for _, i := range stripes {
+		l.stripes[i].Lock()
+	}
+	return func() {
+		for _, i := range stripes {
+			l.stripes[i].Unlock()
+		}
+	}
+}
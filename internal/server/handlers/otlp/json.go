@@ -0,0 +1,252 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Структуры сообщений OTLP в JSON-представлении (proto3 JSON mapping): имена полей в lowerCamelCase,
+// 64-битные целые передаются строками, значения enum числом или именем.
+
+type jsonRequest struct {
+	ResourceMetrics []jsonResourceMetrics `json:"resourceMetrics"`
+}
+
+type jsonResourceMetrics struct {
+	Resource struct {
+		Attributes []jsonKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeMetrics []struct {
+		Metrics []jsonMetric `json:"metrics"`
+	} `json:"scopeMetrics"`
+}
+
+type jsonMetric struct {
+	Name  string `json:"name"`
+	Gauge *struct {
+		DataPoints []jsonNumberPoint `json:"dataPoints"`
+	} `json:"gauge"`
+	Sum *struct {
+		DataPoints             []jsonNumberPoint `json:"dataPoints"`
+		AggregationTemporality jsonTemporality   `json:"aggregationTemporality"`
+		IsMonotonic            bool              `json:"isMonotonic"`
+	} `json:"sum"`
+	Histogram *struct {
+		DataPoints             []jsonHistogramPoint `json:"dataPoints"`
+		AggregationTemporality jsonTemporality      `json:"aggregationTemporality"`
+	} `json:"histogram"`
+	ExponentialHistogram *struct {
+		DataPoints []json.RawMessage `json:"dataPoints"`
+	} `json:"exponentialHistogram"`
+	Summary *struct {
+		DataPoints []json.RawMessage `json:"dataPoints"`
+	} `json:"summary"`
+}
+
+type jsonNumberPoint struct {
+	Attributes   []jsonKeyValue `json:"attributes"`
+	TimeUnixNano jsonUint64     `json:"timeUnixNano"`
+	AsDouble     *jsonFloat     `json:"asDouble"`
+	AsInt        *jsonUint64    `json:"asInt"`
+}
+
+type jsonHistogramPoint struct {
+	Attributes     []jsonKeyValue `json:"attributes"`
+	TimeUnixNano   jsonUint64     `json:"timeUnixNano"`
+	Count          jsonUint64     `json:"count"`
+	Sum            *jsonFloat     `json:"sum"`
+	BucketCounts   []jsonUint64   `json:"bucketCounts"`
+	ExplicitBounds []jsonFloat    `json:"explicitBounds"`
+}
+
+type jsonKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue *string     `json:"stringValue"`
+		BoolValue   *bool       `json:"boolValue"`
+		IntValue    *jsonUint64 `json:"intValue"`
+		DoubleValue *jsonFloat  `json:"doubleValue"`
+	} `json:"value"`
+}
+
+type jsonResponse struct {
+	PartialSuccess *jsonPartialSuccess `json:"partialSuccess,omitempty"`
+}
+
+type jsonPartialSuccess struct {
+	RejectedDataPoints int64  `json:"rejectedDataPoints,string"`
+	ErrorMessage       string `json:"errorMessage,omitempty"`
+}
+
+type jsonStatus struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+}
+
+// jsonUint64 64-битное целое, переданное числом или строкой. Отрицательные значения
+// (asInt, intValue) хранятся в дополнительном коде, как в protobuf.
+type jsonUint64 uint64
+
+// UnmarshalJSON разбирает число или строку с числом.
+func (v *jsonUint64) UnmarshalJSON(b []byte) error {
+	s := string(bytes.Trim(b, `"`))
+	if s == "" || s == "null" {
+		return nil
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		*v = jsonUint64(u)
+		return nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s: %w", b, err)
+	}
+	*v = jsonUint64(i) //nolint:gosec // дополнительный код, как в protobuf
+	return nil
+}
+
+// jsonFloat число с плавающей точкой, переданное числом или строкой ("NaN", "Infinity", "-Infinity").
+type jsonFloat float64
+
+// UnmarshalJSON разбирает число или строку с числом.
+func (v *jsonFloat) UnmarshalJSON(b []byte) error {
+	switch s := string(bytes.Trim(b, `"`)); s {
+	case "NaN":
+		*v = jsonFloat(math.NaN())
+	case "Infinity":
+		*v = jsonFloat(math.Inf(1))
+	case "-Infinity":
+		*v = jsonFloat(math.Inf(-1))
+	default:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %s: %w", b, err)
+		}
+		*v = jsonFloat(f)
+	}
+	return nil
+}
+
+// jsonTemporality значение AggregationTemporality, переданное числом или именем.
+type jsonTemporality int
+
+// UnmarshalJSON разбирает число или имя значения enum.
+func (v *jsonTemporality) UnmarshalJSON(b []byte) error {
+	switch s := string(bytes.Trim(b, `"`)); s {
+	case "AGGREGATION_TEMPORALITY_UNSPECIFIED":
+		*v = temporalityUnspecified
+	case "AGGREGATION_TEMPORALITY_DELTA":
+		*v = temporalityDelta
+	case "AGGREGATION_TEMPORALITY_CUMULATIVE":
+		*v = temporalityCumulative
+	default:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid aggregation temporality %s", b)
+		}
+		*v = jsonTemporality(i)
+	}
+	return nil
+}
+
+// decodeJSON разбирает ExportMetricsServiceRequest в JSON-представлении.
+func decodeJSON(b []byte) ([]resourceMetrics, error) {
+	var req jsonRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, err
+	}
+
+	result := make([]resourceMetrics, 0, len(req.ResourceMetrics))
+	for _, jrm := range req.ResourceMetrics {
+		rm := resourceMetrics{Attributes: jsonAttributes(jrm.Resource.Attributes)}
+		for _, sm := range jrm.ScopeMetrics {
+			for _, jm := range sm.Metrics {
+				rm.Metrics = append(rm.Metrics, jm.toMetric())
+			}
+		}
+		result = append(result, rm)
+	}
+	return result, nil
+}
+
+func (jm jsonMetric) toMetric() metric {
+	m := metric{Name: jm.Name}
+	switch {
+	case jm.Gauge != nil:
+		m.Kind = kindGauge
+		m.Numbers = jsonNumberPoints(jm.Gauge.DataPoints)
+	case jm.Sum != nil:
+		m.Kind = kindSum
+		m.Temporality = int(jm.Sum.AggregationTemporality)
+		m.Monotonic = jm.Sum.IsMonotonic
+		m.Numbers = jsonNumberPoints(jm.Sum.DataPoints)
+	case jm.Histogram != nil:
+		m.Kind = kindHistogram
+		m.Temporality = int(jm.Histogram.AggregationTemporality)
+		for _, jp := range jm.Histogram.DataPoints {
+			p := histogramPoint{
+				Attributes:   jsonAttributes(jp.Attributes),
+				TimeUnixNano: uint64(jp.TimeUnixNano),
+				Count:        uint64(jp.Count),
+			}
+			if jp.Sum != nil {
+				p.Sum, p.HasSum = float64(*jp.Sum), true
+			}
+			for _, c := range jp.BucketCounts {
+				p.BucketCounts = append(p.BucketCounts, uint64(c))
+			}
+			for _, bound := range jp.ExplicitBounds {
+				p.ExplicitBounds = append(p.ExplicitBounds, float64(bound))
+			}
+			m.Histograms = append(m.Histograms, p)
+		}
+	case jm.ExponentialHistogram != nil:
+		m.Kind = kindExponentialHistogram
+		m.Unsupported = len(jm.ExponentialHistogram.DataPoints)
+	case jm.Summary != nil:
+		m.Kind = kindSummary
+		m.Unsupported = len(jm.Summary.DataPoints)
+	}
+	return m
+}
+
+func jsonNumberPoints(points []jsonNumberPoint) []numberPoint {
+	result := make([]numberPoint, 0, len(points))
+	for _, jp := range points {
+		p := numberPoint{Attributes: jsonAttributes(jp.Attributes), TimeUnixNano: uint64(jp.TimeUnixNano)}
+		switch {
+		case jp.AsDouble != nil:
+			p.Value, p.HasValue = float64(*jp.AsDouble), true
+		case jp.AsInt != nil:
+			p.Value, p.HasValue = float64(int64(*jp.AsInt)), true //nolint:gosec // sfixed64
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+func jsonAttributes(kvs []jsonKeyValue) []attribute {
+	attrs := make([]attribute, 0, len(kvs))
+	for _, kv := range kvs {
+		a := attribute{Key: kv.Key}
+		switch v := kv.Value; {
+		case v.StringValue != nil:
+			a.Value = *v.StringValue
+		case v.BoolValue != nil:
+			a.Value = strconv.FormatBool(*v.BoolValue)
+		case v.IntValue != nil:
+			a.Value = strconv.FormatInt(int64(*v.IntValue), 10) //nolint:gosec // int64
+		case v.DoubleValue != nil:
+			a.Value = strconv.FormatFloat(float64(*v.DoubleValue), 'g', -1, 64)
+		default:
+			continue
+		}
+		if a.Key != "" {
+			attrs = append(attrs, a)
+		}
+	}
+	return attrs
+}
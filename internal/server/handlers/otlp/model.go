@@ -0,0 +1,82 @@
+package otlp
+
+// Виды данных метрики OTLP (поле oneof data сообщения Metric).
+type dataKind int
+
+const (
+	kindUnknown dataKind = iota
+	kindGauge
+	kindSum
+	kindHistogram
+	kindExponentialHistogram
+	kindSummary
+)
+
+// String возвращает название вида данных так, как оно называется в OTLP.
+func (k dataKind) String() string {
+	switch k {
+	case kindGauge:
+		return "gauge"
+	case kindSum:
+		return "sum"
+	case kindHistogram:
+		return "histogram"
+	case kindExponentialHistogram:
+		return "exponential_histogram"
+	case kindSummary:
+		return "summary"
+	default:
+		return "unknown"
+	}
+}
+
+// Способ агрегации значений Sum и Histogram (AggregationTemporality).
+const (
+	temporalityUnspecified = 0
+	temporalityDelta       = 1
+	temporalityCumulative  = 2
+)
+
+// attribute атрибут ресурса или точки данных. Значения скалярных типов приводятся к строке,
+// значения-массивы и вложенные списки не поддерживаются и пропускаются.
+type attribute struct {
+	Key   string
+	Value string
+}
+
+// resourceMetrics метрики одного ресурса (сервиса), метрики всех InstrumentationScope объединены.
+type resourceMetrics struct {
+	Attributes []attribute
+	Metrics    []metric
+}
+
+// metric метрика OTLP с точками данных.
+type metric struct {
+	Name        string
+	Kind        dataKind
+	Temporality int
+	Monotonic   bool
+	Numbers     []numberPoint
+	Histograms  []histogramPoint
+	// Количество точек данных неподдерживаемого вида (ExponentialHistogram, Summary).
+	Unsupported int
+}
+
+// numberPoint точка данных Gauge или Sum.
+type numberPoint struct {
+	Attributes   []attribute
+	TimeUnixNano uint64
+	Value        float64
+	HasValue     bool
+}
+
+// histogramPoint точка данных Histogram с явными границами корзин.
+type histogramPoint struct {
+	Attributes     []attribute
+	TimeUnixNano   uint64
+	Count          uint64
+	Sum            float64
+	HasSum         bool
+	BucketCounts   []uint64
+	ExplicitBounds []float64
+}
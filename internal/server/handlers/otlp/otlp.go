@@ -0,0 +1,449 @@
+// Package otlp реализует приемник метрик OpenTelemetry по протоколу OTLP/HTTP (POST /v1/metrics).
+//
+// Запрос ExportMetricsServiceRequest принимается в protobuf (application/x-protobuf) или
+// JSON (application/json) и преобразуется в метрики сервера:
+//   - Gauge сохраняется как gauge с последним значением;
+//   - монотонный Sum сохраняется как counter, для кумулятивной агрегации сохраняется разница
+//     с предыдущим значением ряда;
+//   - немонотонный Sum сохраняется как gauge, для дельта-агрегации значение прибавляется к текущему;
+//   - Histogram раскладывается на counter <name>_count, gauge <name>_sum и counter <name>_bucket
+//     с меткой le для каждой корзины (нарастающим итогом, как в Prometheus).
+//
+// Имя метрики дополняется выбранными атрибутами ресурса (по умолчанию service.name) и атрибутами
+// точки данных. Точки неподдерживаемых видов (ExponentialHistogram, Summary) и некорректные точки
+// отклоняются, их количество возвращается в ответе как частичный успех (partial success).
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/cumulative"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
+	"github.com/maynagashev/go-metrics/internal/server/metricname"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+const (
+	// ContentTypeProtobuf тип содержимого запроса и ответа в формате protobuf.
+	ContentTypeProtobuf = "application/x-protobuf"
+	// ContentTypeJSON тип содержимого запроса и ответа в формате JSON.
+	ContentTypeJSON = "application/json"
+	// MaxBodySize максимальный размер тела запроса после распаковки.
+	MaxBodySize = 32 << 20
+
+	// Коды google.rpc.Status, возвращаемые в теле ответа с ошибкой.
+	codeInvalidArgument = 3
+	codeInternal        = 13
+	codeUnavailable     = 14
+)
+
+// handler приемник OTLP/HTTP.
+type handler struct {
+	st                 storage.Repository
+	log                *zap.Logger
+	resourceAttributes map[string]struct{}
+	// Счетчики с кумулятивной агрегацией передаются нарастающим итогом,
+	// хранилище накапливает приращения.
+	totals *cumulative.Tracker
+	// Блокировки gauge, к которым прибавляются дельты немонотонных Sum.
+	gaugeLocks gaugeLocks
+}
+
+// New создает обработчик OTLP/HTTP.
+func New(config *app.Config, st storage.Repository, log *zap.Logger) http.HandlerFunc {
+	h := &handler{
+		st:                 st,
+		log:                log,
+		resourceAttributes: make(map[string]struct{}),
+		totals:             cumulative.New(st),
+	}
+	for _, key := range config.GetOTLPResourceAttributes() {
+		h.resourceAttributes[key] = struct{}{}
+	}
+	return h.serveHTTP
+}
+
+// serveHTTP принимает пакет метрик OTLP и сохраняет его в хранилище одним вызовом UpdateMetrics.
+// Тело ответа кодируется так же, как тело запроса. Сжатие gzip обрабатывает middleware decompresspool.
+func (h *handler) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != ContentTypeProtobuf && mediaType != ContentTypeJSON {
+		http.Error(w, "unsupported content type, expected "+ContentTypeProtobuf+" or "+ContentTypeJSON,
+			http.StatusUnsupportedMediaType)
+		return
+	}
+	isJSON := mediaType == ContentTypeJSON
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+	if err != nil {
+		writeStatus(w, isJSON, http.StatusBadRequest, codeInvalidArgument, "failed to read request body")
+		return
+	}
+	if len(body) > MaxBodySize {
+		writeStatus(w, isJSON, http.StatusRequestEntityTooLarge, codeInvalidArgument, "request body too large")
+		return
+	}
+
+	var resources []resourceMetrics
+	if isJSON {
+		resources, err = decodeJSON(body)
+	} else {
+		resources, err = decodeProto(body)
+	}
+	if err != nil {
+		h.log.Debug("failed to decode otlp request", zap.Error(err))
+		writeStatus(w, isJSON, http.StatusBadRequest, codeInvalidArgument, "failed to decode request: "+err.Error())
+		return
+	}
+
+	b := newBatch(h.totals.Batch())
+	for _, rm := range resources {
+		h.convertResource(r.Context(), b, rm)
+	}
+	// Дельты gauge прибавляются к значениям из хранилища под блокировкой этих gauge до завершения записи,
+	// иначе одновременные запросы перезаписали бы приращения друг друга.
+	unlock := h.gaugeLocks.lock(b.gaugeDeltaNames())
+	items, err := h.store(r.Context(), b)
+	unlock()
+	if err != nil {
+		h.log.Error("failed to store otlp metrics", zap.Error(err))
+		status := httperr.Status(err)
+		writeStatus(w, isJSON, status, rpcCode(status), "failed to store metrics")
+		return
+	}
+	// Накопленные значения счетчиков запоминаются только после записи приращений,
+	// чтобы повтор запроса после ошибки снова сохранил те же приращения.
+	b.totals.Commit()
+
+	h.log.Debug("otlp request applied",
+		zap.Int("metrics", len(items)),
+		zap.Int64("rejected_data_points", b.rejected))
+	writeResponse(w, isJSON, b.rejected, b.errorMessage())
+}
+
+// store прибавляет дельты gauge к сохраненным значениям и записывает метрики пакета в хранилище
+// одним вызовом UpdateMetrics. Возвращает записанные метрики.
+func (h *handler) store(ctx context.Context, b *batch) ([]metrics.Metric, error) {
+	for name, delta := range b.gaugeDeltas {
+		stored, err := h.st.GetGauge(ctx, name)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		b.gauges[name] = float64(stored) + delta
+	}
+
+	items := b.metrics()
+	if len(items) == 0 {
+		return items, nil
+	}
+	return items, h.st.UpdateMetrics(ctx, items)
+}
+
+// rpcCode возвращает код google.rpc.Status для кода ответа HTTP при ошибке хранилища.
+func rpcCode(status int) int32 {
+	switch status {
+	case http.StatusBadRequest:
+		return codeInvalidArgument
+	case http.StatusServiceUnavailable:
+		return codeUnavailable
+	default:
+		return codeInternal
+	}
+}
+
+// convertResource добавляет в пакет метрики одного ресурса.
+func (h *handler) convertResource(ctx context.Context, b *batch, rm resourceMetrics) {
+	resource := make([]attribute, 0, len(h.resourceAttributes))
+	for _, a := range rm.Attributes {
+		if _, ok := h.resourceAttributes[a.Key]; ok {
+			resource = append(resource, a)
+		}
+	}
+
+	for _, m := range rm.Metrics {
+		switch m.Kind {
+		case kindGauge:
+			h.convertGauge(b, m, resource)
+		case kindSum:
+			h.convertSum(ctx, b, m, resource)
+		case kindHistogram:
+			h.convertHistogram(ctx, b, m, resource)
+		case kindExponentialHistogram, kindSummary:
+			b.reject("unsupported metric type "+m.Kind.String(), m.Unsupported)
+		case kindUnknown:
+			// Метрика без данных не содержит точек, которые можно было бы отклонить.
+		}
+	}
+}
+
+func (h *handler) convertGauge(b *batch, m metric, resource []attribute) {
+	base := metricname.Sanitize(m.Name)
+	for _, p := range sortedNumbers(m.Numbers) {
+		if !b.checkValue(p) {
+			continue
+		}
+		b.setGauge(seriesName(base, resource, p.Attributes), p.Value)
+	}
+}
+
+func (h *handler) convertSum(ctx context.Context, b *batch, m metric, resource []attribute) {
+	if !b.checkTemporality(m, len(m.Numbers)) {
+		return
+	}
+	base := metricname.Sanitize(m.Name)
+	for _, p := range sortedNumbers(m.Numbers) {
+		if !b.checkValue(p) {
+			continue
+		}
+		name := seriesName(base, resource, p.Attributes)
+		switch {
+		case !m.Monotonic && m.Temporality == temporalityCumulative:
+			b.setGauge(name, p.Value)
+		case !m.Monotonic:
+			b.addGauge(name, p.Value)
+		case p.Value < 0:
+			b.reject("negative value of monotonic sum", 1)
+		default:
+			b.addCounter(ctx, name, m.Temporality, p.Value)
+		}
+	}
+}
+
+func (h *handler) convertHistogram(ctx context.Context, b *batch, m metric, resource []attribute) {
+	if !b.checkTemporality(m, len(m.Histograms)) {
+		return
+	}
+	base := metricname.Sanitize(m.Name)
+	points := append([]histogramPoint(nil), m.Histograms...)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].TimeUnixNano < points[j].TimeUnixNano })
+
+	for _, p := range points {
+		if len(p.BucketCounts) > 0 && len(p.BucketCounts) != len(p.ExplicitBounds)+1 {
+			b.reject("histogram bucket counts do not match explicit bounds", 1)
+			continue
+		}
+		if p.HasSum && (math.IsNaN(p.Sum) || math.IsInf(p.Sum, 0)) {
+			b.reject("non-finite value", 1)
+			continue
+		}
+
+		b.addCounter(ctx, seriesName(base+"_count", resource, p.Attributes), m.Temporality, float64(p.Count))
+		if p.HasSum {
+			name := seriesName(base+"_sum", resource, p.Attributes)
+			if m.Temporality == temporalityCumulative {
+				b.setGauge(name, p.Sum)
+			} else {
+				b.addGauge(name, p.Sum)
+			}
+		}
+
+		var total uint64
+		for i, count := range p.BucketCounts {
+			total += count
+			le := "inf"
+			if i < len(p.ExplicitBounds) {
+				le = strconv.FormatFloat(p.ExplicitBounds[i], 'g', -1, 64)
+			}
+			name := seriesName(base+"_bucket", resource, p.Attributes, attribute{Key: "le", Value: le})
+			b.addCounter(ctx, name, m.Temporality, float64(total))
+		}
+	}
+}
+
+// batch метрики, собранные из одного запроса, и отклоненные точки данных.
+type batch struct {
+	gauges map[string]float64
+	// Дельты gauge, которые прибавляются к значениям из хранилища при записи пакета.
+	gaugeDeltas map[string]float64
+	counters    map[string]int64
+	rejected    int64
+	reasons     map[string]int64
+	// Накопленные значения кумулятивных счетчиков запроса.
+	totals *cumulative.Batch
+}
+
+func newBatch(totals *cumulative.Batch) *batch {
+	return &batch{
+		gauges:      make(map[string]float64),
+		gaugeDeltas: make(map[string]float64),
+		counters:    make(map[string]int64),
+		reasons:     make(map[string]int64),
+		totals:      totals,
+	}
+}
+
+// reject учитывает отклоненные точки данных с указанной причиной.
+func (b *batch) reject(reason string, points int) {
+	if points <= 0 {
+		return
+	}
+	b.rejected += int64(points)
+	b.reasons[reason] += int64(points)
+}
+
+// checkValue проверяет, что точка содержит конечное значение, иначе отклоняет ее.
+func (b *batch) checkValue(p numberPoint) bool {
+	switch {
+	case !p.HasValue:
+		b.reject("data point without value", 1)
+		return false
+	case math.IsNaN(p.Value) || math.IsInf(p.Value, 0):
+		b.reject("non-finite value", 1)
+		return false
+	}
+	return true
+}
+
+// checkTemporality проверяет, что способ агрегации метрики указан, иначе отклоняет все ее точки.
+func (b *batch) checkTemporality(m metric, points int) bool {
+	if m.Temporality == temporalityDelta || m.Temporality == temporalityCumulative {
+		return true
+	}
+	b.reject("unsupported aggregation temporality "+strconv.Itoa(m.Temporality)+" of "+m.Kind.String(), points)
+	return false
+}
+
+// addCounter добавляет приращение counter: для кумулятивной агрегации вычисляется разница
+// с предыдущим значением ряда, дельта округляется до целого.
+func (b *batch) addCounter(ctx context.Context, name string, temporality int, v float64) {
+	if temporality == temporalityCumulative {
+		b.counters[name] += b.totals.Delta(ctx, name, v)
+		return
+	}
+	b.counters[name] += int64(math.Round(v))
+}
+
+// setGauge устанавливает значение gauge, отменяя накопленные в запросе дельты.
+func (b *batch) setGauge(name string, value float64) {
+	b.gauges[name] = value
+	delete(b.gaugeDeltas, name)
+}
+
+// addGauge прибавляет значение к gauge: к значению из этого же запроса или, при записи пакета,
+// к сохраненному в хранилище.
+func (b *batch) addGauge(name string, delta float64) {
+	if current, ok := b.gauges[name]; ok {
+		b.gauges[name] = current + delta
+		return
+	}
+	b.gaugeDeltas[name] += delta
+}
+
+// gaugeDeltaNames возвращает имена gauge, к сохраненным значениям которых прибавляются дельты.
+func (b *batch) gaugeDeltaNames() []string {
+	names := make([]string, 0, len(b.gaugeDeltas))
+	for name := range b.gaugeDeltas {
+		names = append(names, name)
+	}
+	return names
+}
+
+// metrics возвращает метрики пакета, упорядоченные по имени.
+func (b *batch) metrics() []metrics.Metric {
+	items := make([]metrics.Metric, 0, len(b.gauges)+len(b.counters))
+	for name, value := range b.gauges {
+		items = append(items, *metrics.NewGauge(name, value))
+	}
+	for name, delta := range b.counters {
+		items = append(items, *metrics.NewCounter(name, delta))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].MType < items[j].MType
+	})
+	return items
+}
+
+// errorMessage описывает причины отклонения точек данных.
+func (b *batch) errorMessage() string {
+	reasons := make([]string, 0, len(b.reasons))
+	for reason, points := range b.reasons {
+		reasons = append(reasons, fmt.Sprintf("%s: %d data points", reason, points))
+	}
+	sort.Strings(reasons)
+	return strings.Join(reasons, "; ")
+}
+
+func sortedNumbers(points []numberPoint) []numberPoint {
+	sorted := append([]numberPoint(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TimeUnixNano < sorted[j].TimeUnixNano })
+	return sorted
+}
+
+// seriesName дополняет имя метрики атрибутами: атрибуты точки данных переопределяют одноименные
+// атрибуты ресурса. Например, http.server.duration с атрибутами service.name=checkout
+// и http.method=GET превращается в http_server_duration_http_method_GET_service_name_checkout.
+func seriesName(base string, resource, point []attribute, extra ...attribute) string {
+	labels := make(map[string]string, len(resource)+len(point)+len(extra))
+	for _, group := range [][]attribute{resource, point, extra} {
+		for _, a := range group {
+			labels[a.Key] = a.Value
+		}
+	}
+	return metricname.WithLabels(base, labels)
+}
+
+// writeResponse записывает ExportMetricsServiceResponse. Поле partial_success заполняется,
+// только если часть точек данных отклонена.
+func writeResponse(w http.ResponseWriter, isJSON bool, rejected int64, message string) {
+	if isJSON {
+		var resp jsonResponse
+		if rejected > 0 {
+			resp.PartialSuccess = &jsonPartialSuccess{RejectedDataPoints: rejected, ErrorMessage: message}
+		}
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	var body []byte
+	if rejected > 0 {
+		var partial []byte
+		partial = protowire.AppendTag(partial, 1, protowire.VarintType)
+		partial = protowire.AppendVarint(partial, uint64(rejected))
+		partial = protowire.AppendTag(partial, 2, protowire.BytesType)
+		partial = protowire.AppendString(partial, message)
+		body = protowire.AppendTag(body, 1, protowire.BytesType)
+		body = protowire.AppendBytes(body, partial)
+	}
+	w.Header().Set("Content-Type", ContentTypeProtobuf)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// writeStatus записывает ответ с ошибкой в виде сообщения google.rpc.Status.
+func writeStatus(w http.ResponseWriter, isJSON bool, httpStatus int, code int32, message string) {
+	if isJSON {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.WriteHeader(httpStatus)
+		_ = json.NewEncoder(w).Encode(jsonStatus{Code: code, Message: message})
+		return
+	}
+
+	var body []byte
+	body = protowire.AppendTag(body, 1, protowire.VarintType)
+	body = protowire.AppendVarint(body, uint64(code))
+	body = protowire.AppendTag(body, 2, protowire.BytesType)
+	body = protowire.AppendString(body, message)
+	w.Header().Set("Content-Type", ContentTypeProtobuf)
+	w.WriteHeader(httpStatus)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,308 @@
+package otlp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/otlp"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/mocks"
+)
+
+// Вспомогательные функции для сборки сообщений OTLP в protobuf.
+
+func message(fields ...[]byte) []byte {
+	return bytes.Join(fields, nil)
+}
+
+func bytesField(num protowire.Number, v []byte) []byte {
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func varintField(num protowire.Number, v uint64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func fixed64Field(num protowire.Number, v uint64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func doubleField(num protowire.Number, v float64) []byte {
+	return fixed64Field(num, math.Float64bits(v))
+}
+
+func keyValue(key, value string) []byte {
+	return message(bytesField(1, []byte(key)), bytesField(2, bytesField(1, []byte(value))))
+}
+
+// numberPoint точка NumberDataPoint со значением as_double и атрибутами.
+func numberPoint(ts uint64, value float64, attrs ...[]byte) []byte {
+	fields := [][]byte{fixed64Field(3, ts), doubleField(4, value)}
+	for _, a := range attrs {
+		fields = append(fields, bytesField(7, a))
+	}
+	return message(fields...)
+}
+
+func exportRequest(resourceAttrs [][]byte, metrics ...[]byte) []byte {
+	var resource [][]byte
+	for _, a := range resourceAttrs {
+		resource = append(resource, bytesField(1, a))
+	}
+	var scope [][]byte
+	for _, m := range metrics {
+		scope = append(scope, bytesField(2, m))
+	}
+	rm := message(bytesField(1, message(resource...)), bytesField(2, message(scope...)))
+	return bytesField(1, rm)
+}
+
+func post(t *testing.T, handler http.HandlerFunc, contentType string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestHandler_Protobuf(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	handler := otlp.New(&app.Config{}, st, zap.NewNop())
+
+	gauge := message(
+		bytesField(1, []byte("process.memory.usage")),
+		bytesField(5, bytesField(1, numberPoint(2, 2048))),
+	)
+	// Монотонный Sum с кумулятивной агрегацией и атрибутом точки.
+	sum := func(value float64) []byte {
+		return message(
+			bytesField(1, []byte("http.requests")),
+			bytesField(7, message(
+				bytesField(1, numberPoint(1, value, keyValue("http.method", "GET"))),
+				varintField(2, 2),
+				varintField(3, 1),
+			)),
+		)
+	}
+	// Немонотонный Sum с дельта-агрегацией.
+	upDown := message(
+		bytesField(1, []byte("queue.size")),
+		bytesField(7, message(bytesField(1, numberPoint(1, 3)), varintField(2, 1))),
+	)
+	histogram := message(
+		bytesField(1, []byte("http.duration")),
+		bytesField(9, message(
+			bytesField(1, message(
+				fixed64Field(3, 1),
+				fixed64Field(4, 5),
+				doubleField(5, 1.25),
+				bytesField(6, protowire.AppendFixed64(protowire.AppendFixed64(nil, 3), 2)),
+				bytesField(7, protowire.AppendFixed64(nil, math.Float64bits(0.5))),
+			)),
+			varintField(2, 2),
+		)),
+	)
+	summary := message(
+		bytesField(1, []byte("rpc.latency")),
+		bytesField(11, message(bytesField(1, nil), bytesField(1, nil))),
+	)
+	resource := [][]byte{keyValue("service.name", "checkout"), keyValue("host.name", "web-1")}
+
+	rec := post(t, handler, otlp.ContentTypeProtobuf,
+		exportRequest(resource, gauge, sum(10), upDown, histogram, summary))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, otlp.ContentTypeProtobuf, rec.Header().Get("Content-Type"))
+
+	// Ответ содержит partial_success с двумя отклоненными точками Summary.
+	wantPartial := message(
+		varintField(1, 2),
+		bytesField(2, []byte("unsupported metric type summary: 2 data points")),
+	)
+	assert.Equal(t, bytesField(1, wantPartial), rec.Body.Bytes())
+
+	ctx := context.Background()
+	g, err := st.GetGauge(ctx, "process_memory_usage_service_name_checkout")
+	require.NoError(t, err)
+	assert.InDelta(t, 2048.0, float64(g), 1e-9)
+
+	c, err := st.GetCounter(ctx, "http_requests_http_method_GET_service_name_checkout")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(10), c)
+
+	g, _ = st.GetGauge(ctx, "queue_size_service_name_checkout")
+	assert.InDelta(t, 3.0, float64(g), 1e-9)
+
+	c, _ = st.GetCounter(ctx, "http_duration_count_service_name_checkout")
+	assert.Equal(t, storage.Counter(5), c)
+	g, _ = st.GetGauge(ctx, "http_duration_sum_service_name_checkout")
+	assert.InDelta(t, 1.25, float64(g), 1e-9)
+	c, _ = st.GetCounter(ctx, "http_duration_bucket_le_0_5_service_name_checkout")
+	assert.Equal(t, storage.Counter(3), c)
+	c, _ = st.GetCounter(ctx, "http_duration_bucket_le_inf_service_name_checkout")
+	assert.Equal(t, storage.Counter(5), c)
+
+	// Повторная отправка: кумулятивный счетчик увеличивается на разницу, дельта прибавляется к gauge.
+	rec = post(t, handler, otlp.ContentTypeProtobuf, exportRequest(resource, sum(14), upDown))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+
+	c, _ = st.GetCounter(ctx, "http_requests_http_method_GET_service_name_checkout")
+	assert.Equal(t, storage.Counter(14), c)
+	g, _ = st.GetGauge(ctx, "queue_size_service_name_checkout")
+	assert.InDelta(t, 6.0, float64(g), 1e-9)
+}
+
+func TestHandler_JSON(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	cfg := &app.Config{OTLPResourceAttributes: []string{"service.name", "service.namespace"}}
+	handler := otlp.New(cfg, st, zap.NewNop())
+
+	body := `{
+	  "resourceMetrics": [{
+	    "resource": {"attributes": [
+	      {"key": "service.name", "value": {"stringValue": "billing"}},
+	      {"key": "service.namespace", "value": {"stringValue": "shop"}},
+	      {"key": "process.pid", "value": {"intValue": "42"}}
+	    ]},
+	    "scopeMetrics": [{"metrics": [
+	      {"name": "jobs.done", "sum": {
+	        "aggregationTemporality": "AGGREGATION_TEMPORALITY_DELTA", "isMonotonic": true,
+	        "dataPoints": [{"timeUnixNano": "1", "asInt": "7"}]
+	      }},
+	      {"name": "cpu.load", "gauge": {"dataPoints": [
+	        {"timeUnixNano": "2", "asDouble": 0.9},
+	        {"timeUnixNano": "1", "asDouble": 0.1},
+	        {"timeUnixNano": "3"}
+	      ]}},
+	      {"name": "sizes", "exponentialHistogram": {"dataPoints": [{}]}},
+	      {"name": "jobs.pending", "sum": {"dataPoints": [{"asInt": "1"}]}}
+	    ]}]
+	  }]
+	}`
+
+	rec := post(t, handler, "application/json; charset=utf-8", []byte(body))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		PartialSuccess struct {
+			RejectedDataPoints string `json:"rejectedDataPoints"`
+			ErrorMessage       string `json:"errorMessage"`
+		} `json:"partialSuccess"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "3", resp.PartialSuccess.RejectedDataPoints)
+	assert.Equal(t,
+		"data point without value: 1 data points; "+
+			"unsupported aggregation temporality 0 of sum: 1 data points; "+
+			"unsupported metric type exponential_histogram: 1 data points",
+		resp.PartialSuccess.ErrorMessage)
+
+	ctx := context.Background()
+	c, err := st.GetCounter(ctx, "jobs_done_service_name_billing_service_namespace_shop")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(7), c)
+
+	g, err := st.GetGauge(ctx, "cpu_load_service_name_billing_service_namespace_shop")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.9, float64(g), 1e-9)
+}
+
+func TestHandler_Errors(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	handler := otlp.New(&app.Config{}, st, zap.NewNop())
+
+	rec := post(t, handler, "text/plain", []byte("x"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+
+	rec = post(t, handler, otlp.ContentTypeProtobuf, []byte{0x0a, 0xff})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, otlp.ContentTypeProtobuf, rec.Header().Get("Content-Type"))
+
+	rec = post(t, handler, otlp.ContentTypeJSON, []byte(`{"resourceMetrics": 1}`))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"code":3`)
+
+	rec = post(t, handler, otlp.ContentTypeJSON, []byte(`{}`))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{}`, rec.Body.String())
+}
+
+func TestHandler_ConcurrentGaugeDeltas(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	handler := otlp.New(&app.Config{}, st, zap.NewNop())
+	body := `{"resourceMetrics": [{"scopeMetrics": [{"metrics": [{"name": "queue.size", "sum": {
+	  "aggregationTemporality": "AGGREGATION_TEMPORALITY_DELTA",
+	  "dataPoints": [{"asInt": "1"}]
+	}}]}]}]}`
+
+	const requests = 50
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusOK, post(t, handler, otlp.ContentTypeJSON, []byte(body)).Code)
+		}()
+	}
+	wg.Wait()
+
+	// Одновременные запросы не перезаписывают приращения друг друга.
+	g, err := st.GetGauge(context.Background(), "queue_size")
+	require.NoError(t, err)
+	assert.InDelta(t, requests, float64(g), 1e-9)
+}
+
+func TestHandler_StorageErrors(t *testing.T) {
+	body := `{"resourceMetrics": [{"scopeMetrics": [{"metrics": [
+	  {"name": "cpu.load", "gauge": {"dataPoints": [{"asDouble": 0.5}]}}
+	]}]}]}`
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantRPC  string
+	}{
+		{name: "unavailable", err: fmt.Errorf("%w: connection refused", storage.ErrUnavailable),
+			wantCode: http.StatusServiceUnavailable, wantRPC: `"code":14`},
+		{name: "invalid metric", err: fmt.Errorf("%w: empty name", storage.ErrInvalidMetric),
+			wantCode: http.StatusBadRequest, wantRPC: `"code":3`},
+		{name: "internal", err: errors.New("disk corrupted"),
+			wantCode: http.StatusInternalServerError, wantRPC: `"code":13`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := new(mocks.Repository)
+			st.On("UpdateMetrics", mock.Anything, mock.Anything).Return(tt.err).Once()
+
+			rec := post(t, otlp.New(&app.Config{}, st, zap.NewNop()), otlp.ContentTypeJSON, []byte(body))
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantRPC)
+			st.AssertExpectations(t)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 )
 
@@ -17,8 +18,12 @@ func New(st storage.Repository) http.HandlerFunc {
 		w.Header().Set("Content-Type", "text/html")
 
 		// Возвращаем метрики в виде списка строк (первоначальный вариант)
-		ms := st.GetMetrics(r.Context())
-		items := make([]string, 0, st.Count(r.Context()))
+		ms, err := st.GetMetrics(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), httperr.Status(err))
+			return
+		}
+		items := make([]string, 0, len(ms))
 		for _, metric := range ms {
 			switch metric.MType {
 			case metrics.TypeGauge:
@@ -2,6 +2,8 @@ package index_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -26,37 +28,42 @@ func (m *MockRepository) Close() error {
 	return args.Error(0)
 }
 
-func (m *MockRepository) Count(ctx context.Context) int {
+func (m *MockRepository) Ping(ctx context.Context) error {
 	args := m.Called(ctx)
-	return args.Int(0)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Count(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
 }
 
-func (m *MockRepository) GetMetrics(ctx context.Context) []metrics.Metric {
+func (m *MockRepository) GetMetrics(ctx context.Context) ([]metrics.Metric, error) {
 	args := m.Called(ctx)
 	result, _ := args.Get(0).([]metrics.Metric)
-	return result
+	return result, args.Error(1)
 }
 
 func (m *MockRepository) GetMetric(
 	ctx context.Context,
 	mType metrics.MetricType,
 	name string,
-) (metrics.Metric, bool) {
+) (metrics.Metric, error) {
 	args := m.Called(ctx, mType, name)
 	result, _ := args.Get(0).(metrics.Metric)
-	return result, args.Bool(1)
+	return result, args.Error(1)
 }
 
-func (m *MockRepository) GetCounter(ctx context.Context, name string) (storage.Counter, bool) {
+func (m *MockRepository) GetCounter(ctx context.Context, name string) (storage.Counter, error) {
 	args := m.Called(ctx, name)
 	result, _ := args.Get(0).(storage.Counter)
-	return result, args.Bool(1)
+	return result, args.Error(1)
 }
 
-func (m *MockRepository) GetGauge(ctx context.Context, name string) (storage.Gauge, bool) {
+func (m *MockRepository) GetGauge(ctx context.Context, name string) (storage.Gauge, error) {
 	args := m.Called(ctx, name)
 	result, _ := args.Get(0).(storage.Gauge)
-	return result, args.Bool(1)
+	return result, args.Error(1)
 }
 
 func (m *MockRepository) UpdateMetric(ctx context.Context, metric metrics.Metric) error {
@@ -80,8 +87,7 @@ func TestNew(t *testing.T) {
 		{
 			name: "Empty metrics list",
 			setupMock: func(m *MockRepository) {
-				m.On("GetMetrics", mock.Anything).Return([]metrics.Metric{})
-				m.On("Count", mock.Anything).Return(0)
+				m.On("GetMetrics", mock.Anything).Return([]metrics.Metric{}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   []string{},
@@ -97,8 +103,7 @@ func TestNew(t *testing.T) {
 						Value: &value,
 					},
 				}
-				m.On("GetMetrics", mock.Anything).Return(metrics)
-				m.On("Count", mock.Anything).Return(1)
+				m.On("GetMetrics", mock.Anything).Return(metrics, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   []string{"gauge/test_gauge: 42.5"},
@@ -114,8 +119,7 @@ func TestNew(t *testing.T) {
 						Delta: &delta,
 					},
 				}
-				m.On("GetMetrics", mock.Anything).Return(metrics)
-				m.On("Count", mock.Anything).Return(1)
+				m.On("GetMetrics", mock.Anything).Return(metrics, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   []string{"counter/test_counter: 10"},
@@ -129,8 +133,7 @@ func TestNew(t *testing.T) {
 						MType: "unknown",
 					},
 				}
-				m.On("GetMetrics", mock.Anything).Return(metrics)
-				m.On("Count", mock.Anything).Return(1)
+				m.On("GetMetrics", mock.Anything).Return(metrics, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   []string{"unknown/test_unknown"},
@@ -152,8 +155,7 @@ func TestNew(t *testing.T) {
 						Delta: &delta,
 					},
 				}
-				m.On("GetMetrics", mock.Anything).Return(metrics)
-				m.On("Count", mock.Anything).Return(2)
+				m.On("GetMetrics", mock.Anything).Return(metrics, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   []string{"gauge/test_gauge: 42.5", "counter/test_counter: 10"},
@@ -204,3 +206,36 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_StorageError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{
+			name:           "storage unavailable",
+			err:            fmt.Errorf("%w: connection refused", storage.ErrUnavailable),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "query error",
+			err:            errors.New("syntax error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("GetMetrics", mock.Anything).Return(nil, tc.err)
+
+			rr := httptest.NewRecorder()
+			index.New(mockRepo).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Contains(t, rr.Body.String(), tc.err.Error())
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
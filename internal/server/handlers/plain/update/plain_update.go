@@ -3,6 +3,7 @@
 package update
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 )
 
@@ -63,19 +65,23 @@ func New(st storage.Repository, log *zap.Logger) http.HandlerFunc {
 		}
 
 		// Обновляем метрику в хранилище
-		err := st.UpdateMetric(r.Context(), *m)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if err := st.UpdateMetric(r.Context(), *m); err != nil {
+			http.Error(w, err.Error(), httperr.Status(err))
+			return
 		}
 
 		var resMessage string
 		// Получаем значение метрики из хранилища
-		v, ok := st.GetMetric(r.Context(), metricType, metricName)
-		if ok {
+		v, err := st.GetMetric(r.Context(), metricType, metricName)
+		switch {
+		case err == nil:
 			resMessage = fmt.Sprintf("metric %s/%s updated with value %s, result: %s",
 				metricType, metricName, metricValue, v.String())
-		} else {
+		case errors.Is(err, storage.ErrNotFound):
 			resMessage = fmt.Sprintf("metric %s/%s not found", metricType, metricName)
+		default:
+			http.Error(w, err.Error(), httperr.Status(err))
+			return
 		}
 
 		// Отправляем успешный ответ
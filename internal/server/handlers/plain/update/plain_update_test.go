@@ -1,12 +1,15 @@
 package update_test
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
@@ -14,10 +17,23 @@ import (
 	"github.com/maynagashev/go-metrics/internal/server/handlers/plain/update"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/mocks"
 )
 
+// failingRepository возвращает хранилище, которое завершает обновление метрики ошибкой err.
+func failingRepository(err error) storage.Repository {
+	repo := new(mocks.Repository)
+	repo.On("UpdateMetric", mock.Anything, mock.Anything).Return(err)
+	return repo
+}
+
 // [New]. Тест проверяет корректность обработки запроса на обновление метрики.
 func TestUpdateHandler(t *testing.T) {
+	newStorage := func() storage.Repository {
+		st, err := memory.New(&app.Config{}, zap.NewNop())
+		require.NoError(t, err)
+		return st
+	}
 	type want struct {
 		code        int
 		contentType string
@@ -31,7 +47,7 @@ func TestUpdateHandler(t *testing.T) {
 		{
 			name:    "update gauge",
 			target:  "/update/gauge/test_gauge/1.1",
-			storage: memory.New(&app.Config{}, zap.NewNop()),
+			storage: newStorage(),
 			want: want{
 				code:        200,
 				contentType: "text/plain",
@@ -40,7 +56,7 @@ func TestUpdateHandler(t *testing.T) {
 		{
 			name:    "update counter",
 			target:  "/update/counter/test_counter/1",
-			storage: memory.New(&app.Config{}, zap.NewNop()),
+			storage: newStorage(),
 			want: want{
 				code:        200,
 				contentType: "text/plain",
@@ -49,16 +65,34 @@ func TestUpdateHandler(t *testing.T) {
 		{
 			name:    "invalid metrics type",
 			target:  "/update/invalid/test_counter/1",
-			storage: memory.New(&app.Config{}, zap.NewNop()),
+			storage: newStorage(),
 			want: want{
 				code:        400,
 				contentType: "text/plain; charset=utf-8",
 			},
 		},
+		{
+			name:    "storage failure",
+			target:  "/update/counter/test_counter/1",
+			storage: failingRepository(errors.New("disk I/O error")),
+			want: want{
+				code:        500,
+				contentType: "text/plain; charset=utf-8",
+			},
+		},
+		{
+			name:    "storage unavailable",
+			target:  "/update/counter/test_counter/1",
+			storage: failingRepository(fmt.Errorf("%w: connection refused", storage.ErrUnavailable)),
+			want: want{
+				code:        503,
+				contentType: "text/plain; charset=utf-8",
+			},
+		},
 		{
 			name:    "invalid url",
 			target:  "/update/gauge/1",
-			storage: memory.New(&app.Config{}, zap.NewNop()),
+			storage: newStorage(),
 			want: want{
 				code:        404,
 				contentType: "text/plain; charset=utf-8",
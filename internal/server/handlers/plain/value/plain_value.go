@@ -2,25 +2,27 @@
 package value
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 )
 
 // New хэндлер для получения занчения метрики с сервера /value/{type}/{name}.
-func New(storage storage.Repository) http.HandlerFunc {
+func New(st storage.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
 
 		metricType := metrics.MetricType(chi.URLParam(r, "type"))
 		metricName := chi.URLParam(r, "name")
 
-		metric, ok := storage.GetMetric(r.Context(), metricType, metricName)
-		if !ok {
+		metric, err := st.GetMetric(r.Context(), metricType, metricName)
+		if errors.Is(err, storage.ErrNotFound) {
 			http.Error(
 				w,
 				fmt.Sprintf("%s %s not found", metricType, metricName),
@@ -28,8 +30,12 @@ func New(storage storage.Repository) http.HandlerFunc {
 			)
 			return
 		}
+		if err != nil {
+			http.Error(w, err.Error(), httperr.Status(err))
+			return
+		}
 
-		_, err := w.Write([]byte(metric.ValueString()))
+		_, err = w.Write([]byte(metric.ValueString()))
 		if err != nil {
 			return
 		}
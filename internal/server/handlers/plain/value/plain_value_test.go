@@ -2,19 +2,24 @@ package value_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/internal/server/app"
 	"github.com/maynagashev/go-metrics/internal/server/handlers/plain/value"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
 	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/mocks"
 )
 
 func TestPlainValueHandler(t *testing.T) {
@@ -24,7 +29,8 @@ func TestPlainValueHandler(t *testing.T) {
 	cfg := &app.Config{}
 
 	// Create a storage with some test metrics
-	storage := memory.New(cfg, logger)
+	storage, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 	ctx := context.Background()
 
 	// Add a gauge metric
@@ -111,3 +117,41 @@ func TestPlainValueHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestPlainValueHandler_StorageError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{
+			name:           "Metric not found",
+			err:            storage.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Storage unavailable",
+			err:            fmt.Errorf("%w: connection refused", storage.ErrUnavailable),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "Storage error",
+			err:            errors.New("query failed"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewRepository(t)
+			repo.On("GetMetric", mock.Anything, metrics.TypeGauge, "load").Return(metrics.Metric{}, tt.err)
+
+			r := chi.NewRouter()
+			r.Get("/value/{type}/{name}", value.New(repo))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/value/gauge/load", nil))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
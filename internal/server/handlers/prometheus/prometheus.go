@@ -0,0 +1,220 @@
+// Package prometheus реализует обработчик GET /metrics, отдающий метрики хранилища
+// в текстовом формате Prometheus или, по запросу клиента, в формате OpenMetrics.
+package prometheus
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+const (
+	// ContentTypeText тип содержимого текстового формата Prometheus.
+	ContentTypeText = "text/plain; version=0.0.4; charset=utf-8"
+	// ContentTypeOpenMetrics тип содержимого формата OpenMetrics.
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+	openMetricsMediaType = "application/openmetrics-text"
+	counterSuffix        = "_total"
+	writeBufferSize      = 32 * 1024
+)
+
+// New возвращает обработчик, который выводит все метрики хранилища в формате Prometheus.
+//
+// Метрики записываются в ответ по мере чтения из хранилища: если хранилище реализует
+// storage.MetricsStreamer, весь набор метрик не загружается в память.
+func New(st storage.Repository, log *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		openMetrics := acceptsOpenMetrics(r.Header.Get("Accept"))
+		if openMetrics {
+			w.Header().Set("Content-Type", ContentTypeOpenMetrics)
+		} else {
+			w.Header().Set("Content-Type", ContentTypeText)
+		}
+
+		out := &sentWriter{ResponseWriter: w}
+		bw := bufio.NewWriterSize(out, writeBufferSize)
+		enc := newEncoder(bw, openMetrics, log)
+		if err := stream(r.Context(), st, enc.encode); err != nil {
+			log.Error("failed to write prometheus metrics", zap.Error(err))
+			// Если ответ еще не начат, сообщаем об ошибке хранилища кодом ответа,
+			// иначе заголовки и часть метрик уже отправлены и изменить ответ нельзя.
+			if !out.sent {
+				http.Error(w, err.Error(), httperr.Status(err))
+			}
+			return
+		}
+		if openMetrics {
+			_, _ = bw.WriteString("# EOF\n")
+		}
+		if err := bw.Flush(); err != nil {
+			log.Debug("failed to flush prometheus metrics", zap.Error(err))
+		}
+	}
+}
+
+// sentWriter запоминает, что в ответ уже записаны данные.
+type sentWriter struct {
+	http.ResponseWriter
+	sent bool
+}
+
+func (w *sentWriter) Write(p []byte) (int, error) {
+	w.sent = true
+	return w.ResponseWriter.Write(p)
+}
+
+// stream передает метрики хранилища в fn, используя потоковое чтение, если хранилище его поддерживает.
+func stream(ctx context.Context, st storage.Repository, fn func(metrics.Metric) error) error {
+	if streamer, ok := st.(storage.MetricsStreamer); ok {
+		return streamer.StreamMetrics(ctx, fn)
+	}
+	items, err := st.GetMetrics(ctx)
+	if err != nil {
+		return err
+	}
+	for _, metric := range items {
+		if err = fn(metric); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acceptsOpenMetrics возвращает true, если клиент запросил формат OpenMetrics в заголовке Accept.
+func acceptsOpenMetrics(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		if strings.EqualFold(strings.TrimSpace(mediaType), openMetricsMediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// encoder записывает метрики в формате Prometheus: для каждой метрики строки HELP, TYPE и значение.
+type encoder struct {
+	w           *bufio.Writer
+	openMetrics bool
+	log         *zap.Logger
+	// Имена уже записанных семейств и значений метрик: после приведения имен к допустимому виду
+	// разные метрики могут получить одно имя, повторное имя сделало бы ответ некорректным.
+	written map[string]struct{}
+}
+
+func newEncoder(w *bufio.Writer, openMetrics bool, log *zap.Logger) *encoder {
+	return &encoder{w: w, openMetrics: openMetrics, log: log, written: make(map[string]struct{})}
+}
+
+func (e *encoder) encode(metric metrics.Metric) error {
+	var value string
+	switch metric.MType {
+	case metrics.TypeGauge:
+		if metric.Value == nil {
+			return nil
+		}
+		value = formatFloat(*metric.Value)
+	case metrics.TypeCounter:
+		if metric.Delta == nil {
+			return nil
+		}
+		value = strconv.FormatInt(*metric.Delta, 10)
+	default:
+		return nil
+	}
+
+	// Если имя семейства уже занято метрикой, получившей то же имя после приведения к допустимому
+	// виду (например, gauge foo_total и counter foo), к имени добавляется тип метрики.
+	base := SanitizeName(metric.Name)
+	name, sample := e.names(base, metric.MType)
+	if e.taken(name, sample) {
+		name, sample = e.names(base+"_"+string(metric.MType), metric.MType)
+		if e.taken(name, sample) {
+			e.log.Warn("prometheus metric name collision, metric skipped",
+				zap.String("metric", metric.Name), zap.String("type", string(metric.MType)), zap.String("family", name))
+			return nil
+		}
+		e.log.Debug("prometheus metric name collision, type suffix added",
+			zap.String("metric", metric.Name), zap.String("type", string(metric.MType)), zap.String("family", name))
+	}
+	e.written[name] = struct{}{}
+	e.written[sample] = struct{}{}
+
+	_, _ = e.w.WriteString("# HELP " + name + " " + escapeHelp(string(metric.MType)+" metric "+metric.Name) + "\n")
+	_, _ = e.w.WriteString("# TYPE " + name + " " + string(metric.MType) + "\n")
+	_, err := e.w.WriteString(sample + " " + value + "\n")
+	return err
+}
+
+// taken возвращает true, если имя семейства или значения уже использовано другой метрикой.
+func (e *encoder) taken(name, sample string) bool {
+	_, nameTaken := e.written[name]
+	_, sampleTaken := e.written[sample]
+	return nameTaken || sampleTaken
+}
+
+// names возвращает имя семейства и имя значения метрики типа mType с приведенным именем base.
+func (e *encoder) names(base string, mType metrics.MetricType) (string, string) {
+	if mType != metrics.TypeCounter {
+		return base, base
+	}
+	// Счетчики по соглашению Prometheus имеют суффикс _total, в OpenMetrics
+	// суффикс указывается только в имени значения, но не в имени семейства.
+	base = strings.TrimSuffix(base, counterSuffix)
+	if e.openMetrics {
+		return base, base + counterSuffix
+	}
+	return base + counterSuffix, base + counterSuffix
+}
+
+// SanitizeName приводит имя метрики к допустимому в Prometheus виду [a-zA-Z_][a-zA-Z0-9_]*,
+// заменяя недопустимые символы на подчеркивание. Двоеточие также заменяется:
+// в Prometheus оно зарезервировано для правил записи (recording rules).
+func SanitizeName(name string) string {
+	if name == "" {
+		return "_"
+	}
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// escapeHelp экранирует обратную косую черту и перевод строки в тексте HELP.
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,149 @@
+package prometheus_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/prometheus"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/mocks"
+)
+
+func newStorage(t *testing.T) *memory.MemStorage {
+	t.Helper()
+	st, err := memory.New(&app.Config{}, zap.NewNop(),
+		storage.Gauges{"Alloc": 1.5, "go_gc_heap.goal:bytes": 1024},
+		storage.Counters{"PollCount": 7, "requests_total": 3},
+	)
+	require.NoError(t, err)
+	return st
+}
+
+func TestHandler_TextFormat(t *testing.T) {
+	handler := prometheus.New(newStorage(t), zap.NewNop())
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, prometheus.ContentTypeText, rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "# HELP Alloc gauge metric Alloc\n# TYPE Alloc gauge\nAlloc 1.5\n")
+	assert.Contains(t, body, "# TYPE go_gc_heap_goal_bytes gauge\ngo_gc_heap_goal_bytes 1024\n")
+	assert.Contains(t, body, "# TYPE PollCount_total counter\nPollCount_total 7\n")
+	assert.Contains(t, body, "# TYPE requests_total counter\nrequests_total 3\n")
+	assert.NotContains(t, body, "# EOF")
+}
+
+func TestHandler_OpenMetrics(t *testing.T) {
+	handler := prometheus.New(newStorage(t), zap.NewNop())
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, prometheus.ContentTypeOpenMetrics, rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	// В OpenMetrics суффикс _total указывается только в имени значения счетчика.
+	assert.Contains(t, body, "# TYPE PollCount counter\nPollCount_total 7\n")
+	assert.Contains(t, body, "# TYPE requests counter\nrequests_total 3\n")
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+}
+
+func TestHandler_DuplicateSanitizedNames(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop(),
+		storage.Gauges{"cpu.usage": 1, "cpu_usage": 2},
+	)
+	require.NoError(t, err)
+	handler := prometheus.New(st, zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Вторая метрика получает имя с типом, а не отбрасывается.
+	body := rec.Body.String()
+	assert.Equal(t, 1, strings.Count(body, "# TYPE cpu_usage gauge"))
+	assert.Equal(t, 1, strings.Count(body, "# TYPE cpu_usage_gauge gauge"))
+}
+
+func TestHandler_GaugeCounterNameCollision(t *testing.T) {
+	st := mocks.NewRepository(t)
+	st.On("GetMetrics", mock.Anything).Return([]metrics.Metric{
+		*metrics.NewGauge("foo_total", 1.5),
+		*metrics.NewCounter("foo", 3),
+	}, nil)
+	handler := prometheus.New(st, zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE foo_total gauge\nfoo_total 1.5\n")
+	assert.Contains(t, body, "# TYPE foo_counter_total counter\nfoo_counter_total 3\n")
+
+	// В OpenMetrics семейства называются foo_total и foo, но совпадают имена значений.
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	body = rec.Body.String()
+	assert.Contains(t, body, "# TYPE foo_total gauge\nfoo_total 1.5\n")
+	assert.Contains(t, body, "# TYPE foo_counter counter\nfoo_counter_total 3\n")
+}
+
+func TestHandler_EmptyStorage(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	handler := prometheus.New(st, zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil).WithContext(context.Background()))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := map[string]string{
+		"Alloc":                 "Alloc",
+		"go_gc_heap.goal:bytes": "go_gc_heap_goal_bytes",
+		"1st-metric":            "_1st_metric",
+		"DiskReadBytes_sda/1":   "DiskReadBytes_sda_1",
+		"":                      "_",
+		"метрика":               "_______",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, prometheus.SanitizeName(in), in)
+	}
+}
+
+func TestHandler_StorageUnavailable(t *testing.T) {
+	st := mocks.NewRepository(t)
+	st.On("GetMetrics", mock.Anything).Return(nil, fmt.Errorf("%w: connection refused", storage.ErrUnavailable))
+	handler := prometheus.New(st, zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "connection refused")
+}
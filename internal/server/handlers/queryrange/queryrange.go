@@ -0,0 +1,231 @@
+// Package queryrange реализует обработчик GET /api/v1/query_range, возвращающий значения
+// метрики за период, выровненные по сетке с заданным шагом.
+//
+// Ответ совместим по формату с ответом range query Prometheus (resultType matrix),
+// поэтому его можно использовать в инструментах, которые умеют читать этот формат.
+package queryrange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+const (
+	// Lookback максимальный возраст значения, которое используется для точки сетки:
+	// если за это время до точки значений не было, точка в ответ не попадает.
+	// Для агрегата прореженной истории возраст отсчитывается от конца его интервала.
+	Lookback = 5 * time.Minute
+	// MaxPoints максимальное количество точек в ответе.
+	MaxPoints = 11000
+
+	defaultRange = time.Hour
+	defaultStep  = time.Minute
+)
+
+// Response ответ запроса.
+type Response struct {
+	Status    string `json:"status"`
+	Data      *Data  `json:"data,omitempty"`
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Data результат запроса: ряд значений одной метрики или пустой список, если метрика не найдена.
+type Data struct {
+	ResultType string   `json:"resultType"`
+	Result     []Series `json:"result"`
+}
+
+// Series ряд значений метрики. Значения передаются парами [время в секундах Unix, "значение"].
+type Series struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]any          `json:"values"`
+}
+
+// query параметры запроса.
+type query struct {
+	name     string
+	mType    metrics.MetricType
+	from, to time.Time
+	step     time.Duration
+}
+
+// New возвращает обработчик запроса значений метрики за период.
+//
+// Параметры: name и type метрики (обязательные), from и to — границы периода в секундах Unix
+// или в формате RFC 3339 (по умолчанию последний час), step — шаг сетки в формате
+// длительности Go или в секундах (по умолчанию 1m).
+func New(st storage.Repository, log *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		history, ok := st.(storage.HistoryRepository)
+		if !ok {
+			writeError(w, http.StatusNotImplemented, "unavailable", storage.ErrHistoryDisabled)
+			return
+		}
+
+		q, err := parseQuery(r, time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_data", err)
+			return
+		}
+
+		samples, err := history.QueryRange(r.Context(), q.mType, q.name, q.from.Add(-Lookback), q.to)
+		if errors.Is(err, storage.ErrHistoryDisabled) {
+			writeError(w, http.StatusNotImplemented, "unavailable", err)
+			return
+		}
+		if err != nil {
+			log.Error("failed to query metric history", zap.Error(err))
+			status := httperr.Status(err)
+			writeError(w, status, errorType(status), err)
+			return
+		}
+
+		data := &Data{ResultType: "matrix", Result: []Series{}}
+		if values := align(samples, q.from, q.to, q.step); len(values) > 0 {
+			data.Result = append(data.Result, Series{
+				Metric: map[string]string{"__name__": q.name, "type": string(q.mType)},
+				Values: values,
+			})
+		}
+		writeJSON(w, http.StatusOK, Response{Status: "success", Data: data})
+	}
+}
+
+// align возвращает значения в точках from, from+step, ... не позже to. В каждой точке берется
+// последнее значение не старше Lookback (для агрегата — не старше Lookback после конца интервала),
+// точки без таких значений пропускаются. Значения samples должны быть упорядочены по времени.
+func align(samples []storage.Sample, from, to time.Time, step time.Duration) [][2]any {
+	var values [][2]any
+	i := 0
+	for t := from; !t.After(to); t = t.Add(step) {
+		for i < len(samples) && !samples[i].Time.After(t) {
+			i++
+		}
+		if i == 0 {
+			continue
+		}
+		last := samples[i-1]
+		if t.Sub(last.Time) > last.Resolution+Lookback {
+			continue
+		}
+		values = append(values, [2]any{
+			float64(t.UnixMilli()) / 1000,
+			strconv.FormatFloat(last.Value, 'f', -1, 64),
+		})
+	}
+	return values
+}
+
+func parseQuery(r *http.Request, now time.Time) (query, error) {
+	params := r.URL.Query()
+	q := query{
+		name:  params.Get("name"),
+		mType: metrics.MetricType(params.Get("type")),
+		to:    now,
+		step:  defaultStep,
+	}
+	if q.name == "" {
+		return q, errors.New("missing parameter name")
+	}
+	switch q.mType {
+	case metrics.TypeGauge, metrics.TypeCounter:
+	default:
+		return q, errors.New("invalid parameter type, expected gauge or counter")
+	}
+
+	var err error
+	if v := params.Get("to"); v != "" {
+		if q.to, err = parseTime(v); err != nil {
+			return q, fmt.Errorf("invalid parameter to: %w", err)
+		}
+	}
+	q.from = q.to.Add(-defaultRange)
+	if v := params.Get("from"); v != "" {
+		if q.from, err = parseTime(v); err != nil {
+			return q, fmt.Errorf("invalid parameter from: %w", err)
+		}
+	}
+	if v := params.Get("step"); v != "" {
+		if q.step, err = parseDuration(v); err != nil {
+			return q, fmt.Errorf("invalid parameter step: %w", err)
+		}
+	}
+
+	if q.to.Before(q.from) {
+		return q, errors.New("to must not be before from")
+	}
+	if q.step <= 0 {
+		return q, errors.New("step must be positive")
+	}
+	if q.to.Sub(q.from)/q.step >= MaxPoints {
+		return q, fmt.Errorf("exceeded maximum of %d points per series, increase step", MaxPoints)
+	}
+	return q, nil
+}
+
+// parseTime разбирает время в секундах Unix (возможно, с дробной частью) или в формате RFC 3339.
+func parseTime(v string) (time.Time, error) {
+	if sec, err := strconv.ParseFloat(v, 64); err == nil {
+		if math.IsNaN(sec) || math.IsInf(sec, 0) {
+			return time.Time{}, fmt.Errorf("cannot parse %q as timestamp", v)
+		}
+		whole, frac := math.Modf(sec)
+		return time.Unix(int64(whole), int64(frac*float64(time.Second))), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q as timestamp", v)
+	}
+	return t, nil
+}
+
+// parseDuration разбирает длительность в формате Go (например, 15s) или в секундах.
+func parseDuration(v string) (time.Duration, error) {
+	if sec, err := strconv.ParseFloat(v, 64); err == nil {
+		if math.IsNaN(sec) || math.IsInf(sec, 0) || math.Abs(sec) > math.MaxInt64/float64(time.Second) {
+			return 0, fmt.Errorf("cannot parse %q as duration", v)
+		}
+		return time.Duration(sec * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q as duration", v)
+	}
+	return d, nil
+}
+
+// errorType возвращает тип ошибки ответа Prometheus API для кода статуса.
+func errorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_data"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, errorType string, err error) {
+	writeJSON(w, status, Response{Status: "error", ErrorType: errorType, Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,200 @@
+package queryrange_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/queryrange"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+)
+
+// historyStub хранилище с заданной историей значений.
+type historyStub struct {
+	storage.Repository
+	samples  []storage.Sample
+	err      error
+	from, to time.Time
+}
+
+func (s *historyStub) QueryRange(
+	_ context.Context,
+	_ metrics.MetricType,
+	_ string,
+	from, to time.Time,
+) ([]storage.Sample, error) {
+	s.from, s.to = from, to
+	return s.samples, s.err
+}
+
+func serve(t *testing.T, st storage.Repository, target string) (int, queryrange.Response) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	queryrange.New(st, zap.NewNop())(rr, httptest.NewRequest(http.MethodGet, target, nil))
+
+	var resp queryrange.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	return rr.Code, resp
+}
+
+func TestQueryRange_AlignsSamples(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	st := &historyStub{samples: []storage.Sample{
+		{Time: base.Add(-time.Minute), Value: 1},
+		{Time: base.Add(5 * time.Second), Value: 2},
+		{Time: base.Add(25 * time.Second), Value: 3},
+		{Time: base.Add(28 * time.Second), Value: 4},
+	}}
+
+	code, resp := serve(t, st, "/api/v1/query_range?name=HeapAlloc&type=gauge&from=1700000000&to=1700000030&step=10s")
+	require.Equal(t, http.StatusOK, code)
+
+	// Значения до начала периода запрашиваются на глубину Lookback.
+	assert.Equal(t, base.Add(-queryrange.Lookback), st.from)
+	assert.Equal(t, base.Add(30*time.Second), st.to)
+
+	assert.Equal(t, "success", resp.Status)
+	require.Len(t, resp.Data.Result, 1)
+	series := resp.Data.Result[0]
+	assert.Equal(t, map[string]string{"__name__": "HeapAlloc", "type": "gauge"}, series.Metric)
+	assert.Equal(t, [][2]any{
+		{1700000000.0, "1"},
+		{1700000010.0, "2"},
+		{1700000020.0, "2"},
+		{1700000030.0, "4"},
+	}, series.Values)
+}
+
+func TestQueryRange_SkipsStalePoints(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	st := &historyStub{samples: []storage.Sample{{Time: base, Value: 7}}}
+
+	_, resp := serve(t, st, "/api/v1/query_range?name=PollCount&type=counter&from=1700000000&to=1700000600&step=5m")
+	require.Len(t, resp.Data.Result, 1)
+	assert.Equal(t, [][2]any{{1700000000.0, "7"}, {1700000300.0, "7"}}, resp.Data.Result[0].Values)
+
+	st.samples = nil
+	_, resp = serve(t, st, "/api/v1/query_range?name=PollCount&type=counter")
+	assert.Equal(t, "matrix", resp.Data.ResultType)
+	assert.Empty(t, resp.Data.Result)
+}
+
+func TestQueryRange_RollupLookback(t *testing.T) {
+	base := time.Unix(1699999200, 0) // начало часа
+	st := &historyStub{samples: []storage.Sample{{Time: base, Value: 7, Resolution: time.Hour}}}
+
+	// Агрегат за час используется до конца своего интервала и еще Lookback после него.
+	_, resp := serve(t, st, "/api/v1/query_range?name=PollCount&type=counter&from=1700001000&to=1700003400&step=10m")
+	require.Len(t, resp.Data.Result, 1)
+	assert.Equal(t, [][2]any{
+		{1700001000.0, "7"},
+		{1700001600.0, "7"},
+		{1700002200.0, "7"},
+		{1700002800.0, "7"},
+	}, resp.Data.Result[0].Values)
+}
+
+func TestQueryRange_MemStorageDownsampled(t *testing.T) {
+	st, err := memory.New(&app.Config{History: true}, zap.NewNop())
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, st.UpdateMetric(ctx, *metrics.NewCounter("PollCount", 3)))
+	require.NoError(t, st.UpdateMetric(ctx, *metrics.NewCounter("PollCount", 4)))
+
+	now := time.Now()
+	moved, err := st.Downsample(ctx, 0, time.Hour, now.Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), moved)
+
+	// Агрегат начинается в начале часа, то есть обычно раньше начала периода и на больше чем Lookback.
+	code, resp := serve(t, st, "/api/v1/query_range?name=PollCount&type=counter&step=10s&from="+
+		now.Add(-time.Minute).Format(time.RFC3339Nano)+"&to="+now.Format(time.RFC3339Nano))
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Data.Result, 1)
+	values := resp.Data.Result[0].Values
+	require.Len(t, values, 7)
+	for _, v := range values {
+		assert.Equal(t, "7", v[1])
+	}
+}
+
+func TestQueryRange_BadRequest(t *testing.T) {
+	tests := []string{
+		"/api/v1/query_range?type=gauge",
+		"/api/v1/query_range?name=Alloc&type=histogram",
+		"/api/v1/query_range?name=Alloc&type=gauge&from=yesterday",
+		"/api/v1/query_range?name=Alloc&type=gauge&from=2&to=1",
+		"/api/v1/query_range?name=Alloc&type=gauge&step=0",
+		"/api/v1/query_range?name=Alloc&type=gauge&from=0&to=1700000000&step=1s",
+	}
+	for _, target := range tests {
+		t.Run(target, func(t *testing.T) {
+			code, resp := serve(t, &historyStub{}, target)
+			assert.Equal(t, http.StatusBadRequest, code)
+			assert.Equal(t, "error", resp.Status)
+			assert.Equal(t, "bad_data", resp.ErrorType)
+		})
+	}
+}
+
+func TestQueryRange_StorageErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		code      int
+		errorType string
+	}{
+		{"unavailable", fmt.Errorf("%w: connection refused", storage.ErrUnavailable),
+			http.StatusServiceUnavailable, "unavailable"},
+		{"internal", errors.New("scan failed"), http.StatusInternalServerError, "internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := serve(t, &historyStub{err: tt.err}, "/api/v1/query_range?name=Alloc&type=gauge")
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.errorType, resp.ErrorType)
+		})
+	}
+}
+
+func TestQueryRange_RFC3339(t *testing.T) {
+	st := &historyStub{}
+	code, _ := serve(t, st,
+		"/api/v1/query_range?name=Alloc&type=gauge&from=2024-01-01T00:00:00Z&to=2024-01-01T01:00:00Z&step=60")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), st.to.UTC())
+}
+
+func TestQueryRange_HistoryDisabled(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	code, resp := serve(t, st, "/api/v1/query_range?name=Alloc&type=gauge")
+	assert.Equal(t, http.StatusNotImplemented, code)
+	assert.Equal(t, storage.ErrHistoryDisabled.Error(), resp.Error)
+}
+
+func TestQueryRange_MemStorage(t *testing.T) {
+	st, err := memory.New(&app.Config{History: true}, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, st.UpdateMetric(context.Background(), *metrics.NewGauge("Alloc", 12.5)))
+
+	code, resp := serve(t, st, "/api/v1/query_range?name=Alloc&type=gauge&step=1s&from="+
+		time.Now().Add(-time.Second).Format(time.RFC3339Nano))
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, resp.Data.Result, 1)
+	values := resp.Data.Result[0].Values
+	require.NotEmpty(t, values)
+	assert.Equal(t, "12.5", values[len(values)-1][1])
+}
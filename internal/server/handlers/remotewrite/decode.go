@@ -0,0 +1,165 @@
+package remotewrite
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Номера полей сообщений prometheus.WriteRequest (remote write 1.0), которые читает сервер.
+// Остальные поля (метаданные, exemplars, histograms) пропускаются.
+const (
+	fieldWriteRequestTimeseries protowire.Number = 1
+
+	fieldTimeSeriesLabels  protowire.Number = 1
+	fieldTimeSeriesSamples protowire.Number = 2
+
+	fieldLabelName  protowire.Number = 1
+	fieldLabelValue protowire.Number = 2
+
+	fieldSampleValue     protowire.Number = 1
+	fieldSampleTimestamp protowire.Number = 2
+)
+
+var errInvalidMessage = errors.New("invalid protobuf message")
+
+// label метка временного ряда.
+type label struct {
+	Name  string
+	Value string
+}
+
+// sample значение временного ряда в момент времени Timestamp (миллисекунды Unix).
+type sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// timeSeries временной ряд: набор меток (включая __name__) и значения.
+type timeSeries struct {
+	Labels  []label
+	Samples []sample
+}
+
+// decodeWriteRequest разбирает сообщение WriteRequest из несжатого protobuf.
+func decodeWriteRequest(b []byte) ([]timeSeries, error) {
+	var series []timeSeries
+	err := walk(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num != fieldWriteRequestTimeseries || typ != protowire.BytesType {
+			return nil
+		}
+		ts, err := decodeTimeSeries(v)
+		if err != nil {
+			return fmt.Errorf("timeseries: %w", err)
+		}
+		series = append(series, ts)
+		return nil
+	})
+	return series, err
+}
+
+func decodeTimeSeries(b []byte) (timeSeries, error) {
+	var ts timeSeries
+	err := walk(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case fieldTimeSeriesLabels:
+			l, err := decodeLabel(v)
+			if err != nil {
+				return fmt.Errorf("label: %w", err)
+			}
+			ts.Labels = append(ts.Labels, l)
+		case fieldTimeSeriesSamples:
+			s, err := decodeSample(v)
+			if err != nil {
+				return fmt.Errorf("sample: %w", err)
+			}
+			ts.Samples = append(ts.Samples, s)
+		}
+		return nil
+	})
+	return ts, err
+}
+
+func decodeLabel(b []byte) (label, error) {
+	var l label
+	err := walk(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case fieldLabelName:
+			l.Name = string(v)
+		case fieldLabelValue:
+			l.Value = string(v)
+		}
+		return nil
+	})
+	return l, err
+}
+
+func decodeSample(b []byte) (sample, error) {
+	var s sample
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return s, errInvalidMessage
+		}
+		b = b[n:]
+		switch {
+		case num == fieldSampleValue && typ == protowire.Fixed64Type:
+			v, m := protowire.ConsumeFixed64(b)
+			if m < 0 {
+				return s, errInvalidMessage
+			}
+			s.Value = math.Float64frombits(v)
+			n = m
+		case num == fieldSampleTimestamp && typ == protowire.VarintType:
+			v, m := protowire.ConsumeVarint(b)
+			if m < 0 {
+				return s, errInvalidMessage
+			}
+			s.Timestamp = int64(v) //nolint:gosec // int64 в protobuf кодируется как varint без знака
+			n = m
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return s, errInvalidMessage
+			}
+		}
+		b = b[n:]
+	}
+	return s, nil
+}
+
+// walk обходит поля сообщения и передает в fn номер, тип и содержимое полей с длиной.
+// Для полей остальных типов содержимое не передается (nil).
+func walk(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errInvalidMessage
+		}
+		b = b[n:]
+
+		var v []byte
+		if typ == protowire.BytesType {
+			v, n = protowire.ConsumeBytes(b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return errInvalidMessage
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
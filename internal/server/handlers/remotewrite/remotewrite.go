@@ -0,0 +1,196 @@
+// Package remotewrite реализует приемник Prometheus remote write (POST /api/v1/write).
+//
+// Prometheus отправляет сжатые snappy сообщения WriteRequest в формате protobuf.
+// Каждый временной ряд превращается в одну метрику: имя метрики дополняется метками ряда,
+// ряды с суффиксом имени из настроек (по умолчанию _total) сохраняются как counter,
+// остальные как gauge. Все метрики запроса сохраняются одним вызовом UpdateMetrics.
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/golang/snappy"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/cumulative"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/httperr"
+	"github.com/maynagashev/go-metrics/internal/server/metricname"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+const (
+	// MaxBodySize максимальный размер сжатого тела запроса.
+	MaxBodySize = 32 << 20
+	// MaxDecodedSize максимальный размер сообщения после распаковки snappy. Проверяется по заголовку
+	// сжатых данных до распаковки, чтобы небольшое тело не заставило сервер выделить много памяти.
+	MaxDecodedSize = 64 << 20
+	// metricNameLabel метка с именем метрики.
+	metricNameLabel = "__name__"
+)
+
+// handler приемник remote write.
+type handler struct {
+	st              storage.Repository
+	log             *zap.Logger
+	counterSuffixes []string
+	// Prometheus передает счетчики нарастающим итогом, а хранилище накапливает приращения,
+	// поэтому сохраняется разница с предыдущим значением ряда.
+	totals *cumulative.Tracker
+}
+
+// New создает обработчик remote write.
+func New(config *app.Config, st storage.Repository, log *zap.Logger) http.HandlerFunc {
+	h := &handler{
+		st:              st,
+		log:             log,
+		counterSuffixes: config.GetRemoteWriteCounterSuffixes(),
+		totals:          cumulative.New(st),
+	}
+	return h.serveHTTP
+}
+
+// serveHTTP принимает пакет временных рядов и сохраняет его в хранилище.
+// Возвращает 204 при успехе, 400 для некорректного сообщения (Prometheus не повторяет такой запрос)
+// и 500 при ошибке хранилища (Prometheus повторит запрос).
+func (h *handler) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if enc := r.Header.Get("Content-Encoding"); enc != "" && enc != "snappy" {
+		http.Error(w, "unsupported content encoding: "+enc, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	compressed, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if len(compressed) > MaxBodySize {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	decodedLen, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		h.log.Debug("failed to decode snappy body", zap.Error(err))
+		http.Error(w, "failed to decode snappy body", http.StatusBadRequest)
+		return
+	}
+	if decodedLen > MaxDecodedSize {
+		http.Error(w, "decoded request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	raw, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		h.log.Debug("failed to decode snappy body", zap.Error(err))
+		http.Error(w, "failed to decode snappy body", http.StatusBadRequest)
+		return
+	}
+
+	series, err := decodeWriteRequest(raw)
+	if err != nil {
+		h.log.Debug("failed to decode write request", zap.Error(err))
+		http.Error(w, "failed to decode write request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	totals := h.totals.Batch()
+	items := h.convert(r.Context(), totals, series)
+	if len(items) > 0 {
+		if err = h.st.UpdateMetrics(r.Context(), items); err != nil {
+			h.log.Error("failed to store remote write metrics", zap.Error(err))
+			http.Error(w, "failed to store metrics", httperr.Status(err))
+			return
+		}
+	}
+	// Накопленные значения счетчиков запоминаются только после записи приращений,
+	// чтобы повтор запроса Prometheus после ошибки снова сохранил те же приращения.
+	totals.Commit()
+
+	h.log.Debug("remote write request applied",
+		zap.Int("series", len(series)),
+		zap.Int("metrics", len(items)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// convert превращает временные ряды в метрики. Несколько рядов с одинаковым именем
+// объединяются: для gauge берется последнее значение, приращения counter суммируются.
+func (h *handler) convert(ctx context.Context, totals *cumulative.Batch, series []timeSeries) []metrics.Metric {
+	gauges := make(map[string]float64)
+	counters := make(map[string]int64)
+	for _, ts := range series {
+		baseName, name := seriesName(ts.Labels)
+		if name == "" {
+			continue
+		}
+
+		samples := make([]sample, 0, len(ts.Samples))
+		for _, s := range ts.Samples {
+			// NaN используется Prometheus как маркер устаревшего ряда (stale marker).
+			if !math.IsNaN(s.Value) {
+				samples = append(samples, s)
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+
+		if !h.isCounter(baseName) {
+			gauges[name] = samples[len(samples)-1].Value
+			continue
+		}
+		for _, s := range samples {
+			counters[name] += totals.Delta(ctx, name, s.Value)
+		}
+	}
+
+	items := make([]metrics.Metric, 0, len(gauges)+len(counters))
+	for name, value := range gauges {
+		items = append(items, *metrics.NewGauge(name, value))
+	}
+	for name, delta := range counters {
+		items = append(items, *metrics.NewCounter(name, delta))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].MType < items[j].MType
+	})
+	return items
+}
+
+// isCounter возвращает true, если имя метрики оканчивается на один из суффиксов счетчиков.
+func (h *handler) isCounter(name string) bool {
+	for _, suffix := range h.counterSuffixes {
+		if suffix != "" && strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// seriesName возвращает имя метрики ряда из метки __name__ и полное имя, дополненное
+// остальными метками ряда. Пустое имя означает ряд без __name__.
+func seriesName(labels []label) (string, string) {
+	var baseName string
+	rest := make(map[string]string, len(labels))
+	for _, l := range labels {
+		if l.Name == metricNameLabel {
+			baseName = l.Value
+			continue
+		}
+		rest[l.Name] = l.Value
+	}
+	if baseName == "" {
+		return "", ""
+	}
+	return baseName, metricname.WithLabels(baseName, rest)
+}
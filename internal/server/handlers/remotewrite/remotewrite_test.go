@@ -0,0 +1,223 @@
+package remotewrite_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/remotewrite"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/mocks"
+)
+
+type testSample struct {
+	value     float64
+	timestamp int64
+}
+
+type testSeries struct {
+	labels  [][2]string
+	samples []testSample
+}
+
+// encodeWriteRequest кодирует WriteRequest в protobuf и сжимает его snappy.
+func encodeWriteRequest(series ...testSeries) []byte {
+	var req []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l[0])
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l[1])
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, lb)
+		}
+		for _, smp := range s.samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(smp.value))
+			sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(smp.timestamp))
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sb)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+	return snappy.Encode(nil, req)
+}
+
+func post(t *testing.T, handler http.HandlerFunc, body []byte, encoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestHandler_GaugesAndLabels(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	handler := remotewrite.New(&app.Config{}, st, zap.NewNop())
+
+	body := encodeWriteRequest(
+		testSeries{
+			labels:  [][2]string{{"__name__", "node_load1"}},
+			samples: []testSample{{0.5, 1000}, {0.75, 2000}},
+		},
+		testSeries{
+			labels:  [][2]string{{"job", "api"}, {"__name__", "up"}, {"instance", "host:9100"}},
+			samples: []testSample{{1, 1000}},
+		},
+		testSeries{
+			// Ряд без имени пропускается.
+			labels:  [][2]string{{"job", "api"}},
+			samples: []testSample{{1, 1000}},
+		},
+	)
+
+	rec := post(t, handler, body, "snappy")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	ctx := context.Background()
+	v, err := st.GetGauge(ctx, "node_load1")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.75, float64(v), 1e-9)
+
+	v, err = st.GetGauge(ctx, "up_instance_host_9100_job_api")
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, float64(v), 1e-9)
+	count, err := st.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestHandler_CountersAreStoredAsDeltas(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop(), storage.Gauges{}, storage.Counters{"http_requests_total": 10})
+	require.NoError(t, err)
+	handler := remotewrite.New(&app.Config{}, st, zap.NewNop())
+	ctx := context.Background()
+
+	series := func(values ...float64) testSeries {
+		s := testSeries{labels: [][2]string{{"__name__", "http_requests_total"}}}
+		for i, v := range values {
+			s.samples = append(s.samples, testSample{v, int64(i)})
+		}
+		return s
+	}
+
+	// Первое значение ряда сравнивается с сохраненным в хранилище.
+	require.Equal(t, http.StatusNoContent, post(t, handler, encodeWriteRequest(series(25)), "snappy").Code)
+	c, _ := st.GetCounter(ctx, "http_requests_total")
+	assert.Equal(t, storage.Counter(25), c)
+
+	// Повторная отправка того же значения не увеличивает счетчик, маркер устаревшего ряда пропускается.
+	require.Equal(t, http.StatusNoContent,
+		post(t, handler, encodeWriteRequest(series(25, 30, math.Float64frombits(0x7ff0000000000002))), "snappy").Code)
+	c, _ = st.GetCounter(ctx, "http_requests_total")
+	assert.Equal(t, storage.Counter(30), c)
+
+	// Сброс счетчика источника: приращением считается новое значение.
+	require.Equal(t, http.StatusNoContent, post(t, handler, encodeWriteRequest(series(4)), "snappy").Code)
+	c, _ = st.GetCounter(ctx, "http_requests_total")
+	assert.Equal(t, storage.Counter(34), c)
+}
+
+func TestHandler_FailedWriteIsRetried(t *testing.T) {
+	st := new(mocks.Repository)
+	st.On("GetCounter", mock.Anything, "jobs_total").Return(storage.Counter(10), nil)
+	want := []metrics.Metric{*metrics.NewCounter("jobs_total", 15)}
+	st.On("UpdateMetrics", mock.Anything, want).
+		Return(fmt.Errorf("%w: connection refused", storage.ErrUnavailable)).Once()
+	st.On("UpdateMetrics", mock.Anything, want).Return(nil).Once()
+	handler := remotewrite.New(&app.Config{}, st, zap.NewNop())
+
+	body := encodeWriteRequest(testSeries{
+		labels:  [][2]string{{"__name__", "jobs_total"}},
+		samples: []testSample{{25.5, 1}},
+	})
+	// Ошибка записи не должна запоминать накопленное значение: повтор сохраняет то же приращение.
+	assert.Equal(t, http.StatusServiceUnavailable, post(t, handler, body, "snappy").Code)
+	assert.Equal(t, http.StatusNoContent, post(t, handler, body, "snappy").Code)
+	st.AssertExpectations(t)
+
+	// Дробный остаток 0.5 учитывается в следующем приращении.
+	st.On("UpdateMetrics", mock.Anything, []metrics.Metric{*metrics.NewCounter("jobs_total", 1)}).Return(nil).Once()
+	body = encodeWriteRequest(testSeries{
+		labels:  [][2]string{{"__name__", "jobs_total"}},
+		samples: []testSample{{26, 2}},
+	})
+	assert.Equal(t, http.StatusNoContent, post(t, handler, body, "snappy").Code)
+	st.AssertExpectations(t)
+}
+
+func TestHandler_CustomCounterSuffixes(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	cfg := &app.Config{RemoteWriteCounterSuffixes: []string{"_count"}}
+	handler := remotewrite.New(cfg, st, zap.NewNop())
+
+	body := encodeWriteRequest(
+		testSeries{labels: [][2]string{{"__name__", "rpc_count"}}, samples: []testSample{{3, 1}}},
+		testSeries{labels: [][2]string{{"__name__", "rpc_total"}}, samples: []testSample{{5, 1}}},
+	)
+	require.Equal(t, http.StatusNoContent, post(t, handler, body, "").Code)
+
+	ctx := context.Background()
+	c, err := st.GetCounter(ctx, "rpc_count")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(3), c)
+	_, err = st.GetGauge(ctx, "rpc_total")
+	assert.NoError(t, err)
+}
+
+func TestHandler_Errors(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	handler := remotewrite.New(&app.Config{}, st, zap.NewNop())
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		want     int
+	}{
+		{name: "gzip encoding", body: encodeWriteRequest(), encoding: "gzip", want: http.StatusUnsupportedMediaType},
+		{name: "not snappy", body: []byte("plain text"), encoding: "snappy", want: http.StatusBadRequest},
+		{
+			// Заголовок snappy объявляет размер распакованных данных больше допустимого.
+			name:     "decoded body too large",
+			body:     append(binary.AppendUvarint(nil, remotewrite.MaxDecodedSize+1), 0x00),
+			encoding: "snappy",
+			want:     http.StatusRequestEntityTooLarge,
+		},
+		{name: "broken protobuf", body: snappy.Encode(nil, []byte{0x0a, 0xff}), encoding: "snappy", want: http.StatusBadRequest},
+		{name: "empty request", body: encodeWriteRequest(), encoding: "snappy", want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, post(t, handler, tt.body, tt.encoding).Code)
+		})
+	}
+}
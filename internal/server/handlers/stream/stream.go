@@ -0,0 +1,236 @@
+// Package stream реализует подписку на обновления метрик по Server-Sent Events и WebSocket.
+//
+// GET /stream отдает поток событий text/event-stream, а при запросе с заголовками
+// Upgrade: websocket устанавливает соединение WebSocket. Параметры запроса type (gauge или counter)
+// и prefix (несколько значений или через запятую) ограничивают передаваемые метрики.
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/pubsub"
+)
+
+const (
+	// Интервал комментариев SSE и ping-кадров WebSocket, поддерживающих соединение.
+	heartbeatInterval = 15 * time.Second
+	// Максимальное время записи сообщения WebSocket.
+	wsWriteTimeout = 10 * time.Second
+)
+
+// Названия событий потока.
+const (
+	EventMetric  = "metric"
+	EventDropped = "dropped"
+)
+
+// Message сообщение WebSocket: событие и его данные.
+type Message struct {
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// Dropped данные события dropped: общее количество обновлений, пропущенных подписчиком
+// из-за переполнения буфера.
+type Dropped struct {
+	Dropped uint64 `json:"dropped"`
+}
+
+type handler struct {
+	hub      *pubsub.Hub
+	log      *zap.Logger
+	upgrader websocket.Upgrader
+}
+
+// New возвращает обработчик подписки на обновления метрик.
+func New(hub *pubsub.Hub, log *zap.Logger) http.HandlerFunc {
+	h := &handler{
+		hub: hub,
+		log: log,
+		upgrader: websocket.Upgrader{
+			// Дашборды могут открываться с других доменов, данные метрик не требуют защиты от CSRF.
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+	}
+	return h.serveHTTP
+}
+
+func (h *handler) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebSocket(w, r, filter)
+		return
+	}
+	h.serveSSE(w, r, filter)
+}
+
+// serveSSE передает обновления событиями Server-Sent Events до отключения клиента.
+func (h *handler) serveSSE(w http.ResponseWriter, r *http.Request, filter pubsub.Filter) {
+	rc := http.NewResponseController(w)
+	sub, err := h.hub.Subscribe(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	// Поток длится дольше WriteTimeout сервера.
+	if err = rc.SetWriteDeadline(time.Time{}); err != nil {
+		h.log.Debug("failed to reset write deadline", zap.Error(err))
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err = fmt.Fprint(w, ": connected\n\n"); err != nil {
+		return
+	}
+	if err = rc.Flush(); err != nil {
+		h.log.Error("streaming is not supported by response writer", zap.Error(err))
+		return
+	}
+
+	h.log.Info("stream subscriber connected", zap.String("remote_addr", r.RemoteAddr), zap.Any("filter", filter))
+	defer h.log.Info("stream subscriber disconnected", zap.String("remote_addr", r.RemoteAddr))
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	var reported uint64
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			if d := sub.Dropped(); d > reported {
+				reported = d
+				err = writeEvent(w, EventDropped, Dropped{Dropped: d})
+			}
+			if err == nil {
+				err = writeEvent(w, EventMetric, m)
+			}
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent записывает событие SSE с данными в формате JSON.
+func writeEvent(w http.ResponseWriter, event string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+	return err
+}
+
+// serveWebSocket передает обновления сообщениями Message до закрытия соединения.
+func (h *handler) serveWebSocket(w http.ResponseWriter, r *http.Request, filter pubsub.Filter) {
+	sub, err := h.hub.Subscribe(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту ошибкой.
+		h.log.Debug("websocket upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	h.log.Info("websocket subscriber connected", zap.String("remote_addr", r.RemoteAddr), zap.Any("filter", filter))
+	defer h.log.Info("websocket subscriber disconnected", zap.String("remote_addr", r.RemoteAddr))
+
+	// Чтение нужно для обработки управляющих кадров и обнаружения закрытия соединения клиентом,
+	// сообщения клиента не используются.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, readErr := conn.NextReader(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	var reported uint64
+	for {
+		select {
+		case <-closed:
+			return
+		case m, ok := <-sub.C():
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down"),
+					time.Now().Add(wsWriteTimeout))
+				return
+			}
+			if d := sub.Dropped(); d > reported {
+				reported = d
+				err = writeMessage(conn, Message{Event: EventDropped, Data: Dropped{Dropped: d}})
+			}
+			if err == nil {
+				err = writeMessage(conn, Message{Event: EventMetric, Data: m})
+			}
+		case <-ticker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeMessage(conn *websocket.Conn, msg Message) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(msg)
+}
+
+// parseFilter разбирает параметры отбора метрик из запроса.
+func parseFilter(r *http.Request) (pubsub.Filter, error) {
+	q := r.URL.Query()
+	f := pubsub.Filter{Type: metrics.MetricType(q.Get("type"))}
+	switch f.Type {
+	case "", metrics.TypeGauge, metrics.TypeCounter:
+	default:
+		return f, errors.New("invalid metric type, expected gauge or counter")
+	}
+	for _, v := range q["prefix"] {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				f.Prefixes = append(f.Prefixes, p)
+			}
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,106 @@
+package stream_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/stream"
+	"github.com/maynagashev/go-metrics/internal/server/pubsub"
+)
+
+// waitSubscribers ожидает подключения n подписчиков.
+func waitSubscribers(t *testing.T, hub *pubsub.Hub, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return hub.Len() == n }, time.Second, 5*time.Millisecond)
+}
+
+func TestSSE(t *testing.T) {
+	hub := pubsub.NewHub(10)
+	srv := httptest.NewServer(stream.New(hub, zap.NewNop()))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?type=counter&prefix=Poll", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	waitSubscribers(t, hub, 1)
+
+	hub.Publish(*metrics.NewGauge("PollInterval", 1), *metrics.NewCounter("PollCount", 7))
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, readErr := reader.ReadString('\n')
+		require.NoError(t, readErr)
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	assert.Equal(t, []string{
+		"event: metric",
+		`data: {"id":"PollCount","type":"counter","delta":7}`,
+	}, lines)
+
+	// После отключения клиента подписка отменяется.
+	cancel()
+	waitSubscribers(t, hub, 0)
+}
+
+func TestSSE_InvalidType(t *testing.T) {
+	hub := pubsub.NewHub(10)
+	rr := httptest.NewRecorder()
+	stream.New(hub, zap.NewNop())(rr, httptest.NewRequest(http.MethodGet, "/stream?type=histogram", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, 0, hub.Len())
+}
+
+func TestSSE_HubClosed(t *testing.T) {
+	hub := pubsub.NewHub(10)
+	hub.Close()
+	rr := httptest.NewRecorder()
+	stream.New(hub, zap.NewNop())(rr, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestWebSocket(t *testing.T) {
+	hub := pubsub.NewHub(10)
+	srv := httptest.NewServer(stream.New(hub, zap.NewNop()))
+	defer srv.Close()
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?prefix=Alloc", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	defer conn.Close()
+	waitSubscribers(t, hub, 1)
+
+	hub.Publish(*metrics.NewGauge("HeapAlloc", 3), *metrics.NewGauge("Alloc", 1))
+
+	var msg stream.Message
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, stream.EventMetric, msg.Event)
+	assert.Equal(t, map[string]any{"id": "Alloc", "type": "gauge", "value": 1.0}, msg.Data)
+
+	// Остановка Hub закрывает соединение.
+	hub.Close()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
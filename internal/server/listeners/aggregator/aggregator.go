@@ -0,0 +1,275 @@
+// Package aggregator накапливает значения, полученные приемниками метрик (Graphite, StatsD),
+// в течение окна агрегации и сохраняет их в хранилище одним вызовом UpdateMetrics.
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/metricname"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// DefaultFlushInterval окно агрегации по умолчанию.
+const DefaultFlushInterval = 10 * time.Second
+
+// gaugeValue значение gauge. Если relative равен true, значение прибавляется
+// к сохраненному в хранилище при записи (относительные gauge StatsD: +5, -3).
+type gaugeValue struct {
+	value    float64
+	relative bool
+}
+
+// timerStats статистика значений таймера за окно агрегации.
+type timerStats struct {
+	base   string
+	labels map[string]string
+	// Количество значений с учетом частоты выборки.
+	count    float64
+	samples  int
+	sum      float64
+	min, max float64
+}
+
+// Aggregator накапливает значения метрик и периодически сохраняет их в хранилище.
+// Реализует app.Listener: Serve выполняет периодическую запись, Shutdown записывает
+// оставшиеся значения.
+type Aggregator struct {
+	st       storage.Repository
+	log      *zap.Logger
+	interval time.Duration
+
+	mu       sync.Mutex
+	gauges   map[string]gaugeValue
+	counters map[string]float64
+	timers   map[string]*timerStats
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	// Запущена периодическая запись: Shutdown ожидает ее завершения.
+	serving atomic.Bool
+}
+
+// New создает Aggregator с окном агрегации interval.
+func New(st storage.Repository, log *zap.Logger, interval time.Duration) *Aggregator {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	return &Aggregator{
+		st:       st,
+		log:      log,
+		interval: interval,
+		gauges:   make(map[string]gaugeValue),
+		counters: make(map[string]float64),
+		timers:   make(map[string]*timerStats),
+		stopCh:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Gauge устанавливает значение gauge.
+func (a *Aggregator) Gauge(name string, value float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.gauges[name] = gaugeValue{value: value}
+}
+
+// AddGauge изменяет значение gauge на delta.
+func (a *Aggregator) AddGauge(name string, delta float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	g, ok := a.gauges[name]
+	if !ok {
+		g.relative = true
+	}
+	g.value += delta
+	a.gauges[name] = g
+}
+
+// Counter увеличивает counter на delta. Дробные приращения (с учетом частоты выборки)
+// суммируются, при записи сохраняется целая часть, а дробный остаток переносится в следующее окно.
+func (a *Aggregator) Counter(name string, delta float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.counters[name] += delta
+}
+
+// Timing добавляет значение таймера. При записи для таймера base с метками labels сохраняются
+// counter <base>_count и gauge <base>_sum, <base>_min, <base>_max, <base>_mean за окно агрегации.
+// rate частота выборки значения (0 < rate <= 1).
+func (a *Aggregator) Timing(base string, labels map[string]string, value, rate float64) {
+	key := metricname.WithLabels(base, labels)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t, ok := a.timers[key]
+	if !ok {
+		t = &timerStats{base: base, labels: labels, min: value, max: value}
+		a.timers[key] = t
+	}
+	t.count += 1 / rate
+	t.samples++
+	t.sum += value
+	t.min = math.Min(t.min, value)
+	t.max = math.Max(t.max, value)
+}
+
+// Serve периодически записывает накопленные значения до вызова Shutdown.
+func (a *Aggregator) Serve() error {
+	a.serving.Store(true)
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.Flush(context.Background()); err != nil {
+				a.log.Error("failed to flush aggregated metrics", zap.Error(err))
+			}
+		case <-a.stopCh:
+			return nil
+		}
+	}
+}
+
+// Shutdown останавливает периодическую запись и записывает оставшиеся значения.
+// Если Serve не был запущен (например, не удалось запустить приемник), только записывает значения.
+func (a *Aggregator) Shutdown(ctx context.Context) error {
+	a.stopOnce.Do(func() { close(a.stopCh) })
+	if a.serving.Load() {
+		select {
+		case <-a.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return a.Flush(ctx)
+}
+
+// Flush записывает накопленные значения в хранилище. При ошибке значения возвращаются
+// в окно агрегации и будут записаны при следующей попытке.
+func (a *Aggregator) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	gauges, counters, timers := a.gauges, a.counters, a.timers
+	a.gauges = make(map[string]gaugeValue)
+	a.counters = make(map[string]float64)
+	a.timers = make(map[string]*timerStats)
+	a.mu.Unlock()
+
+	for _, t := range timers {
+		name := func(suffix string) string { return metricname.WithLabels(t.base+suffix, t.labels) }
+		counters[name("_count")] += t.count
+		gauges[name("_sum")] = gaugeValue{value: t.sum}
+		gauges[name("_min")] = gaugeValue{value: t.min}
+		gauges[name("_max")] = gaugeValue{value: t.max}
+		gauges[name("_mean")] = gaugeValue{value: t.sum / float64(t.samples)}
+	}
+
+	// Относительные gauge прибавляются к значению в хранилище. Отсутствующий gauge считается
+	// нулевым, а при другой ошибке значения возвращаются в окно, чтобы не перезаписать gauge
+	// значением, отсчитанным от нуля.
+	resolved := make(map[string]gaugeValue, len(gauges))
+	for name, g := range gauges {
+		if g.relative {
+			stored, err := a.st.GetGauge(ctx, name)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				a.restore(gauges, counters)
+				return fmt.Errorf("failed to get gauge %s: %w", name, err)
+			}
+			g = gaugeValue{value: g.value + float64(stored)}
+		}
+		resolved[name] = g
+	}
+
+	items := make([]metrics.Metric, 0, len(gauges)+len(counters))
+	for name, g := range resolved {
+		items = append(items, *metrics.NewGauge(name, g.value))
+	}
+	wholes := make(map[string]float64, len(counters))
+	remainders := make(map[string]float64)
+	for name, delta := range counters {
+		whole, remainder := split(delta)
+		if remainder != 0 {
+			remainders[name] = remainder
+		}
+		if whole != 0 {
+			wholes[name] = float64(whole)
+			items = append(items, *metrics.NewCounter(name, whole))
+		}
+	}
+	a.carry(remainders)
+	if len(items) == 0 {
+		return nil
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].MType < items[j].MType
+	})
+
+	if err := a.st.UpdateMetrics(ctx, items); err != nil {
+		a.restore(resolved, wholes)
+		return err
+	}
+	a.log.Debug("aggregated metrics flushed", zap.Int("metrics", len(items)))
+	return nil
+}
+
+// split делит приращение counter на целую часть и дробный остаток. Погрешность суммирования
+// дробных приращений (0.1 * 10) не оставляет остаток, близкий к единице.
+func split(delta float64) (int64, float64) {
+	const epsilon = 1e-9
+	whole := math.Trunc(delta)
+	if rounded := math.Round(delta); math.Abs(delta-rounded) < epsilon {
+		whole = rounded
+	}
+	remainder := delta - whole
+	if math.Abs(remainder) < epsilon {
+		remainder = 0
+	}
+	return int64(whole), remainder
+}
+
+// carry переносит дробные остатки приращений counter в текущее окно агрегации.
+func (a *Aggregator) carry(remainders map[string]float64) {
+	if len(remainders) == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name, remainder := range remainders {
+		a.counters[name] += remainder
+	}
+}
+
+// restore возвращает не записанные значения в окно агрегации: приращения counter суммируются,
+// значения gauge восстанавливаются, если за время записи не получены новые. Изменения gauge,
+// полученные за время записи, применяются поверх восстановленного значения.
+func (a *Aggregator) restore(gauges map[string]gaugeValue, counters map[string]float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name, delta := range counters {
+		a.counters[name] += delta
+	}
+	for name, g := range gauges {
+		current, ok := a.gauges[name]
+		switch {
+		case !ok:
+			a.gauges[name] = g
+		case current.relative:
+			a.gauges[name] = gaugeValue{value: g.value + current.value, relative: g.relative}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package aggregator_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/listeners/aggregator"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/mocks"
+)
+
+func TestAggregator_Flush(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop(), storage.Gauges{"queue": 10}, storage.Counters{"hits": 1})
+	require.NoError(t, err)
+	agg := aggregator.New(st, zap.NewNop(), time.Hour)
+	ctx := context.Background()
+
+	agg.Gauge("temp", 20)
+	agg.Gauge("temp", 21.5)
+	agg.AddGauge("queue", 5)
+	agg.AddGauge("queue", -2)
+	agg.Counter("hits", 2)
+	agg.Counter("hits", 2.5)
+	agg.Timing("latency", map[string]string{"route": "api"}, 10, 1)
+	agg.Timing("latency", map[string]string{"route": "api"}, 30, 0.5)
+
+	require.NoError(t, agg.Flush(ctx))
+
+	gauges := map[string]float64{
+		"temp":                   21.5,
+		"queue":                  13,
+		"latency_sum_route_api":  40,
+		"latency_min_route_api":  10,
+		"latency_max_route_api":  30,
+		"latency_mean_route_api": 20,
+	}
+	for name, want := range gauges {
+		v, err := st.GetGauge(ctx, name)
+		require.NoError(t, err, name)
+		assert.InDelta(t, want, float64(v), 1e-9, name)
+	}
+
+	c, _ := st.GetCounter(ctx, "hits")
+	assert.Equal(t, storage.Counter(5), c) // 1 + 4, остаток 0.5 переносится в следующее окно
+	c, _ = st.GetCounter(ctx, "latency_count_route_api")
+	assert.Equal(t, storage.Counter(3), c) // 1 + 1/0.5
+
+	// Окно с одним дробным остатком ничего не записывает.
+	before, err := st.Count(ctx)
+	require.NoError(t, err)
+	require.NoError(t, agg.Flush(ctx))
+	after, err := st.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+	c, _ = st.GetCounter(ctx, "hits")
+	assert.Equal(t, storage.Counter(5), c)
+
+	// Остаток складывается с приращениями следующего окна.
+	agg.Counter("hits", 0.5)
+	require.NoError(t, agg.Flush(ctx))
+	c, _ = st.GetCounter(ctx, "hits")
+	assert.Equal(t, storage.Counter(6), c)
+}
+
+func TestAggregator_FractionalCounters(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	agg := aggregator.New(st, zap.NewNop(), time.Hour)
+	ctx := context.Background()
+
+	// Значения StatsD с частотой выборки 0.3 дают дробные приращения, которые не теряются между окнами.
+	for range 9 {
+		agg.Counter("sampled", 1/0.3)
+		require.NoError(t, agg.Flush(ctx))
+	}
+	c, err := st.GetCounter(ctx, "sampled")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(30), c)
+}
+
+func TestAggregator_ShutdownWithoutServe(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	agg := aggregator.New(st, zap.NewNop(), time.Hour)
+	agg.Counter("events", 2)
+
+	// Serve не запускался: Shutdown не ждет его завершения и записывает накопленные значения.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, agg.Shutdown(ctx))
+
+	c, err := st.GetCounter(ctx, "events")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(2), c)
+}
+
+func TestAggregator_ShutdownFlushesPending(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	agg := aggregator.New(st, zap.NewNop(), time.Hour)
+
+	served := make(chan error, 1)
+	go func() { served <- agg.Serve() }()
+
+	agg.Counter("events", 3)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, agg.Shutdown(ctx))
+	require.NoError(t, <-served)
+
+	c, err := st.GetCounter(ctx, "events")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(3), c)
+}
+
+func TestAggregator_RelativeGaugeStorageError(t *testing.T) {
+	st := new(mocks.Repository)
+	agg := aggregator.New(st, zap.NewNop(), time.Hour)
+	ctx := context.Background()
+
+	// Хранилище недоступно: относительный gauge не отсчитывается от нуля, значения остаются в окне
+	st.On("GetGauge", mock.Anything, "queue").Return(storage.Gauge(0), storage.ErrUnavailable).Once()
+	agg.AddGauge("queue", 5)
+	agg.Counter("hits", 1.5)
+	require.ErrorIs(t, agg.Flush(ctx), storage.ErrUnavailable)
+	st.AssertNotCalled(t, "UpdateMetrics", mock.Anything, mock.Anything)
+
+	// Изменения, полученные до следующей записи, складываются с возвращенными
+	agg.AddGauge("queue", -2)
+	st.On("GetGauge", mock.Anything, "queue").Return(storage.Gauge(10), nil).Once()
+	st.On("UpdateMetrics", mock.Anything, []metrics.Metric{
+		*metrics.NewCounter("hits", 1),
+		*metrics.NewGauge("queue", 13),
+	}).Return(nil).Once()
+	require.NoError(t, agg.Flush(ctx))
+	st.AssertExpectations(t)
+}
+
+func TestAggregator_RelativeGaugeNotFound(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	agg := aggregator.New(st, zap.NewNop(), time.Hour)
+	ctx := context.Background()
+
+	// Отсутствующий gauge считается нулевым
+	agg.AddGauge("queue", -3)
+	require.NoError(t, agg.Flush(ctx))
+	v, err := st.GetGauge(ctx, "queue")
+	require.NoError(t, err)
+	assert.InDelta(t, -3.0, float64(v), 1e-9)
+}
@@ -0,0 +1,174 @@
+// Package graphite реализует прием метрик по протоколу Graphite plaintext через TCP.
+//
+// Каждая строка имеет вид "<путь> <значение> [<метка времени>]", путь может содержать теги
+// в формате Graphite: "<путь>;тег=значение;тег2=значение2". Значения сохраняются как gauge
+// с именем, в котором недопустимые символы пути заменены на подчеркивание, дополненным тегами.
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/server/listeners/aggregator"
+	"github.com/maynagashev/go-metrics/internal/server/metricname"
+)
+
+// maxLineSize максимальная длина строки протокола.
+const maxLineSize = 64 * 1024
+
+// Listener TCP-сервер приема метрик Graphite.
+type Listener struct {
+	ln     net.Listener
+	agg    *aggregator.Aggregator
+	log    *zap.Logger
+	closed atomic.Bool
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// Listen открывает TCP-порт addr для приема метрик, значения передаются в agg.
+func Listen(addr string, agg *aggregator.Aggregator, log *zap.Logger) (*Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("graphite listener: %w", err)
+	}
+	return &Listener{ln: ln, agg: agg, log: log, conns: make(map[net.Conn]struct{})}, nil
+}
+
+// Addr возвращает адрес, на котором принимаются соединения.
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// Serve принимает соединения до вызова Shutdown.
+func (l *Listener) Serve() error {
+	l.log.Info("graphite listener started", zap.String("addr", l.Addr().String()))
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if l.closed.Load() {
+				return nil
+			}
+			return fmt.Errorf("graphite listener: %w", err)
+		}
+
+		l.mu.Lock()
+		if l.closed.Load() {
+			// Соединение принято одновременно с вызовом Shutdown.
+			l.mu.Unlock()
+			_ = conn.Close()
+			return nil
+		}
+		l.conns[conn] = struct{}{}
+		l.wg.Add(1)
+		l.mu.Unlock()
+		go l.handle(conn)
+	}
+}
+
+// Shutdown прекращает прием соединений, закрывает открытые соединения
+// и дожидается обработки уже прочитанных строк.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	l.closed.Store(true)
+	err := l.ln.Close()
+
+	l.mu.Lock()
+	for conn := range l.conns {
+		_ = conn.Close()
+	}
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (l *Listener) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		l.wg.Done()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		name, value, err := ParseLine(line)
+		if err != nil {
+			l.log.Debug("invalid graphite line",
+				zap.String("remote", conn.RemoteAddr().String()),
+				zap.String("line", line),
+				zap.Error(err))
+			continue
+		}
+		l.agg.Gauge(name, value)
+	}
+	if err := scanner.Err(); err != nil && !l.closed.Load() {
+		l.log.Debug("graphite connection closed", zap.Error(err))
+	}
+}
+
+// ParseLine разбирает строку Graphite plaintext и возвращает имя метрики и значение.
+// Метка времени проверяется, но не используется: значения агрегируются по времени получения.
+func ParseLine(line string) (string, float64, error) {
+	parts := strings.Fields(line)
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", 0, errors.New("expected \"<path> <value> [<timestamp>]\"")
+	}
+
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return "", 0, fmt.Errorf("invalid value %q", parts[1])
+	}
+	if len(parts) == 3 {
+		if _, err = strconv.ParseFloat(parts[2], 64); err != nil {
+			return "", 0, fmt.Errorf("invalid timestamp %q", parts[2])
+		}
+	}
+
+	path, tagList, _ := strings.Cut(parts[0], ";")
+	if path == "" {
+		return "", 0, errors.New("empty metric path")
+	}
+	var tags map[string]string
+	if tagList != "" {
+		tags = make(map[string]string)
+		for _, tag := range strings.Split(tagList, ";") {
+			k, v, ok := strings.Cut(tag, "=")
+			if !ok || k == "" || v == "" {
+				return "", 0, fmt.Errorf("invalid tag %q", tag)
+			}
+			tags[k] = v
+		}
+	}
+	return metricname.WithLabels(metricname.Sanitize(path), tags), value, nil
+}
@@ -0,0 +1,85 @@
+package graphite_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/listeners/aggregator"
+	"github.com/maynagashev/go-metrics/internal/server/listeners/graphite"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantName  string
+		wantValue float64
+		wantErr   bool
+	}{
+		{line: "servers.web1.cpu 12.5 1700000000", wantName: "servers_web1_cpu", wantValue: 12.5},
+		{line: "disk.used 42", wantName: "disk_used", wantValue: 42},
+		{line: "disk.used;host=web-1;mount=/ 7 1700000000", wantName: "disk_used_host_web_1_mount__", wantValue: 7},
+		{line: "disk.used", wantErr: true},
+		{line: "disk.used abc", wantErr: true},
+		{line: "disk.used 1 soon", wantErr: true},
+		{line: "disk.used;host 1", wantErr: true},
+		{line: "disk.used NaN", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			name, value, err := graphite.ParseLine(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, name)
+			assert.InDelta(t, tt.wantValue, value, 1e-9)
+		})
+	}
+}
+
+func TestListener(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	agg := aggregator.New(st, zap.NewNop(), time.Hour)
+	l, err := graphite.Listen("127.0.0.1:0", agg, zap.NewNop())
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- l.Serve() }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	_, err = fmt.Fprint(conn, "app.requests 5 1700000000\ninvalid line here now\napp.requests 7\n")
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool {
+		require.NoError(t, agg.Flush(ctx))
+		_, err := st.GetGauge(ctx, "app_requests")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	v, _ := st.GetGauge(ctx, "app_requests")
+	assert.InDelta(t, 7.0, float64(v), 1e-9)
+
+	// Открытое соединение не мешает остановке.
+	idle, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer idle.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, l.Shutdown(shutdownCtx))
+	require.NoError(t, <-served)
+}
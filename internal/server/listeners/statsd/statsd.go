@@ -0,0 +1,199 @@
+// Package statsd реализует прием метрик по протоколу StatsD через UDP.
+//
+// Пакет может содержать несколько строк вида "<имя>:<значение>|<тип>[|@<частота>][|#<теги>]":
+//   - c — counter, значение делится на частоту выборки;
+//   - g — gauge, значение со знаком + или - изменяет текущее значение;
+//   - ms и h — таймер, за окно агрегации сохраняются counter <имя>_count и gauge <имя>_sum,
+//     <имя>_min, <имя>_max и <имя>_mean.
+//
+// Теги в формате DogStatsD (#тег:значение,тег2:значение2) добавляются к имени метрики.
+// Множества (s) не поддерживаются.
+package statsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/server/listeners/aggregator"
+	"github.com/maynagashev/go-metrics/internal/server/metricname"
+)
+
+// maxPacketSize максимальный размер UDP-пакета.
+const maxPacketSize = 64 * 1024
+
+// Типы метрик StatsD.
+const (
+	TypeCounter   = "c"
+	TypeGauge     = "g"
+	TypeTimer     = "ms"
+	TypeHistogram = "h"
+)
+
+// Sample значение метрики из строки StatsD.
+type Sample struct {
+	Name  string
+	Tags  map[string]string
+	Value float64
+	Type  string
+	// Частота выборки, 1 если не указана.
+	Rate float64
+	// Relative true для gauge со знаком: значение изменяет текущее.
+	Relative bool
+}
+
+// Listener UDP-сервер приема метрик StatsD.
+type Listener struct {
+	conn   net.PacketConn
+	agg    *aggregator.Aggregator
+	log    *zap.Logger
+	closed atomic.Bool
+	done   chan struct{}
+}
+
+// Listen открывает UDP-порт addr для приема метрик, значения передаются в agg.
+func Listen(addr string, agg *aggregator.Aggregator, log *zap.Logger) (*Listener, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("statsd listener: %w", err)
+	}
+	return &Listener{conn: conn, agg: agg, log: log, done: make(chan struct{})}, nil
+}
+
+// Addr возвращает адрес, на котором принимаются пакеты.
+func (l *Listener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// Serve принимает пакеты до вызова Shutdown.
+func (l *Listener) Serve() error {
+	defer close(l.done)
+	l.log.Info("statsd listener started", zap.String("addr", l.Addr().String()))
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, remote, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			if l.closed.Load() {
+				return nil
+			}
+			return fmt.Errorf("statsd listener: %w", err)
+		}
+		l.handlePacket(string(buf[:n]), remote)
+	}
+}
+
+// Shutdown прекращает прием пакетов и дожидается обработки последнего прочитанного пакета.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	l.closed.Store(true)
+	err := l.conn.Close()
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (l *Listener) handlePacket(packet string, remote net.Addr) {
+	for _, line := range strings.Split(packet, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		s, err := ParseLine(line)
+		if err != nil {
+			l.log.Debug("invalid statsd line",
+				zap.String("remote", remote.String()),
+				zap.String("line", line),
+				zap.Error(err))
+			continue
+		}
+
+		base := metricname.Sanitize(s.Name)
+		switch s.Type {
+		case TypeCounter:
+			l.agg.Counter(metricname.WithLabels(base, s.Tags), s.Value/s.Rate)
+		case TypeGauge:
+			name := metricname.WithLabels(base, s.Tags)
+			if s.Relative {
+				l.agg.AddGauge(name, s.Value)
+			} else {
+				l.agg.Gauge(name, s.Value)
+			}
+		case TypeTimer, TypeHistogram:
+			l.agg.Timing(base, s.Tags, s.Value, s.Rate)
+		}
+	}
+}
+
+// ParseLine разбирает строку StatsD.
+func ParseLine(line string) (Sample, error) {
+	s := Sample{Rate: 1}
+
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return s, errors.New("expected \"<name>:<value>|<type>\"")
+	}
+	s.Name = name
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return s, errors.New("missing metric type")
+	}
+
+	s.Type = parts[1]
+	switch s.Type {
+	case TypeCounter, TypeGauge, TypeTimer, TypeHistogram:
+	default:
+		return s, fmt.Errorf("unsupported metric type %q", s.Type)
+	}
+
+	raw := parts[0]
+	s.Relative = s.Type == TypeGauge && (strings.HasPrefix(raw, "+") || strings.HasPrefix(raw, "-"))
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return s, fmt.Errorf("invalid value %q", raw)
+	}
+	s.Value = value
+
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			rate, rateErr := strconv.ParseFloat(part[1:], 64)
+			if rateErr != nil || rate <= 0 || rate > 1 {
+				return s, fmt.Errorf("invalid sample rate %q", part)
+			}
+			s.Rate = rate
+		case strings.HasPrefix(part, "#"):
+			s.Tags = parseTags(part[1:])
+		}
+	}
+	return s, nil
+}
+
+// parseTags разбирает теги DogStatsD. Тег без значения добавляется со значением true.
+func parseTags(list string) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(list, ",") {
+		k, v, ok := strings.Cut(tag, ":")
+		if k == "" {
+			continue
+		}
+		if !ok {
+			v = "true"
+		}
+		tags[k] = v
+	}
+	return tags
+}
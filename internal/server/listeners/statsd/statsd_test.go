@@ -0,0 +1,94 @@
+package statsd_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/listeners/aggregator"
+	"github.com/maynagashev/go-metrics/internal/server/listeners/statsd"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    statsd.Sample
+		wantErr bool
+	}{
+		{
+			line: "api.hits:1|c|@0.1",
+			want: statsd.Sample{Name: "api.hits", Value: 1, Type: statsd.TypeCounter, Rate: 0.1},
+		},
+		{
+			line: "queue:-3|g",
+			want: statsd.Sample{Name: "queue", Value: -3, Type: statsd.TypeGauge, Rate: 1, Relative: true},
+		},
+		{
+			line: "db.query:12.5|ms|#table:users,primary",
+			want: statsd.Sample{
+				Name: "db.query", Value: 12.5, Type: statsd.TypeTimer, Rate: 1,
+				Tags: map[string]string{"table": "users", "primary": "true"},
+			},
+		},
+		{line: "users:42|s", wantErr: true},
+		{line: "api.hits:1", wantErr: true},
+		{line: "api.hits:x|c", wantErr: true},
+		{line: "api.hits:1|c|@2", wantErr: true},
+		{line: ":1|c", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			s, err := statsd.ParseLine(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s)
+		})
+	}
+}
+
+func TestListener(t *testing.T) {
+	st, err := memory.New(&app.Config{}, zap.NewNop(), storage.Gauges{"queue": 10}, storage.Counters{})
+	require.NoError(t, err)
+	agg := aggregator.New(st, zap.NewNop(), time.Hour)
+	l, err := statsd.Listen("127.0.0.1:0", agg, zap.NewNop())
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- l.Serve() }()
+
+	conn, err := net.Dial("udp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("api.hits:1|c|@0.5\napi.hits:3|c\nqueue:+2|g\nbroken\nreq.time:20|ms"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool {
+		require.NoError(t, agg.Flush(ctx))
+		_, err := st.GetCounter(ctx, "api_hits")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	c, _ := st.GetCounter(ctx, "api_hits")
+	assert.Equal(t, storage.Counter(5), c)
+	g, _ := st.GetGauge(ctx, "queue")
+	assert.InDelta(t, 12.0, float64(g), 1e-9)
+	g, _ = st.GetGauge(ctx, "req_time_mean")
+	assert.InDelta(t, 20.0, float64(g), 1e-9)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, l.Shutdown(shutdownCtx))
+	require.NoError(t, <-served)
+}
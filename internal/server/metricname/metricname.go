@@ -0,0 +1,45 @@
+// Package metricname формирует имена метрик сервера из имен и меток внешних форматов
+// (Prometheus remote write, OTLP, Influx line protocol): у метрик сервера нет меток,
+// поэтому метки добавляются к имени метрики.
+package metricname
+
+import (
+	"sort"
+	"strings"
+)
+
+// Sanitize заменяет символы, недопустимые в имени метрики, на подчеркивание.
+func Sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// WithLabels дополняет имя метрики метками в порядке их имен, имена и значения меток
+// приводятся к допустимому виду: http_requests_total{code="200",job="api"} превращается
+// в http_requests_total_code_200_job_api. Имя метрики не изменяется.
+func WithLabels(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, key := range keys {
+		b.WriteByte('_')
+		b.WriteString(Sanitize(key))
+		b.WriteByte('_')
+		b.WriteString(Sanitize(labels[key]))
+	}
+	return b.String()
+}
@@ -0,0 +1,22 @@
+package metricname_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/maynagashev/go-metrics/internal/server/metricname"
+)
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "http_server_duration", metricname.Sanitize("http.server.duration"))
+	assert.Equal(t, "cpu_load_1m", metricname.Sanitize("cpu load-1m"))
+	assert.Equal(t, "_", metricname.Sanitize("ы"))
+}
+
+func TestWithLabels(t *testing.T) {
+	assert.Equal(t, "up", metricname.WithLabels("up", nil))
+	assert.Equal(t,
+		"http_requests_total_code_200_instance_host_9100",
+		metricname.WithLabels("http_requests_total", map[string]string{"instance": "host:9100", "code": "200"}))
+}
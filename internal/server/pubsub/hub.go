@@ -0,0 +1,139 @@
+// Package pubsub реализует рассылку принятых обновлений метрик подписчикам.
+//
+// Hub передает каждое опубликованное значение подписчикам, фильтр которых ему соответствует.
+// У каждого подписчика ограниченный буфер: если подписчик не успевает читать, новые значения
+// для него отбрасываются и учитываются в Subscription.Dropped, а публикация не блокируется.
+package pubsub
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// DefaultBufferSize размер буфера подписчика по умолчанию.
+const DefaultBufferSize = 256
+
+// ErrClosed возвращается при подписке на остановленный Hub.
+var ErrClosed = errors.New("pubsub: hub is closed")
+
+// Filter условие отбора метрик для подписчика. Пустые поля не ограничивают отбор.
+type Filter struct {
+	// Тип метрики: gauge или counter.
+	Type metrics.MetricType
+	// Префиксы имени метрики, достаточно совпадения с одним из них.
+	Prefixes []string
+}
+
+// Match проверяет, соответствует ли метрика фильтру.
+func (f Filter) Match(m metrics.Metric) bool {
+	if f.Type != "" && f.Type != m.MType {
+		return false
+	}
+	if len(f.Prefixes) == 0 {
+		return true
+	}
+	for _, p := range f.Prefixes {
+		if strings.HasPrefix(m.Name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription подписка на обновления метрик.
+type Subscription struct {
+	hub     *Hub
+	filter  Filter
+	ch      chan metrics.Metric
+	dropped atomic.Uint64
+}
+
+// C возвращает канал обновлений. Канал закрывается после Close или остановки Hub.
+func (s *Subscription) C() <-chan metrics.Metric {
+	return s.ch
+}
+
+// Dropped возвращает количество значений, отброшенных из-за переполнения буфера.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close отменяет подписку.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subs[s]; ok {
+		delete(s.hub.subs, s)
+		close(s.ch)
+	}
+}
+
+// Hub рассылает опубликованные значения метрик подписчикам.
+type Hub struct {
+	bufferSize int
+
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewHub создает Hub с буфером bufferSize значений на подписчика.
+func NewHub(bufferSize int) *Hub {
+	if bufferSize < 1 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Hub{bufferSize: bufferSize, subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe создает подписку на значения, соответствующие фильтру.
+func (h *Hub) Subscribe(f Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	s := &Subscription{hub: h, filter: f, ch: make(chan metrics.Metric, h.bufferSize)}
+	h.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Len возвращает количество подписчиков.
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// Publish передает значения подписчикам без ожидания: при заполненном буфере
+// значение для подписчика отбрасывается.
+func (h *Hub) Publish(items ...metrics.Metric) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subs {
+		for _, m := range items {
+			if !s.filter.Match(m) {
+				continue
+			}
+			select {
+			case s.ch <- m:
+			default:
+				s.dropped.Add(1)
+			}
+		}
+	}
+}
+
+// Close останавливает Hub и закрывает каналы всех подписок.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subs {
+		delete(h.subs, s)
+		close(s.ch)
+	}
+}
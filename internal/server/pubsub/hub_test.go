@@ -0,0 +1,105 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/pubsub"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+)
+
+func TestFilter_Match(t *testing.T) {
+	alloc := *metrics.NewGauge("Alloc", 1)
+	poll := *metrics.NewCounter("PollCount", 1)
+
+	assert.True(t, pubsub.Filter{}.Match(alloc))
+	assert.True(t, pubsub.Filter{Type: metrics.TypeGauge}.Match(alloc))
+	assert.False(t, pubsub.Filter{Type: metrics.TypeGauge}.Match(poll))
+	assert.True(t, pubsub.Filter{Prefixes: []string{"Heap", "All"}}.Match(alloc))
+	assert.False(t, pubsub.Filter{Prefixes: []string{"Heap"}}.Match(alloc))
+}
+
+func TestHub_PublishDoesNotBlockOnSlowSubscriber(t *testing.T) {
+	hub := pubsub.NewHub(2)
+	slow, err := hub.Subscribe(pubsub.Filter{})
+	require.NoError(t, err)
+	counters, err := hub.Subscribe(pubsub.Filter{Type: metrics.TypeCounter})
+	require.NoError(t, err)
+
+	for i := range 5 {
+		hub.Publish(*metrics.NewGauge("Alloc", float64(i)))
+	}
+	hub.Publish(*metrics.NewCounter("PollCount", 1))
+
+	assert.Equal(t, uint64(4), slow.Dropped())
+	assert.InDelta(t, 0.0, *(<-slow.C()).Value, 0)
+	assert.InDelta(t, 1.0, *(<-slow.C()).Value, 0)
+
+	assert.Equal(t, uint64(0), counters.Dropped())
+	assert.Equal(t, "PollCount", (<-counters.C()).Name)
+
+	slow.Close()
+	slow.Close()
+	_, ok := <-slow.C()
+	assert.False(t, ok)
+	assert.Equal(t, 1, hub.Len())
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := pubsub.NewHub(1)
+	sub, err := hub.Subscribe(pubsub.Filter{})
+	require.NoError(t, err)
+
+	hub.Close()
+	_, ok := <-sub.C()
+	assert.False(t, ok)
+	sub.Close()
+	hub.Publish(*metrics.NewGauge("Alloc", 1))
+
+	_, err = hub.Subscribe(pubsub.Filter{})
+	require.ErrorIs(t, err, pubsub.ErrClosed)
+}
+
+func TestRepository_PublishesCurrentValues(t *testing.T) {
+	ctx := context.Background()
+	cfg := &app.Config{}
+	hub := pubsub.NewHub(10)
+	st, err := memory.New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	repo := pubsub.NewRepository(st, hub)
+
+	// Без подписчиков обновления не публикуются.
+	require.NoError(t, repo.UpdateMetric(ctx, *metrics.NewCounter("PollCount", 5)))
+
+	sub, err := hub.Subscribe(pubsub.Filter{})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdateMetrics(ctx, []metrics.Metric{
+		*metrics.NewCounter("PollCount", 1),
+		*metrics.NewCounter("PollCount", 2),
+		*metrics.NewGauge("Alloc", 3),
+	}))
+	m := <-sub.C()
+	assert.Equal(t, "PollCount", m.Name)
+	assert.Equal(t, int64(8), *m.Delta, "публикуется накопленное значение счетчика")
+	assert.Equal(t, "Alloc", (<-sub.C()).Name)
+	assert.Empty(t, sub.C())
+
+	// Повторный пакет с тем же ключом не применяется и не публикуется.
+	batch := []metrics.Metric{*metrics.NewCounter("PollCount", 1)}
+	duplicate, err := repo.UpdateMetricsOnce(ctx, "key", batch)
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, int64(9), *(<-sub.C()).Delta)
+
+	duplicate, err = repo.UpdateMetricsOnce(ctx, "key", batch)
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+	assert.Empty(t, sub.C())
+}
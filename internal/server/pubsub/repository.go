@@ -0,0 +1,78 @@
+package pubsub
+
+import (
+	"context"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// Repository хранилище, которое после успешного обновления публикует в Hub
+// текущие значения обновленных метрик. Остальные методы передаются хранилищу без изменений.
+type Repository struct {
+	storage.Repository
+	hub *Hub
+}
+
+// NewRepository оборачивает хранилище st публикацией обновлений в hub.
+func NewRepository(st storage.Repository, hub *Hub) *Repository {
+	return &Repository{Repository: st, hub: hub}
+}
+
+// UpdateMetric обновляет метрику и публикует ее значение.
+func (r *Repository) UpdateMetric(ctx context.Context, metric metrics.Metric) error {
+	if err := r.Repository.UpdateMetric(ctx, metric); err != nil {
+		return err
+	}
+	r.publish(ctx, []metrics.Metric{metric})
+	return nil
+}
+
+// UpdateMetrics обновляет пакет метрик и публикует их значения.
+func (r *Repository) UpdateMetrics(ctx context.Context, items []metrics.Metric) error {
+	if err := r.Repository.UpdateMetrics(ctx, items); err != nil {
+		return err
+	}
+	r.publish(ctx, items)
+	return nil
+}
+
+// UpdateMetricsOnce применяет пакет однократно, если хранилище это поддерживает, и публикует
+// значения только примененного пакета. Иначе пакет применяется как в UpdateMetrics.
+func (r *Repository) UpdateMetricsOnce(ctx context.Context, key string, items []metrics.Metric) (bool, error) {
+	idempotent, ok := r.Repository.(storage.IdempotentRepository)
+	if !ok {
+		return false, r.UpdateMetrics(ctx, items)
+	}
+	duplicate, err := idempotent.UpdateMetricsOnce(ctx, key, items)
+	if err != nil || duplicate {
+		return duplicate, err
+	}
+	r.publish(ctx, items)
+	return false, nil
+}
+
+// publish публикует текущие значения метрик: для counter это накопленная сумма, а не приращение.
+// Метрика, повторяющаяся в пакете, публикуется один раз.
+func (r *Repository) publish(ctx context.Context, items []metrics.Metric) {
+	if r.hub.Len() == 0 {
+		return
+	}
+	type key struct {
+		mType metrics.MetricType
+		name  string
+	}
+	seen := make(map[key]struct{}, len(items))
+	current := make([]metrics.Metric, 0, len(items))
+	for _, m := range items {
+		k := key{m.MType, m.Name}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		if v, err := r.Repository.GetMetric(ctx, m.MType, m.Name); err == nil {
+			current = append(current, v)
+		}
+	}
+	r.hub.Publish(current...)
+}
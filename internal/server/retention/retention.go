@@ -0,0 +1,196 @@
+// Package retention реализует политику хранения истории значений метрик: прореживание
+// исходных значений в агрегаты с крупным шагом и удаление значений, срок хранения которых истек.
+//
+// Политика состоит из уровней, например raw:24h,1m:30d,1h:365d — исходные значения хранятся сутки,
+// затем сворачиваются в минутные агрегаты, которые хранятся 30 дней, а затем в часовые,
+// которые хранятся год. Значения последнего уровня по истечении срока удаляются.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// rawResolution обозначение уровня исходных значений в описании политики.
+const rawResolution = "raw"
+
+// Tier уровень хранения истории: значения с шагом Resolution (0 — исходные значения)
+// хранятся, пока их возраст не превысит Retention.
+type Tier struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// String возвращает уровень в формате описания политики.
+func (t Tier) String() string {
+	resolution := rawResolution
+	if t.Resolution > 0 {
+		resolution = t.Resolution.String()
+	}
+	return resolution + ":" + t.Retention.String()
+}
+
+// ParsePolicy разбирает описание политики хранения: уровни resolution:retention через запятую.
+// Первый уровень описывает исходные значения (raw), шаг каждого следующего уровня
+// кратен шагу предыдущего, а срок хранения больше. Длительности задаются в формате Go
+// или в днях (d), неделях (w) и годах (y). Пустое описание означает хранение без ограничений.
+func ParsePolicy(s string) ([]Tier, error) {
+	var tiers []Tier
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		resolution, retention, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid retention tier %q, expected resolution:retention", item)
+		}
+
+		var tier Tier
+		var err error
+		if resolution != rawResolution {
+			if tier.Resolution, err = parseDuration(resolution); err != nil {
+				return nil, fmt.Errorf("invalid retention tier %q: %w", item, err)
+			}
+		}
+		if tier.Retention, err = parseDuration(retention); err != nil {
+			return nil, fmt.Errorf("invalid retention tier %q: %w", item, err)
+		}
+		if err = validateTier(tiers, tier); err != nil {
+			return nil, fmt.Errorf("invalid retention tier %q: %w", item, err)
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// validateTier проверяет уровень tier, следующий за уровнями tiers.
+func validateTier(tiers []Tier, tier Tier) error {
+	if tier.Retention <= 0 {
+		return errors.New("retention must be positive")
+	}
+	if len(tiers) == 0 {
+		if tier.Resolution != 0 {
+			return errors.New("first tier must be raw")
+		}
+		return nil
+	}
+
+	prev := tiers[len(tiers)-1]
+	switch {
+	case tier.Resolution <= 0:
+		return errors.New("only the first tier can be raw")
+	case tier.Resolution%time.Second != 0:
+		return errors.New("resolution must be a whole number of seconds")
+	case prev.Resolution > 0 && tier.Resolution%prev.Resolution != 0:
+		return fmt.Errorf("resolution must be a multiple of previous resolution %s", prev.Resolution)
+	case tier.Retention <= prev.Retention:
+		return fmt.Errorf("retention must be longer than previous retention %s", prev.Retention)
+	}
+	return nil
+}
+
+// parseDuration разбирает длительность в формате Go или целое число дней, недель или лет.
+func parseDuration(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// Compactor фоновая задача, которая применяет политику хранения к истории хранилища.
+type Compactor struct {
+	st       storage.HistoryCompactor
+	tiers    []Tier
+	interval time.Duration
+	log      *zap.Logger
+	now      func() time.Time
+}
+
+// New создает задачу применения политики tiers к истории хранилища st с периодом interval.
+func New(st storage.HistoryCompactor, tiers []Tier, interval time.Duration, log *zap.Logger) *Compactor {
+	return &Compactor{st: st, tiers: tiers, interval: interval, log: log, now: time.Now}
+}
+
+// Run применяет политику сразу и затем с периодом interval до отмены ctx.
+func (c *Compactor) Run(ctx context.Context) {
+	c.log.Info("history compaction started",
+		zap.Stringers("tiers", c.tiers), zap.Duration("interval", c.interval))
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Compact(ctx); err != nil && ctx.Err() == nil {
+			c.log.Error("history compaction failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			c.log.Info("history compaction stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compact выполняет один проход политики: значения каждого уровня старше срока хранения
+// сворачиваются в агрегаты следующего уровня, а на последнем уровне удаляются.
+// Каждый шаг выполняется хранилищем атомарно, при отмене ctx проход прерывается между шагами
+// и продолжается при следующем вызове.
+func (c *Compactor) Compact(ctx context.Context) error {
+	started := c.now()
+	var moved, deleted int64
+	for i, tier := range c.tiers {
+		if err := ctx.Err(); err != nil {
+			c.log.Info("history compaction interrupted", zap.Stringer("tier", tier))
+			return err
+		}
+
+		if i+1 < len(c.tiers) {
+			next := c.tiers[i+1]
+			// Граница выравнивается по шагу следующего уровня, чтобы интервалы агрегации не делились.
+			before := started.Add(-tier.Retention).Truncate(next.Resolution)
+			n, err := c.st.Downsample(ctx, tier.Resolution, next.Resolution, before)
+			if err != nil {
+				return fmt.Errorf("downsample %s to %s: %w", tier, next, err)
+			}
+			moved += n
+			c.log.Info("history tier downsampled",
+				zap.Stringer("tier", tier), zap.Stringer("into", next),
+				zap.Time("before", before), zap.Int64("values", n))
+			continue
+		}
+
+		before := started.Add(-tier.Retention)
+		n, err := c.st.DeleteHistory(ctx, tier.Resolution, before)
+		if err != nil {
+			return fmt.Errorf("delete expired %s: %w", tier, err)
+		}
+		deleted += n
+		c.log.Info("history tier expired",
+			zap.Stringer("tier", tier), zap.Time("before", before), zap.Int64("values", n))
+	}
+
+	c.log.Info("history compaction completed",
+		zap.Int64("downsampled", moved),
+		zap.Int64("deleted", deleted),
+		zap.Duration("duration", c.now().Sub(started)))
+	return nil
+}
@@ -0,0 +1,121 @@
+package retention_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/server/retention"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tiers, err := retention.ParsePolicy("raw:24h, 1m:30d,1h:1y")
+	require.NoError(t, err)
+	assert.Equal(t, []retention.Tier{
+		{Resolution: 0, Retention: 24 * time.Hour},
+		{Resolution: time.Minute, Retention: 30 * 24 * time.Hour},
+		{Resolution: time.Hour, Retention: 365 * 24 * time.Hour},
+	}, tiers)
+	assert.Equal(t, "1m0s:720h0m0s", tiers[1].String())
+
+	tiers, err = retention.ParsePolicy("")
+	require.NoError(t, err)
+	assert.Empty(t, tiers)
+
+	invalid := []string{
+		"raw",
+		"raw:soon",
+		"1m:30d",
+		"raw:24h,raw:30d",
+		"raw:24h,1500ms:30d",
+		"raw:24h,1m:30d,90s:1y",
+		"raw:24h,1m:12h",
+		"raw:0s",
+	}
+	for _, policy := range invalid {
+		_, err = retention.ParsePolicy(policy)
+		assert.Error(t, err, policy)
+	}
+}
+
+type call struct {
+	op       string
+	src, dst time.Duration
+	before   time.Time
+}
+
+// compactorStub записывает шаги прореживания.
+type compactorStub struct {
+	calls  []call
+	err    error
+	cancel context.CancelFunc
+}
+
+func (s *compactorStub) Downsample(_ context.Context, src, dst time.Duration, before time.Time) (int64, error) {
+	s.calls = append(s.calls, call{op: "downsample", src: src, dst: dst, before: before})
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return 2, s.err
+}
+
+func (s *compactorStub) DeleteHistory(_ context.Context, resolution time.Duration, before time.Time) (int64, error) {
+	s.calls = append(s.calls, call{op: "delete", src: resolution, before: before})
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return 1, s.err
+}
+
+func TestCompactor_Compact(t *testing.T) {
+	tiers, err := retention.ParsePolicy("raw:24h,1m:30d,1h:1y")
+	require.NoError(t, err)
+	st := &compactorStub{}
+
+	started := time.Now()
+	require.NoError(t, retention.New(st, tiers, time.Minute, zap.NewNop()).Compact(context.Background()))
+
+	require.Len(t, st.calls, 3)
+	assert.Equal(t, "downsample", st.calls[0].op)
+	assert.Equal(t, time.Duration(0), st.calls[0].src)
+	assert.Equal(t, time.Minute, st.calls[0].dst)
+	assert.WithinDuration(t, started.Add(-24*time.Hour), st.calls[0].before, time.Minute)
+	assert.Equal(t, st.calls[0].before, st.calls[0].before.Truncate(time.Minute), "граница выровнена по шагу")
+
+	assert.Equal(t, "downsample", st.calls[1].op)
+	assert.Equal(t, time.Minute, st.calls[1].src)
+	assert.Equal(t, time.Hour, st.calls[1].dst)
+	assert.Equal(t, st.calls[1].before, st.calls[1].before.Truncate(time.Hour))
+
+	assert.Equal(t, "delete", st.calls[2].op)
+	assert.Equal(t, time.Hour, st.calls[2].src)
+	assert.WithinDuration(t, started.Add(-365*24*time.Hour), st.calls[2].before, time.Second)
+}
+
+func TestCompactor_Interrupted(t *testing.T) {
+	tiers, err := retention.ParsePolicy("raw:24h,1m:30d")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	st := &compactorStub{cancel: cancel}
+
+	err = retention.New(st, tiers, time.Minute, zap.NewNop()).Compact(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	// После отмены следующий шаг не выполняется.
+	assert.Len(t, st.calls, 1)
+}
+
+func TestCompactor_Run(t *testing.T) {
+	tiers, err := retention.ParsePolicy("raw:24h")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	st := &compactorStub{err: errors.New("storage unavailable"), cancel: cancel}
+
+	// Первый проход выполняется сразу при запуске, задача завершается после отмены контекста.
+	retention.New(st, tiers, time.Hour, zap.NewNop()).Run(ctx)
+	assert.Len(t, st.calls, 1)
+}
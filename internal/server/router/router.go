@@ -10,22 +10,39 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/health"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/influx"
 	"github.com/maynagashev/go-metrics/internal/server/handlers/json/ping"
 	jsonUpdate "github.com/maynagashev/go-metrics/internal/server/handlers/json/update"
 	jsonUpdates "github.com/maynagashev/go-metrics/internal/server/handlers/json/updates"
 	jasonValue "github.com/maynagashev/go-metrics/internal/server/handlers/json/value"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/otlp"
 	plainIndex "github.com/maynagashev/go-metrics/internal/server/handlers/plain/index"
 	plainUpdate "github.com/maynagashev/go-metrics/internal/server/handlers/plain/update"
 	plainValue "github.com/maynagashev/go-metrics/internal/server/handlers/plain/value"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/prometheus"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/queryrange"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/remotewrite"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/stream"
 	"github.com/maynagashev/go-metrics/internal/server/middleware/decompresspool"
 	"github.com/maynagashev/go-metrics/internal/server/middleware/logger"
+	"github.com/maynagashev/go-metrics/internal/server/pubsub"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 	cryptoMiddleware "github.com/maynagashev/go-metrics/pkg/middleware/crypto"
 )
 
-// New инстанцирует новый роутер.
-func New(config *app.Config, storage storage.Repository, log *zap.Logger) chi.Router {
+// New инстанцирует новый роутер. Обновления, принятые /update, /updates и /update/*,
+// публикуются в hub и передаются подписчикам GET /stream. Проверки /healthz и /readyz
+// отвечают по состоянию ready.
+func New(
+	config *app.Config,
+	storage storage.Repository,
+	log *zap.Logger,
+	hub *pubsub.Hub,
+	ready *health.Health,
+) chi.Router {
 	compressLevel := 5
+	published := pubsub.NewRepository(storage, hub)
 
 	r := chi.NewRouter()
 
@@ -35,25 +52,48 @@ func New(config *app.Config, storage storage.Repository, log *zap.Logger) chi.Ro
 	r.Use(middleware.Recoverer)
 	// Удаляем слеши в конце URL
 	r.Use(middleware.StripSlashes)
-	// Добавляем middleware для сжатия ответов
-	r.Use(middleware.Compress(compressLevel, "application/json", "text/html"))
-	// Обработка сжатых запросов, когда от клиента сразу пришел заголовок Content-Encoding: gzip
-	r.Use(decompresspool.New(log))
-	// Используем единый логгер для запросов, вместо встроенного логгера chi
-	r.Use(logger.New(log))
-	// Добавляем middleware для обработки шифрования
-	r.Use(cryptoMiddleware.New(config, log))
 
-	// Обработчики запросов
-	r.Get("/", plainIndex.New(storage))
-	r.Post("/update", jsonUpdate.New(config, storage, log))
-	r.Post("/updates", jsonUpdates.NewBulkUpdate(config, storage, log))
-	r.Post("/value", jasonValue.New(config, storage))
-	r.Get("/ping", ping.New(config, log))
+	// Prometheus remote write передает тело, сжатое snappy, без шифрования и подписи,
+	// поэтому маршрут обрабатывается без middleware распаковки gzip и расшифровки.
+	r.Group(func(r chi.Router) {
+		r.Use(logger.New(log))
+		r.Post("/api/v1/write", remotewrite.New(config, storage, log))
+	})
 
-	// Первые версии обработчиков для работы тестов начальных итераций
-	r.Post("/update/*", plainUpdate.New(storage, log))
-	r.Get("/value/{type}/{name}", plainValue.New(storage))
+	// Проверки живости и готовности запрашиваются балансировщиком часто, поэтому не логируются,
+	// а их ответы не сжимаются и не подписываются.
+	r.Get("/healthz", ready.Liveness())
+	r.Get("/readyz", ready.Readiness())
+
+	// Поток обновлений не проходит через middleware сжатия, подписи и логирования тел запросов,
+	// которые буферизуют ответ или не поддерживают его отправку по частям.
+	r.Get("/stream", stream.New(hub, log))
+
+	r.Group(func(r chi.Router) {
+		// Добавляем middleware для сжатия ответов
+		r.Use(middleware.Compress(compressLevel, "application/json", "text/html"))
+		// Обработка сжатых запросов, когда от клиента сразу пришел заголовок Content-Encoding: gzip
+		r.Use(decompresspool.New(log))
+		// Используем единый логгер для запросов, вместо встроенного логгера chi
+		r.Use(logger.New(log))
+		// Добавляем middleware для обработки шифрования
+		r.Use(cryptoMiddleware.New(config, log))
+
+		// Обработчики запросов
+		r.Get("/", plainIndex.New(storage))
+		r.Post("/update", jsonUpdate.New(config, published, log))
+		r.Post("/updates", jsonUpdates.NewBulkUpdate(config, published, log))
+		r.Post("/value", jasonValue.New(config, storage))
+		r.Get("/ping", ping.New(storage))
+		r.Get("/metrics", prometheus.New(storage, log))
+		r.Get("/api/v1/query_range", queryrange.New(storage, log))
+		r.Post("/v1/metrics", otlp.New(config, storage, log))
+		r.Post("/write", influx.New(storage, log))
+
+		// Первые версии обработчиков для работы тестов начальных итераций
+		r.Post("/update/*", plainUpdate.New(published, log))
+		r.Get("/value/{type}/{name}", plainValue.New(storage))
+	})
 
 	// Добавляем pprof хендлеры только если включено профилирование
 	if config.EnablePprof {
@@ -1,18 +1,30 @@
 package router_test
 
 import (
+	"bytes"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/golang/snappy"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/handlers/health"
+	"github.com/maynagashev/go-metrics/internal/server/pubsub"
 	"github.com/maynagashev/go-metrics/internal/server/router"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
 	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+	"github.com/maynagashev/go-metrics/pkg/sign"
 )
 
+// newHealth создает проверку готовности сервера по хранилищу st.
+func newHealth(st storage.Repository) *health.Health {
+	return health.New(map[string]health.Check{"storage": st.Ping}, zap.NewNop())
+}
+
 func TestNew(t *testing.T) {
 	// Create a logger
 	logger, _ := zap.NewDevelopment()
@@ -26,10 +38,11 @@ func TestNew(t *testing.T) {
 	}
 
 	// Create a real storage implementation
-	storage := memory.New(config, logger)
+	storage, err := memory.New(config, logger)
+	require.NoError(t, err)
 
 	// Create the router
-	router := router.New(config, storage, logger)
+	router := router.New(config, storage, logger, pubsub.NewHub(0), newHealth(storage))
 
 	// Verify the router was created
 	assert.NotNil(t, router)
@@ -69,7 +82,19 @@ func TestNew(t *testing.T) {
 			name:           "GET /ping",
 			method:         http.MethodGet,
 			path:           "/ping",
-			expectedStatus: http.StatusInternalServerError, // Without a valid DB connection, we expect an error
+			expectedStatus: http.StatusOK, // Memory storage is ready
+		},
+		{
+			name:           "GET /healthz",
+			method:         http.MethodGet,
+			path:           "/healthz",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "GET /readyz",
+			method:         http.MethodGet,
+			path:           "/readyz",
+			expectedStatus: http.StatusOK,
 		},
 	}
 
@@ -104,10 +129,11 @@ func TestNew_WithPprof(t *testing.T) {
 	}
 
 	// Create a real storage implementation
-	storage := memory.New(config, logger)
+	storage, err := memory.New(config, logger)
+	require.NoError(t, err)
 
 	// Create the router
-	router := router.New(config, storage, logger)
+	router := router.New(config, storage, logger, pubsub.NewHub(0), newHealth(storage))
 
 	// Verify the router was created
 	assert.NotNil(t, router)
@@ -155,3 +181,50 @@ func TestNew_WithPprof(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_RemoteWriteBypassesCryptoMiddleware(t *testing.T) {
+	logger := zap.NewNop()
+	// С ключом подписи crypto middleware подписывает ответы, для remote write подпись не добавляется.
+	config := &app.Config{PrivateKey: "secret"}
+	storage, err := memory.New(config, logger)
+	require.NoError(t, err)
+	r := router.New(config, storage, logger, pubsub.NewHub(0), newHealth(storage))
+
+	// Пустой WriteRequest, сжатый snappy.
+	body := snappy.Encode(nil, nil)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/write", bytes.NewReader(body))
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Empty(t, rr.Header().Get(sign.HeaderKey))
+}
+
+func TestNew_UpdatesArePublished(t *testing.T) {
+	config := &app.Config{}
+	storage, err := memory.New(config, zap.NewNop())
+	require.NoError(t, err)
+	hub := pubsub.NewHub(10)
+	r := router.New(config, storage, zap.NewNop(), hub, newHealth(storage))
+
+	sub, err := hub.Subscribe(pubsub.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/update/counter/PollCount/5", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/updates",
+		bytes.NewBufferString(`[{"id":"PollCount","type":"counter","delta":2}]`))
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	assert.Equal(t, int64(5), *(<-sub.C()).Delta)
+	assert.Equal(t, int64(7), *(<-sub.C()).Delta)
+}
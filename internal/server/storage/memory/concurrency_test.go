@@ -0,0 +1,159 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+)
+
+// getMetrics возвращает все метрики хранилища.
+func getMetrics(t *testing.T, ms *memory.MemStorage) []metrics.Metric {
+	items, err := ms.GetMetrics(context.Background())
+	require.NoError(t, err)
+	return items
+}
+
+// runConcurrentLoad выполняет одновременные обновления и чтения хранилища: writers горутин
+// увеличивают каждый из names счетчиков на 1 по rounds раз и обновляют gauge, а читатели
+// в это время получают метрики всеми способами. Запускать с -race.
+func runConcurrentLoad(t *testing.T, ms *memory.MemStorage, writers, names, rounds int) {
+	t.Helper()
+	ctx := context.Background()
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				items, err := ms.GetMetrics(ctx)
+				assert.NoError(t, err)
+				for _, m := range items {
+					assert.NotEmpty(t, m.Name)
+				}
+				_ = ms.StreamMetrics(ctx, func(metrics.Metric) error { return nil })
+				_, _ = ms.GetMetric(ctx, metrics.TypeCounter, "counter_0")
+				_, _ = ms.Count(ctx)
+				ms.GetCounters()
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				batch := make([]metrics.Metric, 0, names+1)
+				for i := range names {
+					batch = append(batch, *metrics.NewCounter(fmt.Sprintf("counter_%d", i), 1))
+				}
+				batch = append(batch, *metrics.NewGauge(fmt.Sprintf("gauge_%d", w), float64(w)))
+				assert.NoError(t, ms.UpdateMetrics(ctx, batch))
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+}
+
+func TestMemStorage_ConcurrentAccess(t *testing.T) {
+	const writers, names, rounds = 8, 50, 100
+	ms := setupTestStorage(t)
+	runConcurrentLoad(t, ms, writers, names, rounds)
+
+	ctx := context.Background()
+	count, err := ms.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, names+writers, count)
+	assert.Len(t, getMetrics(t, ms), names+writers)
+	for i := range names {
+		assert.Equal(t, int64(writers*rounds), counterValue(t, ms, fmt.Sprintf("counter_%d", i)))
+	}
+}
+
+func TestMemStorage_ConcurrentAccessWithFileStore(t *testing.T) {
+	const writers, names, rounds = 4, 20, 50
+	cfg := newFileConfig(t)
+	cfg.Fsync = app.FsyncNever
+	ms := setupTestStorageWithConfig(t, cfg)
+	runConcurrentLoad(t, ms, writers, names, rounds)
+	require.NoError(t, ms.Close())
+
+	// Снимок и журнал согласованы: после восстановления счетчики не потеряны и не удвоены
+	restored := setupTestStorageWithConfig(t, cfg)
+	defer restored.Close()
+	for i := range names {
+		assert.Equal(t, int64(writers*rounds), counterValue(t, restored, fmt.Sprintf("counter_%d", i)))
+	}
+}
+
+func TestMemStorage_GetMetricsReflectsUpdates(t *testing.T) {
+	ms := setupTestStorage(t)
+	ctx := context.Background()
+
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewGauge("load", 1)))
+	items := getMetrics(t, ms)
+	require.Len(t, items, 1)
+	assert.InDelta(t, 1.0, *items[0].Value, 1e-9)
+
+	// Повторное чтение без изменений возвращает те же значения, а после обновления — новые
+	require.Len(t, getMetrics(t, ms), 1)
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewGauge("load", 2)))
+	items = getMetrics(t, ms)
+	require.Len(t, items, 1)
+	assert.InDelta(t, 2.0, *items[0].Value, 1e-9)
+
+	// Ранее полученный список не изменяется последующими обновлениями
+	ms.UpdateGauge("load", 3)
+	assert.InDelta(t, 2.0, *items[0].Value, 1e-9)
+}
+
+func TestMemStorage_GetMetricsNotStaleUnderConcurrentReads(t *testing.T) {
+	ms := setupTestStorage(t)
+	ctx := context.Background()
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					_, _ = ms.GetMetrics(ctx)
+				}
+			}
+		}()
+	}
+
+	// Список, полученный после обновления, содержит это обновление, даже если
+	// копию сегмента в это время строит другой читатель
+	for i := range 2000 {
+		require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewGauge("load", float64(i))))
+		items := getMetrics(t, ms)
+		require.Len(t, items, 1)
+		require.InDelta(t, float64(i), *items[0].Value, 0)
+	}
+	close(stop)
+	readers.Wait()
+}
@@ -0,0 +1,249 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// seriesKey идентификатор ряда значений метрики.
+type seriesKey struct {
+	mType metrics.MetricType
+	name  string
+}
+
+// sample исходное значение истории. Для counter кроме накопленного значения
+// хранится приращение, из которого при прореживании считается сумма.
+type sample struct {
+	storage.Sample
+	delta float64
+}
+
+// aggregate возвращает агрегат из одного исходного значения.
+func (s sample) aggregate(mType metrics.MetricType, start time.Time) storage.Aggregate {
+	sum := s.Value
+	if mType == metrics.TypeCounter {
+		sum = s.delta
+	}
+	return storage.Aggregate{Start: start, Count: 1, Min: s.Value, Max: s.Value, Sum: sum, Last: s.Value}
+}
+
+// ring кольцевой буфер последних значений метрики, при заполнении
+// новое значение замещает самое старое.
+type ring struct {
+	samples []sample
+	// Индекс самого старого значения.
+	start int
+	count int
+}
+
+func (r *ring) add(s sample) {
+	if r.count < len(r.samples) {
+		r.samples[(r.start+r.count)%len(r.samples)] = s
+		r.count++
+		return
+	}
+	r.samples[r.start] = s
+	r.start = (r.start + 1) % len(r.samples)
+}
+
+// at возвращает i-е значение буфера, начиная с самого старого.
+func (r *ring) at(i int) sample {
+	return r.samples[(r.start+i)%len(r.samples)]
+}
+
+// dropOldest удаляет n самых старых значений.
+func (r *ring) dropOldest(n int) {
+	r.start = (r.start + n) % len(r.samples)
+	r.count -= n
+}
+
+// countBefore возвращает количество значений, записанных раньше before.
+func (r *ring) countBefore(before time.Time) int {
+	n := 0
+	for n < r.count && r.at(n).Time.Before(before) {
+		n++
+	}
+	return n
+}
+
+// history история значений метрик в памяти: не более size исходных значений на метрику
+// и агрегаты прореженных значений по шагу прореживания.
+type history struct {
+	mu      sync.RWMutex
+	size    int
+	series  map[seriesKey]*ring
+	rollups map[time.Duration]map[seriesKey][]storage.Aggregate
+}
+
+func newHistory(size int) *history {
+	return &history{
+		size:    size,
+		series:  make(map[seriesKey]*ring),
+		rollups: make(map[time.Duration]map[seriesKey][]storage.Aggregate),
+	}
+}
+
+func (h *history) add(mType metrics.MetricType, name string, s sample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := seriesKey{mType: mType, name: name}
+	r, ok := h.series[key]
+	if !ok {
+		r = &ring{samples: make([]sample, h.size)}
+		h.series[key] = r
+	}
+	r.add(s)
+}
+
+func (h *history) query(mType metrics.MetricType, name string, from, to time.Time) []storage.Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	inRange := func(t time.Time) bool { return !t.Before(from) && !t.After(to) }
+	key := seriesKey{mType: mType, name: name}
+
+	var samples []storage.Sample
+	for resolution, tier := range h.rollups {
+		for _, a := range tier[key] {
+			// Агрегат попадает в результат, если его интервал пересекается с периодом запроса.
+			if a.Start.Add(resolution).After(from) && !a.Start.After(to) {
+				samples = append(samples, storage.Sample{Time: a.Start, Value: a.Value(mType), Resolution: resolution})
+			}
+		}
+	}
+	if r, ok := h.series[key]; ok {
+		for i := range r.count {
+			if s := r.at(i); inRange(s.Time) {
+				samples = append(samples, s.Sample)
+			}
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples
+}
+
+// downsample сворачивает значения с шагом src, записанные раньше before, в агрегаты с шагом dst.
+func (h *history) downsample(src, dst time.Duration, before time.Time) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var moved int64
+	if src == 0 {
+		for key, r := range h.series {
+			n := r.countBefore(before)
+			for i := range n {
+				s := r.at(i)
+				h.mergeRollup(dst, key, s.aggregate(key.mType, s.Time.Truncate(dst)))
+			}
+			r.dropOldest(n)
+			moved += int64(n)
+		}
+		return moved
+	}
+
+	for key, aggregates := range h.rollups[src] {
+		n := sort.Search(len(aggregates), func(i int) bool { return !aggregates[i].Start.Before(before) })
+		for _, a := range aggregates[:n] {
+			a.Start = a.Start.Truncate(dst)
+			h.mergeRollup(dst, key, a)
+		}
+		h.setRollup(src, key, aggregates[n:])
+		moved += int64(n)
+	}
+	return moved
+}
+
+// mergeRollup добавляет агрегат в ряд с шагом resolution. Агрегаты добавляются в порядке времени,
+// поэтому совпадать может только последний интервал ряда.
+func (h *history) mergeRollup(resolution time.Duration, key seriesKey, a storage.Aggregate) {
+	tier, ok := h.rollups[resolution]
+	if !ok {
+		tier = make(map[seriesKey][]storage.Aggregate)
+		h.rollups[resolution] = tier
+	}
+	aggregates := tier[key]
+	if n := len(aggregates); n > 0 && aggregates[n-1].Start.Equal(a.Start) {
+		aggregates[n-1].Merge(a)
+		return
+	}
+	tier[key] = append(aggregates, a)
+}
+
+// setRollup заменяет агрегаты ряда, удаляя пустые ряды.
+func (h *history) setRollup(resolution time.Duration, key seriesKey, aggregates []storage.Aggregate) {
+	if len(aggregates) == 0 {
+		delete(h.rollups[resolution], key)
+		return
+	}
+	h.rollups[resolution][key] = aggregates
+}
+
+// deleteBefore удаляет значения с шагом resolution, записанные раньше before.
+func (h *history) deleteBefore(resolution time.Duration, before time.Time) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var deleted int64
+	if resolution == 0 {
+		for _, r := range h.series {
+			n := r.countBefore(before)
+			r.dropOldest(n)
+			deleted += int64(n)
+		}
+		return deleted
+	}
+	for key, aggregates := range h.rollups[resolution] {
+		n := sort.Search(len(aggregates), func(i int) bool { return !aggregates[i].Start.Before(before) })
+		h.setRollup(resolution, key, aggregates[n:])
+		deleted += int64(n)
+	}
+	return deleted
+}
+
+// QueryRange возвращает значения метрики за период [from, to] из истории в памяти.
+// Прореженные значения возвращаются со временем начала и шагом интервала агрегации.
+func (ms *MemStorage) QueryRange(
+	_ context.Context,
+	mType metrics.MetricType,
+	name string,
+	from, to time.Time,
+) ([]storage.Sample, error) {
+	if ms.history == nil {
+		return nil, storage.ErrHistoryDisabled
+	}
+	return ms.history.query(mType, name, from, to), nil
+}
+
+// Downsample сворачивает значения истории с шагом src, записанные раньше before, в агрегаты с шагом dst.
+func (ms *MemStorage) Downsample(_ context.Context, src, dst time.Duration, before time.Time) (int64, error) {
+	if ms.history == nil {
+		return 0, storage.ErrHistoryDisabled
+	}
+	return ms.history.downsample(src, dst, before), nil
+}
+
+// DeleteHistory удаляет значения истории с шагом resolution, записанные раньше before.
+func (ms *MemStorage) DeleteHistory(_ context.Context, resolution time.Duration, before time.Time) (int64, error) {
+	if ms.history == nil {
+		return 0, storage.ErrHistoryDisabled
+	}
+	return ms.history.deleteBefore(resolution, before), nil
+}
+
+// recordHistory сохраняет в историю значение метрики value после обновления metric, если история включена.
+func (ms *MemStorage) recordHistory(metric metrics.Metric, value float64) {
+	if ms.history == nil {
+		return
+	}
+	s := sample{Sample: storage.Sample{Time: time.Now(), Value: value}}
+	if metric.MType == metrics.TypeCounter {
+		s.delta = float64(*metric.Delta)
+	}
+	ms.history.add(metric.MType, metric.Name, s)
+}
@@ -0,0 +1,121 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+func TestMemStorage_QueryRange(t *testing.T) {
+	ctx := context.Background()
+	ms := setupTestStorageWithConfig(t, &app.Config{History: true, HistorySize: 3})
+
+	from := time.Now()
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("PollCount", int64(i))))
+	}
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewGauge("Alloc", 1.5)))
+	to := time.Now()
+
+	// Хранятся три последних значения, для counter — накопленная сумма.
+	samples, err := ms.QueryRange(ctx, metrics.TypeCounter, "PollCount", from, to)
+	require.NoError(t, err)
+	values := make([]float64, 0, len(samples))
+	for i, s := range samples {
+		values = append(values, s.Value)
+		if i > 0 {
+			assert.False(t, s.Time.Before(samples[i-1].Time))
+		}
+	}
+	assert.Equal(t, []float64{3, 6, 10}, values)
+
+	samples, err = ms.QueryRange(ctx, metrics.TypeGauge, "Alloc", from, to)
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.InDelta(t, 1.5, samples[0].Value, 0)
+
+	// Значения вне периода и неизвестные метрики не возвращаются.
+	samples, err = ms.QueryRange(ctx, metrics.TypeGauge, "Alloc", to.Add(time.Second), to.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+	samples, err = ms.QueryRange(ctx, metrics.TypeGauge, "PollCount", from, to)
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+
+	// Текущие значения по-прежнему доступны.
+	counter, err := ms.GetCounter(ctx, "PollCount")
+	assert.NoError(t, err)
+	assert.Equal(t, storage.Counter(10), counter)
+}
+
+func TestMemStorage_QueryRange_Disabled(t *testing.T) {
+	ms := setupTestStorage(t)
+	require.NoError(t, ms.UpdateMetric(context.Background(), *metrics.NewGauge("Alloc", 1)))
+
+	_, err := ms.QueryRange(context.Background(), metrics.TypeGauge, "Alloc", time.Time{}, time.Now())
+	require.ErrorIs(t, err, storage.ErrHistoryDisabled)
+}
+
+func TestMemStorage_Downsample(t *testing.T) {
+	ctx := context.Background()
+	ms := setupTestStorageWithConfig(t, &app.Config{History: true})
+
+	from := time.Now().Add(-time.Hour)
+	for _, v := range []float64{1, 5, 3} {
+		require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewGauge("Alloc", v)))
+	}
+	for _, d := range []int64{2, 3} {
+		require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("PollCount", d)))
+	}
+	before := time.Now().Add(time.Millisecond)
+
+	moved, err := ms.Downsample(ctx, 0, time.Hour, before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), moved)
+
+	// Вместо исходных значений возвращается агрегат: для gauge среднее, для counter накопленное значение.
+	to := before.Add(time.Hour)
+	samples, err := ms.QueryRange(ctx, metrics.TypeGauge, "Alloc", from, to)
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.InDelta(t, 3.0, samples[0].Value, 1e-9)
+	assert.Equal(t, samples[0].Time, samples[0].Time.Truncate(time.Hour))
+
+	samples, err = ms.QueryRange(ctx, metrics.TypeCounter, "PollCount", from, to)
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.InDelta(t, 5.0, samples[0].Value, 0)
+
+	// Новые значения добавляются к исходным, свернутые значения повторно не переносятся.
+	time.Sleep(2 * time.Millisecond)
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewGauge("Alloc", 7)))
+	moved, err = ms.Downsample(ctx, 0, time.Hour, before)
+	require.NoError(t, err)
+	assert.Zero(t, moved)
+	samples, err = ms.QueryRange(ctx, metrics.TypeGauge, "Alloc", from, to)
+	require.NoError(t, err)
+	assert.Len(t, samples, 2)
+
+	// Агрегаты переносятся на следующий уровень и удаляются по истечении срока хранения.
+	moved, err = ms.Downsample(ctx, time.Hour, 24*time.Hour, to)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), moved)
+
+	deleted, err := ms.DeleteHistory(ctx, 24*time.Hour, to)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	deleted, err = ms.DeleteHistory(ctx, 0, to)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	samples, err = ms.QueryRange(ctx, metrics.TypeGauge, "Alloc", time.Time{}, to)
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+)
+
+// appliedRequests ключи идемпотентности примененных пакетов метрик с временем применения.
+// Ключи хранятся в течение ttl, после чего повторный пакет с тем же ключом будет применен заново.
+type appliedRequests struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	keys map[string]*appliedRequest
+	// Примененные ключи в порядке применения. TTL у всех ключей одинаковый,
+	// поэтому порядок применения совпадает с порядком истечения срока хранения.
+	order []expiringKey
+}
+
+// appliedRequest состояние пакета с ключом идемпотентности.
+type appliedRequest struct {
+	// Закрывается, когда применение пакета завершено, nil после применения.
+	pending   chan struct{}
+	appliedAt time.Time
+}
+
+type expiringKey struct {
+	key     string
+	request *appliedRequest
+}
+
+func newAppliedRequests(ttl time.Duration) *appliedRequests {
+	return &appliedRequests{
+		ttl:  ttl,
+		keys: make(map[string]*appliedRequest),
+	}
+}
+
+// purge удаляет ключи с истекшим сроком хранения. Вызывается под блокировкой.
+// Ключи проверяются в порядке истечения срока, поэтому проход останавливается на первом актуальном ключе.
+func (r *appliedRequests) purge(now time.Time) {
+	for len(r.order) > 0 && now.Sub(r.order[0].request.appliedAt) >= r.ttl {
+		expired := r.order[0]
+		r.order[0] = expiringKey{}
+		r.order = r.order[1:]
+		if r.keys[expired.key] == expired.request {
+			delete(r.keys, expired.key)
+		}
+	}
+}
+
+// acquire резервирует ключ для применения пакета. Возвращает true, если пакет с ключом уже применен.
+// Если пакет с тем же ключом применяется в другой горутине, acquire дожидается результата.
+func (r *appliedRequests) acquire(ctx context.Context, key string) (*appliedRequest, bool, error) {
+	for {
+		r.mu.Lock()
+		r.purge(time.Now())
+		req, ok := r.keys[key]
+		if !ok {
+			req = &appliedRequest{pending: make(chan struct{})}
+			r.keys[key] = req
+			r.mu.Unlock()
+			return req, false, nil
+		}
+		pending := req.pending
+		r.mu.Unlock()
+		if pending == nil {
+			return nil, true, nil
+		}
+
+		select {
+		case <-pending:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// release завершает применение пакета: при успехе ключ запоминается, при ошибке освобождается
+// для повторной попытки.
+func (r *appliedRequests) release(key string, req *appliedRequest, applied bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	close(req.pending)
+	req.pending = nil
+	if !applied {
+		delete(r.keys, key)
+		return
+	}
+	req.appliedAt = time.Now()
+	r.order = append(r.order, expiringKey{key: key, request: req})
+}
+
+// UpdateMetricsOnce применяет пакет метрик, если пакет с таким ключом не применялся в течение TTL.
+// Возвращает true, если пакет уже был применен ранее.
+func (ms *MemStorage) UpdateMetricsOnce(
+	ctx context.Context,
+	key string,
+	items []metrics.Metric,
+) (bool, error) {
+	// Ключ резервируется на время применения пакета, поэтому одновременные повторы одного пакета
+	// не применяются дважды, а пакеты с разными ключами применяются параллельно.
+	req, applied, err := ms.requests.acquire(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if applied {
+		ms.log.Debug("metrics batch already applied", zap.String("idempotency_key", key))
+		return true, nil
+	}
+
+	err = ms.UpdateMetrics(ctx, items)
+	ms.requests.release(key, req, err == nil)
+	if err != nil {
+		return false, err
+	}
+	return false, nil
+}
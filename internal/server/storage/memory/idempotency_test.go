@@ -0,0 +1,109 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+func TestMemStorage_UpdateMetricsOnce(t *testing.T) {
+	ms := setupTestStorage(t)
+	ctx := context.Background()
+	batch := []metrics.Metric{*metrics.NewCounter("PollCount", 5), *metrics.NewGauge("Alloc", 1)}
+
+	var _ storage.IdempotentRepository = ms
+
+	duplicate, err := ms.UpdateMetricsOnce(ctx, "req-1", batch)
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	// Повтор пакета с тем же ключом не применяется.
+	duplicate, err = ms.UpdateMetricsOnce(ctx, "req-1", batch)
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+
+	counter, err := ms.GetCounter(ctx, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(5), counter)
+
+	// Пакет с другим ключом применяется.
+	duplicate, err = ms.UpdateMetricsOnce(ctx, "req-2", batch)
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	counter, _ = ms.GetCounter(ctx, "PollCount")
+	assert.Equal(t, storage.Counter(10), counter)
+}
+
+func TestMemStorage_UpdateMetricsOnce_Expired(t *testing.T) {
+	ms := setupTestStorageWithConfig(t, &app.Config{IdempotencyTTL: time.Millisecond})
+	ctx := context.Background()
+	batch := []metrics.Metric{*metrics.NewCounter("PollCount", 1)}
+
+	_, err := ms.UpdateMetricsOnce(ctx, "req-1", batch)
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+
+	// После истечения TTL ключ забыт и пакет применяется снова.
+	duplicate, err := ms.UpdateMetricsOnce(ctx, "req-1", batch)
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	counter, _ := ms.GetCounter(ctx, "PollCount")
+	assert.Equal(t, storage.Counter(2), counter)
+}
+
+func TestMemStorage_UpdateMetricsOnce_ErrorNotRemembered(t *testing.T) {
+	ms := setupTestStorage(t)
+	ctx := context.Background()
+
+	invalid := []metrics.Metric{{Name: "broken", MType: metrics.TypeCounter}}
+	_, err := ms.UpdateMetricsOnce(ctx, "req-1", invalid)
+	require.Error(t, err)
+
+	// Неудачный пакет не запоминается, исправленный повтор с тем же ключом применяется.
+	duplicate, err := ms.UpdateMetricsOnce(ctx, "req-1", []metrics.Metric{*metrics.NewCounter("broken", 1)})
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+}
+
+func TestMemStorage_UpdateMetricsOnce_Concurrent(t *testing.T) {
+	ms := setupTestStorage(t)
+	ctx := context.Background()
+	batch := []metrics.Metric{*metrics.NewCounter("PollCount", 1)}
+
+	// Одновременные повторы одного пакета применяются один раз, пакеты с другими ключами — каждый
+	const retries, keys = 8, 16
+	var applied atomic.Int64
+	var wg sync.WaitGroup
+	for k := range keys {
+		for range retries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				duplicate, err := ms.UpdateMetricsOnce(ctx, fmt.Sprintf("req-%d", k), batch)
+				assert.NoError(t, err)
+				if !duplicate {
+					applied.Add(1)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(keys), applied.Load())
+	counter, err := ms.GetCounter(ctx, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(keys), counter)
+}
@@ -3,10 +3,12 @@ package memory
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -16,31 +18,65 @@ import (
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 )
 
-const maxRetries = 3
+// Период сброса журнала изменений на диск при политике app.FsyncInterval.
+const walSyncInterval = time.Second
 
+// Размер журнала изменений, при превышении которого сохраняется снимок и начинается новый журнал.
+const walSnapshotSize = 64 << 20
+
+// MemStorage хранилище метрик в памяти. Метрики распределены по сегментам со своими блокировками,
+// поэтому методы хранилища можно вызывать из нескольких горутин.
 type MemStorage struct {
-	gauges   storage.Gauges
-	counters storage.Counters
-	cfg      *app.Config
-	log      *zap.Logger
+	shards *shards
+	cfg    *app.Config
+	log    *zap.Logger
+	// Ключи идемпотентности примененных пакетов метрик.
+	requests *appliedRequests
+	// История значений метрик, nil если сохранение истории выключено.
+	history *history
+
+	// Включено ли сохранение метрик в файл. Не изменяется после создания хранилища.
+	persistent bool
+	// Блокировка применения обновлений при сохранении в файл. Обновления записываются в журнал
+	// и применяются под блокировкой на чтение, а снимок копирует метрики под блокировкой на запись,
+	// поэтому снимок содержит ровно записи журнала до номера seq.
+	applyMu sync.RWMutex
+	// Блокировка журнала изменений. Удерживается только на время операции с журналом.
+	persistMu sync.Mutex
+	// Журнал изменений, nil если сохранение метрик в файл выключено.
+	wal *wal
+	// Запросы на сохранение снимка при превышении размера журнала.
+	snapshotRequests chan struct{}
+	// Остановка фонового сохранения.
+	stop chan struct{}
+	done chan struct{}
 }
 
 // New создает новый экземпляр хранилища метрик в памяти, на вход
 // можно передать набор gauges или counters для инициализации в тестах.
-func New(cfg *app.Config, log *zap.Logger, options ...interface{}) *MemStorage {
+//
+// Если включено восстановление и файл хранилища не удалось прочитать, возвращается ошибка,
+// а снимок и журнал изменений остаются без изменений, чтобы их можно было восстановить вручную.
+func New(cfg *app.Config, log *zap.Logger, options ...interface{}) (*MemStorage, error) {
 	memStorage := &MemStorage{
-		gauges:   make(storage.Gauges),
-		counters: make(storage.Counters),
-		cfg:      cfg,
-		log:      log,
+		shards:     newShards(),
+		cfg:        cfg,
+		log:        log,
+		requests:   newAppliedRequests(cfg.GetIdempotencyTTL()),
+		persistent: cfg.IsStoreEnabled(),
+	}
+	if cfg.IsHistoryEnabled() {
+		memStorage.history = newHistory(cfg.GetHistorySize())
 	}
 	log.Debug("memory storage created", zap.Any("storage", memStorage))
 
-	// Если включено восстановление метрик из файла, то пытаемся прочитать метрики из файла.
-	if cfg.IsRestoreEnabled() {
-		err := memStorage.restoreMetricsFromFile()
-		if err != nil {
-			log.Error("failed to read metrics from file", zap.Error(err))
+	// Если включено восстановление метрик из файла, то восстанавливаем последний снимок и журнал изменений.
+	var seq uint64
+	restored := cfg.IsRestoreEnabled()
+	if restored {
+		var err error
+		if seq, err = memStorage.restoreMetricsFromFile(); err != nil {
+			return nil, fmt.Errorf("failed to restore metrics from file: %w", err)
 		}
 	}
 
@@ -48,240 +84,505 @@ func New(cfg *app.Config, log *zap.Logger, options ...interface{}) *MemStorage {
 	for _, option := range options {
 		switch opt := option.(type) {
 		case storage.Gauges:
-			memStorage.gauges = opt
+			memStorage.replaceGauges(opt)
 		case storage.Counters:
-			memStorage.counters = opt
+			memStorage.replaceCounters(opt)
 		}
 	}
 
-	// Запускаем сохранение метрик в файл с указанным интервалом.
-	if cfg.IsStoreEnabled() && !cfg.IsSyncStore() {
-		interval := time.Duration(cfg.GetStoreInterval()) * time.Second
-		go func() {
-			for {
-				time.Sleep(interval)
-				log.Info(
-					fmt.Sprintf(
-						"store %d metrics to file %s",
-						memStorage.Count(context.Background()),
-						cfg.GetStorePath(),
-					),
-				)
-				err := memStorage.storeMetricsToFile()
-				if err != nil {
-					log.Error("failed to store metrics to file", zap.Error(err))
-				}
+	// Запускаем запись журнала изменений и сохранение снимков.
+	if memStorage.persistent {
+		if err := memStorage.startPersistence(seq, restored); err != nil {
+			return nil, fmt.Errorf("failed to open metrics log: %w", err)
+		}
+	}
+
+	return memStorage, nil
+}
+
+// startPersistence открывает журнал изменений и запускает фоновое сохранение. Если восстановление
+// выключено, прежние снимок и журнал заменяются текущим состоянием хранилища.
+func (ms *MemStorage) startPersistence(seq uint64, restored bool) error {
+	path := ms.cfg.GetStorePath()
+	if !restored {
+		for _, p := range []string{path + rotatedWALSuffix, path + walSuffix} {
+			if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to remove stale wal: %w", err)
+			}
+		}
+		seq = 0
+		if err := writeSnapshot(path, snapshot{Metrics: ms.items()}); err != nil {
+			return err
+		}
+	}
+
+	w, err := openWAL(path+walSuffix, ms.cfg.GetFsyncPolicy(), seq)
+	if err != nil {
+		return err
+	}
+	ms.wal = w
+	ms.snapshotRequests = make(chan struct{}, 1)
+	ms.stop = make(chan struct{})
+	ms.done = make(chan struct{})
+	go ms.persist()
+	return nil
+}
+
+// persist сохраняет снимки с интервалом StoreInterval и при превышении размера журнала,
+// а также сбрасывает журнал на диск при политике app.FsyncInterval.
+func (ms *MemStorage) persist() {
+	defer close(ms.done)
+
+	var snapshotTick, syncTick <-chan time.Time
+	if interval := time.Duration(ms.cfg.GetStoreInterval()) * time.Second; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		snapshotTick = ticker.C
+	}
+	if ms.cfg.GetFsyncPolicy() == app.FsyncInterval {
+		ticker := time.NewTicker(walSyncInterval)
+		defer ticker.Stop()
+		syncTick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ms.stop:
+			return
+		case <-snapshotTick:
+			ms.storeSnapshotAndLog()
+		case <-ms.snapshotRequests:
+			ms.storeSnapshotAndLog()
+		case <-syncTick:
+			ms.persistMu.Lock()
+			err := ms.wal.sync()
+			ms.persistMu.Unlock()
+			if err != nil {
+				ms.log.Error("failed to sync metrics log", zap.Error(err))
 			}
-		}()
+		}
 	}
+}
 
-	return memStorage
+func (ms *MemStorage) storeSnapshotAndLog() {
+	if err := ms.storeSnapshot(); err != nil {
+		ms.log.Error("failed to store metrics to file", zap.Error(err))
+	}
 }
 
+// Close останавливает фоновое сохранение, сохраняет снимок метрик и закрывает журнал изменений.
 func (ms *MemStorage) Close() error {
-	if ms.cfg.IsStoreEnabled() && !ms.cfg.IsSyncStore() {
-		return ms.storeMetricsToFile()
+	ms.persistMu.Lock()
+	w := ms.wal
+	ms.persistMu.Unlock()
+	if w == nil {
+		return nil
+	}
+
+	close(ms.stop)
+	<-ms.done
+	err := ms.storeSnapshot()
+
+	ms.persistMu.Lock()
+	defer ms.persistMu.Unlock()
+	closeErr := ms.wal.close()
+	ms.wal = nil
+	return errors.Join(err, closeErr)
+}
+
+// Ping проверяет готовность хранилища. Без сохранения в файл хранилище всегда готово, иначе
+// проверяется, что журнал изменений открыт и в каталоге файла хранилища можно создать файл.
+func (ms *MemStorage) Ping(_ context.Context) error {
+	if !ms.persistent {
+		return nil
+	}
+	ms.persistMu.Lock()
+	var walErr error
+	if ms.wal == nil {
+		walErr = errors.New("metrics log is not open")
+	} else {
+		walErr = ms.wal.err
+	}
+	ms.persistMu.Unlock()
+	if walErr != nil {
+		return fmt.Errorf("%w: %w", storage.ErrUnavailable, walErr)
+	}
+
+	path := ms.cfg.GetStorePath()
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".ping-*")
+	if err != nil {
+		return fmt.Errorf("%w: %w", storage.ErrUnavailable, err)
+	}
+	name := f.Name()
+	err = f.Close()
+	if removeErr := os.Remove(name); removeErr != nil {
+		err = errors.Join(err, removeErr)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", storage.ErrUnavailable, err)
 	}
 	return nil
 }
 
 // UpdateGauge перезаписывает значение gauge.
 func (ms *MemStorage) UpdateGauge(metricName string, metricValue storage.Gauge) {
-	ms.gauges[metricName] = metricValue
+	sh := ms.shards.get(metricName)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.gauges[metricName] = metricValue
+	sh.dirty.Store(true)
 }
 
 // IncrementCounter увеличивает значение счетчика на заданное значение.
 func (ms *MemStorage) IncrementCounter(metricName string, metricValue storage.Counter) {
-	ms.counters[metricName] += metricValue
+	sh := ms.shards.get(metricName)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.counters[metricName] += metricValue
+	sh.dirty.Store(true)
 }
 
 // UpdateMetric универсальный метод обновления метрики в хранилище: gauge, counter.
-func (ms *MemStorage) UpdateMetric(_ context.Context, metric metrics.Metric) error {
-	switch metric.MType {
-	case metrics.TypeGauge:
-		if metric.Value == nil {
-			return errors.New("gauge value is nil")
-		}
-		ms.UpdateGauge(metric.Name, storage.Gauge(*metric.Value))
-	case metrics.TypeCounter:
-		if metric.Delta == nil {
-			return errors.New("counter delta is nil")
+func (ms *MemStorage) UpdateMetric(ctx context.Context, metric metrics.Metric) error {
+	return ms.UpdateMetrics(ctx, []metrics.Metric{metric})
+}
+
+// UpdateMetrics записывает пакет обновлений в журнал изменений и применяет его.
+// Если хотя бы одна метрика некорректна или пакет не удалось записать в журнал, пакет не применяется.
+func (ms *MemStorage) UpdateMetrics(_ context.Context, items []metrics.Metric) error {
+	for _, item := range items {
+		if err := storage.ValidateMetric(item); err != nil {
+			return err
 		}
-		ms.IncrementCounter(metric.Name, storage.Counter(*metric.Delta))
-	default:
-		return fmt.Errorf("unsupported metric type: %s", metric.MType)
 	}
 
-	// Сохраняем метрики в файл сразу после изменения, если включено синхронное сохранение.
-	if ms.cfg.IsStoreEnabled() && ms.cfg.IsSyncStore() {
-		err := ms.storeMetricsToFile()
-		if err != nil {
-			// Информация об ошибке синхронной записи для клиента может быть избыточной, поэтому просто логируем ошибку.
-			ms.log.Error(
-				fmt.Sprintf("error while trying to syncroniously store metrics to file: %s", err),
-			)
-		}
+	if ms.persistent {
+		return ms.logAndApply(items)
+	}
+	for _, item := range items {
+		ms.applyMetric(item, true)
 	}
 	return nil
 }
 
-func (ms *MemStorage) UpdateMetrics(ctx context.Context, items []metrics.Metric) error {
+// logAndApply записывает пакет в журнал изменений и применяет его. Сегменты метрик пакета
+// блокируются до записи в журнал, поэтому обновления одной метрики применяются в порядке
+// записей журнала. Пакеты с метриками других сегментов ожидают друг друга только на время
+// записи в журнал.
+func (ms *MemStorage) logAndApply(items []metrics.Metric) error {
+	ms.applyMu.RLock()
+	defer ms.applyMu.RUnlock()
+	unlock := ms.shards.lock(items)
+	defer unlock()
+
+	ms.persistMu.Lock()
+	err := ms.appendWAL(items)
+	ms.persistMu.Unlock()
+	if err != nil {
+		return err
+	}
 	for _, item := range items {
-		err := ms.UpdateMetric(ctx, item)
-		if err != nil {
-			return err
+		ms.applyToShard(ms.shards.get(item.Name), item, true)
+	}
+	return nil
+}
+
+// appendWAL записывает обновления в журнал изменений. Вызывается под блокировкой persistMu.
+// Ошибка оборачивает storage.ErrUnavailable: обновления не сохранены, клиент может повторить запрос.
+func (ms *MemStorage) appendWAL(items []metrics.Metric) error {
+	if ms.wal == nil {
+		return fmt.Errorf("%w: metrics log is closed", storage.ErrUnavailable)
+	}
+	if err := ms.wal.append(items); err != nil {
+		ms.log.Error("failed to write metrics log", zap.Error(err))
+		if ms.wal.err != nil {
+			// Журнал больше не принимает записи: снимок с новым журналом восстанавливает запись.
+			ms.requestSnapshot()
 		}
+		return fmt.Errorf("%w: %w", storage.ErrUnavailable, err)
+	}
+	if ms.wal.size >= walSnapshotSize {
+		ms.requestSnapshot()
 	}
 	return nil
 }
 
+// requestSnapshot запрашивает сохранение снимка фоновой горутиной, не дожидаясь его.
+func (ms *MemStorage) requestSnapshot() {
+	select {
+	case ms.snapshotRequests <- struct{}{}:
+	default:
+	}
+}
+
+// applyMetric применяет проверенное обновление метрики и, если record, сохраняет новое значение в историю.
+// История обновляется под блокировкой сегмента, чтобы значения одной метрики попадали в нее по порядку.
+func (ms *MemStorage) applyMetric(metric metrics.Metric, record bool) {
+	sh := ms.shards.get(metric.Name)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	ms.applyToShard(sh, metric, record)
+}
+
+// applyToShard применяет обновление метрики к сегменту sh. Вызывается под блокировкой сегмента на запись.
+func (ms *MemStorage) applyToShard(sh *shard, metric metrics.Metric, record bool) {
+	var value float64
+	switch metric.MType {
+	case metrics.TypeGauge:
+		sh.gauges[metric.Name] = storage.Gauge(*metric.Value)
+		value = *metric.Value
+	case metrics.TypeCounter:
+		sh.counters[metric.Name] += storage.Counter(*metric.Delta)
+		value = float64(sh.counters[metric.Name])
+	}
+	sh.dirty.Store(true)
+
+	if record {
+		ms.recordHistory(metric, value)
+	}
+}
+
+// replaceGauges заменяет все значения gauge хранилища.
+func (ms *MemStorage) replaceGauges(gauges storage.Gauges) {
+	for _, sh := range ms.shards.list {
+		sh.mu.Lock()
+		sh.gauges = make(storage.Gauges)
+		sh.dirty.Store(true)
+		sh.mu.Unlock()
+	}
+	for name, value := range gauges {
+		ms.UpdateGauge(name, value)
+	}
+}
+
+// replaceCounters заменяет все значения counter хранилища.
+func (ms *MemStorage) replaceCounters(counters storage.Counters) {
+	for _, sh := range ms.shards.list {
+		sh.mu.Lock()
+		sh.counters = make(storage.Counters)
+		sh.dirty.Store(true)
+		sh.mu.Unlock()
+	}
+	for name, value := range counters {
+		ms.IncrementCounter(name, value)
+	}
+}
+
+// GetGauges возвращает копию значений всех gauge.
 func (ms *MemStorage) GetGauges() storage.Gauges {
-	return ms.gauges
+	gauges := make(storage.Gauges)
+	for _, sh := range ms.shards.list {
+		sh.mu.RLock()
+		for name, value := range sh.gauges {
+			gauges[name] = value
+		}
+		sh.mu.RUnlock()
+	}
+	return gauges
 }
 
+// GetCounters возвращает копию значений всех counter.
 func (ms *MemStorage) GetCounters() storage.Counters {
-	return ms.counters
+	counters := make(storage.Counters)
+	for _, sh := range ms.shards.list {
+		sh.mu.RLock()
+		for name, value := range sh.counters {
+			counters[name] = value
+		}
+		sh.mu.RUnlock()
+	}
+	return counters
 }
 
-func (ms *MemStorage) GetGauge(_ context.Context, name string) (storage.Gauge, bool) {
-	value, ok := ms.gauges[name]
-	return value, ok
+func (ms *MemStorage) GetGauge(_ context.Context, name string) (storage.Gauge, error) {
+	sh := ms.shards.get(name)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	value, ok := sh.gauges[name]
+	if !ok {
+		return 0, storage.ErrNotFound
+	}
+	return value, nil
+}
+
+func (ms *MemStorage) GetCounter(_ context.Context, name string) (storage.Counter, error) {
+	sh := ms.shards.get(name)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	value, ok := sh.counters[name]
+	if !ok {
+		return 0, storage.ErrNotFound
+	}
+	return value, nil
 }
 
-func (ms *MemStorage) GetCounter(_ context.Context, name string) (storage.Counter, bool) {
-	value, ok := ms.counters[name]
-	return value, ok
+func (ms *MemStorage) Count(_ context.Context) (int, error) {
+	return ms.count(), nil
 }
 
-func (ms *MemStorage) Count(_ context.Context) int {
-	return len(ms.gauges) + len(ms.counters)
+// count возвращает количество метрик во всех сегментах.
+func (ms *MemStorage) count() int {
+	n := 0
+	for _, sh := range ms.shards.list {
+		n += sh.len()
+	}
+	return n
 }
 
 func (ms *MemStorage) GetMetric(
 	ctx context.Context,
 	mType metrics.MetricType,
 	id string,
-) (metrics.Metric, bool) {
+) (metrics.Metric, error) {
 	switch mType {
 	case metrics.TypeCounter:
-		v, ok := ms.GetCounter(ctx, id)
+		v, err := ms.GetCounter(ctx, id)
+		if err != nil {
+			return metrics.Metric{}, err
+		}
 		return metrics.Metric{
 			Name:  id,
 			MType: mType,
 			Delta: (*int64)(&v),
-		}, ok
+		}, nil
 	case metrics.TypeGauge:
-		v, ok := ms.GetGauge(ctx, id)
+		v, err := ms.GetGauge(ctx, id)
+		if err != nil {
+			return metrics.Metric{}, err
+		}
 		return metrics.Metric{
 			Name:  id,
 			MType: mType,
 			Value: (*float64)(&v),
-		}, ok
+		}, nil
 	}
-	return metrics.Metric{}, false
+	return metrics.Metric{}, storage.ErrNotFound
+}
+
+// GetMetrics возвращает список метрик в формате слайса структур. Список собирается из копий
+// сегментов, которые не изменялись с прошлого чтения, без блокировок. Значения, на которые
+// ссылаются метрики списка, общие для всех читателей и не должны изменяться.
+func (ms *MemStorage) GetMetrics(_ context.Context) ([]metrics.Metric, error) {
+	return ms.items(), nil
 }
 
-// GetMetrics возвращает отсортированный список метрик в формате слайса структур.
-func (ms *MemStorage) GetMetrics(ctx context.Context) []metrics.Metric {
-	items := make([]metrics.Metric, 0, ms.Count(ctx))
-	for id, value := range ms.GetGauges() {
-		items = append(
-			items,
-			metrics.Metric{Name: id, MType: metrics.TypeGauge, Value: (*float64)(&value)},
-		)
+// items собирает список метрик из копий сегментов.
+func (ms *MemStorage) items() []metrics.Metric {
+	parts := make([][]metrics.Metric, shardCount)
+	n := 0
+	for i, sh := range ms.shards.list {
+		parts[i] = sh.snapshot()
+		n += len(parts[i])
 	}
-	for id, value := range ms.GetCounters() {
-		items = append(
-			items,
-			metrics.Metric{Name: id, MType: metrics.TypeCounter, Delta: (*int64)(&value)},
-		)
+	items := make([]metrics.Metric, 0, n)
+	for _, part := range parts {
+		items = append(items, part...)
 	}
-	// slices.Sort(items)
 	return items
 }
 
-// StoreMetricsToFile сохраняет метрики в файл.
-func (ms *MemStorage) storeMetricsToFile() error {
-	path := ms.cfg.GetStorePath()
-	ms.log.Debug("store metrics to file",
-		zap.String("path", path),
-		zap.Any("gauges", ms.GetGauges()),
-		zap.Any("counters", ms.GetCounters()))
-
-	var f *os.File
-	var err error
-
-	for i := 0; i <= maxRetries; i++ {
-		// попытка открытия файла для записи
-		f, err = os.Create(path)
-		if err == nil {
-			break
+// StreamMetrics передает метрики хранилища по одной в fn, не копируя их в промежуточный список.
+func (ms *MemStorage) StreamMetrics(_ context.Context, fn func(metrics.Metric) error) error {
+	for _, sh := range ms.shards.list {
+		for _, m := range sh.snapshot() {
+			if err := fn(m); err != nil {
+				return err
+			}
 		}
-		ms.log.Error(fmt.Sprintf("Attempt %d: Error opening file: %v", i+1, err))
-		time.Sleep(time.Duration((i+1)*2-1) * time.Second)
 	}
+	return nil
+}
+
+// storeSnapshot атомарно сохраняет снимок метрик в файл и начинает новый журнал изменений.
+// Записи прежнего журнала содержатся в снимке, поэтому после сохранения снимка журнал удаляется.
+func (ms *MemStorage) storeSnapshot() error {
+	path := ms.cfg.GetStorePath()
 
+	ms.applyMu.Lock()
+	ms.persistMu.Lock()
+	s := snapshot{Seq: ms.wal.seq, Metrics: ms.items()}
+	err := ms.wal.rotate(path + rotatedWALSuffix)
+	ms.persistMu.Unlock()
+	ms.applyMu.Unlock()
 	if err != nil {
-		return fmt.Errorf("failed to open file after %d attempts: %w", maxRetries, err)
+		return err
 	}
 
-	// отложенное закрытие файла
-	defer func() {
-		err = f.Close()
-		if err != nil {
-			ms.log.Error(fmt.Sprintf("error closing file: %s", err))
-		}
-	}()
+	ms.log.Info("store metrics to file",
+		zap.String("path", path), zap.Int("metrics", len(s.Metrics)), zap.Uint64("seq", s.Seq))
+	if err = writeSnapshot(path, s); err != nil {
+		return err
+	}
+	if err = os.Remove(path + rotatedWALSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove rotated wal: %w", err)
+	}
+	return nil
+}
 
-	// сериализация метрик metrics.Metric в json и запись сразу в файл
-	encoder := json.NewEncoder(f)
-	encoder.SetIndent("", "    ")
-	err = encoder.Encode(ms.GetMetrics(context.Background()))
+// Load создает хранилище метрик в памяти с метриками из файла path, сохраненного хранилищем
+// с включенным сохранением в файл: последний снимок и записи журнала изменений после него.
+// Файлы только читаются: хранилище не сохраняет изменения, а оборванный журнал не обрезается.
+func Load(path string, log *zap.Logger) (*MemStorage, error) {
+	ms, err := New(&app.Config{}, log)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if _, err = ms.restore(path, false); err != nil {
+		return nil, err
 	}
+	return ms, nil
+}
 
-	return nil
+// restoreMetricsFromFile загружает метрики из последнего сохраненного снимка и применяет поверх него
+// записи журнала изменений, сделанные после снимка. Возвращает номер последней примененной записи.
+func (ms *MemStorage) restoreMetricsFromFile() (uint64, error) {
+	return ms.restore(ms.cfg.GetStorePath(), true)
 }
 
-// RestoreMetricsFromFile загружает метрики из файла.
-func (ms *MemStorage) restoreMetricsFromFile() error {
-	path := ms.cfg.GetStorePath()
+// restore загружает метрики из снимка path и журналов изменений рядом с ним.
+// Если truncate, журнал с оборванной записью обрезается до последней целой записи.
+func (ms *MemStorage) restore(path string, truncate bool) (uint64, error) {
 	ms.log.Debug("load metrics from file", zap.String("path", path))
 
-	// открытие файла для чтения и парсинг json метрик metrics.Metric
-	f, err := os.Open(path)
+	s, err := readSnapshot(path)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer func() {
-		err = f.Close()
-		if err != nil {
-			ms.log.Error(fmt.Sprintf("error closing file: %s", err), zap.Any("file", f))
+	for _, m := range s.Metrics {
+		if err = ms.restoreMetric(m); err != nil {
+			return 0, err
 		}
-	}()
-
-	// парсинг json метрик metrics.Metric
-	var parsed []metrics.Metric
-	decoder := json.NewDecoder(f)
-	err = decoder.Decode(&parsed)
-	if err != nil {
-		return err
 	}
 
-	// обновление метрик в хранилище в памяти
-	for m := range parsed {
-		err = ms.UpdateMetric(context.Background(), parsed[m])
+	// Журнал, переименованный перед незавершенным сохранением снимка, содержит более ранние записи.
+	seq := s.Seq
+	for _, walPath := range []string{path + rotatedWALSuffix, path + walSuffix} {
+		seq, err = replayWAL(walPath, seq, truncate, ms.restoreMetric)
+		if errors.Is(err, errTruncatedWAL) {
+			ms.log.Warn("metrics log truncated", zap.String("file", walPath), zap.Error(err))
+			continue
+		}
 		if err != nil {
-			return err
+			return 0, err
 		}
 	}
 
 	// Выводим информацию о восстановленных метриках в лог.
 	ms.log.Info(
 		"Metrics restored from file",
-		zap.String("file", ms.cfg.GetStorePath()),
-		zap.Int("metrics", len(ms.GetMetrics(context.Background()))),
+		zap.String("file", path),
+		zap.Int("metrics", ms.count()),
+		zap.Uint64("snapshot_seq", s.Seq),
+		zap.Uint64("seq", seq),
 	)
+	return seq, nil
+}
+
+// restoreMetric применяет обновление из снимка или журнала без записи в журнал и историю.
+func (ms *MemStorage) restoreMetric(metric metrics.Metric) error {
+	if err := storage.ValidateMetric(metric); err != nil {
+		return err
+	}
+	ms.applyMetric(metric, false)
 	return nil
 }
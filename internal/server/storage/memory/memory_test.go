@@ -20,7 +20,8 @@ func setupTestStorage(t *testing.T) *memory.MemStorage {
 	require.NoError(t, err)
 
 	cfg := &app.Config{}
-	ms := memory.New(cfg, logger)
+	ms, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 	require.NotNil(t, ms)
 
 	return ms
@@ -30,7 +31,8 @@ func setupTestStorageWithConfig(t *testing.T, cfg *app.Config) *memory.MemStorag
 	logger, err := zap.NewDevelopment()
 	require.NoError(t, err)
 
-	ms := memory.New(cfg, logger)
+	ms, err := memory.New(cfg, logger)
+	require.NoError(t, err)
 	require.NotNil(t, ms)
 
 	return ms
@@ -44,8 +46,8 @@ func TestMemStorage_UpdateGauge(t *testing.T) {
 	ms.UpdateGauge("test_gauge", storage.Gauge(42.0))
 
 	// Проверяем что метрика читается
-	value, ok := ms.GetGauge(ctx, "test_gauge")
-	assert.True(t, ok)
+	value, err := ms.GetGauge(ctx, "test_gauge")
+	assert.NoError(t, err)
 	assert.InDelta(t, 42.0, float64(value), 1e-9)
 }
 
@@ -57,20 +59,20 @@ func TestMemStorage_IncrementCounter(t *testing.T) {
 	ms.IncrementCounter("test_counter", storage.Counter(1))
 
 	// Проверяем что метрика читается
-	value, ok := ms.GetCounter(ctx, "test_counter")
-	assert.True(t, ok)
+	value, err := ms.GetCounter(ctx, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, int64(1), int64(value))
 
 	// Проверяем что метрика инкрементируется
 	ms.IncrementCounter("test_counter", storage.Counter(2))
-	value, ok = ms.GetCounter(ctx, "test_counter")
-	assert.True(t, ok)
+	value, err = ms.GetCounter(ctx, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, int64(3), int64(value))
 
 	// Проверяем что метрика инкрементируется с отрицательным значением
 	ms.IncrementCounter("test_counter", storage.Counter(-1))
-	value, ok = ms.GetCounter(ctx, "test_counter")
-	assert.True(t, ok)
+	value, err = ms.GetCounter(ctx, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, int64(2), int64(value))
 }
 
@@ -89,8 +91,8 @@ func TestMemStorage_UpdateMetric(t *testing.T) {
 	require.NoError(t, err)
 
 	// Проверяем что gauge метрика читается
-	result, ok := ms.GetMetric(ctx, metrics.TypeGauge, "test_gauge")
-	assert.True(t, ok)
+	result, err := ms.GetMetric(ctx, metrics.TypeGauge, "test_gauge")
+	assert.NoError(t, err)
 	assert.InDelta(t, gaugeValue, *result.Value, 1e-9)
 
 	// Проверяем обновление counter
@@ -103,8 +105,8 @@ func TestMemStorage_UpdateMetric(t *testing.T) {
 	require.NoError(t, err)
 
 	// Проверяем что counter метрика читается
-	metric, ok = ms.GetMetric(ctx, metrics.TypeCounter, "test_counter")
-	assert.True(t, ok)
+	metric, err = ms.GetMetric(ctx, metrics.TypeCounter, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, "test_counter", metric.Name)
 	assert.Equal(t, metrics.TypeCounter, metric.MType)
 	assert.NotNil(t, metric.Delta)
@@ -120,8 +122,8 @@ func TestMemStorage_UpdateMetric(t *testing.T) {
 	require.NoError(t, err)
 
 	// Проверяем что counter метрика инкрементировалась
-	metric, ok = ms.GetMetric(ctx, metrics.TypeCounter, "test_counter")
-	assert.True(t, ok)
+	metric, err = ms.GetMetric(ctx, metrics.TypeCounter, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, "test_counter", metric.Name)
 	assert.Equal(t, metrics.TypeCounter, metric.MType)
 	assert.NotNil(t, metric.Delta)
@@ -133,7 +135,7 @@ func TestMemStorage_UpdateMetric(t *testing.T) {
 		MType: "invalid",
 	}
 	err = ms.UpdateMetric(ctx, invalidMetric)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, storage.ErrInvalidMetric)
 }
 
 func TestMemStorage_GetMetric(t *testing.T) {
@@ -141,12 +143,12 @@ func TestMemStorage_GetMetric(t *testing.T) {
 	ctx := context.Background()
 
 	// Проверяем отсутствие метрики
-	_, ok := ms.GetMetric(ctx, metrics.TypeGauge, "non_existent")
-	assert.False(t, ok)
+	_, err := ms.GetMetric(ctx, metrics.TypeGauge, "non_existent")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
 
 	// Добавляем gauge метрику
 	gaugeValue := 42.0
-	err := ms.UpdateMetric(ctx, metrics.Metric{
+	err = ms.UpdateMetric(ctx, metrics.Metric{
 		Name:  "test_gauge",
 		MType: metrics.TypeGauge,
 		Value: &gaugeValue,
@@ -154,8 +156,8 @@ func TestMemStorage_GetMetric(t *testing.T) {
 	require.NoError(t, err)
 
 	// Проверяем что gauge метрика читается
-	metric, ok := ms.GetMetric(ctx, metrics.TypeGauge, "test_gauge")
-	assert.True(t, ok)
+	metric, err := ms.GetMetric(ctx, metrics.TypeGauge, "test_gauge")
+	assert.NoError(t, err)
 	assert.InDelta(t, gaugeValue, *metric.Value, 1e-9)
 
 	// Добавляем counter метрику
@@ -168,8 +170,8 @@ func TestMemStorage_GetMetric(t *testing.T) {
 	require.NoError(t, err)
 
 	// Проверяем наличие counter метрики
-	metric, ok = ms.GetMetric(ctx, metrics.TypeCounter, "test_counter")
-	assert.True(t, ok)
+	metric, err = ms.GetMetric(ctx, metrics.TypeCounter, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, "test_counter", metric.Name)
 	assert.Equal(t, metrics.TypeCounter, metric.MType)
 	assert.NotNil(t, metric.Delta)
@@ -181,17 +183,19 @@ func TestMemStorage_Count(t *testing.T) {
 	ctx := context.Background()
 
 	// Проверяем начальное количество метрик
-	count := ms.Count(ctx)
+	count, err := ms.Count(ctx)
+	require.NoError(t, err)
 	assert.Equal(t, 0, count)
 
 	// Добавляем gauge метрику
 	gaugeValue := 42.0
 	gaugeMetric := metrics.NewGauge("test_gauge", gaugeValue)
-	err := ms.UpdateMetric(ctx, *gaugeMetric)
+	err = ms.UpdateMetric(ctx, *gaugeMetric)
 	require.NoError(t, err)
 
 	// Проверяем количество метрик после добавления gauge
-	count = ms.Count(ctx)
+	count, err = ms.Count(ctx)
+	require.NoError(t, err)
 	assert.Equal(t, 1, count)
 
 	// Добавляем counter метрику
@@ -201,7 +205,8 @@ func TestMemStorage_Count(t *testing.T) {
 	require.NoError(t, err)
 
 	// Проверяем количество метрик после добавления counter
-	count = ms.Count(ctx)
+	count, err = ms.Count(ctx)
+	require.NoError(t, err)
 	assert.Equal(t, 2, count)
 }
 
@@ -223,16 +228,16 @@ func TestMemStorage_UpdateMetrics(t *testing.T) {
 	require.NoError(t, err)
 
 	// Проверяем что gauge метрика обновилась
-	metric, ok := ms.GetMetric(ctx, metrics.TypeGauge, "test_gauge")
-	assert.True(t, ok)
+	metric, err := ms.GetMetric(ctx, metrics.TypeGauge, "test_gauge")
+	assert.NoError(t, err)
 	assert.Equal(t, "test_gauge", metric.Name)
 	assert.Equal(t, metrics.TypeGauge, metric.MType)
 	assert.NotNil(t, metric.Value)
 	assert.InDelta(t, gaugeValue, *metric.Value, 1e-9)
 
 	// Проверяем что counter метрика обновилась
-	metric, ok = ms.GetMetric(ctx, metrics.TypeCounter, "test_counter")
-	assert.True(t, ok)
+	metric, err = ms.GetMetric(ctx, metrics.TypeCounter, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, "test_counter", metric.Name)
 	assert.Equal(t, metrics.TypeCounter, metric.MType)
 	assert.NotNil(t, metric.Delta)
@@ -244,13 +249,14 @@ func TestMemStorage_GetMetrics(t *testing.T) {
 	ctx := context.Background()
 
 	// Проверяем начальное количество метрик
-	metricsSlice := ms.GetMetrics(ctx)
+	metricsSlice, err := ms.GetMetrics(ctx)
+	require.NoError(t, err)
 	assert.Empty(t, metricsSlice)
 
 	// Добавляем gauge метрику
 	gaugeValue := 42.0
 	gaugeMetric := metrics.NewGauge("test_gauge", gaugeValue)
-	err := ms.UpdateMetric(ctx, *gaugeMetric)
+	err = ms.UpdateMetric(ctx, *gaugeMetric)
 	require.NoError(t, err)
 
 	// Добавляем counter метрику
@@ -260,7 +266,8 @@ func TestMemStorage_GetMetrics(t *testing.T) {
 	require.NoError(t, err)
 
 	// Получаем все метрики
-	allMetrics := ms.GetMetrics(ctx)
+	allMetrics, err := ms.GetMetrics(ctx)
+	require.NoError(t, err)
 	assert.Len(t, allMetrics, 2)
 
 	// Проверяем наличие gauge метрики в списке
@@ -287,6 +294,7 @@ func TestMemStorage_FileOperations(t *testing.T) {
 	tmpFile, err := os.CreateTemp("", "metrics_test")
 	require.NoError(t, err)
 	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".wal")
 
 	// Создаем конфигурацию с сохранением в файл
 	cfg := &app.Config{
@@ -319,13 +327,13 @@ func TestMemStorage_FileOperations(t *testing.T) {
 	newMS := setupTestStorageWithConfig(t, cfg)
 
 	// Проверяем что gauge метрика восстановилась
-	metric, ok := newMS.GetMetric(ctx, metrics.TypeGauge, "test_gauge")
-	assert.True(t, ok)
+	metric, err := newMS.GetMetric(ctx, metrics.TypeGauge, "test_gauge")
+	assert.NoError(t, err)
 	assert.InDelta(t, gaugeValue, *metric.Value, 1e-9)
 
 	// Проверяем что counter метрика восстановилась
-	metric, ok = newMS.GetMetric(ctx, metrics.TypeCounter, "test_counter")
-	assert.True(t, ok)
+	metric, err = newMS.GetMetric(ctx, metrics.TypeCounter, "test_counter")
+	assert.NoError(t, err)
 	assert.Equal(t, "test_counter", metric.Name)
 	assert.Equal(t, metrics.TypeCounter, metric.MType)
 	assert.NotNil(t, metric.Delta)
@@ -346,6 +354,7 @@ func TestMemStorage_Close(t *testing.T) {
 	tmpFile, tmpErr := os.CreateTemp("", "metrics_test")
 	require.NoError(t, tmpErr)
 	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".wal")
 
 	// Создаем конфигурацию с сохранением в файл
 	cfg := &app.Config{
@@ -393,7 +402,8 @@ func TestMemStorage_GetAllMetrics(t *testing.T) {
 	require.NoError(t, err)
 
 	// Получаем все метрики
-	allMetrics := ms.GetMetrics(ctx)
+	allMetrics, err := ms.GetMetrics(ctx)
+	require.NoError(t, err)
 
 	// Проверяем что есть обе метрики
 	assert.Len(t, allMetrics, 2)
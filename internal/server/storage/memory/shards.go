@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// shardCount количество сегментов хранилища. Метрики распределяются по сегментам по хешу имени,
+// поэтому обновления разных метрик, как правило, не конкурируют за одну блокировку.
+const shardCount = 64
+
+// shard сегмент хранилища метрик в памяти со своей блокировкой.
+type shard struct {
+	mu       sync.RWMutex
+	gauges   storage.Gauges
+	counters storage.Counters
+	// Признак изменения сегмента после построения копии items.
+	dirty atomic.Bool
+	// Копия метрик сегмента, которая читается без блокировки, пока сегмент не изменился.
+	items atomic.Pointer[[]metrics.Metric]
+}
+
+// shards сегменты хранилища метрик.
+type shards struct {
+	list [shardCount]*shard
+}
+
+func newShards() *shards {
+	s := &shards{}
+	for i := range s.list {
+		s.list[i] = &shard{
+			gauges:   make(storage.Gauges),
+			counters: make(storage.Counters),
+		}
+	}
+	return s
+}
+
+// get возвращает сегмент, в котором хранится метрика с именем name.
+func (s *shards) get(name string) *shard {
+	return s.list[shardIndex(name)]
+}
+
+// shardIndex возвращает номер сегмента метрики с именем name.
+func shardIndex(name string) uint32 {
+	// Хеш FNV-1a вычисляется по строке без выделения памяти.
+	h := uint32(2166136261)
+	for i := range len(name) {
+		h ^= uint32(name[i])
+		h *= 16777619
+	}
+	return h % shardCount
+}
+
+// lock блокирует на запись сегменты метрик items и возвращает функцию снятия блокировок.
+// Сегменты блокируются в порядке номеров, поэтому пакеты с общими сегментами не блокируют друг друга взаимно.
+func (s *shards) lock(items []metrics.Metric) func() {
+	indexes := make([]uint32, 0, len(items))
+	for _, item := range items {
+		indexes = append(indexes, shardIndex(item.Name))
+	}
+	slices.Sort(indexes)
+	indexes = slices.Compact(indexes)
+	for _, i := range indexes {
+		s.list[i].mu.Lock()
+	}
+	return func() {
+		for _, i := range indexes {
+			s.list[i].mu.Unlock()
+		}
+	}
+}
+
+// snapshot возвращает копию метрик сегмента. Пока сегмент не изменялся, возвращается
+// ранее построенная копия без блокировки. Копия общая для всех читателей и не должна изменяться.
+func (sh *shard) snapshot() []metrics.Metric {
+	if !sh.dirty.Load() {
+		if items := sh.items.Load(); items != nil {
+			return *items
+		}
+	}
+
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	items := make([]metrics.Metric, 0, len(sh.gauges)+len(sh.counters))
+	for id, value := range sh.gauges {
+		v := float64(value)
+		items = append(items, metrics.Metric{Name: id, MType: metrics.TypeGauge, Value: &v})
+	}
+	for id, value := range sh.counters {
+		d := int64(value)
+		items = append(items, metrics.Metric{Name: id, MType: metrics.TypeCounter, Delta: &d})
+	}
+	// Признак сбрасывается только после публикации копии: иначе читатель без блокировки
+	// мог бы увидеть сброшенный признак и прежнюю копию. Изменения сегмента невозможны
+	// до снятия блокировки, поэтому опубликованная копия соответствует текущему состоянию.
+	sh.items.Store(&items)
+	sh.dirty.Store(false)
+	return items
+}
+
+// len возвращает количество метрик сегмента.
+func (sh *shard) len() int {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return len(sh.gauges) + len(sh.counters)
+}
@@ -0,0 +1,327 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+)
+
+// Суффиксы файлов журнала изменений рядом с файлом снимка.
+const (
+	walSuffix        = ".wal"
+	rotatedWALSuffix = ".wal.old"
+)
+
+// walRecord запись журнала изменений: обновление метрики в том виде, в котором оно было получено
+// (для counter — приращение), с порядковым номером.
+type walRecord struct {
+	Seq    uint64         `json:"seq"`
+	Metric metrics.Metric `json:"metric"`
+}
+
+// snapshot снимок всех метрик хранилища, содержащий обновления журнала с номерами до Seq включительно.
+type snapshot struct {
+	Seq     uint64           `json:"seq"`
+	Metrics []metrics.Metric `json:"metrics"`
+}
+
+// wal журнал изменений хранилища в памяти. Каждая запись — строка с контрольной суммой CRC-32
+// и записью walRecord в формате JSON, поэтому оборванная при сбое запись обнаруживается при чтении.
+// Методы вызываются под блокировкой хранилища.
+type wal struct {
+	path   string
+	fsync  string
+	file   *os.File
+	buf    *bufio.Writer
+	seq    uint64
+	size   int64
+	synced bool
+	// Ошибка, после которой журнал не удалось вернуть к последней целой записи.
+	// Дальнейшие записи в такой журнал невозможны.
+	err error
+}
+
+// openWAL открывает журнал для дозаписи, seq — номер последней примененной записи.
+func openWAL(path, fsync string, seq uint64) (*wal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wal: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to stat wal: %w", err)
+	}
+	return &wal{
+		path:   path,
+		fsync:  fsync,
+		file:   f,
+		buf:    bufio.NewWriter(f),
+		seq:    seq,
+		size:   info.Size(),
+		synced: true,
+	}, nil
+}
+
+// append записывает обновления в журнал. Записи передаются операционной системе сразу,
+// а на диск сбрасываются в соответствии с политикой fsync. При ошибке журнал обрезается
+// до записей, сделанных до вызова, чтобы неудачный пакет не применился при восстановлении.
+func (w *wal) append(items []metrics.Metric) error {
+	if w.err != nil {
+		return w.err
+	}
+	seq, size := w.seq, w.size
+	if err := w.write(items); err != nil {
+		return w.rollback(seq, size, err)
+	}
+	return nil
+}
+
+func (w *wal) write(items []metrics.Metric) error {
+	for _, m := range items {
+		w.seq++
+		line, err := encodeRecord(walRecord{Seq: w.seq, Metric: m})
+		if err != nil {
+			return err
+		}
+		n, err := w.buf.Write(line)
+		w.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("failed to write wal: %w", err)
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write wal: %w", err)
+	}
+	w.synced = false
+	if w.fsync == app.FsyncAlways {
+		return w.sync()
+	}
+	return nil
+}
+
+// rollback отменяет записи неудачного вызова append: журнал обрезается до размера size.
+// Если обрезать журнал не удалось, в нем может остаться оборванная запись, за которой
+// следующие записи были бы потеряны при восстановлении, поэтому журнал перестает принимать записи.
+func (w *wal) rollback(seq uint64, size int64, cause error) error {
+	w.buf.Reset(w.file)
+	w.seq, w.size = seq, size
+	if err := w.file.Truncate(size); err != nil {
+		w.err = fmt.Errorf("wal is broken: %w", errors.Join(cause, err))
+		return w.err
+	}
+	return cause
+}
+
+// sync сбрасывает записанные данные журнала на диск.
+func (w *wal) sync() error {
+	if w.synced {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync wal: %w", err)
+	}
+	w.synced = true
+	return nil
+}
+
+// rotate переименовывает текущий журнал в rotatedPath и начинает новый. Если предыдущий
+// переименованный журнал еще не удален, записи продолжают добавляться в текущий журнал.
+// Журнал, переставший принимать записи, тоже заменяется новым: снимок, сохраняемый
+// при ротации, содержит все примененные обновления.
+func (w *wal) rotate(rotatedPath string) error {
+	if _, err := os.Stat(rotatedPath); err == nil {
+		return nil
+	}
+	if err := w.close(); err != nil && w.err == nil {
+		return err
+	}
+	// Журнал открывается заново и при ошибке переименования, чтобы запись обновлений продолжилась.
+	renameErr := os.Rename(w.path, rotatedPath)
+	next, err := openWAL(w.path, w.fsync, w.seq)
+	if err != nil {
+		return errors.Join(renameErr, err)
+	}
+	*w = *next
+	if renameErr != nil {
+		return fmt.Errorf("failed to rotate wal: %w", renameErr)
+	}
+	return syncDir(w.path)
+}
+
+func (w *wal) close() error {
+	if w.err != nil {
+		return errors.Join(w.err, w.file.Close())
+	}
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write wal: %w", err)
+	}
+	if err := w.sync(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
+
+func encodeRecord(r walRecord) ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode wal record: %w", err)
+	}
+	line := fmt.Appendf(nil, "%08x ", crc32.ChecksumIEEE(data))
+	line = append(line, data...)
+	return append(line, '\n'), nil
+}
+
+func decodeRecord(line []byte) (walRecord, error) {
+	var r walRecord
+	sum, data, ok := bytes.Cut(bytes.TrimSuffix(line, []byte("\n")), []byte(" "))
+	if !ok {
+		return r, errors.New("malformed wal record")
+	}
+	var crc uint32
+	if _, err := fmt.Sscanf(string(sum), "%08x", &crc); err != nil || crc != crc32.ChecksumIEEE(data) {
+		return r, errors.New("wal record checksum mismatch")
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("malformed wal record: %w", err)
+	}
+	return r, nil
+}
+
+// replayWAL передает в apply записи журнала с номерами больше after и возвращает номер последней
+// записи. Чтение прекращается на первой поврежденной или оборванной записи: такая запись и все
+// следующие за ней отбрасываются, а если truncate — журнал обрезается до последней целой записи.
+// Отсутствующий журнал считается пустым.
+func replayWAL(path string, after uint64, truncate bool, apply func(metrics.Metric) error) (uint64, error) {
+	flag := os.O_RDONLY
+	if truncate {
+		flag = os.O_RDWR
+	}
+	f, err := os.OpenFile(path, flag, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return after, nil
+	}
+	if err != nil {
+		return after, fmt.Errorf("failed to open wal: %w", err)
+	}
+	defer f.Close()
+
+	last := after
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		line, readErr := r.ReadBytes('\n')
+		if errors.Is(readErr, io.EOF) && len(line) == 0 {
+			return last, nil
+		}
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return last, fmt.Errorf("failed to read wal: %w", readErr)
+		}
+
+		rec, decodeErr := decodeRecord(line)
+		if readErr != nil || decodeErr != nil {
+			// Оборванная или поврежденная запись: дальнейшее содержимое журнала недостоверно.
+			if !truncate {
+				return last, errTruncatedWAL
+			}
+			if err = f.Truncate(offset); err != nil {
+				return last, fmt.Errorf("failed to truncate wal: %w", err)
+			}
+			return last, errTruncatedWAL
+		}
+		offset += int64(len(line))
+		if rec.Seq > last {
+			if err = apply(rec.Metric); err != nil {
+				return last, err
+			}
+			last = rec.Seq
+		}
+	}
+}
+
+// errTruncatedWAL возвращается, если журнал был обрезан из-за поврежденной записи.
+var errTruncatedWAL = errors.New("wal contains a torn or corrupted record, tail discarded")
+
+// readSnapshot читает снимок. Поддерживается и прежний формат файла — массив метрик без номера записи.
+// Отсутствующий или пустой файл считается пустым снимком.
+func readSnapshot(path string) (snapshot, error) {
+	var s snapshot
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return s, nil
+	}
+	if data[0] == '[' {
+		err = json.Unmarshal(data, &s.Metrics)
+	} else {
+		err = json.Unmarshal(data, &s)
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// writeSnapshot атомарно записывает снимок: данные пишутся во временный файл в том же каталоге,
+// сбрасываются на диск и переименовываются в path, поэтому при сбое остается прежний целый снимок.
+func writeSnapshot(path string, s snapshot) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	if err = encoder.Encode(s); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename snapshot: %w", err)
+	}
+	return syncDir(path)
+}
+
+// syncDir сбрасывает на диск каталог файла path, чтобы сохранить создание и переименование файлов.
+func syncDir(path string) error {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+	defer dir.Close()
+	if err = dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+func TestMemStorage_WALWriteFailure(t *testing.T) {
+	ctx := context.Background()
+	cfg := &app.Config{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+	}
+	ms, err := New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 10)))
+
+	// Файл журнала закрыт: записать и обрезать журнал невозможно
+	ms.persistMu.Lock()
+	require.NoError(t, ms.wal.file.Close())
+	ms.persistMu.Unlock()
+
+	err = ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 5))
+	require.ErrorIs(t, err, storage.ErrUnavailable)
+	require.ErrorIs(t, ms.Ping(ctx), storage.ErrUnavailable)
+
+	// Пакет, не записанный в журнал, не применяется
+	m, err := ms.GetMetric(ctx, metrics.TypeCounter, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *m.Delta)
+
+	// Снимок, запрошенный после ошибки, начинает новый журнал, и запись возобновляется
+	require.Eventually(t, func() bool {
+		return ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 1)) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, ms.Ping(ctx))
+	require.NoError(t, ms.Close())
+
+	restored, err := New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	defer restored.Close()
+	m, err = restored.GetMetric(ctx, metrics.TypeCounter, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), *m.Delta)
+}
+
+func TestMemStorage_SnapshotDuringConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	cfg := &app.Config{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		Restore:         true,
+		Fsync:           app.FsyncNever,
+	}
+	ms, err := New(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	stop := make(chan struct{})
+	snapshots := make(chan struct{})
+	go func() {
+		defer close(snapshots)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				assert.NoError(t, ms.storeSnapshot())
+			}
+		}
+	}()
+
+	// Писатели обновляют одни и те же метрики, а снимки сохраняются одновременно с записью
+	const writers, rounds = 8, 200
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				assert.NoError(t, ms.UpdateMetrics(ctx, []metrics.Metric{
+					*metrics.NewCounter("requests", 1),
+					*metrics.NewGauge("load", float64(w*rounds+i)),
+					*metrics.NewGauge(fmt.Sprintf("writer_%d", w), float64(i)),
+				}))
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	<-snapshots
+
+	want, err := ms.GetMetrics(ctx)
+	require.NoError(t, err)
+	// Хранилище не закрывается: состояние восстанавливается из последнего снимка и журнала
+	ms.persistMu.Lock()
+	require.NoError(t, ms.wal.sync())
+	ms.persistMu.Unlock()
+
+	restored, err := New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	defer restored.Close()
+	got, err := restored.GetMetrics(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, got)
+}
@@ -0,0 +1,231 @@
+package memory_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
+)
+
+func newFileConfig(t *testing.T) *app.Config {
+	return &app.Config{
+		FileStoragePath: filepath.Join(t.TempDir(), "metrics.json"),
+		StoreInterval:   0,
+		Restore:         true,
+	}
+}
+
+func counterValue(t *testing.T, ms *memory.MemStorage, name string) int64 {
+	m, err := ms.GetMetric(context.Background(), metrics.TypeCounter, name)
+	require.NoError(t, err, "counter %s not found", name)
+	return *m.Delta
+}
+
+func TestMemStorage_RecoverFromWAL(t *testing.T) {
+	cfg := newFileConfig(t)
+	ctx := context.Background()
+
+	// Хранилище не закрывается, как при аварийном завершении: снимок не сохранен
+	ms := setupTestStorageWithConfig(t, cfg)
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 10)))
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 5)))
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewGauge("load", 0.5)))
+
+	restored := setupTestStorageWithConfig(t, cfg)
+	defer restored.Close()
+	assert.Equal(t, int64(15), counterValue(t, restored, "requests"))
+	m, err := restored.GetMetric(ctx, metrics.TypeGauge, "load")
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, *m.Value, 1e-9)
+}
+
+func TestMemStorage_RecoverFromTornWAL(t *testing.T) {
+	cfg := newFileConfig(t)
+	ctx := context.Background()
+
+	ms := setupTestStorageWithConfig(t, cfg)
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 10)))
+
+	// Последняя запись оборвана при сбое
+	f, err := os.OpenFile(cfg.GetStorePath()+".wal", os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`1a2b3c4d {"seq":2,"metric":{"id":"requ`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	restored := setupTestStorageWithConfig(t, cfg)
+	assert.Equal(t, int64(10), counterValue(t, restored, "requests"))
+
+	// Журнал обрезан до целой записи, поэтому новые записи читаются при следующем восстановлении
+	require.NoError(t, restored.UpdateMetric(ctx, *metrics.NewCounter("requests", 1)))
+	again := setupTestStorageWithConfig(t, cfg)
+	defer again.Close()
+	assert.Equal(t, int64(11), counterValue(t, again, "requests"))
+}
+
+func TestMemStorage_RecoverSkipsSnapshottedRecords(t *testing.T) {
+	cfg := newFileConfig(t)
+	ctx := context.Background()
+
+	ms := setupTestStorageWithConfig(t, cfg)
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 10)))
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 5)))
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 7)))
+
+	// Снимок содержит первые две записи журнала, которые не должны примениться повторно
+	snapshot := `{"seq":2,"metrics":[{"id":"requests","type":"counter","delta":15}]}`
+	require.NoError(t, os.WriteFile(cfg.GetStorePath(), []byte(snapshot), 0o600))
+
+	restored := setupTestStorageWithConfig(t, cfg)
+	defer restored.Close()
+	assert.Equal(t, int64(22), counterValue(t, restored, "requests"))
+}
+
+func TestMemStorage_SnapshotOnClose(t *testing.T) {
+	cfg := newFileConfig(t)
+	ctx := context.Background()
+
+	ms := setupTestStorageWithConfig(t, cfg)
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 10)))
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 5)))
+	require.NoError(t, ms.Close())
+	require.NoError(t, ms.Close())
+
+	// Снимок записан целиком, временные файлы и прежний журнал удалены
+	data, err := os.ReadFile(cfg.GetStorePath())
+	require.NoError(t, err)
+	var snapshot struct {
+		Seq     uint64           `json:"seq"`
+		Metrics []metrics.Metric `json:"metrics"`
+	}
+	require.NoError(t, json.Unmarshal(data, &snapshot))
+	assert.Equal(t, uint64(2), snapshot.Seq)
+	require.Len(t, snapshot.Metrics, 1)
+	assert.Equal(t, int64(15), *snapshot.Metrics[0].Delta)
+
+	entries, err := os.ReadDir(filepath.Dir(cfg.GetStorePath()))
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"metrics.json", "metrics.json.wal"}, names)
+
+	restored := setupTestStorageWithConfig(t, cfg)
+	defer restored.Close()
+	assert.Equal(t, int64(15), counterValue(t, restored, "requests"))
+}
+
+func TestMemStorage_RestoreLegacySnapshot(t *testing.T) {
+	cfg := newFileConfig(t)
+	require.NoError(t, os.WriteFile(cfg.GetStorePath(), []byte(validJSON), 0o600))
+
+	ms := setupTestStorageWithConfig(t, cfg)
+	defer ms.Close()
+	assert.Equal(t, int64(10), counterValue(t, ms, "test_counter"))
+}
+
+func TestMemStorage_RestoreDisabledDiscardsWAL(t *testing.T) {
+	cfg := newFileConfig(t)
+	ctx := context.Background()
+
+	ms := setupTestStorageWithConfig(t, cfg)
+	require.NoError(t, ms.UpdateMetric(ctx, *metrics.NewCounter("requests", 10)))
+
+	// Запуск без восстановления начинает хранение заново
+	cfg.Restore = false
+	fresh := setupTestStorageWithConfig(t, cfg)
+	require.NoError(t, fresh.Close())
+
+	cfg.Restore = true
+	restored := setupTestStorageWithConfig(t, cfg)
+	defer restored.Close()
+	_, err := restored.GetMetric(ctx, metrics.TypeCounter, "requests")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestLoad_ReadOnly(t *testing.T) {
+	cfg := newFileConfig(t)
+	ms := setupTestStorageWithConfig(t, cfg)
+	require.NoError(t, ms.UpdateMetric(context.Background(), *metrics.NewCounter("requests", 10)))
+
+	// Оборванная запись в журнале не обрезается при чтении без восстановления хранилища
+	walPath := cfg.GetStorePath() + ".wal"
+	f, err := os.OpenFile(walPath, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`1a2b3c4d {"seq":2`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	before, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+
+	loaded, err := memory.Load(cfg.GetStorePath(), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), counterValue(t, loaded, "requests"))
+	require.NoError(t, loaded.Close())
+
+	after, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestMemStorage_Ping(t *testing.T) {
+	ctx := context.Background()
+
+	// Без сохранения в файл хранилище всегда готово
+	inMemory, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, inMemory.Ping(ctx))
+
+	cfg := newFileConfig(t)
+	ms := setupTestStorageWithConfig(t, cfg)
+	require.NoError(t, ms.Ping(ctx))
+
+	// Проверка не оставляет файлов в каталоге хранилища
+	entries, err := os.ReadDir(filepath.Dir(cfg.GetStorePath()))
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Name(), ".ping-")
+	}
+
+	// Каталог хранилища удален: записать снимок невозможно
+	require.NoError(t, os.RemoveAll(filepath.Dir(cfg.GetStorePath())))
+	require.ErrorIs(t, ms.Ping(ctx), storage.ErrUnavailable)
+
+	// После закрытия журнал изменений не открыт
+	closed := setupTestStorageWithConfig(t, newFileConfig(t))
+	require.NoError(t, closed.Close())
+	require.ErrorIs(t, closed.Ping(ctx), storage.ErrUnavailable)
+}
+
+func TestMemStorage_RestoreFailureKeepsFiles(t *testing.T) {
+	cfg := newFileConfig(t)
+	path := cfg.GetStorePath()
+
+	// Снимок поврежден: хранилище не запускается, а файлы остаются для ручного восстановления
+	snapshot := []byte(`{"seq":2,"metrics":[{"id":"requ`)
+	require.NoError(t, os.WriteFile(path, snapshot, 0o600))
+	walData := []byte("journal")
+	require.NoError(t, os.WriteFile(path+".wal", walData, 0o600))
+	require.NoError(t, os.WriteFile(path+".wal.old", walData, 0o600))
+
+	ms, err := memory.New(cfg, zap.NewNop())
+	require.Error(t, err)
+	assert.Nil(t, ms)
+
+	for name, want := range map[string][]byte{path: snapshot, path + ".wal": walData, path + ".wal.old": walData} {
+		got, readErr := os.ReadFile(name)
+		require.NoError(t, readErr)
+		assert.Equal(t, want, got, name)
+	}
+}
@@ -0,0 +1,152 @@
+package pgstorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// insertSampleQuery сохраняет в историю текущее значение метрики из таблицы metrics,
+// поэтому для counter в истории оказывается накопленное значение, а приращение
+// сохраняется отдельно для суммы при прореживании.
+const insertSampleQuery = `INSERT INTO metric_samples (name, type, ts, value, delta)
+          SELECT name, type, now(), COALESCE(value, delta::DOUBLE PRECISION), $3::DOUBLE PRECISION
+          FROM metrics WHERE name = $1 AND type = $2`
+
+// queryRangeQuery выбирает исходные значения за период и агрегаты прореженной истории метрики,
+// интервалы которых пересекаются с периодом. Для агрегатов gauge возвращается среднее,
+// для counter — накопленное значение в конце интервала.
+const queryRangeQuery = `SELECT ts, value, 0 FROM metric_samples
+          WHERE name = $1 AND type = $2 AND ts BETWEEN $3 AND $4
+          UNION ALL
+          SELECT ts, CASE WHEN type = 'counter' THEN last ELSE sum / count END, resolution FROM metric_rollups
+          WHERE name = $1 AND type = $2 AND ts + resolution * INTERVAL '1 second' > $3 AND ts <= $4
+          ORDER BY 1`
+
+// downsampleSamplesQuery переносит исходные значения старше $1 в агрегаты с шагом $2 секунд
+// и возвращает количество перенесенных значений.
+const downsampleSamplesQuery = `WITH moved AS (
+              DELETE FROM metric_samples WHERE ts < $1 RETURNING name, type, ts, value, delta
+          ), merged AS (
+          INSERT INTO metric_rollups AS r (name, type, resolution, ts, count, min, max, sum, last)
+          SELECT name, type, $2::INTEGER,
+                 to_timestamp(floor(extract(epoch FROM ts) / $2::INTEGER) * $2::INTEGER) AS bucket,
+                 count(*), min(value), max(value),
+                 CASE WHEN type = 'counter' THEN COALESCE(sum(delta), 0) ELSE sum(value) END,
+                 (array_agg(value ORDER BY ts DESC))[1]
+          FROM moved GROUP BY name, type, bucket
+          ` + mergeRollupsClause + `
+          )
+          SELECT count(*) FROM moved`
+
+// downsampleRollupsQuery переносит агрегаты с шагом $3 секунд старше $1 в агрегаты с шагом $2 секунд
+// и возвращает количество перенесенных агрегатов.
+const downsampleRollupsQuery = `WITH moved AS (
+              DELETE FROM metric_rollups WHERE resolution = $3::INTEGER AND ts < $1
+              RETURNING name, type, ts, count, min, max, sum, last
+          ), merged AS (
+          INSERT INTO metric_rollups AS r (name, type, resolution, ts, count, min, max, sum, last)
+          SELECT name, type, $2::INTEGER,
+                 to_timestamp(floor(extract(epoch FROM ts) / $2::INTEGER) * $2::INTEGER) AS bucket,
+                 sum(count), min(min), max(max), sum(sum), (array_agg(last ORDER BY ts DESC))[1]
+          FROM moved GROUP BY name, type, bucket
+          ` + mergeRollupsClause + `
+          )
+          SELECT count(*) FROM moved`
+
+// mergeRollupsClause объединяет новый агрегат с уже существующим агрегатом того же интервала,
+// который мог остаться от предыдущего прореживания. Переносимые значения всегда новее.
+const mergeRollupsClause = `ON CONFLICT (name, type, resolution, ts) DO UPDATE SET
+              count = r.count + EXCLUDED.count,
+              min = LEAST(r.min, EXCLUDED.min),
+              max = GREATEST(r.max, EXCLUDED.max),
+              sum = r.sum + EXCLUDED.sum,
+              last = EXCLUDED.last`
+
+// QueryRange возвращает значения метрики за период [from, to] из таблиц metric_samples и metric_rollups.
+func (p *PgStorage) QueryRange(
+	ctx context.Context,
+	mType metrics.MetricType,
+	name string,
+	from, to time.Time,
+) ([]storage.Sample, error) {
+	if !p.isHistoryEnabled() {
+		return nil, storage.ErrHistoryDisabled
+	}
+
+	rows, err := p.conn.Query(ctx, queryRangeQuery, name, mType, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metric samples: %w", wrapError(err))
+	}
+	defer rows.Close()
+
+	var samples []storage.Sample
+	for rows.Next() {
+		var (
+			s          storage.Sample
+			resolution int64
+		)
+		if err = rows.Scan(&s.Time, &s.Value, &resolution); err != nil {
+			return nil, fmt.Errorf("failed to scan metric sample: %w", wrapError(err))
+		}
+		s.Resolution = time.Duration(resolution) * time.Second
+		samples = append(samples, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metric samples: %w", wrapError(err))
+	}
+	return samples, nil
+}
+
+// Downsample сворачивает значения истории с шагом src, записанные раньше before, в агрегаты с шагом dst.
+// Перенос выполняется одним запросом, поэтому прерванное прореживание не оставляет частичных изменений.
+func (p *PgStorage) Downsample(ctx context.Context, src, dst time.Duration, before time.Time) (int64, error) {
+	if !p.isHistoryEnabled() {
+		return 0, storage.ErrHistoryDisabled
+	}
+
+	q, args := downsampleSamplesQuery, []interface{}{before, int64(dst.Seconds())}
+	if src > 0 {
+		q, args = downsampleRollupsQuery, append(args, int64(src.Seconds()))
+	}
+	var moved int64
+	if err := p.conn.QueryRow(ctx, q, args...).Scan(&moved); err != nil {
+		return 0, fmt.Errorf("failed to downsample metric history: %w", wrapError(err))
+	}
+	return moved, nil
+}
+
+// DeleteHistory удаляет значения истории с шагом resolution, записанные раньше before.
+func (p *PgStorage) DeleteHistory(ctx context.Context, resolution time.Duration, before time.Time) (int64, error) {
+	if !p.isHistoryEnabled() {
+		return 0, storage.ErrHistoryDisabled
+	}
+
+	q, args := `DELETE FROM metric_samples WHERE ts < $1`, []interface{}{before}
+	if resolution > 0 {
+		q = `DELETE FROM metric_rollups WHERE resolution = $2::INTEGER AND ts < $1`
+		args = append(args, int64(resolution.Seconds()))
+	}
+	tag, err := p.conn.Exec(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete metric history: %w", wrapError(err))
+	}
+	return tag.RowsAffected(), nil
+}
+
+// isHistoryEnabled возвращает true, если значения метрик сохраняются в историю.
+func (p *PgStorage) isHistoryEnabled() bool {
+	return p.cfg != nil && p.cfg.IsHistoryEnabled()
+}
+
+// sampleDelta возвращает приращение counter для сохранения в историю, для gauge — nil.
+func sampleDelta(metric metrics.Metric) *float64 {
+	if metric.Delta == nil {
+		return nil
+	}
+	delta := float64(*metric.Delta)
+	return &delta
+}
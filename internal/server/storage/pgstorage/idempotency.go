@@ -0,0 +1,89 @@
+package pgstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// UpdateMetricsOnce применяет пакет метрик, если пакет с таким ключом не применялся в течение TTL.
+// Ключ сохраняется в таблицу applied_requests в той же транзакции, что и метрики,
+// поэтому пакет либо применяется вместе с ключом, либо не применяется вовсе.
+// Возвращает true, если пакет уже был применен ранее.
+func (p *PgStorage) UpdateMetricsOnce(
+	ctx context.Context,
+	key string,
+	items []metrics.Metric,
+) (bool, error) {
+	if err := storage.ValidateMetrics(items); err != nil {
+		return false, err
+	}
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return false, wrapError(err)
+	}
+
+	// Откатываем транзакцию в случае ошибки или повторного пакета
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if rErr := tx.Rollback(ctx); rErr != nil && !errors.Is(rErr, pgx.ErrTxClosed) {
+			p.log.Error(fmt.Sprintf("Failed to rollback transaction: %v", rErr))
+		}
+	}()
+
+	// Ключ с истекшим сроком хранения перезаписывается, поэтому повтор после TTL применяется снова.
+	// Устаревшие ключи удаляет периодическая задача PurgeAppliedRequests.
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO applied_requests (id) VALUES ($1)
+		ON CONFLICT (id) DO UPDATE SET applied_at = now()
+		WHERE applied_requests.applied_at < now() - make_interval(secs => $2)`,
+		key, p.cfg.GetIdempotencyTTL().Seconds())
+	if err != nil {
+		p.log.Error(fmt.Sprintf("Failed to save idempotency key: %v", err))
+		return false, wrapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		p.log.Debug(fmt.Sprintf("Metrics batch %s already applied", key))
+		return true, nil
+	}
+
+	br := tx.SendBatch(ctx, p.upsertBatch(items))
+	_, err = br.Exec()
+	if errClose := br.Close(); errClose != nil {
+		p.log.Error(fmt.Sprintf("Failed to close batch: %v", errClose))
+		return false, wrapError(errClose)
+	}
+	if err != nil {
+		p.log.Error(fmt.Sprintf("Failed to update metrics: %v", err))
+		return false, wrapError(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		p.log.Error(fmt.Sprintf("Failed to commit transaction: %v", err))
+		return false, wrapError(err)
+	}
+	committed = true
+
+	return false, nil
+}
+
+// PurgeAppliedRequests удаляет ключи идемпотентности с истекшим сроком хранения,
+// чтобы таблица applied_requests не росла бесконечно. Возвращает количество удаленных ключей.
+func (p *PgStorage) PurgeAppliedRequests(ctx context.Context) (int64, error) {
+	tag, err := p.conn.Exec(ctx,
+		`DELETE FROM applied_requests WHERE applied_at < now() - make_interval(secs => $1)`,
+		p.cfg.GetIdempotencyTTL().Seconds())
+	if err != nil {
+		return 0, wrapError(err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -4,11 +4,20 @@ package migration
 
 import (
 	"errors"
+	"fmt"
+	"io/fs"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 
 	"github.com/golang-migrate/migrate/v4"
 	// Подключение драйвера для работы с PostgreSQL.
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	// Подключение драйвера для работы с SQLite, строка подключения sqlite://<путь>.
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
 	// Подключение драйвера файловой системы, для чтения миграций из файлов.
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
@@ -33,3 +42,179 @@ func Up(migrationsPath string, dsn string) error {
 	slog.Info("Миграции применены.")
 	return nil
 }
+
+// Status состояние схемы базы данных.
+type Status struct {
+	// Version версия последней примененной миграции, 0 — миграции не применялись.
+	Version uint
+	// Dirty признак прерванной миграции: схему нужно исправить вручную и указать версию через Force.
+	Dirty bool
+	// Pending версии миграций, которые еще не применены.
+	Pending []uint
+}
+
+// Migrator управляет версией схемы базы данных по миграциям из директории.
+type Migrator struct {
+	m    *migrate.Migrate
+	path string
+}
+
+// Open открывает базу данных dsn и директорию с миграциями migrationsPath.
+func Open(migrationsPath string, dsn string) (*Migrator, error) {
+	m, err := migrate.New("file://"+migrationsPath, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations: %w", err)
+	}
+	return &Migrator{m: m, path: migrationsPath}, nil
+}
+
+// Close закрывает базу данных и директорию с миграциями.
+func (mg *Migrator) Close() error {
+	srcErr, dbErr := mg.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// Up применяет n следующих миграций, при n = 0 — все непримененные миграции.
+func (mg *Migrator) Up(n int) error {
+	if n < 0 {
+		return errors.New("number of migrations must be positive")
+	}
+	slog.Info("Применение миграций...", "path", mg.path, "steps", n)
+	if n == 0 {
+		return mg.done(mg.m.Up())
+	}
+	return mg.done(mg.m.Steps(n))
+}
+
+// Down откатывает n последних примененных миграций.
+func (mg *Migrator) Down(n int) error {
+	if n <= 0 {
+		return errors.New("number of migrations must be positive")
+	}
+	slog.Info("Откат миграций...", "path", mg.path, "steps", n)
+	return mg.done(mg.m.Steps(-n))
+}
+
+// Goto применяет или откатывает миграции до версии version.
+func (mg *Migrator) Goto(version uint) error {
+	slog.Info("Переход к версии схемы...", "path", mg.path, "version", version)
+	return mg.done(mg.m.Migrate(version))
+}
+
+// Force записывает версию схемы version и снимает признак прерванной миграции, не выполняя миграций.
+// Версия -1 означает, что миграции не применялись.
+func (mg *Migrator) Force(version int) error {
+	if err := mg.m.Force(version); err != nil {
+		return err
+	}
+	slog.Info("Версия схемы установлена.", "version", version)
+	return nil
+}
+
+// Status возвращает текущую версию схемы и список непримененных миграций.
+func (mg *Migrator) Status() (Status, error) {
+	var st Status
+	version, dirty, err := mg.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return st, err
+	}
+	st.Version, st.Dirty = version, dirty
+
+	versions, err := listVersions(mg.path)
+	if err != nil {
+		return st, err
+	}
+	for _, v := range versions {
+		if v > st.Version {
+			st.Pending = append(st.Pending, v)
+		}
+	}
+	return st, nil
+}
+
+// done записывает в лог результат миграции. Отсутствие миграций для применения не считается ошибкой.
+func (mg *Migrator) done(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		slog.Info("Нет миграций для применения.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	version, dirty, verErr := mg.m.Version()
+	if verErr != nil && !errors.Is(verErr, migrate.ErrNilVersion) {
+		return verErr
+	}
+	slog.Info("Миграции применены.", "version", version, "dirty", dirty)
+	return nil
+}
+
+// listVersions возвращает версии миграций из директории migrationsPath по возрастанию.
+func listVersions(migrationsPath string) ([]uint, error) {
+	src, err := source.Open("file://" + migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations: %w", err)
+	}
+	defer src.Close()
+
+	var versions []uint
+	v, err := src.First()
+	for err == nil {
+		versions = append(versions, v)
+		v, err = src.Next(v)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return versions, nil
+}
+
+var (
+	// migrationName допустимое имя новой миграции.
+	migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+	// migrationFile имя файла миграции: номер версии, имя и направление.
+	migrationFile = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+)
+
+// Create создает в директории migrationsPath пустые файлы миграции name с номером,
+// следующим за последней миграцией, и возвращает их пути.
+func Create(migrationsPath string, name string) ([]string, error) {
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q, expected lowercase letters, digits and _", name)
+	}
+	entries, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var last uint64
+	for _, e := range entries {
+		match := migrationFile.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+		v, parseErr := strconv.ParseUint(match[1], 10, 64)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", e.Name(), parseErr)
+		}
+		last = max(last, v)
+	}
+
+	base := filepath.Join(migrationsPath, fmt.Sprintf("%d_%s", last+1, name))
+	files := []string{base + ".up.sql", base + ".down.sql"}
+	for _, path := range files {
+		f, createErr := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if createErr != nil {
+			return nil, fmt.Errorf("failed to create migration: %w", createErr)
+		}
+		_, writeErr := fmt.Fprintf(f, "/* %s */\n", name)
+		if closeErr := f.Close(); writeErr == nil {
+			writeErr = closeErr
+		}
+		if writeErr != nil {
+			return nil, fmt.Errorf("failed to write migration: %w", writeErr)
+		}
+	}
+	slog.Info("Созданы файлы миграции.", "up", files[0], "down", files[1])
+	return files, nil
+}
@@ -0,0 +1,87 @@
+package migration_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maynagashev/go-metrics/internal/server/storage/pgstorage/migration"
+)
+
+// Миграции SQLite используются как пример, так как для PostgreSQL нужна запущенная база.
+const sqliteMigrations = "../../../../../migrations/sqlite"
+
+func openMigrator(t *testing.T) *migration.Migrator {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "metrics.db")
+	m, err := migration.Open(sqliteMigrations, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+func requireStatus(t *testing.T, m *migration.Migrator, version uint, pending []uint) {
+	st, err := m.Status()
+	require.NoError(t, err)
+	assert.Equal(t, version, st.Version)
+	assert.False(t, st.Dirty)
+	assert.Equal(t, pending, st.Pending)
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	m := openMigrator(t)
+	requireStatus(t, m, 0, []uint{1, 2})
+
+	require.NoError(t, m.Up(1))
+	requireStatus(t, m, 1, []uint{2})
+
+	require.NoError(t, m.Up(0))
+	requireStatus(t, m, 2, nil)
+
+	// Повторное применение без новых миграций не считается ошибкой
+	require.NoError(t, m.Up(0))
+
+	require.NoError(t, m.Down(1))
+	requireStatus(t, m, 1, []uint{2})
+
+	require.NoError(t, m.Goto(2))
+	requireStatus(t, m, 2, nil)
+
+	require.Error(t, m.Down(0))
+	require.Error(t, m.Down(5))
+}
+
+func TestMigrator_Force(t *testing.T) {
+	m := openMigrator(t)
+	require.NoError(t, m.Up(0))
+
+	require.NoError(t, m.Force(1))
+	requireStatus(t, m, 1, []uint{2})
+
+	require.NoError(t, m.Force(-1))
+	requireStatus(t, m, 0, []uint{1, 2})
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "7_init.up.sql"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "7_init.down.sql"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), nil, 0o600))
+
+	files, err := migration.Create(dir, "add_index")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "8_add_index.up.sql"),
+		filepath.Join(dir, "8_add_index.down.sql"),
+	}, files)
+	for _, f := range files {
+		assert.FileExists(t, f)
+	}
+
+	_, err = migration.Create(dir, "Add Index")
+	require.Error(t, err)
+	_, err = migration.Create(filepath.Join(dir, "missing"), "add_index")
+	require.Error(t, err)
+}
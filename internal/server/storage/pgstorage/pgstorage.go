@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/jackc/pgerrcode"
@@ -29,6 +30,7 @@ type PgxPoolInterface interface {
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 	Begin(ctx context.Context) (pgx.Tx, error)
+	Ping(ctx context.Context) error
 }
 
 // Убедимся, что pgxpool.Pool реализует наш интерфейс.
@@ -73,35 +75,57 @@ func (p *PgStorage) Close() error {
 	return nil
 }
 
-func (p *PgStorage) Count(ctx context.Context) int {
+// Ping проверяет соединение с базой данных, получая соединение из пула.
+func (p *PgStorage) Ping(ctx context.Context) error {
+	if err := p.conn.Ping(ctx); err != nil {
+		return fmt.Errorf("%w: %w", storage.ErrUnavailable, err)
+	}
+	return nil
+}
+
+func (p *PgStorage) Count(ctx context.Context) (int, error) {
 	var count int
 	err := p.conn.QueryRow(ctx, `SELECT count(*) FROM metrics`).Scan(&count)
 	if err != nil {
 		p.log.Error(err.Error())
+		return 0, wrapError(err)
 	}
-	return count
+	return count, nil
 }
 
-func (p *PgStorage) GetMetrics(ctx context.Context) []metrics.Metric {
+func (p *PgStorage) GetMetrics(ctx context.Context) ([]metrics.Metric, error) {
 	var items []metrics.Metric
-	rows, err := p.conn.Query(ctx, `SELECT name, type, value, delta FROM metrics ORDER BY name`)
+	err := p.StreamMetrics(ctx, func(metric metrics.Metric) error {
+		items = append(items, metric)
+		return nil
+	})
 	if err != nil {
 		p.log.Error(err.Error())
-		return nil
+		return nil, wrapError(err)
+	}
+
+	return items, nil
+}
+
+// StreamMetrics передает метрики по одной в fn по мере чтения строк результата запроса.
+func (p *PgStorage) StreamMetrics(ctx context.Context, fn func(metrics.Metric) error) error {
+	rows, err := p.conn.Query(ctx, `SELECT name, type, value, delta FROM metrics ORDER BY name`)
+	if err != nil {
+		return fmt.Errorf("failed to query metrics: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var metric metrics.Metric
-		err = rows.Scan(&metric.Name, &metric.MType, &metric.Value, &metric.Delta)
-		if err != nil {
-			p.log.Error(err.Error())
-			return nil
+		if err = rows.Scan(&metric.Name, &metric.MType, &metric.Value, &metric.Delta); err != nil {
+			return fmt.Errorf("failed to scan metric: %w", err)
+		}
+		if err = fn(metric); err != nil {
+			return err
 		}
-		items = append(items, metric)
 	}
 
-	return items
+	return rows.Err()
 }
 
 // GetMetric получение значения метрики указанного типа в виде универсальной структуры.
@@ -109,7 +133,7 @@ func (p *PgStorage) GetMetric(
 	ctx context.Context,
 	mType metrics.MetricType,
 	name string,
-) (metrics.Metric, bool) {
+) (metrics.Metric, error) {
 	q := `SELECT name, type, value, delta FROM public.metrics WHERE name = $1 AND type = $2`
 
 	var metric metrics.Metric
@@ -119,7 +143,7 @@ func (p *PgStorage) GetMetric(
 		err = row.Scan(&metric.Name, &metric.MType, &metric.Value, &metric.Delta)
 
 		if err == nil {
-			return metric, true
+			return metric, nil
 		}
 
 		// Проверяем, является ли ошибка retriable
@@ -136,63 +160,78 @@ func (p *PgStorage) GetMetric(
 		break
 	}
 
-	// Логируем и возвращаем ошибку, если не удалось получить метрику
-	if err != nil {
-		p.log.Error(fmt.Sprintf("Failed to get metric after %d tries: %v", maxRetries+1, err))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return metrics.Metric{}, storage.ErrNotFound
 	}
-	return metrics.Metric{}, false
+
+	// Логируем и возвращаем ошибку, если не удалось получить метрику
+	p.log.Error(fmt.Sprintf("Failed to get metric after %d tries: %v", maxRetries+1, err))
+	return metrics.Metric{}, wrapError(err)
 }
 
 // GetCounter возвращает счетчик по имени.
-func (p *PgStorage) GetCounter(ctx context.Context, name string) (storage.Counter, bool) {
-	m, ok := p.GetMetric(ctx, metrics.TypeCounter, name)
-	if !ok {
-		return 0, false
+func (p *PgStorage) GetCounter(ctx context.Context, name string) (storage.Counter, error) {
+	m, err := p.GetMetric(ctx, metrics.TypeCounter, name)
+	if err != nil {
+		return 0, err
 	}
-	return storage.Counter(*m.Delta), true
+	return storage.Counter(*m.Delta), nil
 }
 
 // GetGauge возвращает измерение по имени.
-func (p *PgStorage) GetGauge(ctx context.Context, name string) (storage.Gauge, bool) {
-	m, ok := p.GetMetric(ctx, metrics.TypeGauge, name)
-	if !ok {
-		return 0, false
+func (p *PgStorage) GetGauge(ctx context.Context, name string) (storage.Gauge, error) {
+	m, err := p.GetMetric(ctx, metrics.TypeGauge, name)
+	if err != nil {
+		return 0, err
 	}
-	return storage.Gauge(*m.Value), true
+	return storage.Gauge(*m.Value), nil
 }
 
 func (p *PgStorage) UpdateMetric(ctx context.Context, metric metrics.Metric) error {
+	if err := storage.ValidateMetric(metric); err != nil {
+		return err
+	}
+
 	var q string
 
 	// Если метрика существует, то обновляем, иначе создаем новую.
-	_, ok := p.GetMetric(ctx, metric.MType, metric.Name)
-	if ok {
+	_, err := p.GetMetric(ctx, metric.MType, metric.Name)
+	switch {
+	case err == nil:
 		q = `UPDATE metrics SET value = $3, delta = delta + $4 WHERE name = $1 AND type = $2`
-	} else {
+	case errors.Is(err, storage.ErrNotFound):
 		q = `INSERT INTO metrics (name, type, value, delta) VALUES ($1, $2, $3, $4)`
+	default:
+		return err
 	}
 
 	// Выполнение запроса
-	_, err := p.conn.Exec(ctx, q, metric.Name, metric.MType, metric.Value, metric.Delta)
+	_, err = p.conn.Exec(ctx, q, metric.Name, metric.MType, metric.Value, metric.Delta)
 	if err != nil {
 		p.log.Error(fmt.Sprintf("Failed to update metric: %v", err))
-		return err
+		return wrapError(err)
+	}
+
+	if p.isHistoryEnabled() {
+		if _, err = p.conn.Exec(ctx, insertSampleQuery, metric.Name, metric.MType, sampleDelta(metric)); err != nil {
+			p.log.Error(fmt.Sprintf("Failed to save metric sample: %v", err))
+			return wrapError(err)
+		}
 	}
 	return nil
 }
 
 // UpdateMetrics пакетно обновляет метрики в хранилище.
 func (p *PgStorage) UpdateMetrics(ctx context.Context, items []metrics.Metric) error {
-	var err error
-	q := `INSERT INTO metrics (name, type, value, delta) 
-          VALUES ($1, $2, $3, $4)
-          ON CONFLICT (name, type) 
-          DO UPDATE SET value = EXCLUDED.value, delta = metrics.delta + EXCLUDED.delta`
+	err := storage.ValidateMetrics(items)
+	if err != nil {
+		return err
+	}
 
 	// Начало транзакции
 	tx, err := p.conn.Begin(ctx)
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 
 	// Откатываем транзакцию в случае ошибки
@@ -204,33 +243,70 @@ func (p *PgStorage) UpdateMetrics(ctx context.Context, items []metrics.Metric) e
 		}
 	}()
 
-	batch := &pgx.Batch{}
-	for _, item := range items {
-		batch.Queue(q, item.Name, item.MType, item.Value, item.Delta)
-	}
-
 	// Выполнение батч-запроса
-	br := tx.SendBatch(ctx, batch)
+	br := tx.SendBatch(ctx, p.upsertBatch(items))
 	_, err = br.Exec()
 	if errClose := br.Close(); errClose != nil {
 		p.log.Error(fmt.Sprintf("Failed to close batch: %v", errClose))
-		return errClose
+		return wrapError(errClose)
 	}
 
 	if err != nil {
 		p.log.Error(fmt.Sprintf("Failed to update metrics: %v", err))
-		return err
+		return wrapError(err)
 	}
 
 	// Подтверждаем транзакцию
 	if err = tx.Commit(ctx); err != nil {
 		p.log.Error(fmt.Sprintf("Failed to commit transaction: %v", err))
-		return err
+		return wrapError(err)
 	}
 
 	return nil
 }
 
+// upsertBatch формирует батч-запрос для пакетного обновления метрик.
+// Если включена история, после обновления каждой метрики ее значение сохраняется в metric_samples.
+func (p *PgStorage) upsertBatch(items []metrics.Metric) *pgx.Batch {
+	q := `INSERT INTO metrics (name, type, value, delta) 
+          VALUES ($1, $2, $3, $4)
+          ON CONFLICT (name, type) 
+          DO UPDATE SET value = EXCLUDED.value, delta = metrics.delta + EXCLUDED.delta`
+
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		batch.Queue(q, item.Name, item.MType, item.Value, item.Delta)
+		if p.isHistoryEnabled() {
+			batch.Queue(insertSampleQuery, item.Name, item.MType, sampleDelta(item))
+		}
+	}
+	return batch
+}
+
+// wrapError оборачивает ошибки соединения с базой данных в storage.ErrUnavailable,
+// остальные ошибки возвращает без изменений.
+func wrapError(err error) error {
+	if err == nil || !isConnectionError(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", storage.ErrUnavailable, err)
+}
+
+// isConnectionError проверяет, что ошибка вызвана недоступностью базы данных:
+// не удалось подключиться, истек таймаут или сервер сообщил о проблеме соединения.
+func isConnectionError(err error) bool {
+	var connErr *pgconn.ConnectError
+	var netErr net.Error
+	if errors.As(err, &connErr) || errors.As(err, &netErr) || pgconn.Timeout(err) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgerrcode.IsConnectionException(pgErr.Code) || pgerrcode.IsOperatorIntervention(pgErr.Code)
+	}
+	return false
+}
+
 // Проверка, является ли ошибка retriable.
 func isRetriableError(err *pgconn.PgError) bool {
 	switch err.Code {
@@ -4,7 +4,9 @@ package pgstorage
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
@@ -16,6 +18,7 @@ import (
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
 )
 
 // Mock for pgxpool.Pool.
@@ -62,6 +65,11 @@ func (m *MockPgxPool) Begin(ctx context.Context) (pgx.Tx, error) {
 	return val, args.Error(1)
 }
 
+func (m *MockPgxPool) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 // Mock for pgx.Rows.
 type MockPgxRows struct {
 	mock.Mock
@@ -173,9 +181,9 @@ func (m *MockPgxTx) LargeObjects() pgx.LargeObjects {
 func (m *MockPgxTx) Prepare(
 	ctx context.Context,
 	name, sql string,
-) (pgconn.StatementDescription, error) {
+) (*pgconn.StatementDescription, error) {
 	args := m.Called(ctx, name, sql)
-	val, _ := args.Get(0).(pgconn.StatementDescription)
+	val, _ := args.Get(0).(*pgconn.StatementDescription)
 	return val, args.Error(1)
 }
 
@@ -201,6 +209,13 @@ func (m *MockPgxTx) QueryRow(ctx context.Context, sql string, args ...interface{
 	return val
 }
 
+func (m *MockPgxTx) Conn() *pgx.Conn {
+	return nil
+}
+
+// Убедимся, что мок реализует интерфейс pgx.Tx.
+var _ pgx.Tx = (*MockPgxTx)(nil)
+
 // Mock for pgx.BatchResults.
 type MockBatchResults struct {
 	mock.Mock
@@ -324,9 +339,10 @@ func TestPgStorage_Count(t *testing.T) {
 	}
 
 	// Вызываем тестируемый метод
-	count := storage.Count(ctx)
+	count, err := storage.Count(ctx)
 
 	// Проверяем результат
+	require.NoError(t, err)
 	assert.Equal(t, 42, count)
 
 	// Проверяем, что моки были вызваны с ожидаемыми параметрами
@@ -351,9 +367,10 @@ func TestPgStorage_Count(t *testing.T) {
 	}
 
 	// Вызываем тестируемый метод
-	count = storage2.Count(ctx)
+	count, err = storage2.Count(ctx)
 
-	// Проверяем результат (должен быть 0 при ошибке)
+	// Проверяем результат (ошибка возвращается вызывающему, количество 0)
+	require.EqualError(t, err, "database error")
 	assert.Equal(t, 0, count)
 
 	// Проверяем, что моки были вызваны с ожидаемыми параметрами
@@ -414,10 +431,10 @@ func TestPgStorage_GetMetric(t *testing.T) {
 	}
 
 	// Вызываем тестируемый метод
-	metric, ok := storage.GetMetric(ctx, mType, name)
+	metric, err := storage.GetMetric(ctx, mType, name)
 
 	// Проверяем результат
-	assert.True(t, ok)
+	require.NoError(t, err)
 	assert.Equal(t, name, metric.Name)
 	assert.Equal(t, mType, metric.MType)
 	assert.Equal(t, &value, metric.Value)
@@ -428,6 +445,51 @@ func TestPgStorage_GetMetric(t *testing.T) {
 	mockRow.AssertExpectations(t)
 }
 
+// Test for GetMetric method when the metric is missing or the database is unavailable.
+func TestPgStorage_GetMetric_Errors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		scanErr error
+		wantErr error
+	}{
+		{
+			name:    "not found",
+			scanErr: pgx.ErrNoRows,
+			wantErr: storage.ErrNotFound,
+		},
+		{
+			name:    "connection lost",
+			scanErr: &pgconn.PgError{Code: pgerrcode.AdminShutdown},
+			wantErr: storage.ErrUnavailable,
+		},
+		{
+			name:    "query error",
+			scanErr: &pgconn.PgError{Code: pgerrcode.UndefinedTable},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPool := new(MockPgxPool)
+			mockRow := new(MockPgxRow)
+			mockPool.On("QueryRow", ctx, mock.Anything, mock.Anything, mock.Anything).Return(mockRow)
+			mockRow.On("Scan", mock.Anything).Return(tt.scanErr)
+
+			p := &PgStorage{conn: mockPool, log: zap.NewNop()}
+			_, err := p.GetMetric(ctx, metrics.TypeGauge, "test_metric")
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.ErrorIs(t, err, tt.scanErr)
+				require.NotErrorIs(t, err, storage.ErrNotFound)
+				require.NotErrorIs(t, err, storage.ErrUnavailable)
+			}
+		})
+	}
+}
+
 // Test for GetCounter method.
 func TestPgStorage_GetCounter(t *testing.T) {
 	t.Skip("Skipping test that requires complex mocking")
@@ -648,3 +710,212 @@ func TestNew(t *testing.T) {
 	// Закрываем соединение
 	storage.Close()
 }
+
+// Test for Ping method.
+func TestPgStorage_Ping(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(MockPgxPool)
+	mockPool.On("Ping", ctx).Return(nil).Once()
+	mockPool.On("Ping", ctx).Return(errors.New("connection refused")).Once()
+
+	p := &PgStorage{conn: mockPool, cfg: &app.Config{}, log: zap.NewNop()}
+	require.NoError(t, p.Ping(ctx))
+
+	// Ошибка пула означает, что база данных недоступна.
+	err := p.Ping(ctx)
+	require.ErrorIs(t, err, storage.ErrUnavailable)
+	assert.Contains(t, err.Error(), "connection refused")
+	mockPool.AssertExpectations(t)
+}
+
+// Test for UpdateMetricsOnce method with a batch that was already applied.
+func TestPgStorage_UpdateMetricsOnce_Duplicate(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(MockPgxPool)
+	mockTx := new(MockPgxTx)
+
+	mockPool.On("Begin", ctx).Return(mockTx, nil)
+	mockTx.On("Exec", ctx, mock.MatchedBy(func(sql string) bool {
+		return len(sql) > 6 && sql[:6] == "INSERT"
+	}), []interface{}{"req-1", app.DefaultIdempotencyTTL().Seconds()}).Return(pgconn.NewCommandTag("INSERT 0 0"), nil)
+	mockTx.On("Rollback", ctx).Return(nil)
+
+	p := &PgStorage{conn: mockPool, cfg: &app.Config{}, log: zap.NewNop()}
+	duplicate, err := p.UpdateMetricsOnce(ctx, "req-1", []metrics.Metric{*metrics.NewCounter("c", 1)})
+
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+	// Метрики повторного пакета не отправляются в базу данных.
+	mockTx.AssertNotCalled(t, "SendBatch", mock.Anything, mock.Anything)
+	mockTx.AssertCalled(t, "Rollback", ctx)
+	mockPool.AssertExpectations(t)
+}
+
+// Test for UpdateMetricsOnce method with a new batch.
+func TestPgStorage_UpdateMetricsOnce_Applied(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(MockPgxPool)
+	mockTx := new(MockPgxTx)
+	mockBatch := new(MockBatchResults)
+
+	mockPool.On("Begin", ctx).Return(mockTx, nil)
+	mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+	mockTx.On("SendBatch", ctx, mock.MatchedBy(func(b *pgx.Batch) bool {
+		return b.Len() == 2
+	})).Return(mockBatch)
+	mockBatch.On("Exec").Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+	mockBatch.On("Close").Return(nil)
+	mockTx.On("Commit", ctx).Return(nil)
+
+	p := &PgStorage{conn: mockPool, cfg: &app.Config{}, log: zap.NewNop()}
+	duplicate, err := p.UpdateMetricsOnce(ctx, "req-1", []metrics.Metric{
+		*metrics.NewCounter("c", 1),
+		*metrics.NewGauge("g", 1),
+	})
+
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	mockTx.AssertNotCalled(t, "Rollback", ctx)
+	mockTx.AssertExpectations(t)
+	mockBatch.AssertExpectations(t)
+}
+
+// Test for PurgeAppliedRequests method.
+func TestPgStorage_PurgeAppliedRequests(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(MockPgxPool)
+	mockPool.On("Exec", ctx, mock.MatchedBy(func(sql string) bool {
+		return len(sql) > 6 && sql[:6] == "DELETE"
+	}), []interface{}{float64(60)}).Return(pgconn.NewCommandTag("DELETE 3"), nil).Once()
+
+	p := &PgStorage{conn: mockPool, cfg: &app.Config{IdempotencyTTL: time.Minute}, log: zap.NewNop()}
+	purged, err := p.PurgeAppliedRequests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+
+	// Недоступность базы данных возвращается как storage.ErrUnavailable
+	mockPool.On("Exec", ctx, mock.Anything, mock.Anything).
+		Return(pgconn.CommandTag{}, &pgconn.PgError{Code: pgerrcode.AdminShutdown})
+	_, err = p.PurgeAppliedRequests(ctx)
+	require.ErrorIs(t, err, storage.ErrUnavailable)
+	mockPool.AssertExpectations(t)
+}
+
+// Test for UpdateMetricsOnce method with transaction error.
+func TestPgStorage_UpdateMetricsOnce_BeginError(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(MockPgxPool)
+	mockPool.On("Begin", ctx).Return(nil, errors.New("connection refused"))
+
+	p := &PgStorage{conn: mockPool, cfg: &app.Config{}, log: zap.NewNop()}
+	_, err := p.UpdateMetricsOnce(ctx, "req-1", nil)
+	require.Error(t, err)
+
+	// Сервер базы данных останавливается: пакет не применен, хранилище временно недоступно
+	mockPool = new(MockPgxPool)
+	mockPool.On("Begin", ctx).Return(nil, &pgconn.PgError{Code: pgerrcode.AdminShutdown})
+	p = &PgStorage{conn: mockPool, cfg: &app.Config{}, log: zap.NewNop()}
+	_, err = p.UpdateMetricsOnce(ctx, "req-1", nil)
+	require.ErrorIs(t, err, storage.ErrUnavailable)
+
+	// Некорректный пакет отклоняется без обращения к базе данных
+	_, err = p.UpdateMetricsOnce(ctx, "req-2", []metrics.Metric{{Name: "load", MType: metrics.TypeGauge}})
+	require.ErrorIs(t, err, storage.ErrInvalidMetric)
+}
+
+// Test for QueryRange method.
+func TestPgStorage_QueryRange(t *testing.T) {
+	ctx := context.Background()
+	from := time.Unix(1700000000, 0)
+	to := from.Add(time.Hour)
+
+	mockPool := new(MockPgxPool)
+	mockRows := new(MockPgxRows)
+	mockPool.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+		return strings.Contains(sql, "FROM metric_samples")
+	}), []interface{}{"PollCount", metrics.TypeCounter, from, to}).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Scan", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		if ts, ok := args.Get(0).(*time.Time); ok {
+			*ts = from
+		}
+		if value, ok := args.Get(1).(*float64); ok {
+			*value = 42
+		}
+		if resolution, ok := args.Get(2).(*int64); ok {
+			*resolution = 3600
+		}
+	}).Return(nil)
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close").Return()
+
+	p := &PgStorage{conn: mockPool, cfg: &app.Config{History: true}, log: zap.NewNop()}
+	samples, err := p.QueryRange(ctx, metrics.TypeCounter, "PollCount", from, to)
+
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Sample{{Time: from, Value: 42, Resolution: time.Hour}}, samples)
+	mockPool.AssertExpectations(t)
+	mockRows.AssertExpectations(t)
+
+	// Без включенной истории запрос к базе данных не выполняется.
+	p = &PgStorage{conn: new(MockPgxPool), cfg: &app.Config{}, log: zap.NewNop()}
+	_, err = p.QueryRange(ctx, metrics.TypeCounter, "PollCount", from, to)
+	require.ErrorIs(t, err, storage.ErrHistoryDisabled)
+}
+
+// Test for QueryRange method when the database is unavailable.
+func TestPgStorage_QueryRange_Unavailable(t *testing.T) {
+	ctx := context.Background()
+	from := time.Unix(1700000000, 0)
+	to := from.Add(time.Hour)
+	cfg := &app.Config{History: true}
+
+	// Запрос не выполнен: сервер базы данных останавливается
+	mockPool := new(MockPgxPool)
+	mockPool.On("Query", ctx, mock.Anything, mock.Anything).
+		Return(nil, &pgconn.PgError{Code: pgerrcode.AdminShutdown})
+	p := &PgStorage{conn: mockPool, cfg: cfg, log: zap.NewNop()}
+	_, err := p.QueryRange(ctx, metrics.TypeGauge, "Alloc", from, to)
+	require.ErrorIs(t, err, storage.ErrUnavailable)
+
+	// Соединение разорвано во время чтения результатов
+	mockPool = new(MockPgxPool)
+	mockRows := new(MockPgxRows)
+	mockPool.On("Query", ctx, mock.Anything, mock.Anything).Return(mockRows, nil)
+	mockRows.On("Next").Return(false)
+	mockRows.On("Err").Return(&pgconn.PgError{Code: pgerrcode.AdminShutdown})
+	mockRows.On("Close").Return()
+	p = &PgStorage{conn: mockPool, cfg: cfg, log: zap.NewNop()}
+	_, err = p.QueryRange(ctx, metrics.TypeGauge, "Alloc", from, to)
+	require.ErrorIs(t, err, storage.ErrUnavailable)
+}
+
+// Test for Downsample and DeleteHistory methods.
+func TestPgStorage_CompactHistory(t *testing.T) {
+	ctx := context.Background()
+	before := time.Unix(1700000000, 0)
+
+	mockPool := new(MockPgxPool)
+	mockRow := new(MockPgxRow)
+	mockPool.On("QueryRow", ctx, downsampleRollupsQuery, []interface{}{before, int64(3600), int64(60)}).
+		Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		destSlice, _ := args.Get(0).([]interface{})
+		if ptr, ok := destSlice[0].(*int64); ok {
+			*ptr = 12
+		}
+	}).Return(nil)
+	mockPool.On("Exec", ctx, "DELETE FROM metric_samples WHERE ts < $1", []interface{}{before}).
+		Return(pgconn.NewCommandTag("DELETE 3"), nil)
+
+	p := &PgStorage{conn: mockPool, cfg: &app.Config{History: true}, log: zap.NewNop()}
+	moved, err := p.Downsample(ctx, time.Minute, time.Hour, before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), moved)
+
+	deleted, err := p.DeleteHistory(ctx, 0, before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	mockPool.AssertExpectations(t)
+}
@@ -0,0 +1,70 @@
+package sqlitestorage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// UpdateMetricsOnce применяет пакет метрик, если пакет с таким ключом не применялся в течение TTL.
+// Ключ сохраняется в таблицу applied_requests в той же транзакции, что и метрики,
+// поэтому пакет либо применяется вместе с ключом, либо не применяется вовсе.
+// Возвращает true, если пакет уже был применен ранее.
+func (s *SQLiteStorage) UpdateMetricsOnce(
+	ctx context.Context,
+	key string,
+	items []metrics.Metric,
+) (bool, error) {
+	if err := storage.ValidateMetrics(items); err != nil {
+		return false, err
+	}
+
+	replayed := false
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now()
+
+		// Ключ с истекшим сроком хранения перезаписывается, поэтому повтор после TTL применяется снова.
+		// Устаревшие ключи удаляет периодическая задача PurgeAppliedRequests.
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO applied_requests (id, applied_at) VALUES (?, ?)
+			ON CONFLICT (id) DO UPDATE SET applied_at = excluded.applied_at
+			WHERE applied_requests.applied_at < ?`,
+			key, now.UnixMilli(), now.Add(-s.cfg.GetIdempotencyTTL()).UnixMilli())
+		if err != nil {
+			return err
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if inserted == 0 {
+			replayed = true
+			return nil
+		}
+		return upsert(ctx, tx, items)
+	})
+	if err != nil {
+		s.log.Error("failed to apply metrics batch", zap.String("idempotency_key", key), zap.Error(err))
+		return false, err
+	}
+	if replayed {
+		s.log.Debug("metrics batch already applied", zap.String("idempotency_key", key))
+	}
+	return replayed, nil
+}
+
+// PurgeAppliedRequests удаляет ключи идемпотентности с истекшим сроком хранения,
+// чтобы таблица applied_requests не росла бесконечно. Возвращает количество удаленных ключей.
+func (s *SQLiteStorage) PurgeAppliedRequests(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM applied_requests WHERE applied_at < ?`,
+		time.Now().Add(-s.cfg.GetIdempotencyTTL()).UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
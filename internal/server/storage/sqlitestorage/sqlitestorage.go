@@ -0,0 +1,279 @@
+// Package sqlitestorage реализует хранилище метрик во встроенной базе данных SQLite.
+// Используется драйвер на чистом Go, поэтому сборка не требует cgo.
+package sqlitestorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/golang-migrate/migrate/v4"
+	sqlitemigrate "github.com/golang-migrate/migrate/v4/database/sqlite"
+	// Подключение драйвера файловой системы, для чтения миграций из файлов.
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"go.uber.org/zap"
+	// Пакет драйвера также регистрирует драйвер SQLite для database/sql.
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+)
+
+// busyTimeoutMs время ожидания блокировки базы данных другим соединением, в миллисекундах.
+const busyTimeoutMs = 5000
+
+// upsertQuery создает метрику или обновляет существующую так же, как PgStorage.UpdateMetrics:
+// значение gauge перезаписывается, приращение counter прибавляется к накопленному.
+const upsertQuery = `INSERT INTO metrics (name, type, value, delta)
+          VALUES (?, ?, ?, ?)
+          ON CONFLICT (name, type)
+          DO UPDATE SET value = excluded.value, delta = metrics.delta + excluded.delta`
+
+type SQLiteStorage struct {
+	db  *sql.DB
+	cfg *app.Config
+	log *zap.Logger
+}
+
+// New открывает базу данных SQLite по пути из конфигурации, накатывает миграции и возвращает экземпляр хранилища.
+// База работает в режиме журнала WAL, поэтому чтение не блокируется записью.
+func New(ctx context.Context, config *app.Config, log *zap.Logger) (*SQLiteStorage, error) {
+	log.Debug("opening sqlite database", zap.String("path", config.SQLite.Path))
+	db, err := sql.Open("sqlite", dsn(config.SQLite.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	s := &SQLiteStorage{db: db, cfg: config, log: log}
+
+	// Автоматически накатываем миграции при создании экземпляра хранилища.
+	if err = s.migrate(config.SQLite.MigrationsPath); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return s, nil
+}
+
+// dsn возвращает строку подключения к файлу базы данных: журнал WAL, ожидание блокировки
+// вместо немедленной ошибки SQLITE_BUSY и захват блокировки записи в начале транзакции.
+func dsn(path string) string {
+	q := url.Values{}
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", "synchronous(NORMAL)")
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeoutMs))
+	q.Set("_txlock", "immediate")
+	return "file:" + path + "?" + q.Encode()
+}
+
+// migrate применяет миграции из директории migrationsPath.
+func (s *SQLiteStorage) migrate(migrationsPath string) error {
+	s.log.Info("applying sqlite migrations", zap.String("path", migrationsPath))
+	driver, err := sqlitemigrate.WithInstance(s.db, &sqlitemigrate.Config{})
+	if err != nil {
+		return err
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://"+migrationsPath, "sqlite", driver)
+	if err != nil {
+		return err
+	}
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Close закрывает базу данных.
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// Ping проверяет, что файл базы данных открыт и доступен для запросов.
+func (s *SQLiteStorage) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %w", storage.ErrUnavailable, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM metrics`).Scan(&count)
+	if err != nil {
+		s.log.Error("failed to count metrics", zap.Error(err))
+		return 0, wrapError(fmt.Errorf("failed to count metrics: %w", err))
+	}
+	return count, nil
+}
+
+func (s *SQLiteStorage) GetMetrics(ctx context.Context) ([]metrics.Metric, error) {
+	var items []metrics.Metric
+	err := s.StreamMetrics(ctx, func(metric metrics.Metric) error {
+		items = append(items, metric)
+		return nil
+	})
+	if err != nil {
+		s.log.Error("failed to get metrics", zap.Error(err))
+		return nil, wrapError(err)
+	}
+	return items, nil
+}
+
+// StreamMetrics передает метрики по одной в fn по мере чтения строк результата запроса.
+func (s *SQLiteStorage) StreamMetrics(ctx context.Context, fn func(metrics.Metric) error) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, type, value, delta FROM metrics ORDER BY name`)
+	if err != nil {
+		return fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var metric metrics.Metric
+		if err = rows.Scan(&metric.Name, &metric.MType, &metric.Value, &metric.Delta); err != nil {
+			return fmt.Errorf("failed to scan metric: %w", err)
+		}
+		if err = fn(metric); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetMetric получение значения метрики указанного типа в виде универсальной структуры.
+func (s *SQLiteStorage) GetMetric(
+	ctx context.Context,
+	mType metrics.MetricType,
+	name string,
+) (metrics.Metric, error) {
+	var metric metrics.Metric
+	err := s.db.QueryRowContext(ctx,
+		`SELECT name, type, value, delta FROM metrics WHERE name = ? AND type = ?`, name, mType).
+		Scan(&metric.Name, &metric.MType, &metric.Value, &metric.Delta)
+	if errors.Is(err, sql.ErrNoRows) {
+		return metrics.Metric{}, storage.ErrNotFound
+	}
+	if err != nil {
+		s.log.Error("failed to get metric", zap.String("name", name), zap.Error(err))
+		return metrics.Metric{}, wrapError(fmt.Errorf("failed to get metric: %w", err))
+	}
+	return metric, nil
+}
+
+// GetCounter возвращает счетчик по имени.
+func (s *SQLiteStorage) GetCounter(ctx context.Context, name string) (storage.Counter, error) {
+	m, err := s.GetMetric(ctx, metrics.TypeCounter, name)
+	if err != nil {
+		return 0, err
+	}
+	return storage.Counter(*m.Delta), nil
+}
+
+// GetGauge возвращает измерение по имени.
+func (s *SQLiteStorage) GetGauge(ctx context.Context, name string) (storage.Gauge, error) {
+	m, err := s.GetMetric(ctx, metrics.TypeGauge, name)
+	if err != nil {
+		return 0, err
+	}
+	return storage.Gauge(*m.Value), nil
+}
+
+// UpdateMetric создает метрику или обновляет существующую.
+func (s *SQLiteStorage) UpdateMetric(ctx context.Context, metric metrics.Metric) error {
+	if err := storage.ValidateMetric(metric); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, upsertQuery, metric.Name, metric.MType, metric.Value, metric.Delta)
+	if err != nil {
+		s.log.Error("failed to update metric", zap.Error(err))
+		return wrapError(err)
+	}
+	return nil
+}
+
+// UpdateMetrics пакетно обновляет метрики в хранилище в одной транзакции.
+func (s *SQLiteStorage) UpdateMetrics(ctx context.Context, items []metrics.Metric) error {
+	if err := storage.ValidateMetrics(items); err != nil {
+		return err
+	}
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return upsert(ctx, tx, items)
+	})
+}
+
+// inTx выполняет fn в транзакции, которая подтверждается, если fn не вернула ошибку.
+// Ошибки недоступности базы данных оборачиваются в storage.ErrUnavailable.
+func (s *SQLiteStorage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapError(err)
+	}
+	if err = fn(tx); err != nil {
+		if rErr := tx.Rollback(); rErr != nil {
+			s.log.Error("failed to rollback transaction", zap.Error(rErr))
+		}
+		return wrapError(err)
+	}
+	if err = tx.Commit(); err != nil {
+		s.log.Error("failed to commit transaction", zap.Error(err))
+		return wrapError(err)
+	}
+	return nil
+}
+
+// upsert создает или обновляет метрики пакета подготовленным запросом.
+func upsert(ctx context.Context, tx *sql.Tx, items []metrics.Metric) error {
+	stmt, err := tx.PrepareContext(ctx, upsertQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, item := range items {
+		if _, err = stmt.ExecContext(ctx, item.Name, item.MType, item.Value, item.Delta); err != nil {
+			return fmt.Errorf("failed to update metric %s: %w", item.Name, err)
+		}
+	}
+	return nil
+}
+
+// wrapError оборачивает ошибки недоступности базы данных в storage.ErrUnavailable,
+// остальные ошибки возвращает без изменений.
+func wrapError(err error) error {
+	if err == nil || !isUnavailableError(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", storage.ErrUnavailable, err)
+}
+
+// isUnavailableError проверяет, что ошибка вызвана недоступностью базы данных, а не запросом:
+// соединение закрыто, база заблокирована другим соединением дольше busy_timeout, файл не открывается,
+// доступен только для чтения или диск переполнен, либо произошла ошибка ввода-вывода.
+func isUnavailableError(err error) bool {
+	if errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// Младший байт расширенного кода ошибки — основной код.
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY,
+		sqlite3.SQLITE_LOCKED,
+		sqlite3.SQLITE_NOMEM,
+		sqlite3.SQLITE_READONLY,
+		sqlite3.SQLITE_IOERR,
+		sqlite3.SQLITE_FULL,
+		sqlite3.SQLITE_CANTOPEN:
+		return true
+	default:
+		return false
+	}
+}
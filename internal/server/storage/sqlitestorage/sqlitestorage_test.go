@@ -0,0 +1,209 @@
+package sqlitestorage_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
+	"github.com/maynagashev/go-metrics/internal/server/app"
+	"github.com/maynagashev/go-metrics/internal/server/storage"
+	"github.com/maynagashev/go-metrics/internal/server/storage/sqlitestorage"
+)
+
+// Проверяем, что хранилище реализует интерфейсы хранилищ сервера.
+var (
+	_ storage.Repository           = (*sqlitestorage.SQLiteStorage)(nil)
+	_ storage.IdempotentRepository = (*sqlitestorage.SQLiteStorage)(nil)
+	_ storage.MetricsStreamer      = (*sqlitestorage.SQLiteStorage)(nil)
+)
+
+func newConfig(t *testing.T) *app.Config {
+	return &app.Config{
+		SQLite: app.SQLiteConfig{
+			Path:           filepath.Join(t.TempDir(), "metrics.db"),
+			MigrationsPath: "../../../../migrations/sqlite",
+		},
+	}
+}
+
+func openStorage(t *testing.T, cfg *app.Config) *sqlitestorage.SQLiteStorage {
+	s, err := sqlitestorage.New(context.Background(), cfg, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestSQLiteStorage_UpdateMetrics(t *testing.T) {
+	s := openStorage(t, newConfig(t))
+	ctx := context.Background()
+
+	_, err := s.GetMetric(ctx, metrics.TypeGauge, "load")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.NoError(t, s.UpdateMetric(ctx, *metrics.NewGauge("load", 1.5)))
+	require.NoError(t, s.UpdateMetric(ctx, *metrics.NewCounter("requests", 10)))
+	require.NoError(t, s.UpdateMetrics(ctx, []metrics.Metric{
+		*metrics.NewGauge("load", 2.5),
+		*metrics.NewCounter("requests", 5),
+		*metrics.NewCounter("requests", 1),
+	}))
+
+	// Значение gauge перезаписывается, приращения counter суммируются
+	gauge, err := s.GetGauge(ctx, "load")
+	require.NoError(t, err)
+	assert.InDelta(t, 2.5, float64(gauge), 1e-9)
+	counter, err := s.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(16), counter)
+
+	count, err := s.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	items, err := s.GetMetrics(ctx)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "load", items[0].Name)
+	assert.Equal(t, "requests", items[1].Name)
+}
+
+func TestSQLiteStorage_UpdateMetricsOnce(t *testing.T) {
+	s := openStorage(t, newConfig(t))
+	ctx := context.Background()
+	batch := []metrics.Metric{*metrics.NewCounter("requests", 10)}
+
+	replayed, err := s.UpdateMetricsOnce(ctx, "key-1", batch)
+	require.NoError(t, err)
+	assert.False(t, replayed)
+
+	// Повтор пакета с тем же ключом не увеличивает счетчик
+	replayed, err = s.UpdateMetricsOnce(ctx, "key-1", batch)
+	require.NoError(t, err)
+	assert.True(t, replayed)
+
+	counter, err := s.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(10), counter)
+}
+
+func TestSQLiteStorage_UpdateMetricsOnce_Expired(t *testing.T) {
+	cfg := newConfig(t)
+	cfg.IdempotencyTTL = 20 * time.Millisecond
+	s := openStorage(t, cfg)
+	ctx := context.Background()
+	batch := []metrics.Metric{*metrics.NewCounter("requests", 1)}
+
+	_, err := s.UpdateMetricsOnce(ctx, "key-1", batch)
+	require.NoError(t, err)
+	_, err = s.UpdateMetricsOnce(ctx, "key-2", batch)
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+
+	// Ключ с истекшим сроком хранения не мешает повторному применению, даже если еще не удален
+	replayed, err := s.UpdateMetricsOnce(ctx, "key-1", batch)
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	counter, err := s.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(3), counter)
+
+	// Удаляется только устаревший ключ, примененный заново ключ остается
+	purged, err := s.PurgeAppliedRequests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	replayed, err = s.UpdateMetricsOnce(ctx, "key-1", batch)
+	require.NoError(t, err)
+	assert.True(t, replayed)
+}
+
+func TestSQLiteStorage_Ping(t *testing.T) {
+	ctx := context.Background()
+	s, err := sqlitestorage.New(ctx, newConfig(t), zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, s.Ping(ctx))
+
+	// После закрытия база данных недоступна
+	require.NoError(t, s.Close())
+	require.ErrorIs(t, s.Ping(ctx), storage.ErrUnavailable)
+}
+
+func TestSQLiteStorage_Errors(t *testing.T) {
+	cfg := newConfig(t)
+	s := openStorage(t, cfg)
+	ctx := context.Background()
+
+	// Некорректное обновление отклоняется до обращения к базе данных
+	err := s.UpdateMetric(ctx, metrics.Metric{Name: "load", MType: metrics.TypeGauge})
+	require.ErrorIs(t, err, storage.ErrInvalidMetric)
+	err = s.UpdateMetrics(ctx, []metrics.Metric{{Name: "x", MType: "histogram"}})
+	require.ErrorIs(t, err, storage.ErrInvalidMetric)
+
+	// База заблокирована другим соединением дольше busy_timeout: хранилище временно недоступно
+	db, err := sql.Open("sqlite", cfg.SQLite.Path)
+	require.NoError(t, err)
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, `BEGIN EXCLUSIVE`)
+	require.NoError(t, err)
+	defer conn.ExecContext(ctx, `ROLLBACK`) //nolint:errcheck // откат блокировки в конце теста
+
+	err = s.UpdateMetric(ctx, *metrics.NewCounter("requests", 1))
+	require.ErrorIs(t, err, storage.ErrUnavailable)
+}
+
+func TestSQLiteStorage_Reopen(t *testing.T) {
+	cfg := newConfig(t)
+	ctx := context.Background()
+
+	s, err := sqlitestorage.New(ctx, cfg, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateMetric(ctx, *metrics.NewCounter("requests", 7)))
+	require.NoError(t, s.Close())
+
+	// Метрики сохраняются между запусками, повторные миграции не применяются
+	reopened := openStorage(t, cfg)
+	counter, err := reopened.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(7), counter)
+
+	// База переведена в режим журнала WAL
+	db, err := sql.Open("sqlite", cfg.SQLite.Path)
+	require.NoError(t, err)
+	defer db.Close()
+	var mode string
+	require.NoError(t, db.QueryRow(`PRAGMA journal_mode`).Scan(&mode))
+	assert.Equal(t, "wal", mode)
+}
+
+func TestSQLiteStorage_ConcurrentWrites(t *testing.T) {
+	const writers, rounds = 4, 25
+	s := openStorage(t, newConfig(t))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				assert.NoError(t, s.UpdateMetrics(ctx, []metrics.Metric{*metrics.NewCounter("requests", 1)}))
+				_, err := s.GetMetrics(ctx)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	counter, err := s.GetCounter(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Counter(writers*rounds), counter)
+}
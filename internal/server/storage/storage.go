@@ -4,7 +4,10 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 )
@@ -21,28 +24,44 @@ func (v Counter) String() string {
 	return strconv.FormatInt(int64(v), 10)
 }
 
+// Ошибки хранилища метрик. Реализации Repository оборачивают ошибки, чтобы вызывающий код
+// различал их через errors.Is: отсутствие метрики, некорректное обновление, недоступность хранилища
+// и прочие сбои.
+var (
+	// ErrNotFound метрика с указанным типом и именем отсутствует в хранилище.
+	ErrNotFound = errors.New("metric not found")
+	// ErrInvalidMetric обновление метрики некорректно: неизвестный тип или нет значения.
+	ErrInvalidMetric = errors.New("invalid metric")
+	// ErrUnavailable хранилище временно недоступно, например нет соединения с базой данных.
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
 // Repository предоставляет интерфейс для работы с хранилищем метрик.
 type Repository interface {
 	// Close закрывает хранилище метрик.
 	Close() error
 
+	// Ping проверяет, что хранилище готово принимать запросы: доступна база данных или
+	// возможна запись в файл хранилища. Возвращает ошибку, обернутую в ErrUnavailable.
+	Ping(ctx context.Context) error
+
 	// Count возвращает общее количество метрик в хранилище.
-	Count(ctx context.Context) int
+	Count(ctx context.Context) (int, error)
 
 	// GetMetrics возвращает все метрики в виде структур.
-	GetMetrics(ctx context.Context) []metrics.Metric
+	GetMetrics(ctx context.Context) ([]metrics.Metric, error)
 
 	// GetMetric получает значение метрики указанного типа.
-	// Возвращает метрику и флаг, указывающий на её наличие в хранилище.
-	GetMetric(ctx context.Context, mType metrics.MetricType, name string) (metrics.Metric, bool)
+	// Возвращает ErrNotFound, если метрики нет в хранилище.
+	GetMetric(ctx context.Context, mType metrics.MetricType, name string) (metrics.Metric, error)
 
 	// GetCounter возвращает значение счетчика по имени.
-	// Возвращает значение и флаг, указывающий на наличие счетчика.
-	GetCounter(ctx context.Context, name string) (Counter, bool)
+	// Возвращает ErrNotFound, если счетчика нет в хранилище.
+	GetCounter(ctx context.Context, name string) (Counter, error)
 
 	// GetGauge возвращает значение gauge-метрики по имени.
-	// Возвращает значение и флаг, указывающий на наличие метрики.
-	GetGauge(ctx context.Context, name string) (Gauge, bool)
+	// Возвращает ErrNotFound, если метрики нет в хранилище.
+	GetGauge(ctx context.Context, name string) (Gauge, error)
 
 	// UpdateMetric обновляет или создает метрику в хранилище.
 	// Поддерживает типы gauge и counter.
@@ -51,3 +70,128 @@ type Repository interface {
 	// UpdateMetrics пакетно обновляет набор метрик в хранилище.
 	UpdateMetrics(ctx context.Context, metrics []metrics.Metric) error
 }
+
+// ValidateMetric проверяет тип метрики и наличие значения. Возвращает ошибку, обернутую в ErrInvalidMetric.
+func ValidateMetric(metric metrics.Metric) error {
+	switch metric.MType {
+	case metrics.TypeGauge:
+		if metric.Value == nil {
+			return fmt.Errorf("%w: gauge value is nil", ErrInvalidMetric)
+		}
+	case metrics.TypeCounter:
+		if metric.Delta == nil {
+			return fmt.Errorf("%w: counter delta is nil", ErrInvalidMetric)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported metric type: %s", ErrInvalidMetric, metric.MType)
+	}
+	return nil
+}
+
+// ValidateMetrics проверяет все метрики пакета функцией ValidateMetric.
+func ValidateMetrics(items []metrics.Metric) error {
+	for _, item := range items {
+		if err := ValidateMetric(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IdempotentRepository хранилище, поддерживающее однократное применение пакетов метрик.
+//
+// Агент передает с каждым пакетом ключ идемпотентности, хранилище запоминает ключи
+// примененных пакетов, чтобы повторная отправка того же пакета (например, после таймаута,
+// когда сервер уже сохранил метрики) не увеличивала счетчики второй раз.
+type IdempotentRepository interface {
+	// UpdateMetricsOnce применяет пакет метрик, если пакет с таким ключом еще не применялся.
+	// Возвращает true, если пакет уже был применен ранее и повторно не применялся.
+	UpdateMetricsOnce(ctx context.Context, key string, metrics []metrics.Metric) (bool, error)
+}
+
+// AppliedRequestsPurger хранилище, в котором ключи идемпотентности с истекшим сроком хранения
+// удаляются периодической задачей, а не при применении пакетов.
+type AppliedRequestsPurger interface {
+	// PurgeAppliedRequests удаляет ключи идемпотентности старше TTL и возвращает количество удаленных ключей.
+	PurgeAppliedRequests(ctx context.Context) (int64, error)
+}
+
+// MetricsStreamer хранилище, отдающее метрики по одной без загрузки всего набора в память.
+type MetricsStreamer interface {
+	// StreamMetrics вызывает fn для каждой метрики хранилища.
+	// Обход прекращается, как только fn вернет ошибку, эта ошибка возвращается вызывающему.
+	StreamMetrics(ctx context.Context, fn func(metrics.Metric) error) error
+}
+
+// ErrHistoryDisabled возвращается при запросе истории, если хранилище не сохраняет историю значений.
+var ErrHistoryDisabled = errors.New("metrics history is disabled")
+
+// Sample значение метрики в момент времени. Для counter сохраняется накопленное значение после обновления.
+type Sample struct {
+	Time  time.Time
+	Value float64
+	// Шаг прореживания для агрегата, который относится к интервалу [Time, Time+Resolution),
+	// 0 для исходного значения.
+	Resolution time.Duration
+}
+
+// Aggregate агрегат значений метрики за интервал, начинающийся в Start.
+// Для gauge Sum содержит сумму значений, для counter — сумму приращений за интервал;
+// Min, Max и Last — минимальное, максимальное и последнее значение (для counter — накопленное).
+type Aggregate struct {
+	Start time.Time
+	Count int64
+	Min   float64
+	Max   float64
+	Sum   float64
+	Last  float64
+}
+
+// Merge добавляет к агрегату значения более позднего агрегата next.
+func (a *Aggregate) Merge(next Aggregate) {
+	if a.Count == 0 {
+		*a = next
+		return
+	}
+	a.Count += next.Count
+	a.Min = min(a.Min, next.Min)
+	a.Max = max(a.Max, next.Max)
+	a.Sum += next.Sum
+	a.Last = next.Last
+}
+
+// Value значение агрегата в результатах запроса истории:
+// для gauge — среднее, для counter — накопленное значение в конце интервала.
+func (a Aggregate) Value(mType metrics.MetricType) float64 {
+	if mType == metrics.TypeCounter || a.Count == 0 {
+		return a.Last
+	}
+	return a.Sum / float64(a.Count)
+}
+
+// HistoryRepository хранилище, сохраняющее историю значений метрик.
+type HistoryRepository interface {
+	// QueryRange возвращает значения метрики за период [from, to] в порядке возрастания времени:
+	// исходные значения, записанные в этот период, и агрегаты, интервалы которых пересекаются с ним.
+	// Возвращает ErrHistoryDisabled, если сохранение истории выключено.
+	QueryRange(
+		ctx context.Context,
+		mType metrics.MetricType,
+		name string,
+		from, to time.Time,
+	) ([]Sample, error)
+}
+
+// HistoryCompactor хранилище истории, поддерживающее прореживание и удаление старых значений.
+//
+// Каждый вызов выполняется атомарно, поэтому прерванное прореживание не теряет
+// и не дублирует значения, а продолжается при следующем запуске.
+type HistoryCompactor interface {
+	// Downsample сворачивает значения с шагом src (0 — исходные значения), записанные раньше before,
+	// в агрегаты с шагом dst и возвращает количество свернутых значений.
+	Downsample(ctx context.Context, src, dst time.Duration, before time.Time) (int64, error)
+
+	// DeleteHistory удаляет значения с шагом resolution (0 — исходные значения), записанные раньше before,
+	// и возвращает количество удаленных значений.
+	DeleteHistory(ctx context.Context, resolution time.Duration, before time.Time) (int64, error)
+}
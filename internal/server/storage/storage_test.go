@@ -1,29 +1,36 @@
 package storage_test
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/internal/server/app"
 	"github.com/maynagashev/go-metrics/internal/server/storage"
 	"github.com/maynagashev/go-metrics/internal/server/storage/memory"
 )
 
 func TestMemoryStorage(t *testing.T) {
+	want, err := memory.New(&app.Config{}, zap.NewNop())
+	require.NoError(t, err)
 	tests := []struct {
 		name string
 		want storage.Repository
 	}{
 		{
 			name: "memory storage",
-			want: memory.New(&app.Config{}, zap.NewNop()),
+			want: want,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := memory.New(&app.Config{}, zap.NewNop()); !reflect.DeepEqual(got, tt.want) {
+			got, err := memory.New(&app.Config{}, zap.NewNop())
+			require.NoError(t, err)
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("MemoryStorage() = %v, want %v", got, tt.want)
 			}
 		})
@@ -123,3 +130,30 @@ func TestCounter_String(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateMetric(t *testing.T) {
+	value := 1.5
+	delta := int64(2)
+	tests := []struct {
+		name    string
+		metric  metrics.Metric
+		wantErr bool
+	}{
+		{name: "gauge", metric: metrics.Metric{Name: "g", MType: metrics.TypeGauge, Value: &value}},
+		{name: "counter", metric: metrics.Metric{Name: "c", MType: metrics.TypeCounter, Delta: &delta}},
+		{name: "gauge without value", metric: metrics.Metric{Name: "g", MType: metrics.TypeGauge}, wantErr: true},
+		{name: "counter without delta", metric: metrics.Metric{Name: "c", MType: metrics.TypeCounter}, wantErr: true},
+		{name: "unknown type", metric: metrics.Metric{Name: "x", MType: "histogram", Value: &value}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := storage.ValidateMetric(tt.metric)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("ValidateMetric() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, storage.ErrInvalidMetric) {
+				t.Errorf("ValidateMetric() error = %v, want ErrInvalidMetric", err)
+			}
+		})
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS applied_requests;
\ No newline at end of file
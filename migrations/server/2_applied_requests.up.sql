@@ -0,0 +1,9 @@
+/* Ключи идемпотентности примененных пакетов метрик, повторный пакет с тем же ключом не применяется. */
+CREATE TABLE IF NOT EXISTS applied_requests
+(
+    id         VARCHAR(128) PRIMARY KEY,
+    applied_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+/* Индекс для удаления устаревших ключей. */
+CREATE INDEX IF NOT EXISTS idx_applied_requests_applied_at ON applied_requests (applied_at);
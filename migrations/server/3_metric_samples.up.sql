@@ -0,0 +1,11 @@
+/* История значений метрик: значение после каждого обновления, для counter накопленное. */
+CREATE TABLE IF NOT EXISTS metric_samples
+(
+    name  VARCHAR(250)     NOT NULL,
+    type  VARCHAR(50)      NOT NULL,
+    ts    TIMESTAMPTZ      NOT NULL DEFAULT now(),
+    value DOUBLE PRECISION NOT NULL
+);
+
+/* Индекс для выборки значений метрики за период. */
+CREATE INDEX IF NOT EXISTS idx_metric_samples_name_type_ts ON metric_samples (name, type, ts);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS metric_rollups;
+DROP INDEX IF EXISTS idx_metric_samples_ts;
+ALTER TABLE metric_samples DROP COLUMN IF EXISTS delta;
@@ -0,0 +1,23 @@
+/* Приращение counter для суммы при прореживании истории. */
+ALTER TABLE metric_samples ADD COLUMN IF NOT EXISTS delta DOUBLE PRECISION NULL;
+
+/* Индекс для выборки значений, срок хранения которых истек. */
+CREATE INDEX IF NOT EXISTS idx_metric_samples_ts ON metric_samples (ts);
+
+/* Агрегаты прореженной истории значений метрик. resolution — шаг агрегации в секундах,
+   ts — начало интервала. sum для gauge — сумма значений, для counter — сумма приращений. */
+CREATE TABLE IF NOT EXISTS metric_rollups
+(
+    name       VARCHAR(250)     NOT NULL,
+    type       VARCHAR(50)      NOT NULL,
+    resolution INTEGER          NOT NULL,
+    ts         TIMESTAMPTZ      NOT NULL,
+    count      BIGINT           NOT NULL,
+    min        DOUBLE PRECISION NOT NULL,
+    max        DOUBLE PRECISION NOT NULL,
+    sum        DOUBLE PRECISION NOT NULL,
+    last       DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (name, type, resolution, ts)
+);
+
+CREATE INDEX IF NOT EXISTS idx_metric_rollups_resolution_ts ON metric_rollups (resolution, ts);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS metrics
+(
+    name  TEXT    NOT NULL,
+    type  TEXT    NOT NULL,
+    delta INTEGER NULL,
+    value REAL    NULL,
+    /* Комбинации имени и типа метрики в БД должны быть уникальны. */
+    PRIMARY KEY (name, type)
+);
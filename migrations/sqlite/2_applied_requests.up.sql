@@ -0,0 +1,10 @@
+/* Ключи идемпотентности примененных пакетов метрик, повторный пакет с тем же ключом не применяется.
+   Время применения хранится в миллисекундах с начала эпохи Unix. */
+CREATE TABLE IF NOT EXISTS applied_requests
+(
+    id         TEXT    PRIMARY KEY,
+    applied_at INTEGER NOT NULL
+);
+
+/* Индекс для удаления устаревших ключей. */
+CREATE INDEX IF NOT EXISTS idx_applied_requests_applied_at ON applied_requests (applied_at);
@@ -4,6 +4,8 @@
 package mocks
 
 import (
+	context "context"
+
 	metrics "github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	mock "github.com/stretchr/testify/mock"
 
@@ -33,139 +35,177 @@ func (_m *Repository) Close() error {
 	return r0
 }
 
-// Count provides a mock function with given fields:
-func (_m *Repository) Count() int {
-	ret := _m.Called()
+// Count provides a mock function with given fields: ctx
+func (_m *Repository) Count(ctx context.Context) (int, error) {
+	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Count")
 	}
 
 	var r0 int
-	if rf, ok := ret.Get(0).(func() int); ok {
-		r0 = rf()
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// GetCounter provides a mock function with given fields: name
-func (_m *Repository) GetCounter(name string) (storage.Counter, bool) {
-	ret := _m.Called(name)
+// GetCounter provides a mock function with given fields: ctx, name
+func (_m *Repository) GetCounter(ctx context.Context, name string) (storage.Counter, error) {
+	ret := _m.Called(ctx, name)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetCounter")
 	}
 
 	var r0 storage.Counter
-	var r1 bool
-	if rf, ok := ret.Get(0).(func(string) (storage.Counter, bool)); ok {
-		return rf(name)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (storage.Counter, error)); ok {
+		return rf(ctx, name)
 	}
-	if rf, ok := ret.Get(0).(func(string) storage.Counter); ok {
-		r0 = rf(name)
+	if rf, ok := ret.Get(0).(func(context.Context, string) storage.Counter); ok {
+		r0 = rf(ctx, name)
 	} else {
 		r0 = ret.Get(0).(storage.Counter)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) bool); ok {
-		r1 = rf(name)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
 	} else {
-		r1 = ret.Get(1).(bool)
+		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
 
-// GetGauge provides a mock function with given fields: name
-func (_m *Repository) GetGauge(name string) (storage.Gauge, bool) {
-	ret := _m.Called(name)
+// GetGauge provides a mock function with given fields: ctx, name
+func (_m *Repository) GetGauge(ctx context.Context, name string) (storage.Gauge, error) {
+	ret := _m.Called(ctx, name)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetGauge")
 	}
 
 	var r0 storage.Gauge
-	var r1 bool
-	if rf, ok := ret.Get(0).(func(string) (storage.Gauge, bool)); ok {
-		return rf(name)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (storage.Gauge, error)); ok {
+		return rf(ctx, name)
 	}
-	if rf, ok := ret.Get(0).(func(string) storage.Gauge); ok {
-		r0 = rf(name)
+	if rf, ok := ret.Get(0).(func(context.Context, string) storage.Gauge); ok {
+		r0 = rf(ctx, name)
 	} else {
 		r0 = ret.Get(0).(storage.Gauge)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) bool); ok {
-		r1 = rf(name)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
 	} else {
-		r1 = ret.Get(1).(bool)
+		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
 
-// GetMetric provides a mock function with given fields: mType, name
-func (_m *Repository) GetMetric(mType metrics.MetricType, name string) (metrics.Metric, bool) {
-	ret := _m.Called(mType, name)
+// GetMetric provides a mock function with given fields: ctx, mType, name
+func (_m *Repository) GetMetric(ctx context.Context, mType metrics.MetricType, name string) (metrics.Metric, error) {
+	ret := _m.Called(ctx, mType, name)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetMetric")
 	}
 
 	var r0 metrics.Metric
-	var r1 bool
-	if rf, ok := ret.Get(0).(func(metrics.MetricType, string) (metrics.Metric, bool)); ok {
-		return rf(mType, name)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, metrics.MetricType, string) (metrics.Metric, error)); ok {
+		return rf(ctx, mType, name)
 	}
-	if rf, ok := ret.Get(0).(func(metrics.MetricType, string) metrics.Metric); ok {
-		r0 = rf(mType, name)
+	if rf, ok := ret.Get(0).(func(context.Context, metrics.MetricType, string) metrics.Metric); ok {
+		r0 = rf(ctx, mType, name)
 	} else {
 		r0 = ret.Get(0).(metrics.Metric)
 	}
 
-	if rf, ok := ret.Get(1).(func(metrics.MetricType, string) bool); ok {
-		r1 = rf(mType, name)
+	if rf, ok := ret.Get(1).(func(context.Context, metrics.MetricType, string) error); ok {
+		r1 = rf(ctx, mType, name)
 	} else {
-		r1 = ret.Get(1).(bool)
+		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
 
-// GetMetrics provides a mock function with given fields:
-func (_m *Repository) GetMetrics() []metrics.Metric {
-	ret := _m.Called()
+// GetMetrics provides a mock function with given fields: ctx
+func (_m *Repository) GetMetrics(ctx context.Context) ([]metrics.Metric, error) {
+	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetMetrics")
 	}
 
 	var r0 []metrics.Metric
-	if rf, ok := ret.Get(0).(func() []metrics.Metric); ok {
-		r0 = rf()
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]metrics.Metric, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []metrics.Metric); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]metrics.Metric)
 		}
 	}
 
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Ping provides a mock function with given fields: ctx
+func (_m *Repository) Ping(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Ping")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
 	return r0
 }
 
-// UpdateMetric provides a mock function with given fields: metric
-func (_m *Repository) UpdateMetric(metric metrics.Metric) error {
-	ret := _m.Called(metric)
+// UpdateMetric provides a mock function with given fields: ctx, metric
+func (_m *Repository) UpdateMetric(ctx context.Context, metric metrics.Metric) error {
+	ret := _m.Called(ctx, metric)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateMetric")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(metrics.Metric) error); ok {
-		r0 = rf(metric)
+	if rf, ok := ret.Get(0).(func(context.Context, metrics.Metric) error); ok {
+		r0 = rf(ctx, metric)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -173,17 +213,17 @@ func (_m *Repository) UpdateMetric(metric metrics.Metric) error {
 	return r0
 }
 
-// UpdateMetrics provides a mock function with given fields: _a0
-func (_m *Repository) UpdateMetrics(_a0 []metrics.Metric) error {
-	ret := _m.Called(_a0)
+// UpdateMetrics provides a mock function with given fields: ctx, _a1
+func (_m *Repository) UpdateMetrics(ctx context.Context, _a1 []metrics.Metric) error {
+	ret := _m.Called(ctx, _a1)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateMetrics")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func([]metrics.Metric) error); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, []metrics.Metric) error); ok {
+		r0 = rf(ctx, _a1)
 	} else {
 		r0 = ret.Error(0)
 	}
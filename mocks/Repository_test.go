@@ -1,10 +1,12 @@
 package mocks_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
@@ -12,6 +14,9 @@ import (
 	"github.com/maynagashev/go-metrics/mocks"
 )
 
+// Проверяем, что мок соответствует интерфейсу хранилища.
+var _ storage.Repository = (*mocks.Repository)(nil)
+
 func TestRepository_Close(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -76,15 +81,16 @@ func TestRepository_Count(t *testing.T) {
 			r := mocks.NewRepository(t)
 
 			// Setup expectations
-			r.On("Count").Return(tt.mockCount)
+			r.On("Count", mock.Anything).Return(tt.mockCount, nil)
 
 			// Call the method
-			count := r.Count()
+			count, err := r.Count(context.Background())
 
 			// Assert expectations
 			r.AssertExpectations(t)
 
 			// Check the result
+			require.NoError(t, err)
 			assert.Equal(t, tt.mockCount, count)
 		})
 	}
@@ -95,31 +101,31 @@ func TestRepository_GetCounter(t *testing.T) {
 		name       string
 		metricName string
 		mockValue  storage.Counter
-		mockExists bool
+		mockErr    error
 	}{
 		{
 			name:       "counter exists",
 			metricName: "test_counter",
 			mockValue:  storage.Counter(42),
-			mockExists: true,
+			mockErr:    nil,
 		},
 		{
 			name:       "counter does not exist",
 			metricName: "non_existent_counter",
 			mockValue:  storage.Counter(0),
-			mockExists: false,
+			mockErr:    storage.ErrNotFound,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := mocks.NewRepository(t)
-			repo.On("GetCounter", tt.metricName).Return(tt.mockValue, tt.mockExists)
+			repo.On("GetCounter", mock.Anything, tt.metricName).Return(tt.mockValue, tt.mockErr)
 
-			value, exists := repo.GetCounter(tt.metricName)
+			value, err := repo.GetCounter(context.Background(), tt.metricName)
 
 			assert.Equal(t, tt.mockValue, value)
-			assert.Equal(t, tt.mockExists, exists)
+			assert.ErrorIs(t, err, tt.mockErr)
 			repo.AssertExpectations(t)
 		})
 	}
@@ -130,31 +136,31 @@ func TestRepository_GetGauge(t *testing.T) {
 		name       string
 		metricName string
 		mockValue  storage.Gauge
-		mockExists bool
+		mockErr    error
 	}{
 		{
 			name:       "gauge exists",
 			metricName: "test_gauge",
 			mockValue:  storage.Gauge(3.14),
-			mockExists: true,
+			mockErr:    nil,
 		},
 		{
 			name:       "gauge does not exist",
 			metricName: "non_existent_gauge",
 			mockValue:  storage.Gauge(0),
-			mockExists: false,
+			mockErr:    storage.ErrNotFound,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := mocks.NewRepository(t)
-			repo.On("GetGauge", tt.metricName).Return(tt.mockValue, tt.mockExists)
+			repo.On("GetGauge", mock.Anything, tt.metricName).Return(tt.mockValue, tt.mockErr)
 
-			value, exists := repo.GetGauge(tt.metricName)
+			value, err := repo.GetGauge(context.Background(), tt.metricName)
 
 			assert.InDelta(t, float64(tt.mockValue), float64(value), 0.0001)
-			assert.Equal(t, tt.mockExists, exists)
+			assert.ErrorIs(t, err, tt.mockErr)
 			repo.AssertExpectations(t)
 		})
 	}
@@ -166,7 +172,7 @@ func TestRepository_GetMetric(t *testing.T) {
 		metricType metrics.MetricType
 		metricName string
 		mockMetric metrics.Metric
-		mockExists bool
+		mockErr    error
 	}{
 		{
 			name:       "gauge metric exists",
@@ -177,7 +183,7 @@ func TestRepository_GetMetric(t *testing.T) {
 				MType: metrics.TypeGauge,
 				Value: func() *float64 { v := 3.14; return &v }(),
 			},
-			mockExists: true,
+			mockErr: nil,
 		},
 		{
 			name:       "counter metric exists",
@@ -188,26 +194,26 @@ func TestRepository_GetMetric(t *testing.T) {
 				MType: metrics.TypeCounter,
 				Delta: func() *int64 { v := int64(42); return &v }(),
 			},
-			mockExists: true,
+			mockErr: nil,
 		},
 		{
 			name:       "metric does not exist",
 			metricType: metrics.TypeGauge,
 			metricName: "non_existent_metric",
 			mockMetric: metrics.Metric{},
-			mockExists: false,
+			mockErr:    storage.ErrNotFound,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := mocks.NewRepository(t)
-			repo.On("GetMetric", tt.metricType, tt.metricName).Return(tt.mockMetric, tt.mockExists)
+			repo.On("GetMetric", mock.Anything, tt.metricType, tt.metricName).Return(tt.mockMetric, tt.mockErr)
 
-			metric, exists := repo.GetMetric(tt.metricType, tt.metricName)
+			metric, err := repo.GetMetric(context.Background(), tt.metricType, tt.metricName)
 
 			assert.Equal(t, tt.mockMetric, metric)
-			assert.Equal(t, tt.mockExists, exists)
+			assert.ErrorIs(t, err, tt.mockErr)
 			repo.AssertExpectations(t)
 		})
 	}
@@ -242,10 +248,11 @@ func TestRepository_GetMetrics(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := mocks.NewRepository(t)
-			repo.On("GetMetrics").Return(tt.mockMetrics)
+			repo.On("GetMetrics", mock.Anything).Return(tt.mockMetrics, nil)
 
-			metrics := repo.GetMetrics()
+			metrics, err := repo.GetMetrics(context.Background())
 
+			require.NoError(t, err)
 			assert.Equal(t, tt.mockMetrics, metrics)
 			repo.AssertExpectations(t)
 		})
@@ -293,9 +300,9 @@ func TestRepository_UpdateMetric(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := mocks.NewRepository(t)
-			repo.On("UpdateMetric", tt.metric).Return(tt.mockErr)
+			repo.On("UpdateMetric", mock.Anything, tt.metric).Return(tt.mockErr)
 
-			err := repo.UpdateMetric(tt.metric)
+			err := repo.UpdateMetric(context.Background(), tt.metric)
 
 			if tt.shouldFail {
 				require.Error(t, err)
@@ -354,9 +361,9 @@ func TestRepository_UpdateMetrics(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := mocks.NewRepository(t)
-			repo.On("UpdateMetrics", tt.metrics).Return(tt.mockErr)
+			repo.On("UpdateMetrics", mock.Anything, tt.metrics).Return(tt.mockErr)
 
-			err := repo.UpdateMetrics(tt.metrics)
+			err := repo.UpdateMetrics(context.Background(), tt.metrics)
 
 			if tt.shouldFail {
 				require.Error(t, err)
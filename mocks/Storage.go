@@ -5,7 +5,6 @@ package mocks
 import (
 	context "context"
 
-	metrics "github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -14,21 +13,19 @@ type Storage struct {
 	mock.Mock
 }
 
-// GetMetrics provides a mock function with given fields: ctx
-func (_m *Storage) GetMetrics(ctx context.Context) []metrics.Metric {
+// Ping provides a mock function with given fields: ctx
+func (_m *Storage) Ping(ctx context.Context) error {
 	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetMetrics")
+		panic("no return value specified for Ping")
 	}
 
-	var r0 []metrics.Metric
-	if rf, ok := ret.Get(0).(func(context.Context) []metrics.Metric); ok {
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
 		r0 = rf(ctx)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]metrics.Metric)
-		}
+		r0 = ret.Error(0)
 	}
 
 	return r0
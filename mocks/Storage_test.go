@@ -2,30 +2,27 @@ package mocks_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
-	"github.com/maynagashev/go-metrics/internal/contracts/metrics"
 	"github.com/maynagashev/go-metrics/mocks"
 )
 
-func TestStorage_GetMetrics(t *testing.T) {
+func TestStorage_Ping(t *testing.T) {
 	tests := []struct {
-		name        string
-		mockMetrics []metrics.Metric
+		name    string
+		mockErr error
 	}{
 		{
-			name:        "empty storage",
-			mockMetrics: []metrics.Metric{},
+			name:    "storage is ready",
+			mockErr: nil,
 		},
 		{
-			name: "storage with metrics",
-			mockMetrics: []metrics.Metric{
-				*metrics.NewGauge("gauge1", 1.0),
-				*metrics.NewCounter("counter1", 1),
-			},
+			name:    "storage is unavailable",
+			mockErr: errors.New("connection refused"),
 		},
 	}
 
@@ -35,16 +32,16 @@ func TestStorage_GetMetrics(t *testing.T) {
 			s := mocks.NewStorage(t)
 
 			// Setup expectations
-			s.On("GetMetrics", mock.Anything).Return(tt.mockMetrics)
+			s.On("Ping", mock.Anything).Return(tt.mockErr)
 
 			// Call the method
-			result := s.GetMetrics(context.Background())
+			err := s.Ping(context.Background())
 
 			// Assert expectations
 			s.AssertExpectations(t)
 
 			// Check the result
-			assert.Equal(t, tt.mockMetrics, result)
+			assert.Equal(t, tt.mockErr, err)
 		})
 	}
 }